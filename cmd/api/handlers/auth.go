@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -44,3 +46,19 @@ func (ctx *Context) IsAuthenticated() gin.HandlerFunc {
 	}
 
 }
+
+// AdminRequired - checks `X-Admin-Token` header. Admin API is disabled if the token is not set in config.
+func (ctx *Context) AdminRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ctx.AdminToken == "" {
+			handleError(c, errors.New("Admin API is disabled"), http.StatusForbidden)
+			return
+		}
+		token := c.GetHeader("X-Admin-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(ctx.AdminToken)) != 1 {
+			handleError(c, errors.New("Invalid admin token"), http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GetContractBalanceHistory - returns balance of the contract at every level where it was changed
+func (ctx *Context) GetContractBalanceHistory(c *gin.Context) {
+	var req getContractRequest
+	if err := c.BindUri(&req); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	changes, err := ctx.ES.GetBalanceChanges(req.Network, req.Address)
+	if handleError(c, err, 0) {
+		return
+	}
+
+	c.JSON(http.StatusOK, prepareBalanceHistory(changes))
+}
+
+// prepareBalanceHistory - folds changes sorted by level into balance points. Withdrawn is the total amount sent by the contract.
+func prepareBalanceHistory(changes []models.BalanceChange) []BalanceHistoryItem {
+	history := make([]BalanceHistoryItem, 0)
+	var balance, withdrawn int64
+	for i := range changes {
+		balance += changes[i].Change
+		if changes[i].Kind == consts.Transaction && changes[i].Change < 0 {
+			withdrawn -= changes[i].Change
+		}
+
+		if len(history) > 0 && history[len(history)-1].Level == changes[i].Level {
+			history[len(history)-1].Balance = balance
+			history[len(history)-1].Withdrawn = withdrawn
+			continue
+		}
+		history = append(history, BalanceHistoryItem{
+			Level:     changes[i].Level,
+			Timestamp: changes[i].Timestamp,
+			Balance:   balance,
+			Withdrawn: withdrawn,
+		})
+	}
+	return history
+}
@@ -4,10 +4,13 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/contractparser/filter"
 	"github.com/baking-bad/bcdhub/internal/contractparser/meta"
 	"github.com/baking-bad/bcdhub/internal/contractparser/newmiguel"
 	"github.com/baking-bad/bcdhub/internal/contractparser/stringer"
 	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
 	"github.com/gin-gonic/gin"
 	"github.com/tidwall/gjson"
 )
@@ -63,6 +66,153 @@ func (ctx *Context) GetBigMapByKeyHash(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// GetBigMapKeys - returns keys present in the big map at `level` (the head by default). Keys can be filtered by typed conditions on value fields: `filter=balance:gt:100`
+func (ctx *Context) GetBigMapKeys(c *gin.Context) {
+	var req getBigMapRequest
+	if err := c.BindUri(&req); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	var keysReq bigMapKeysRequest
+	if err := c.BindQuery(&keysReq); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	var keys []elastic.BigMapDiff
+	var total int64
+	if len(keysReq.Filters) > 0 {
+		filters := make([]filter.Filter, len(keysReq.Filters))
+		for i := range keysReq.Filters {
+			f, err := filter.Parse(keysReq.Filters[i])
+			if handleError(c, err, http.StatusBadRequest) {
+				return
+			}
+			filters[i] = f
+		}
+
+		var err error
+		keys, total, err = ctx.filterBigMapKeys(req.Address, req.Ptr, keysReq, filters)
+		if _, ok := err.(invalidFilterError); ok {
+			handleError(c, err, http.StatusBadRequest)
+			return
+		}
+		if handleError(c, err, 0) {
+			return
+		}
+	} else {
+		var err error
+		total, err = ctx.ES.GetBigMapKeysCount(req.Address, req.Ptr, keysReq.Level)
+		if handleError(c, err, 0) {
+			return
+		}
+
+		keys, err = ctx.ES.GetBigMapKeys(elastic.GetBigMapKeysContext{
+			Address: req.Address,
+			Ptr:     req.Ptr,
+			Level:   keysReq.Level,
+			Size:    keysReq.Size,
+			Offset:  keysReq.Offset,
+		})
+		if handleError(c, err, 0) {
+			return
+		}
+	}
+
+	items, err := ctx.prepareBigMap(keys, req.Network, req.Address)
+	if handleError(c, err, 0) {
+		return
+	}
+
+	c.JSON(http.StatusOK, BigMapKeys{
+		Level: keysReq.Level,
+		Total: total,
+		Keys:  items,
+	})
+}
+
+// invalidFilterError - filter does not fit the big map value type
+type invalidFilterError struct {
+	error
+}
+
+// filterBigMapKeys - returns the page of keys which values match all `filters` and the total count of matched keys.
+// Values are decoded by metadata of the protocol the value was written in.
+func (ctx *Context) filterBigMapKeys(address string, ptr int64, req bigMapKeysRequest, filters []filter.Filter) ([]elastic.BigMapDiff, int64, error) {
+	contractMetadata, err := meta.GetContractMetadata(ctx.ES, address)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	matchers := make(map[string]*filter.Matcher)
+	return ctx.ES.FilterBigMapKeys(elastic.GetBigMapKeysContext{
+		Address: address,
+		Ptr:     ptr,
+		Level:   req.Level,
+		Size:    req.Size,
+		Offset:  req.Offset,
+	}, func(key elastic.BigMapDiff) (bool, error) {
+		matcherKey := key.Protocol + key.BinPath
+		matcher, ok := matchers[matcherKey]
+		if !ok {
+			metadata, err := contractMetadata.Get(consts.STORAGE, key.Protocol)
+			if err != nil {
+				return false, err
+			}
+			if matcher, err = filter.New(metadata, key.BinPath, filters); err != nil {
+				return false, invalidFilterError{err}
+			}
+			matchers[matcherKey] = matcher
+		}
+		return matcher.Match(gjson.Parse(key.Value))
+	})
+}
+
+// GetBigMapKeysCount - returns count of keys present in the big map at `level` (the head by default)
+func (ctx *Context) GetBigMapKeysCount(c *gin.Context) {
+	var req getBigMapRequest
+	if err := c.BindUri(&req); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	var levelReq levelRequest
+	if err := c.BindQuery(&levelReq); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	count, err := ctx.ES.GetBigMapKeysCount(req.Address, req.Ptr, levelReq.Level)
+	if handleError(c, err, 0) {
+		return
+	}
+
+	c.JSON(http.StatusOK, BigMapKeysCount{
+		Level: levelReq.Level,
+		Count: count,
+	})
+}
+
+// GetBigMapKeyHistory - returns all values of the key with operations which set them
+func (ctx *Context) GetBigMapKeyHistory(c *gin.Context) {
+	var req getBigMapByKeyHashRequest
+	if err := c.BindUri(&req); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	diffs, err := ctx.ES.GetBigMapKeyHistory(req.Address, req.Ptr, req.KeyHash)
+	if handleError(c, err, 0) {
+		return
+	}
+	if len(diffs) == 0 {
+		handleError(c, fmt.Errorf("Unknown key %s in big map %d", req.KeyHash, req.Ptr), http.StatusNotFound)
+		return
+	}
+
+	response, err := ctx.prepareBigMapKeyHistory(diffs, req.Address)
+	if handleError(c, err, 0) {
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
 func (ctx *Context) prepareBigMap(data []elastic.BigMapDiff, network, address string) (res []BigMapResponseItem, err error) {
 	contractMetadata, err := meta.GetContractMetadata(ctx.ES, address)
 	if err != nil {
@@ -169,3 +319,128 @@ func (ctx *Context) prepareBigMapItem(data []elastic.BigMapDiff, network, addres
 	res.Key = key
 	return
 }
+
+// prepareBigMapKeyHistory - diffs are sorted from the oldest to the newest. Every value is compared with the previous one.
+func (ctx *Context) prepareBigMapKeyHistory(diffs []elastic.BigMapDiff, address string) (res BigMapKeyHistory, err error) {
+	contractMetadata, err := meta.GetContractMetadata(ctx.ES, address)
+	if err != nil {
+		return
+	}
+
+	ids := make([]string, len(diffs))
+	for i := range diffs {
+		ids[i] = diffs[i].OperationID
+	}
+	operations := make([]models.Operation, 0)
+	if err = ctx.ES.GetByIDs(ids, &operations); err != nil {
+		return
+	}
+	operationsByID := make(map[string]models.Operation)
+	for i := range operations {
+		operationsByID[operations[i].ID] = operations[i]
+	}
+
+	res.KeyHash = diffs[0].KeyHash
+	res.Values = make([]BigMapHistoryItem, len(diffs))
+	var prev elastic.BigMapDiff
+	for i := range diffs {
+		var metadata meta.Metadata
+		metadata, err = contractMetadata.Get(consts.STORAGE, diffs[i].Protocol)
+		if err != nil {
+			return
+		}
+
+		if res.Key == nil && diffs[i].Key != "" {
+			res.Key, err = newmiguel.BigMapToMiguel(gjson.Parse(diffs[i].Key), diffs[i].BinPath+"/k", metadata)
+			if err != nil {
+				return
+			}
+		}
+
+		item := BigMapHistoryItem{
+			Level:       diffs[i].Level,
+			Timestamp:   diffs[i].Timestamp,
+			OperationID: diffs[i].OperationID,
+			Removed:     diffs[i].Value == "",
+		}
+		if op, ok := operationsByID[diffs[i].OperationID]; ok {
+			item.Hash = op.Hash
+			item.Counter = op.Counter
+			item.Entrypoint = op.Entrypoint
+			item.Source = op.Source
+		}
+
+		// nodes are decoded again on every comparison because `Diff` changes the previous node
+		var prevValue *newmiguel.Node
+		if i > 0 && prev.Value != "" {
+			prevValue, err = newmiguel.BigMapToMiguel(gjson.Parse(prev.Value), prev.BinPath+"/v", metadata)
+			if err != nil {
+				return
+			}
+		}
+
+		if diffs[i].Value != "" {
+			var value *newmiguel.Node
+			value, err = newmiguel.BigMapToMiguel(gjson.Parse(diffs[i].Value), diffs[i].BinPath+"/v", metadata)
+			if err != nil {
+				return
+			}
+			if value != nil {
+				value.Diff(prevValue)
+				item.Value = value
+			}
+		} else if prevValue != nil {
+			prevValue.MarkDeleted()
+			item.Value = prevValue
+		}
+
+		res.Values[len(diffs)-1-i] = item
+		prev = diffs[i]
+	}
+	return
+}
+
+// GetBigMapInfo - returns the registry entry of the big map by its pointer
+func (ctx *Context) GetBigMapInfo(c *gin.Context) {
+	var req getBigMapInfoRequest
+	if err := c.BindUri(&req); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	bigMap := models.BigMap{ID: models.BigMapID(req.Network, req.Ptr)}
+	if err := ctx.ES.GetByID(&bigMap); handleError(c, err, 0) {
+		return
+	}
+
+	var response BigMapInfo
+	response.FromModel(bigMap)
+
+	ids := make([]string, 0)
+	for _, id := range []string{bigMap.OperationID, bigMap.RemovedOperationID} {
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > 0 {
+		operations := make([]models.Operation, 0)
+		if err := ctx.ES.GetByIDs(ids, &operations); handleError(c, err, 0) {
+			return
+		}
+		for i := range operations {
+			if operations[i].ID == bigMap.OperationID {
+				response.Hash = operations[i].Hash
+			}
+			if operations[i].ID == bigMap.RemovedOperationID {
+				response.RemovedHash = operations[i].Hash
+			}
+		}
+	}
+
+	activeKeys, err := ctx.ES.GetBigMapKeysCount(bigMap.Address, bigMap.Ptr, 0)
+	if handleError(c, err, 0) {
+		return
+	}
+	response.ActiveKeys = activeKeys
+
+	c.JSON(http.StatusOK, response)
+}
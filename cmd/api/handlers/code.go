@@ -36,13 +36,16 @@ func (ctx *Context) GetContractCode(c *gin.Context) {
 		return
 	}
 
-	collapsed, err := macros.Collapse(code)
+	if req.Expanded {
+		code, err = macros.Expand(code)
+	} else {
+		code, err = macros.Collapse(code)
+	}
 	if err != nil {
 		logger.Error(err)
-		collapsed = code
 	}
 
-	resp, err := formatter.MichelineToMichelson(collapsed, false, formatter.DefLineSize)
+	resp, err := formatter.MichelineToMichelson(code, false, formatter.DefLineSize)
 	if handleError(c, err, 0) {
 		return
 	}
@@ -50,12 +53,18 @@ func (ctx *Context) GetContractCode(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
-// GetDiff -
+// GetDiff - every side of the diff is a contract by network and address or a script passed in `code`
 func (ctx *Context) GetDiff(c *gin.Context) {
 	var req CodeDiffRequest
 	if err := c.BindJSON(&req); handleError(c, err, http.StatusBadRequest) {
 		return
 	}
+	for _, leg := range []CodeDiffLeg{req.Left, req.Right} {
+		if len(leg.Code) == 0 && (leg.Address == "" || leg.Network == "") {
+			handleError(c, fmt.Errorf("Invalid diff side: network and address or code are required"), http.StatusBadRequest)
+			return
+		}
+	}
 
 	resp, err := ctx.getContractCodeDiff(req.Left, req.Right)
 	if handleError(c, err, 0) {
@@ -88,6 +97,16 @@ func (ctx *Context) getContractCodeDiff(left, right CodeDiffLeg) (res CodeDiffRe
 	sides := make([]gjson.Result, 2)
 
 	for i, leg := range []*CodeDiffLeg{&left, &right} {
+		if len(leg.Code) > 0 {
+			code, err := parseCode(leg.Code)
+			if err != nil {
+				return res, err
+			}
+			if sides[i], err = macros.Collapse(code); err != nil {
+				return res, err
+			}
+			continue
+		}
 		if leg.Protocol == "" {
 			protocol, ok := currentProtocols[leg.Network]
 			if !ok {
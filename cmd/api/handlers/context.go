@@ -8,7 +8,8 @@ import (
 // Context -
 type Context struct {
 	*config.Context
-	OAUTH oauth.Config
+	OAUTH      oauth.Config
+	AdminToken string
 }
 
 // NewContext -
@@ -24,6 +25,7 @@ func NewContext(cfg config.Config) (*Context, error) {
 
 	ctx := config.NewContext(
 		config.WithElasticSearch(cfg.Elastic),
+		config.WithStorage(cfg.Storage),
 		config.WithRPC(cfg.RPC),
 		config.WithDatabase(cfg.DB),
 		config.WithShare(cfg.Share.Path),
@@ -31,7 +33,8 @@ func NewContext(cfg config.Config) (*Context, error) {
 		config.WithLoadErrorDescriptions("data/errors.json"),
 	)
 	return &Context{
-		Context: ctx,
-		OAUTH:   oauthCfg,
+		Context:    ctx,
+		OAUTH:      oauthCfg,
+		AdminToken: cfg.API.Admin.Token,
 	}, nil
 }
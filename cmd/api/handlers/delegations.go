@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GetDelegations - returns delegation history of the account starting from the latest one
+func (ctx *Context) GetDelegations(c *gin.Context) {
+	var req getContractRequest
+	if err := c.BindUri(&req); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	var pageReq pageableRequest
+	if err := c.BindQuery(&pageReq); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	delegations, err := ctx.ES.GetDelegations(req.Network, req.Address, pageReq.Size, pageReq.Offset)
+	if handleError(c, err, 0) {
+		return
+	}
+
+	c.JSON(http.StatusOK, prepareDelegations(delegations))
+}
+
+// GetReveal - returns reveal of the public key of the account
+func (ctx *Context) GetReveal(c *gin.Context) {
+	var req getContractRequest
+	if err := c.BindUri(&req); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	reveal, err := ctx.ES.GetReveal(req.Network, req.Address)
+	if handleError(c, err, 0) {
+		return
+	}
+
+	c.JSON(http.StatusOK, Reveal{
+		Level:     reveal.Level,
+		Timestamp: reveal.Timestamp,
+		Hash:      reveal.Hash,
+		Counter:   reveal.Counter,
+		Status:    reveal.Status,
+		Source:    reveal.Source,
+		PublicKey: reveal.PublicKey,
+		Fee:       reveal.Fee,
+	})
+}
+
+func prepareDelegations(data []models.Delegation) []Delegation {
+	result := make([]Delegation, len(data))
+	for i := range data {
+		result[i] = Delegation{
+			Level:         data[i].Level,
+			Timestamp:     data[i].Timestamp,
+			Hash:          data[i].Hash,
+			Counter:       data[i].Counter,
+			Internal:      data[i].Internal,
+			InternalIndex: data[i].InternalIndex,
+			Kind:          data[i].Kind,
+			Status:        data[i].Status,
+			Source:        data[i].Source,
+			Delegate:      data[i].Delegate,
+			Fee:           data[i].Fee,
+		}
+	}
+	return result
+}
@@ -11,9 +11,10 @@ import (
 	"github.com/baking-bad/bcdhub/internal/jsonschema"
 	"github.com/gin-gonic/gin"
 	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
 )
 
-func getParameterMetadata(es *elastic.Elastic, address, network string) (meta.Metadata, error) {
+func getParameterMetadata(es elastic.IElastic, address, network string) (meta.Metadata, error) {
 	state, err := es.CurrentState(network)
 	if err != nil {
 		return nil, err
@@ -39,21 +40,29 @@ func (ctx *Context) GetEntrypoints(c *gin.Context) {
 		return
 	}
 
-	entrypoints, err := docstring.GetEntrypoints(metadata)
+	resp, err := getEntrypointsSchema(metadata)
 	if handleError(c, err, 0) {
 		return
 	}
 
+	c.JSON(http.StatusOK, resp)
+}
+
+func getEntrypointsSchema(metadata meta.Metadata) ([]EntrypointSchema, error) {
+	entrypoints, err := docstring.GetEntrypoints(metadata)
+	if err != nil {
+		return nil, err
+	}
+
 	resp := make([]EntrypointSchema, len(entrypoints))
 	for i, entrypoint := range entrypoints {
 		resp[i].EntrypointType = entrypoint
 		resp[i].Schema, resp[i].DefaultModel, err = jsonschema.Create(entrypoint.BinPath, metadata)
-		if handleError(c, err, 0) {
-			return
+		if err != nil {
+			return nil, err
 		}
 	}
-
-	c.JSON(http.StatusOK, resp)
+	return resp, nil
 }
 
 // GetEntrypointData - returns entrypoint data from schema object
@@ -93,3 +102,27 @@ func (ctx *Context) buildEntrypointMicheline(network, address, binPath string, d
 
 	return metadata.BuildEntrypointMicheline(binPath, data)
 }
+
+// buildCallMicheline - parameter passed as Micheline or as Michelson text string is sent to `entrypoint` as is,
+// otherwise the parameter is built from `data` form by `bin_path`
+func (ctx *Context) buildCallMicheline(network, address string, req runCodeRequest) (gjson.Result, error) {
+	if len(req.Parameter) == 0 {
+		return ctx.buildEntrypointMicheline(network, address, req.BinPath, req.Data)
+	}
+	value, err := parseCode(req.Parameter)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	entrypoint := req.Entrypoint
+	if entrypoint == "" {
+		entrypoint = "default"
+	}
+	input, err := sjson.Set("{}", "entrypoint", entrypoint)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	if input, err = sjson.SetRaw(input, "value", value.Raw); err != nil {
+		return gjson.Result{}, err
+	}
+	return gjson.Parse(input), nil
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/baking-bad/bcdhub/internal/contractparser/formatter"
 	"github.com/baking-bad/bcdhub/internal/elastic"
 	"github.com/gin-gonic/gin"
 )
@@ -27,5 +28,9 @@ func getErrorCode(err error) int {
 	if strings.Contains(err.Error(), elastic.RecordNotFound) {
 		return http.StatusNotFound
 	}
+	var parseErr *formatter.ParseError
+	if errors.As(err, &parseErr) {
+		return http.StatusBadRequest
+	}
 	return http.StatusInternalServerError
 }
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/baking-bad/bcdhub/internal/noderpc"
+	"github.com/gin-gonic/gin"
+)
+
+// GetRPCHealth - returns state of RPC nodes for each network
+func (ctx *Context) GetRPCHealth(c *gin.Context) {
+	health := make(map[string][]noderpc.NodeHealth, len(ctx.RPC))
+	for network, rpc := range ctx.RPC {
+		health[network] = rpc.Health()
+	}
+	c.JSON(http.StatusOK, health)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser"
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/logger"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// ListInterfaces - returns all versions of user-defined interfaces
+func (ctx *Context) ListInterfaces(c *gin.Context) {
+	items, err := ctx.getInterfaces()
+	if handleError(c, err, 0) {
+		return
+	}
+
+	response := make([]Interface, len(items))
+	for i := range items {
+		response[i].FromModel(items[i])
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// AddInterface - saves new version of the interface. Indexers pick it up and re-tag existing contracts in background.
+// Version is created only once, so concurrent requests adding the same version get conflict and have to be retried.
+func (ctx *Context) AddInterface(c *gin.Context) {
+	var req addInterfaceRequest
+	if err := c.BindJSON(&req); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	items, err := ctx.getInterfaces()
+	if handleError(c, err, 0) {
+		return
+	}
+	var version int64
+	for i := range items {
+		if items[i].Name == req.Name && items[i].Version > version {
+			version = items[i].Version
+		}
+	}
+	version++
+
+	iface, err := contractparser.NewInterface(req.Name, version, gjson.ParseBytes(req.Entrypoints))
+	if handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	item := models.NewInterface(req.Name, version, string(req.Entrypoints))
+	if err := ctx.ES.CreateDocument(item); err != nil {
+		if elastic.IsDocumentExists(err) {
+			handleError(c, fmt.Errorf("Version %d of interface %s is already added", version, req.Name), http.StatusConflict)
+			return
+		}
+		handleError(c, err, 0)
+		return
+	}
+	contractparser.RegisterInterface(iface)
+
+	var response Interface
+	response.FromModel(*item)
+	c.JSON(http.StatusCreated, response)
+}
+
+// RegisterInterfaces - adds stored interfaces to the registry used for tagging of contracts by API
+func (ctx *Context) RegisterInterfaces() error {
+	items, err := ctx.getInterfaces()
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		iface, err := contractparser.NewInterface(items[i].Name, items[i].Version, gjson.Parse(items[i].Entrypoints))
+		if err != nil {
+			logger.Errorf("Interface %s version %d: %s", items[i].Name, items[i].Version, err)
+			continue
+		}
+		contractparser.RegisterInterface(iface)
+	}
+	return nil
+}
+
+func (ctx *Context) getInterfaces() ([]models.Interface, error) {
+	var items []models.Interface
+	if err := ctx.ES.GetAll(&items); err != nil {
+		return nil, err
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Name == items[j].Name {
+			return items[i].Version < items[j].Version
+		}
+		return items[i].Name < items[j].Name
+	})
+	return items, nil
+}
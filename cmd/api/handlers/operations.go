@@ -153,7 +153,7 @@ func formatErrors(errs []cerrors.IError, op *Operation) error {
 	return nil
 }
 
-func prepareOperation(es *elastic.Elastic, operation models.Operation) (Operation, error) {
+func prepareOperation(es elastic.IElastic, operation models.Operation) (Operation, error) {
 	op := Operation{
 		ID:        operation.ID,
 		Protocol:  operation.Protocol,
@@ -207,7 +207,7 @@ func prepareOperation(es *elastic.Elastic, operation models.Operation) (Operatio
 	return op, nil
 }
 
-func prepareOperations(es *elastic.Elastic, ops []models.Operation) ([]Operation, error) {
+func prepareOperations(es elastic.IElastic, ops []models.Operation) ([]Operation, error) {
 	resp := make([]Operation, len(ops))
 	for i := 0; i < len(ops); i++ {
 		op, err := prepareOperation(es, ops[i])
@@ -219,7 +219,7 @@ func prepareOperations(es *elastic.Elastic, ops []models.Operation) ([]Operation
 	return resp, nil
 }
 
-func setParameters(es *elastic.Elastic, parameters string, op *Operation) error {
+func setParameters(es elastic.IElastic, parameters string, op *Operation) error {
 	metadata, err := meta.GetMetadata(es, op.Destination, consts.PARAMETER, op.Protocol)
 	if err != nil {
 		return nil
@@ -236,7 +236,7 @@ func setParameters(es *elastic.Elastic, parameters string, op *Operation) error
 	return nil
 }
 
-func setStorageDiff(es *elastic.Elastic, address, network, storage string, op *Operation) error {
+func setStorageDiff(es elastic.IElastic, address, network, storage string, op *Operation) error {
 	metadata, err := meta.GetContractMetadata(es, address)
 	if err != nil {
 		return err
@@ -310,7 +310,7 @@ func enrichStorage(s string, bmd []models.BigMapDiff, protocol string, skipEmpty
 	return parser.Enrich(s, bmd, skipEmpty)
 }
 
-func getPrevBmd(es *elastic.Elastic, bmd []models.BigMapDiff, indexedTime int64, address string) ([]models.BigMapDiff, error) {
+func getPrevBmd(es elastic.IElastic, bmd []models.BigMapDiff, indexedTime int64, address string) ([]models.BigMapDiff, error) {
 	return es.GetPrevBigMapDiffs(bmd, indexedTime, address)
 }
 
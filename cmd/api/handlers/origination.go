@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser"
+	"github.com/baking-bad/bcdhub/internal/contractparser/cerrors"
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/contractparser/formatter"
+	"github.com/baking-bad/bcdhub/internal/contractparser/macros"
+	"github.com/baking-bad/bcdhub/internal/contractparser/meta"
+	"github.com/baking-bad/bcdhub/internal/contractparser/pack"
+	"github.com/baking-bad/bcdhub/internal/jsonschema"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// SimulateOrigination - type checks the script and the initial storage on the node before deployment.
+// Code is passed as Micheline or as Michelson text string. Storage is passed as Micheline or as `storage_data` form
+// built by the storage schema, missing fields take default values.
+func (ctx *Context) SimulateOrigination(c *gin.Context) {
+	var req getByNetwork
+	if err := c.BindUri(&req); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+	var reqOrigination originationSimulationRequest
+	if err := c.BindJSON(&reqOrigination); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	rpc, err := ctx.GetRPC(req.Network)
+	if handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	code, err := parseCode(reqOrigination.Code)
+	if handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+	if len(code.Array()) != 3 {
+		handleError(c, fmt.Errorf("Invalid script code: expected parameter, storage and code sections"), http.StatusBadRequest)
+		return
+	}
+	storageType := code.Get(fmt.Sprintf("#(prim==\"%s\").args", consts.STORAGE))
+	parameterType := code.Get(fmt.Sprintf("#(prim==\"%s\").args", consts.PARAMETER))
+	if !storageType.Exists() || !parameterType.Exists() {
+		handleError(c, fmt.Errorf("Invalid script code: parameter or storage section is missing"), http.StatusBadRequest)
+		return
+	}
+
+	storage, err := buildInitialStorage(storageType, reqOrigination)
+	if handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	script, err := contractparser.New(gjson.Parse(fmt.Sprintf(`{"code":%s,"storage":%s}`, code.Raw, storage.Raw)))
+	if handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+	script.Parse()
+
+	language, err := script.Language()
+	if handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	parameterMetadata, err := meta.ParseMetadata(parameterType)
+	if handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+	entrypoints, err := getEntrypointsSchema(parameterMetadata)
+	if handleError(c, err, 0) {
+		return
+	}
+
+	constants, err := rpc.GetNetworkConstants()
+	if handleError(c, err, 0) {
+		return
+	}
+	gasLimit := reqOrigination.GasLimit
+	if gasLimit == 0 {
+		gasLimit = constants.Get("hard_gas_limit_per_operation").Int()
+	}
+
+	response := OriginationSimulation{
+		Status:             consts.Applied,
+		Storage:            storage.Value(),
+		Language:           language,
+		Tags:               script.Tags.Values(),
+		Annotations:        script.Annotations.Values(),
+		HardcodedAddresses: script.HardcodedAddresses.Values(),
+		Entrypoints:        entrypoints,
+	}
+
+	codeResponse, err := rpc.TypeCheckCode(code, gasLimit)
+	if handleError(c, err, 0) {
+		return
+	}
+	ok, err := setTypeCheckResult(codeResponse, gasLimit, &response)
+	if handleError(c, err, 0) {
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	dataResponse, err := rpc.TypeCheckData(storage, storageType.Get("0"), gasLimit)
+	if handleError(c, err, 0) {
+		return
+	}
+	ok, err = setTypeCheckResult(dataResponse, gasLimit, &response)
+	if handleError(c, err, 0) {
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	codeBytes, err := pack.Micheline(code)
+	if handleError(c, err, 0) {
+		return
+	}
+	storageBytes, err := pack.Micheline(storage)
+	if handleError(c, err, 0) {
+		return
+	}
+	// script is serialized as two length-prefixed byte sequences: code and storage
+	response.StorageSize = int64(len(codeBytes) + len(storageBytes) + 8)
+	response.StorageBurn = (response.StorageSize + constants.Get("origination_size").Int()) * constants.Get("cost_per_byte").Int()
+
+	c.JSON(http.StatusOK, response)
+}
+
+// setTypeCheckResult - adds gas consumed by the type checking to the response. Returns false if the node rejected the script.
+func setTypeCheckResult(result gjson.Result, gasLimit int64, response *OriginationSimulation) (bool, error) {
+	if result.IsArray() {
+		response.Status = consts.Failed
+		response.Errors = cerrors.ParseArray(result)
+		for i := range response.Errors {
+			if err := response.Errors[i].Format(); err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+	}
+	if !result.IsObject() {
+		return false, fmt.Errorf("Unknown response: %v", result.Value())
+	}
+
+	if gas := result.Get("gas"); gas.Exists() && gas.String() != "unaccounted" {
+		response.ConsumedGas += gasLimit - gas.Int()
+	}
+	return true, nil
+}
+
+func buildInitialStorage(storageType gjson.Result, req originationSimulationRequest) (gjson.Result, error) {
+	if len(req.Storage) > 0 {
+		storage := gjson.ParseBytes(req.Storage)
+		if !storage.IsObject() && !storage.IsArray() {
+			return gjson.Result{}, fmt.Errorf("Invalid storage: Micheline expected")
+		}
+		return storage, nil
+	}
+
+	metadata, err := meta.ParseMetadata(storageType)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	_, model, err := jsonschema.Create("0", metadata)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+
+	data := make(map[string]interface{})
+	for binPath, nm := range metadata {
+		if nm.Prim == consts.BIGMAP { // new contract can not refer to existing big map
+			data[binPath] = []interface{}{}
+		}
+	}
+	for k, v := range model {
+		data[k] = v
+	}
+	for k, v := range req.StorageData {
+		if _, isNumber := v.(float64); isNumber && metadata[k] != nil && metadata[k].Prim == consts.BIGMAP {
+			return gjson.Result{}, fmt.Errorf("Big map %s has to be passed as the list of items", k)
+		}
+		data[k] = v
+	}
+	return metadata.BuildMicheline("0", data)
+}
+
+// parseCode - JSON string is treated as Michelson source, any other JSON as Micheline
+func parseCode(raw []byte) (gjson.Result, error) {
+	code := gjson.ParseBytes(raw)
+	if code.Type != gjson.String {
+		return code, nil
+	}
+	micheline, err := formatter.MichelsonToMicheline(code.String())
+	if err != nil {
+		return micheline, err
+	}
+	return macros.Expand(micheline)
+}
@@ -1,5 +1,14 @@
 package handlers
 
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+)
+
 type aliasRequest struct {
 	Address string `form:"address" binding:"required,address"`
 	Network string `form:"network" binding:"required,network"`
@@ -11,14 +20,16 @@ type getContractCodeRequest struct {
 	Network  string `uri:"network" binding:"required,network"`
 	Protocol string `form:"protocol,omitempty"`
 	Level    int64  `form:"level,omitempty"`
+	Expanded bool   `form:"expanded,omitempty"`
 }
 
-// CodeDiffLeg -
+// CodeDiffLeg - contract by network and address or script passed as Micheline or as Michelson text string
 type CodeDiffLeg struct {
-	Address  string `json:"address" binding:"required,address"`
-	Network  string `json:"network" binding:"required,network"`
-	Protocol string `json:"protocol,omitempty"`
-	Level    int64  `json:"level,omitempty"`
+	Address  string          `json:"address" binding:"omitempty,address"`
+	Network  string          `json:"network" binding:"omitempty,network"`
+	Protocol string          `json:"protocol,omitempty"`
+	Level    int64           `json:"level,omitempty"`
+	Code     json.RawMessage `json:"code,omitempty"`
 }
 
 // CodeDiffRequest -
@@ -32,12 +43,34 @@ type getContractRequest struct {
 	Network string `uri:"network" binding:"required,network"`
 }
 
+type getTokenBalanceRequest struct {
+	Address string `uri:"address" binding:"required,address"`
+	Network string `uri:"network" binding:"required,network"`
+	Holder  string `uri:"holder" binding:"required,address"`
+}
+
+type levelRequest struct {
+	Level int64 `form:"level" binding:"min=0"`
+}
+
 type getBigMapRequest struct {
 	Address string `uri:"address" binding:"required,address"`
 	Network string `uri:"network" binding:"required,network"`
 	Ptr     int64  `uri:"ptr" binding:"min=0"`
 }
 
+type getBigMapInfoRequest struct {
+	Network string `uri:"network" binding:"required,network"`
+	Ptr     int64  `uri:"ptr" binding:"min=0"`
+}
+
+type bigMapKeysRequest struct {
+	Level   int64    `form:"level" binding:"min=0"`
+	Offset  int64    `form:"offset" binding:"min=0"`
+	Size    int64    `form:"size" binding:"min=0,max=1000"`
+	Filters []string `form:"filter"`
+}
+
 type getBigMapByKeyHashRequest struct {
 	Address string `uri:"address" binding:"required,address"`
 	Network string `uri:"network" binding:"required,network"`
@@ -65,6 +98,46 @@ type operationsRequest struct {
 	Entrypoints string `form:"entrypoints" binding:"omitempty,excludesall=\"'"`
 }
 
+type transfersRequest struct {
+	LastID    string `form:"last_id" binding:"omitempty"`
+	Size      int64  `form:"size" binding:"min=0,max=10000"`
+	Start     uint   `form:"start" binding:"omitempty"`
+	End       uint   `form:"end" binding:"omitempty,gtfield=Start"`
+	Contracts string `form:"contracts" binding:"omitempty"`
+	Sender    string `form:"sender" binding:"omitempty,address"`
+	Receiver  string `form:"receiver" binding:"omitempty,address"`
+	MinAmount string `form:"min_amount" binding:"omitempty,numeric"`
+	Format    string `form:"format" binding:"omitempty,oneof=json csv"`
+}
+
+// context - `Contracts` is a comma-separated list of token addresses
+func (req transfersRequest) context(network string) (elastic.GetTransfersContext, error) {
+	ctx := elastic.GetTransfersContext{
+		Network:   network,
+		Sender:    req.Sender,
+		Receiver:  req.Receiver,
+		Start:     int64(req.Start),
+		End:       int64(req.End),
+		MinAmount: req.MinAmount,
+		LastID:    req.LastID,
+		Size:      req.Size,
+	}
+	if req.LastID != "" {
+		if _, err := elastic.ParseTransferCursor(req.LastID); err != nil {
+			return ctx, err
+		}
+	}
+	if req.MinAmount != "" {
+		if amount, ok := new(big.Int).SetString(req.MinAmount, 10); !ok || amount.Sign() < 0 {
+			return ctx, fmt.Errorf("Invalid min amount: %s", req.MinAmount)
+		}
+	}
+	if req.Contracts != "" {
+		ctx.Contracts = strings.Split(req.Contracts, ",")
+	}
+	return ctx, nil
+}
+
 type pageableRequest struct {
 	Offset int64 `form:"offset" binding:"min=0"`
 	Size   int64 `form:"size" binding:"min=0"`
@@ -142,10 +215,58 @@ type getOperationByIDRequest struct {
 }
 
 type runCodeRequest struct {
-	Data     map[string]interface{} `json:"data" binding:"required"`
-	BinPath  string                 `json:"bin_path" binding:"required"`
-	Amount   int64                  `json:"amount,omitempty"`
-	GasLimit int64                  `json:"gas_limit,omitempty"`
-	Source   string                 `json:"source,omitempty" binding:"omitempty,address"`
-	Sender   string                 `json:"sender,omitempty" binding:"omitempty,address"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	BinPath    string                 `json:"bin_path,omitempty"`
+	Parameter  json.RawMessage        `json:"parameter,omitempty"`
+	Entrypoint string                 `json:"entrypoint,omitempty"`
+	Amount     int64                  `json:"amount,omitempty"`
+	GasLimit   int64                  `json:"gas_limit,omitempty"`
+	Source     string                 `json:"source,omitempty" binding:"omitempty,address"`
+	Sender     string                 `json:"sender,omitempty" binding:"omitempty,address"`
+}
+
+func (req runCodeRequest) validate() error {
+	if len(req.Parameter) == 0 && (req.Data == nil || req.BinPath == "") {
+		return fmt.Errorf("Invalid call: data and bin_path or parameter are required")
+	}
+	return nil
+}
+
+type simulationCall struct {
+	Address string `json:"address" binding:"required,address"`
+	runCodeRequest
+}
+
+type simulationRequest struct {
+	Calls []simulationCall `json:"calls" binding:"required,min=1,max=20,dive"`
+}
+
+// validate - balances are not carried between calls, so the contract receiving tez can't be called again
+func (req simulationRequest) validate() error {
+	for i := range req.Calls {
+		if err := req.Calls[i].validate(); err != nil {
+			return err
+		}
+		if req.Calls[i].Amount == 0 {
+			continue
+		}
+		for j := i + 1; j < len(req.Calls); j++ {
+			if req.Calls[j].Address == req.Calls[i].Address {
+				return fmt.Errorf("Call %d transfers tez to %s which is called again: simulation doesn't carry balances between calls", i, req.Calls[i].Address)
+			}
+		}
+	}
+	return nil
+}
+
+type originationSimulationRequest struct {
+	Code        json.RawMessage        `json:"code" binding:"required"`
+	Storage     json.RawMessage        `json:"storage,omitempty"`
+	StorageData map[string]interface{} `json:"storage_data,omitempty"`
+	GasLimit    int64                  `json:"gas_limit,omitempty"`
+}
+
+type addInterfaceRequest struct {
+	Name        string          `json:"name" binding:"required"`
+	Entrypoints json.RawMessage `json:"entrypoints" binding:"required"`
 }
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/baking-bad/bcdhub/internal/contractparser/cerrors"
@@ -130,6 +131,41 @@ type Migration struct {
 	Kind         string    `json:"kind"`
 }
 
+// Delegation -
+type Delegation struct {
+	Level         int64     `json:"level"`
+	Timestamp     time.Time `json:"timestamp"`
+	Hash          string    `json:"hash"`
+	Counter       int64     `json:"counter"`
+	Internal      bool      `json:"internal"`
+	InternalIndex int64     `json:"internal_index,omitempty"`
+	Kind          string    `json:"kind"`
+	Status        string    `json:"status"`
+	Source        string    `json:"source"`
+	Delegate      string    `json:"delegate,omitempty"`
+	Fee           int64     `json:"fee,omitempty"`
+}
+
+// Reveal -
+type Reveal struct {
+	Level     int64     `json:"level"`
+	Timestamp time.Time `json:"timestamp"`
+	Hash      string    `json:"hash"`
+	Counter   int64     `json:"counter"`
+	Status    string    `json:"status"`
+	Source    string    `json:"source"`
+	PublicKey string    `json:"public_key"`
+	Fee       int64     `json:"fee,omitempty"`
+}
+
+// BalanceHistoryItem -
+type BalanceHistoryItem struct {
+	Level     int64     `json:"level"`
+	Timestamp time.Time `json:"timestamp"`
+	Balance   int64     `json:"balance"`
+	Withdrawn int64     `json:"withdrawn"`
+}
+
 // TokenContract -
 type TokenContract struct {
 	Network       string    `json:"network"`
@@ -143,6 +179,9 @@ type TokenContract struct {
 	Type          string    `json:"type"`
 	Balance       int64     `json:"balance"`
 	TxCount       int64     `json:"tx_count,omitempty"`
+	Name          string    `json:"name,omitempty"`
+	Symbol        string    `json:"symbol,omitempty"`
+	Decimals      *int64    `json:"decimals,omitempty"`
 }
 
 // TokenTransfer -
@@ -157,16 +196,47 @@ type TokenTransfer struct {
 	Level     int64     `json:"level"`
 	From      string    `json:"from,omitempty"`
 	To        string    `json:"to"`
-	Amount    int64     `json:"amount"`
+	TokenID   string    `json:"token_id"`
+	Amount    string    `json:"amount"`
 	Source    string    `json:"source"`
 }
 
+// FromModel -
+func (t *TokenTransfer) FromModel(model models.Transfer) {
+	t.Contract = model.Contract
+	t.Network = model.Network
+	t.Protocol = model.Protocol
+	t.Hash = model.Hash
+	t.Counter = model.Counter
+	t.Status = model.Status
+	t.Timestamp = model.Timestamp
+	t.Level = model.Level
+	t.From = model.From
+	t.To = model.To
+	t.TokenID = model.TokenID
+	t.Amount = model.Amount
+	t.Source = model.Source
+}
+
 // PageableTokenTransfers -
 type PageableTokenTransfers struct {
 	Transfers []TokenTransfer `json:"transfers"`
 	LastID    string          `json:"last_id"`
 }
 
+// TokenHolders -
+type TokenHolders struct {
+	TotalSupply string                `json:"total_supply"`
+	Holders     []models.TokenBalance `json:"holders"`
+}
+
+// TokenBalance -
+type TokenBalance struct {
+	Address string `json:"address"`
+	Balance string `json:"balance"`
+	Level   int64  `json:"level,omitempty"`
+}
+
 // BigMapDiffItem -
 type BigMapDiffItem struct {
 	Value     interface{} `json:"value"`
@@ -182,6 +252,143 @@ type BigMapDiffByKeyResponse struct {
 	Total   int64            `json:"total"`
 }
 
+// SimulationStep - result of the call in the simulated batch: the call itself with storage diff and emitted internal operations
+type SimulationStep struct {
+	Operations  []Operation `json:"operations"`
+	ConsumedGas int64       `json:"consumed_gas"`
+}
+
+// SimulationResponse -
+type SimulationResponse struct {
+	Status      string           `json:"status"`
+	ConsumedGas int64            `json:"consumed_gas"`
+	Steps       []SimulationStep `json:"steps"`
+}
+
+// OriginationSimulation - result of type checking of the script before deployment. Storage is Micheline.
+type OriginationSimulation struct {
+	Status             string             `json:"status"`
+	Errors             []cerrors.IError   `json:"errors,omitempty"`
+	ConsumedGas        int64              `json:"consumed_gas"`
+	StorageSize        int64              `json:"storage_size"`
+	StorageBurn        int64              `json:"storage_burn"`
+	Storage            interface{}        `json:"storage"`
+	Language           string             `json:"language"`
+	Tags               []string           `json:"tags"`
+	Annotations        []string           `json:"annotations"`
+	HardcodedAddresses []string           `json:"hardcoded_addresses"`
+	Entrypoints        []EntrypointSchema `json:"entrypoints"`
+}
+
+// ExecutionTrace - result of the local execution of the entrypoint call
+type ExecutionTrace struct {
+	Status      string          `json:"status"`
+	Error       string          `json:"error,omitempty"`
+	ConsumedGas int64           `json:"consumed_gas"`
+	Steps       []ExecutionStep `json:"steps"`
+	Truncated   bool            `json:"truncated,omitempty"`
+	Expanded    bool            `json:"expanded"`
+}
+
+// ExecutionStep - executed instruction. Row is 1-based row of `MichelineToMichelson` output of the code with expanded macros,
+// it is 0 for instructions of lambdas passed as data.
+type ExecutionStep struct {
+	Location    int         `json:"location"`
+	Prim        string      `json:"prim"`
+	Gas         int64       `json:"gas"`
+	Row         int         `json:"row,omitempty"`
+	StartColumn int         `json:"start_col,omitempty"`
+	EndColumn   int         `json:"end_col,omitempty"`
+	Before      []StackItem `json:"before"`
+	After       []StackItem `json:"after"`
+}
+
+// StackItem - stack value rendered by miguel or Micheline if it can not be rendered. Stack is top first.
+type StackItem struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// BigMapInfo - registry entry of the big map. Key and value types are Micheline.
+type BigMapInfo struct {
+	Network     string          `json:"network"`
+	Ptr         int64           `json:"ptr"`
+	Address     string          `json:"address"`
+	BinPath     string          `json:"bin_path,omitempty"`
+	KeyType     json.RawMessage `json:"key_type,omitempty"`
+	ValueType   json.RawMessage `json:"value_type,omitempty"`
+	Level       int64           `json:"level,omitempty"`
+	Timestamp   *time.Time      `json:"timestamp,omitempty"`
+	OperationID string          `json:"operation_id,omitempty"`
+	Hash        string          `json:"hash,omitempty"`
+	SourcePtr   *int64          `json:"source_ptr,omitempty"`
+	Temporary   int64           `json:"temporary,omitempty"`
+	ActiveKeys  int64           `json:"active_keys"`
+
+	Removed            bool   `json:"removed"`
+	RemovedLevel       int64  `json:"removed_level,omitempty"`
+	RemovedOperationID string `json:"removed_operation_id,omitempty"`
+	RemovedHash        string `json:"removed_hash,omitempty"`
+}
+
+// FromModel -
+func (b *BigMapInfo) FromModel(bigMap models.BigMap) {
+	b.Network = bigMap.Network
+	b.Ptr = bigMap.Ptr
+	b.Address = bigMap.Address
+	b.BinPath = bigMap.BinPath
+	if bigMap.KeyType != "" {
+		b.KeyType = json.RawMessage(bigMap.KeyType)
+	}
+	if bigMap.ValueType != "" {
+		b.ValueType = json.RawMessage(bigMap.ValueType)
+	}
+	b.Level = bigMap.Level
+	if bigMap.OperationID != "" {
+		timestamp := bigMap.Timestamp
+		b.Timestamp = &timestamp
+	}
+	b.OperationID = bigMap.OperationID
+	b.SourcePtr = bigMap.SourcePtr
+	b.Temporary = bigMap.Temporary
+	b.Removed = bigMap.IsRemoved()
+	b.RemovedLevel = bigMap.RemovedLevel
+	b.RemovedOperationID = bigMap.RemovedOperationID
+}
+
+// BigMapKeys - keys present in the big map at `Level`
+type BigMapKeys struct {
+	Level int64                `json:"level,omitempty"`
+	Total int64                `json:"total"`
+	Keys  []BigMapResponseItem `json:"keys"`
+}
+
+// BigMapKeysCount -
+type BigMapKeysCount struct {
+	Level int64 `json:"level,omitempty"`
+	Count int64 `json:"count"`
+}
+
+// BigMapHistoryItem - value of the key after the operation. Value of the removed key is the previous value marked as deleted.
+type BigMapHistoryItem struct {
+	Value       interface{} `json:"value,omitempty"`
+	Removed     bool        `json:"removed,omitempty"`
+	Level       int64       `json:"level"`
+	Timestamp   time.Time   `json:"timestamp"`
+	OperationID string      `json:"operation_id"`
+	Hash        string      `json:"hash,omitempty"`
+	Counter     int64       `json:"counter,omitempty"`
+	Entrypoint  string      `json:"entrypoint,omitempty"`
+	Source      string      `json:"source,omitempty"`
+}
+
+// BigMapKeyHistory - changes of the key from the newest to the oldest
+type BigMapKeyHistory struct {
+	Key     interface{}         `json:"key,omitempty"`
+	KeyHash string              `json:"key_hash"`
+	Values  []BigMapHistoryItem `json:"values"`
+}
+
 // CodeDiffResponse -
 type CodeDiffResponse struct {
 	Left  CodeDiffLeg          `json:"left"`
@@ -224,3 +431,24 @@ type GetErrorLocationResponse struct {
 	StartColumn int    `json:"start_col"`
 	EndColumn   int    `json:"end_col"`
 }
+
+// Interface - user-defined interface used for contract tagging
+type Interface struct {
+	Name        string          `json:"name"`
+	Version     int64           `json:"version"`
+	Entrypoints json.RawMessage `json:"entrypoints"`
+	Retagged    []string        `json:"retagged"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// FromModel -
+func (i *Interface) FromModel(model models.Interface) {
+	i.Name = model.Name
+	i.Version = model.Version
+	i.Entrypoints = json.RawMessage(model.Entrypoints)
+	i.Retagged = model.Retagged
+	if i.Retagged == nil {
+		i.Retagged = make([]string, 0)
+	}
+	i.CreatedAt = model.CreatedAt
+}
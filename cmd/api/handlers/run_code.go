@@ -12,7 +12,7 @@ import (
 	"github.com/tidwall/gjson"
 )
 
-// RunCode -
+// RunCode - parameter is passed as Micheline or as Michelson text string in `parameter` or as `data` form by `bin_path`
 func (ctx *Context) RunCode(c *gin.Context) {
 	var req getContractRequest
 	if err := c.BindUri(&req); handleError(c, err, http.StatusBadRequest) {
@@ -22,6 +22,9 @@ func (ctx *Context) RunCode(c *gin.Context) {
 	if err := c.BindJSON(&reqRunCode); handleError(c, err, http.StatusBadRequest) {
 		return
 	}
+	if err := reqRunCode.validate(); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
 
 	rpc, err := ctx.GetRPC(req.Network)
 	if handleError(c, err, http.StatusBadRequest) {
@@ -38,7 +41,7 @@ func (ctx *Context) RunCode(c *gin.Context) {
 		return
 	}
 
-	input, err := ctx.buildEntrypointMicheline(req.Network, req.Address, reqRunCode.BinPath, reqRunCode.Data)
+	input, err := ctx.buildCallMicheline(req.Network, req.Address, reqRunCode)
 	if handleError(c, err, 0) {
 		return
 	}
@@ -0,0 +1,413 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser"
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/contractparser/meta"
+	"github.com/baking-bad/bcdhub/internal/contractparser/newmiguel"
+	contractStorage "github.com/baking-bad/bcdhub/internal/contractparser/storage"
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/baking-bad/bcdhub/internal/noderpc"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// SimulateOperations - runs the ordered batch of calls. The storage produced by the call is used by the next call of the same contract
+// and big map updates are carried forward. The batch stops on the first failed call. Internal operations are not executed and balances
+// are not carried between calls, so batches where the call emitting internal operations or transferring tez to a contract which is called
+// again is followed by other calls are rejected. Emitted internal operations of the last call are returned.
+// Calls changing big maps with more than `maxSimulatedBigMapSize` keys are rejected.
+func (ctx *Context) SimulateOperations(c *gin.Context) {
+	var req getByNetwork
+	if err := c.BindUri(&req); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+	var reqSimulation simulationRequest
+	if err := c.BindJSON(&reqSimulation); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+	if err := reqSimulation.validate(); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	rpc, err := ctx.GetRPC(req.Network)
+	if handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	state, err := ctx.ES.CurrentState(req.Network)
+	if handleError(c, err, 0) {
+		return
+	}
+
+	constants, err := rpc.GetNetworkConstants()
+	if handleError(c, err, 0) {
+		return
+	}
+
+	sim := simulation{
+		ctx:       ctx,
+		rpc:       rpc,
+		state:     state,
+		gasLimit:  constants.Get("hard_gas_limit_per_operation").Int(),
+		contracts: make(map[string]*simulatedContract),
+	}
+
+	response := SimulationResponse{
+		Status: consts.Applied,
+		Steps:  make([]SimulationStep, 0),
+	}
+	for i := range reqSimulation.Calls {
+		step, err := sim.run(reqSimulation.Calls[i])
+		if handleError(c, err, 0) {
+			return
+		}
+		response.Steps = append(response.Steps, step)
+		response.ConsumedGas += step.ConsumedGas
+
+		if len(step.Operations) > 0 && step.Operations[0].Status != consts.Applied {
+			response.Status = step.Operations[0].Status
+			break
+		}
+		if len(step.Operations) > 1 && i < len(reqSimulation.Calls)-1 {
+			handleError(c, fmt.Errorf("Call %d emits internal operations which are not executed by the simulation, so it must be the last call of the batch", i), http.StatusBadRequest)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// maxSimulatedBigMapSize - big maps changed by the simulation are passed to the node inline with all their keys,
+// so the simulation rejects changes of big maps with more keys
+const maxSimulatedBigMapSize = 1000
+
+// simulatedContract - contract state in the simulation. Big maps changed by the simulation are passed to the node inline,
+// others are read by the node from the chain. Keys are grouped by binary path because pointers change after every call.
+type simulatedContract struct {
+	network  string
+	address  string
+	script   gjson.Result
+	storage  gjson.Result
+	metadata meta.Metadata
+	protocol string
+
+	chainPtrs map[int64]string
+	dirty     map[string]bool
+	loaded    map[string]bool
+	bigMaps   map[string]map[string]models.BigMapDiff
+}
+
+type simulation struct {
+	ctx       *Context
+	rpc       noderpc.Pool
+	state     models.Block
+	gasLimit  int64
+	contracts map[string]*simulatedContract
+}
+
+func (sim *simulation) run(call simulationCall) (step SimulationStep, err error) {
+	contract, err := sim.getContract(call.Address)
+	if err != nil {
+		return
+	}
+
+	input, err := sim.ctx.buildCallMicheline(sim.state.Network, call.Address, call.runCodeRequest)
+	if err != nil {
+		return
+	}
+	if !input.Get("entrypoint").Exists() || !input.Get("value").Exists() {
+		return step, fmt.Errorf("Error during build parameters: %s", input.String())
+	}
+	entrypoint := input.Get("entrypoint").String()
+
+	storage, err := contract.inline()
+	if err != nil {
+		return
+	}
+	prevStorage, err := newmiguel.MichelineToMiguel(storage, contract.metadata)
+	if err != nil {
+		return
+	}
+
+	gasLimit := call.GasLimit
+	if gasLimit == 0 {
+		gasLimit = sim.gasLimit
+	}
+	response, err := sim.rpc.TraceCode(contract.script.Get("code"), storage, input.Get("value"), sim.state.ChainID, call.Source, call.Sender, entrypoint, call.Amount, gasLimit)
+	if err != nil {
+		return
+	}
+
+	main := Operation{
+		IndexedTime: time.Now().UTC().UnixNano(),
+		Protocol:    sim.state.Protocol,
+		Network:     sim.state.Network,
+		Timesatmp:   time.Now().UTC(),
+		Source:      call.Source,
+		Destination: call.Address,
+		GasLimit:    gasLimit,
+		Amount:      call.Amount,
+		Kind:        consts.Transaction,
+		Level:       sim.state.Level,
+		Status:      consts.Applied,
+		Entrypoint:  entrypoint,
+	}
+	if err = setParameters(sim.ctx.ES, input.Raw, &main); err != nil {
+		return
+	}
+	if step.Operations, err = sim.ctx.parseRunCodeResponse(response, main); err != nil {
+		return
+	}
+	if !response.IsObject() {
+		return
+	}
+
+	if trace := response.Get("trace").Array(); len(trace) > 0 {
+		step.ConsumedGas = gasLimit - trace[len(trace)-1].Get("gas").Int()
+	}
+
+	if err = contract.apply(sim.ctx.ES, response); err != nil {
+		return
+	}
+	currentStorage, err := contract.inline()
+	if err != nil {
+		return
+	}
+	storageDiff, err := newmiguel.MichelineToMiguel(currentStorage, contract.metadata)
+	if err != nil {
+		return
+	}
+	if storageDiff != nil {
+		storageDiff.Diff(prevStorage)
+	}
+	step.Operations[0].StorageDiff = storageDiff
+	return
+}
+
+func (sim *simulation) getContract(address string) (*simulatedContract, error) {
+	if contract, ok := sim.contracts[address]; ok {
+		return contract, nil
+	}
+
+	script, err := contractparser.GetContract(sim.rpc, address, sim.state.Network, sim.state.Protocol, sim.ctx.SharePath, 0)
+	if err != nil {
+		return nil, err
+	}
+	storage, err := sim.rpc.GetScriptStorageJSON(address, 0)
+	if err != nil {
+		return nil, err
+	}
+	contractMetadata, err := meta.GetContractMetadata(sim.ctx.ES, address)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := contractMetadata.Get(consts.STORAGE, sim.state.Protocol)
+	if err != nil {
+		return nil, err
+	}
+	chainPtrs, err := contractStorage.FindBigMapPointers(metadata, storage)
+	if err != nil {
+		return nil, err
+	}
+
+	contract := &simulatedContract{
+		network:   sim.state.Network,
+		address:   address,
+		script:    script,
+		storage:   storage,
+		metadata:  metadata,
+		protocol:  sim.state.Protocol,
+		chainPtrs: chainPtrs,
+		dirty:     make(map[string]bool),
+		loaded:    make(map[string]bool),
+		bigMaps:   make(map[string]map[string]models.BigMapDiff),
+	}
+	sim.contracts[address] = contract
+	return contract, nil
+}
+
+func (contract *simulatedContract) set(bmd models.BigMapDiff) {
+	keys, ok := contract.bigMaps[bmd.BinPath]
+	if !ok {
+		keys = make(map[string]models.BigMapDiff)
+		contract.bigMaps[bmd.BinPath] = keys
+	}
+	keys[bmd.KeyHash] = bmd
+}
+
+// load - loads the current chain state of the big map by `binPath` before its first change
+func (contract *simulatedContract) load(es elastic.IElastic, binPath string) error {
+	if contract.loaded[binPath] {
+		return nil
+	}
+	contract.loaded[binPath] = true
+
+	for ptr, chainPath := range contract.chainPtrs {
+		if chainPath != binPath {
+			continue
+		}
+		count, err := es.GetBigMapKeysCount(contract.address, ptr, 0)
+		if err != nil {
+			return err
+		}
+		if count > maxSimulatedBigMapSize {
+			return fmt.Errorf("Big map %d of %s has %d keys: simulation supports changes of big maps up to %d keys", ptr, contract.address, count, maxSimulatedBigMapSize)
+		}
+
+		bmd, err := es.GetAllBigMapDiffByPtr(contract.address, contract.network, ptr)
+		if err != nil {
+			return err
+		}
+		for i := range bmd {
+			if bmd[i].Value != "" {
+				bmd[i].BinPath = binPath
+				contract.set(bmd[i])
+			}
+		}
+	}
+	return nil
+}
+
+// inline - returns the storage with changed big maps replaced by their simulated content
+func (contract *simulatedContract) inline() (gjson.Result, error) {
+	ptrMap, err := contractStorage.FindBigMapPointers(contract.metadata, contract.storage)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	binPaths := make(map[string]struct{})
+	for _, binPath := range ptrMap {
+		if _, ok := binPaths[binPath]; ok {
+			return gjson.Result{}, fmt.Errorf("Simulation of big maps inside collections is not supported")
+		}
+		binPaths[binPath] = struct{}{}
+	}
+
+	data := contract.storage.String()
+	bmd := make([]models.BigMapDiff, 0)
+	for ptr, binPath := range ptrMap {
+		if chainPath, ok := contract.chainPtrs[ptr]; ok && chainPath == binPath && !contract.dirty[binPath] {
+			continue
+		}
+
+		keys := contract.bigMaps[binPath]
+		if len(keys) > maxSimulatedBigMapSize {
+			return gjson.Result{}, fmt.Errorf("Big map %s of %s has %d keys: simulation supports changes of big maps up to %d keys", binPath, contract.address, len(keys), maxSimulatedBigMapSize)
+		}
+		if len(keys) > 0 {
+			for _, item := range keys {
+				item.Ptr = ptr
+				bmd = append(bmd, item)
+			}
+			continue
+		}
+
+		path, err := contractStorage.PointerJSONPath(contract.storage, ptr, binPath)
+		if err != nil {
+			return gjson.Result{}, err
+		}
+		if path == "" {
+			data = "[]"
+		} else if data, err = sjson.SetRaw(data, path, "[]"); err != nil {
+			return gjson.Result{}, err
+		}
+	}
+	return enrichStorage(data, bmd, contract.protocol, true)
+}
+
+// apply - saves the storage produced by the call and applies big map changes. Big maps allocated or copied by the call are collected by pointers
+// because they may be copied again by the next diffs, then they are saved by binary paths of the new storage.
+func (contract *simulatedContract) apply(es elastic.IElastic, response gjson.Result) error {
+	storage := response.Get("storage")
+	ptrMap, err := contractStorage.FindBigMapPointers(contract.metadata, storage)
+	if err != nil {
+		return err
+	}
+
+	created := make(map[int64]map[string]models.BigMapDiff)
+	for _, item := range response.Get("big_map_diff").Array() {
+		switch item.Get("action").String() {
+		case "alloc":
+			created[item.Get("big_map").Int()] = make(map[string]models.BigMapDiff)
+		case "copy":
+			keys, err := contract.copyKeys(es, created, item.Get("source_big_map").Int())
+			if err != nil {
+				return err
+			}
+			created[item.Get("destination_big_map").Int()] = keys
+		case "update":
+			ptr := item.Get("big_map").Int()
+			binPath, inStorage := ptrMap[ptr]
+			keys, ok := created[ptr]
+			if !ok {
+				if !inStorage {
+					continue
+				}
+				if err := contract.load(es, binPath); err != nil {
+					return err
+				}
+				contract.dirty[binPath] = true
+				if _, ok := contract.bigMaps[binPath]; !ok {
+					contract.bigMaps[binPath] = make(map[string]models.BigMapDiff)
+				}
+				keys = contract.bigMaps[binPath]
+			}
+
+			keyHash := item.Get("key_hash").String()
+			if !item.Get("value").Exists() {
+				delete(keys, keyHash)
+				continue
+			}
+			keys[keyHash] = models.BigMapDiff{
+				BinPath: binPath,
+				Key:     item.Get("key").Value(),
+				KeyHash: keyHash,
+				Value:   item.Get("value").String(),
+			}
+		}
+	}
+
+	for ptr, keys := range created {
+		binPath, ok := ptrMap[ptr]
+		if !ok {
+			continue
+		}
+		for keyHash, item := range keys {
+			item.BinPath = binPath
+			keys[keyHash] = item
+		}
+		contract.loaded[binPath] = true
+		contract.dirty[binPath] = true
+		contract.bigMaps[binPath] = keys
+	}
+	contract.storage = storage
+	return nil
+}
+
+// copyKeys - returns keys of the copied big map. The source is either the big map created by the call before
+// or the big map of the contract which is passed from the chain.
+func (contract *simulatedContract) copyKeys(es elastic.IElastic, created map[int64]map[string]models.BigMapDiff, ptr int64) (map[string]models.BigMapDiff, error) {
+	source, ok := created[ptr]
+	if !ok {
+		binPath, ok := contract.chainPtrs[ptr]
+		if !ok || contract.dirty[binPath] {
+			return nil, fmt.Errorf("Simulation supports copies of big maps of the called contract only: big map %d is copied by %s", ptr, contract.address)
+		}
+		if err := contract.load(es, binPath); err != nil {
+			return nil, err
+		}
+		source = contract.bigMaps[binPath]
+	}
+
+	keys := make(map[string]models.BigMapDiff, len(source))
+	for keyHash, item := range source {
+		keys[keyHash] = item
+	}
+	return keys, nil
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser"
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/contractparser/interpreter"
+	"github.com/baking-bad/bcdhub/internal/contractparser/meta"
+	"github.com/baking-bad/bcdhub/internal/contractparser/newmiguel"
+	"github.com/baking-bad/bcdhub/internal/noderpc"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// maxExecutionSteps - count of traced instructions returned by `GetExecutionSteps`. The rest of execution is not traced.
+const maxExecutionSteps = 10000
+
+// GetExecutionSteps - executes the entrypoint call by the local interpreter and returns executed instructions
+// with their positions in the formatted code and the stack before and after them. Gas limit is clamped by the protocol limit per operation.
+// The interpreter runs the code with expanded macros, so positions refer to the code returned by `GetContractCode` with `expanded=true`
+// rather than to the collapsed code returned by default. It is marked by `expanded` of the response.
+func (ctx *Context) GetExecutionSteps(c *gin.Context) {
+	var req getContractRequest
+	if err := c.BindUri(&req); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+	var reqRunCode runCodeRequest
+	if err := c.BindJSON(&reqRunCode); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	rpc, err := ctx.GetRPC(req.Network)
+	if handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	state, err := ctx.ES.CurrentState(req.Network)
+	if handleError(c, err, 0) {
+		return
+	}
+
+	script, err := contractparser.GetContract(rpc, req.Address, req.Network, state.Protocol, ctx.SharePath, 0)
+	if handleError(c, err, 0) {
+		return
+	}
+
+	input, err := ctx.buildEntrypointMicheline(req.Network, req.Address, reqRunCode.BinPath, reqRunCode.Data)
+	if handleError(c, err, 0) {
+		return
+	}
+	if !input.Get("entrypoint").Exists() || !input.Get("value").Exists() {
+		handleError(c, fmt.Errorf("Error during build parameters: %s", input.String()), 0)
+		return
+	}
+
+	storage, err := rpc.GetScriptStorageJSON(req.Address, 0)
+	if handleError(c, err, 0) {
+		return
+	}
+	balance, err := rpc.GetContractBalance(req.Address, 0)
+	if handleError(c, err, 0) {
+		return
+	}
+	constants, err := rpc.GetNetworkConstants()
+	if handleError(c, err, 0) {
+		return
+	}
+	gasLimit := constants.Get("hard_gas_limit_per_operation").Int()
+	if reqRunCode.GasLimit > 0 && reqRunCode.GasLimit < gasLimit {
+		gasLimit = reqRunCode.GasLimit
+	}
+
+	i, err := interpreter.New(
+		script.Get("code"),
+		interpreter.WithTrace(maxExecutionSteps),
+		interpreter.WithGasLimit(gasLimit),
+		interpreter.WithContract(req.Address, balance),
+		interpreter.WithTransaction(reqRunCode.Source, reqRunCode.Sender, reqRunCode.Amount),
+		interpreter.WithChain(state.ChainID, time.Now()),
+		interpreter.WithBigMapGetter(ctx.bigMapGetter(req.Address)),
+		interpreter.WithContractGetter(ctx.contractGetter(rpc, req.Network, state.Protocol)),
+	)
+	if handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	result, runErr := i.Run(input.Get("entrypoint").String(), input.Get("value"), storage)
+	response := ExecutionTrace{
+		Status:      consts.Applied,
+		ConsumedGas: result.ConsumedGas,
+		Steps:       make([]ExecutionStep, len(result.Trace)),
+		Truncated:   result.TraceTruncated,
+		Expanded:    true,
+	}
+	if runErr != nil {
+		response.Status = consts.Failed
+		response.Error = runErr.Error()
+	}
+
+	renderer := newStackRenderer()
+	for idx, step := range result.Trace {
+		item := ExecutionStep{
+			Location:    step.Location,
+			Prim:        step.Prim,
+			Gas:         step.Gas,
+			Row:         step.Row,
+			StartColumn: step.StartColumn,
+			EndColumn:   step.EndColumn,
+		}
+		if item.Before, err = renderer.render(step.Before); handleError(c, err, 0) {
+			return
+		}
+		if item.After, err = renderer.render(step.After); handleError(c, err, 0) {
+			return
+		}
+		response.Steps[idx] = item
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// bigMapGetter - reads current values of the contract big maps from the index
+func (ctx *Context) bigMapGetter(address string) interpreter.BigMapGetter {
+	return func(ptr int64, key *interpreter.Value) (gjson.Result, error) {
+		keyHash, err := key.KeyHash()
+		if err != nil {
+			return gjson.Result{}, err
+		}
+		diffs, _, err := ctx.ES.GetBigMapDiffByPtrAndKeyHash(address, ptr, keyHash, 1, 0)
+		if err != nil {
+			return gjson.Result{}, err
+		}
+		if len(diffs) == 0 || diffs[0].Value == "" {
+			return gjson.Result{}, nil
+		}
+		return gjson.Parse(diffs[0].Value), nil
+	}
+}
+
+func (ctx *Context) contractGetter(rpc noderpc.Pool, network, protocol string) interpreter.ContractGetter {
+	return func(address string) (gjson.Result, error) {
+		script, err := contractparser.GetContract(rpc, address, network, protocol, ctx.SharePath, 0)
+		if err != nil {
+			return gjson.Result{}, err
+		}
+		for _, section := range script.Get("code").Array() {
+			if section.Get("prim").String() == consts.PARAMETER {
+				return section.Get("args.0"), nil
+			}
+		}
+		return gjson.Result{}, nil
+	}
+}
+
+// stackRenderer - renders stack values by `newmiguel`. Metadata is parsed once per type. Values are not changed by the interpreter,
+// so every value is rendered once though it stays in the stack for many steps.
+type stackRenderer struct {
+	metadata map[string]meta.Metadata
+	items    map[*interpreter.Value]StackItem
+}
+
+func newStackRenderer() *stackRenderer {
+	return &stackRenderer{
+		metadata: make(map[string]meta.Metadata),
+		items:    make(map[*interpreter.Value]StackItem),
+	}
+}
+
+func (r *stackRenderer) render(values []*interpreter.Value) ([]StackItem, error) {
+	items := make([]StackItem, len(values))
+	for idx, value := range values {
+		item, ok := r.items[value]
+		if !ok {
+			micheline, err := value.Micheline(false)
+			if err != nil {
+				return nil, err
+			}
+			item = StackItem{
+				Type:  value.Type.String(),
+				Value: micheline,
+			}
+			if value.Type.Prim != consts.OPERATION {
+				if node := r.miguel(value.Type, micheline); node != nil {
+					item.Value = node
+				}
+			}
+			r.items[value] = item
+		}
+		items[idx] = item
+	}
+	return items, nil
+}
+
+// miguel - returns nil if the value can not be rendered, then it is shown as Micheline
+func (r *stackRenderer) miguel(typ *interpreter.Type, micheline interface{}) *newmiguel.Node {
+	typeJSON, err := json.Marshal(typ.Micheline())
+	if err != nil {
+		return nil
+	}
+	metadata, ok := r.metadata[string(typeJSON)]
+	if !ok {
+		if metadata, err = meta.ParseMetadata(gjson.ParseBytes(typeJSON)); err != nil {
+			return nil
+		}
+		r.metadata[string(typeJSON)] = metadata
+	}
+	data, err := json.Marshal(micheline)
+	if err != nil {
+		return nil
+	}
+	node, err := newmiguel.MichelineToMiguel(gjson.ParseBytes(data), metadata)
+	if err != nil {
+		return nil
+	}
+	return node
+}
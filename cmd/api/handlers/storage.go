@@ -1,23 +1,35 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
 	"github.com/baking-bad/bcdhub/internal/contractparser/formatter"
 	"github.com/baking-bad/bcdhub/internal/contractparser/meta"
 	"github.com/baking-bad/bcdhub/internal/contractparser/newmiguel"
+	contractStorage "github.com/baking-bad/bcdhub/internal/contractparser/storage"
+	"github.com/baking-bad/bcdhub/internal/models"
 	"github.com/gin-gonic/gin"
 	"github.com/tidwall/gjson"
 )
 
-// GetContractStorage -
+// GetContractStorage - returns the current storage or the storage with big maps at `level` if it's set
 func (ctx *Context) GetContractStorage(c *gin.Context) {
 	var req getContractRequest
 	if err := c.BindUri(&req); handleError(c, err, http.StatusBadRequest) {
 		return
 	}
 
+	var levelReq levelRequest
+	if err := c.BindQuery(&levelReq); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+	if levelReq.Level > 0 {
+		ctx.getContractStorageAtLevel(c, req, levelReq.Level)
+		return
+	}
+
 	storage, err := ctx.ES.GetLastStorage(req.Network, req.Address)
 	if handleError(c, err, 0) {
 		return
@@ -89,7 +101,7 @@ func (ctx *Context) GetContractStorageRich(c *gin.Context) {
 		return
 	}
 
-	bmd, err := ctx.ES.GetBigMapDiffsForAddress(req.Address)
+	bmd, err := ctx.ES.GetBigMapDiffsForAddress(req.Network, req.Address)
 	if handleError(c, err, 0) {
 		return
 	}
@@ -102,3 +114,65 @@ func (ctx *Context) GetContractStorageRich(c *gin.Context) {
 
 	c.JSON(http.StatusOK, resp.Value())
 }
+
+// getContractStorageAtLevel - storage is taken from the last operation at or below `level`, big maps are filled with their keys at `level`
+func (ctx *Context) getContractStorageAtLevel(c *gin.Context, req getContractRequest, level int64) {
+	storage, err := ctx.ES.GetLastStorageAtLevel(req.Network, req.Address, level)
+	if handleError(c, err, 0) {
+		return
+	}
+	if !storage.Exists() {
+		handleError(c, fmt.Errorf("Storage of %s is unknown at level %d", req.Address, level), http.StatusNotFound)
+		return
+	}
+
+	protocol := storage.Get("_source.protocol").String()
+	deffatedStorage := storage.Get("_source.deffated_storage").String()
+	metadata, err := meta.GetMetadata(ctx.ES, req.Address, consts.STORAGE, protocol)
+	if handleError(c, err, 0) {
+		return
+	}
+
+	bmd, err := ctx.ES.GetBigMapDiffsForAddressAtLevel(req.Network, req.Address, level)
+	if handleError(c, err, 0) {
+		return
+	}
+	bmd, err = filterBigMapDiffsByStorage(bmd, deffatedStorage, metadata, protocol)
+	if handleError(c, err, 0) {
+		return
+	}
+
+	s, err := enrichStorage(deffatedStorage, bmd, protocol, true)
+	if handleError(c, err, 0) {
+		return
+	}
+	resp, err := newmiguel.MichelineToMiguel(s, metadata)
+	if handleError(c, err, 0) {
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// filterBigMapDiffsByStorage - Babylon big maps may be copied and removed, so only diffs of pointers present in the storage are kept
+func filterBigMapDiffsByStorage(bmd []models.BigMapDiff, storage string, metadata meta.Metadata, protocol string) ([]models.BigMapDiff, error) {
+	protoSymLink, err := meta.GetProtoSymLink(protocol)
+	if err != nil {
+		return nil, err
+	}
+	if protoSymLink != consts.MetadataBabylon {
+		return bmd, nil
+	}
+
+	ptrs, err := contractStorage.FindBigMapPointers(metadata, gjson.Parse(storage))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]models.BigMapDiff, 0, len(bmd))
+	for i := range bmd {
+		if _, ok := ptrs[bmd[i].Ptr]; ok {
+			result = append(result, bmd[i])
+		}
+	}
+	return result, nil
+}
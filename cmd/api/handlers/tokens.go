@@ -1,18 +1,17 @@
 package handlers
 
 import (
+	"encoding/csv"
 	"fmt"
+	"math/big"
 	"net/http"
 	"strconv"
+	"time"
 
-	"github.com/baking-bad/bcdhub/internal/contractparser/cerrors"
 	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
-	"github.com/baking-bad/bcdhub/internal/contractparser/meta"
-	"github.com/baking-bad/bcdhub/internal/contractparser/newmiguel"
 	"github.com/baking-bad/bcdhub/internal/elastic"
 	"github.com/baking-bad/bcdhub/internal/models"
 	"github.com/gin-gonic/gin"
-	"github.com/tidwall/gjson"
 )
 
 // GetFA12 -
@@ -38,7 +37,8 @@ func (ctx *Context) GetFA12(c *gin.Context) {
 	c.JSON(http.StatusOK, contractToTokens(contracts))
 }
 
-// GetFA12OperationsForAddress -
+// GetFA12OperationsForAddress - returns token transfers made by `mint` and `transfer` calls with the address in parameters.
+// Pages are made of operations and `last_id` is `indexed_time` of the last operation of the page. Filters and CSV export are served by `GetTokenTransferHistory`.
 func (ctx *Context) GetFA12OperationsForAddress(c *gin.Context) {
 	var req getContractRequest
 	if err := c.BindUri(&req); handleError(c, err, http.StatusBadRequest) {
@@ -55,11 +55,204 @@ func (ctx *Context) GetFA12OperationsForAddress(c *gin.Context) {
 		return
 	}
 
-	ops, err := operationToTransfer(ctx.ES, operations)
+	transfers, err := operationsToTransfers(ctx.ES, req.Network, req.Address, operations)
 	if handleError(c, err, 0) {
 		return
 	}
-	c.JSON(http.StatusOK, ops)
+	c.JSON(http.StatusOK, transfers)
+}
+
+// GetTokenTransferHistory - returns token transfers sent or received by the address
+func (ctx *Context) GetTokenTransferHistory(c *gin.Context) {
+	var req getContractRequest
+	if err := c.BindUri(&req); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	var transfersReq transfersRequest
+	if err := c.BindQuery(&transfersReq); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	filters, err := transfersReq.context(req.Network)
+	if handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+	filters.Address = req.Address
+	ctx.getTransfers(c, filters, transfersReq.Format)
+}
+
+// GetContractTransfers - returns transfers of the token contract
+func (ctx *Context) GetContractTransfers(c *gin.Context) {
+	var req getContractRequest
+	if err := c.BindUri(&req); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	var transfersReq transfersRequest
+	if err := c.BindQuery(&transfersReq); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	filters, err := transfersReq.context(req.Network)
+	if handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+	filters.Contracts = []string{req.Address}
+	ctx.getTransfers(c, filters, transfersReq.Format)
+}
+
+func (ctx *Context) getTransfers(c *gin.Context, filters elastic.GetTransfersContext, format string) {
+	transfers, err := ctx.ES.GetTransfers(filters)
+	if handleError(c, err, 0) {
+		return
+	}
+
+	if format == "csv" {
+		err = writeTransfersCSV(c, transfers)
+		handleError(c, err, 0)
+		return
+	}
+
+	response := PageableTokenTransfers{
+		Transfers: make([]TokenTransfer, len(transfers.Transfers)),
+		LastID:    transfers.LastID,
+	}
+	for i := range transfers.Transfers {
+		response.Transfers[i].FromModel(transfers.Transfers[i])
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// maxTransfersSize - limit of transfers of one page of operations, it's the max size of Elasticsearch query
+const maxTransfersSize = 10000
+
+// operationsToTransfers - FA2 transfers are sent in batches, so only transfers from or to `address` are returned for them.
+// Transfers of every operation are returned in the order of the operation parameters.
+func operationsToTransfers(es elastic.IElastic, network, address string, po elastic.PageableOperations) (PageableTokenTransfers, error) {
+	response := PageableTokenTransfers{
+		Transfers: make([]TokenTransfer, 0),
+		LastID:    po.LastID,
+	}
+	if len(po.Operations) == 0 {
+		return response, nil
+	}
+
+	ids := make([]string, len(po.Operations))
+	for i := range po.Operations {
+		ids[i] = po.Operations[i].ID
+	}
+	transfers, err := es.GetTransfers(elastic.GetTransfersContext{
+		Network:      network,
+		Address:      address,
+		OperationIDs: ids,
+		Size:         maxTransfersSize,
+	})
+	if err != nil {
+		return response, err
+	}
+
+	byOperation := make(map[string][]models.Transfer)
+	for _, t := range transfers.Transfers {
+		byOperation[t.OperationID] = append(byOperation[t.OperationID], t)
+	}
+	for _, op := range po.Operations {
+		opTransfers := byOperation[op.ID]
+		for i := len(opTransfers) - 1; i >= 0; i-- {
+			var transfer TokenTransfer
+			transfer.FromModel(opTransfers[i])
+			response.Transfers = append(response.Transfers, transfer)
+		}
+	}
+	return response, nil
+}
+
+// writeTransfersCSV - cursor of the next page is sent in `X-Last-Id` header
+func writeTransfersCSV(c *gin.Context, transfers elastic.PageableTransfers) error {
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", `attachment; filename="transfers.csv"`)
+	c.Header("X-Last-Id", transfers.LastID)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	if err := w.Write([]string{"timestamp", "level", "hash", "counter", "status", "contract", "token_id", "from", "to", "amount", "source"}); err != nil {
+		return err
+	}
+	for _, t := range transfers.Transfers {
+		if err := w.Write([]string{
+			t.Timestamp.Format(time.RFC3339),
+			strconv.FormatInt(t.Level, 10),
+			t.Hash,
+			strconv.FormatInt(t.Counter, 10),
+			t.Status,
+			t.Contract,
+			t.TokenID,
+			t.From,
+			t.To,
+			t.Amount,
+			t.Source,
+		}); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// GetTokenHolders - returns holders of the FA1.2 token and its total supply at `level` (the head by default)
+func (ctx *Context) GetTokenHolders(c *gin.Context) {
+	var req getContractRequest
+	if err := c.BindUri(&req); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	var levelReq levelRequest
+	if err := c.BindQuery(&levelReq); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	holders, err := ctx.ES.GetTokenHolders(req.Network, req.Address, levelReq.Level)
+	if handleError(c, err, 0) {
+		return
+	}
+
+	totalSupply := new(big.Int)
+	for i := range holders {
+		balance, ok := new(big.Int).SetString(holders[i].Balance, 10)
+		if !ok {
+			handleError(c, fmt.Errorf("Invalid balance of %s: %s", holders[i].Address, holders[i].Balance), 0)
+			return
+		}
+		totalSupply.Add(totalSupply, balance)
+	}
+	c.JSON(http.StatusOK, TokenHolders{
+		TotalSupply: totalSupply.String(),
+		Holders:     holders,
+	})
+}
+
+// GetTokenBalance - returns balance of the holder in the FA1.2 token at `level` (the head by default)
+func (ctx *Context) GetTokenBalance(c *gin.Context) {
+	var req getTokenBalanceRequest
+	if err := c.BindUri(&req); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	var levelReq levelRequest
+	if err := c.BindQuery(&levelReq); handleError(c, err, http.StatusBadRequest) {
+		return
+	}
+
+	balance, err := ctx.ES.GetTokenBalance(req.Network, req.Address, req.Holder, levelReq.Level)
+	if handleError(c, err, 0) {
+		return
+	}
+
+	c.JSON(http.StatusOK, TokenBalance{
+		Address: req.Holder,
+		Balance: balance,
+		Level:   levelReq.Level,
+	})
 }
 
 func contractToTokens(contracts []models.Contract) []TokenContract {
@@ -77,9 +270,14 @@ func contractToTokens(contracts []models.Contract) []TokenContract {
 			Balance:       contracts[i].Balance,
 			TxCount:       contracts[i].TxCount,
 		}
+		if tm := contracts[i].TokenMetadata; tm != nil {
+			tokens[i].Name = tm.Name
+			tokens[i].Symbol = tm.Symbol
+			tokens[i].Decimals = tm.Decimals
+		}
 		for _, tag := range contracts[i].Tags {
-			if tag == consts.FA12Tag {
-				tokens[i].Type = consts.FA12Tag
+			if tag == consts.FA12Tag || tag == consts.FA2Tag {
+				tokens[i].Type = tag
 				break
 			} else if tag == consts.FA1Tag {
 				tokens[i].Type = consts.FA1Tag
@@ -88,82 +286,3 @@ func contractToTokens(contracts []models.Contract) []TokenContract {
 	}
 	return tokens
 }
-
-func operationToTransfer(es *elastic.Elastic, po elastic.PageableOperations) (PageableTokenTransfers, error) {
-	transfers := make([]TokenTransfer, 0)
-	contracts := map[string]bool{}
-	metadatas := map[string]meta.Metadata{}
-
-	for _, op := range po.Operations {
-		key := op.Network + op.Destination
-		isFA12, ok := contracts[key]
-		if !ok {
-			val, err := es.IsFAContract(op.Network, op.Destination)
-			if err != nil {
-				return PageableTokenTransfers{}, err
-			}
-			contracts[key] = val
-			isFA12 = val
-		}
-		if !isFA12 {
-			continue
-		}
-		if cerrors.HasParametersError(op.Errors) || cerrors.HasGasExhaustedError(op.Errors) {
-			continue
-		}
-
-		transfer := TokenTransfer{
-			Network:   op.Network,
-			Contract:  op.Destination,
-			Protocol:  op.Protocol,
-			Hash:      op.Hash,
-			Counter:   op.Counter,
-			Status:    op.Status,
-			Timestamp: op.Timestamp,
-			Level:     op.Level,
-			Source:    op.Source,
-		}
-
-		metadata, ok := metadatas[key]
-		if !ok {
-			val, err := meta.GetMetadata(es, op.Destination, consts.PARAMETER, op.Protocol)
-			if err != nil {
-				return PageableTokenTransfers{}, fmt.Errorf("[operationToTransfer] Unknown metadata: %s", op.Destination)
-			}
-			metadatas[key] = val
-			metadata = val
-		}
-
-		params := gjson.Parse(op.Parameters)
-		parameters, err := newmiguel.ParameterToMiguel(params, metadata)
-		if err != nil {
-			return PageableTokenTransfers{}, err
-		}
-
-		if op.Entrypoint == "transfer" && len(parameters.Children) == 3 {
-			transfer.From = parameters.Children[0].Value.(string)
-			transfer.To = parameters.Children[1].Value.(string)
-			amount, err := strconv.ParseInt(parameters.Children[2].Value.(string), 10, 64)
-			if err != nil {
-				return PageableTokenTransfers{}, err
-			}
-			transfer.Amount = amount
-		} else if op.Entrypoint == "mint" && len(parameters.Children) == 2 {
-			transfer.To = parameters.Children[0].Value.(string)
-			amount, err := strconv.ParseInt(parameters.Children[1].Value.(string), 10, 64)
-			if err != nil {
-				return PageableTokenTransfers{}, err
-			}
-			transfer.Amount = amount
-		} else {
-			continue
-		}
-
-		transfers = append(transfers, transfer)
-	}
-	pt := PageableTokenTransfers{
-		LastID:    po.LastID,
-		Transfers: transfers,
-	}
-	return pt, nil
-}
@@ -42,6 +42,10 @@ func main() {
 	}
 	defer ctx.Close()
 
+	if err := ctx.RegisterInterfaces(); err != nil {
+		logger.Error(err)
+	}
+
 	r := gin.Default()
 
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
@@ -104,7 +108,9 @@ func main() {
 					address.GET("code", ctx.GetContractCode)
 					address.GET("operations", ctx.GetContractOperations)
 					address.GET("migrations", ctx.GetContractMigrations)
+					address.GET("balance_history", ctx.GetContractBalanceHistory)
 					address.GET("storage", ctx.GetContractStorage)
+					address.GET("transfers", ctx.GetContractTransfers)
 					address.GET("raw_storage", ctx.GetContractStorageRaw)
 					address.GET("rich_storage", ctx.GetContractStorageRich)
 					address.GET("rating", ctx.GetContractRating)
@@ -115,17 +121,36 @@ func main() {
 					{
 						bigmap.GET(":ptr", ctx.GetBigMap)
 						bigmap.GET(":ptr/:key_hash", ctx.GetBigMapByKeyHash)
+						bigmap.GET(":ptr/:key_hash/history", ctx.GetBigMapKeyHistory)
+					}
+					bigmapKeys := address.Group("bigmap_keys")
+					{
+						bigmapKeys.GET(":ptr", ctx.GetBigMapKeys)
+						bigmapKeys.GET(":ptr/count", ctx.GetBigMapKeysCount)
 					}
 					entrypoints := address.Group("entrypoints")
 					{
 						entrypoints.GET("", ctx.GetEntrypoints)
 						entrypoints.POST("data", ctx.GetEntrypointData)
 						entrypoints.POST("trace", ctx.RunCode)
+						entrypoints.POST("steps", ctx.GetExecutionSteps)
 					}
 				}
 			}
 		}
 
+		account := v1.Group("account")
+		{
+			network := account.Group(":network")
+			{
+				address := network.Group(":address")
+				{
+					address.GET("delegations", ctx.GetDelegations)
+					address.GET("reveal", ctx.GetReveal)
+				}
+			}
+		}
+
 		fa12 := v1.Group("tokens")
 		{
 			network := fa12.Group(":network")
@@ -134,10 +159,16 @@ func main() {
 				address := network.Group(":address")
 				{
 					address.GET("transfers", ctx.GetFA12OperationsForAddress)
+					address.GET("transfer_history", ctx.GetTokenTransferHistory)
+					address.GET("holders", ctx.GetTokenHolders)
+					address.GET("balance/:holder", ctx.GetTokenBalance)
 				}
 			}
 		}
 
+		v1.GET("bigmap/:network/:ptr", ctx.GetBigMapInfo)
+		v1.POST("simulate/:network", ctx.SimulateOperations)
+		v1.POST("simulate/:network/origination", ctx.SimulateOrigination)
 		v1.GET("opg/:hash", ctx.GetOperation)
 		v1.GET("operation/:id/error_location", ctx.GetOperationErrorLocation)
 
@@ -145,6 +176,7 @@ func main() {
 		v1.POST("diff", ctx.GetDiff)
 		v1.GET("projects", ctx.GetProjects)
 		v1.GET("formatter", ctx.GetFormatter)
+		v1.GET("rpc/health", ctx.GetRPCHealth)
 
 		// PRIVATE
 		// TODO - remove in prod
@@ -159,6 +191,16 @@ func main() {
 			}
 		}
 
+		admin := v1.Group("admin")
+		admin.Use(ctx.AdminRequired())
+		{
+			interfaces := admin.Group("interfaces")
+			{
+				interfaces.GET("", ctx.ListInterfaces)
+				interfaces.POST("", ctx.AddInterface)
+			}
+		}
+
 		authorized := v1.Group("/")
 		authorized.Use(ctx.AuthJWTRequired())
 		{
@@ -18,21 +18,31 @@ import (
 	"github.com/baking-bad/bcdhub/internal/models"
 	"github.com/baking-bad/bcdhub/internal/mq"
 	"github.com/baking-bad/bcdhub/internal/noderpc"
+	"github.com/baking-bad/bcdhub/internal/outbox"
 	"github.com/baking-bad/bcdhub/internal/rollback"
+	"github.com/tidwall/gjson"
 )
 
+// outboxPeriod - how often notifications which were not delivered right after the block commit are retried
+const outboxPeriod = 10 * time.Second
+
 // BoostIndexer -
 type BoostIndexer struct {
-	Network         string
-	UpdateTimer     int64
-	rpc             noderpc.Pool
-	es              *elastic.Elastic
-	externalIndexer index.Indexer
-	state           models.Block
-	currentProtocol models.Protocol
-	messageQueue    *mq.MQ
-	filesDirectory  string
-	boost           bool
+	Network             string
+	UpdateTimer         int64
+	rpc                 noderpc.Pool
+	es                  elastic.IElastic
+	externalIndexer     index.Indexer
+	state               models.Block
+	currentProtocol     models.Protocol
+	messageQueue        mq.Publisher
+	relay               *outbox.Relay
+	filesDirectory      string
+	interfacesDirectory string
+	boost               bool
+	concurrency         int
+	window              int
+	policy              parsers.Policy
 
 	stop    chan struct{}
 	stopped bool
@@ -69,7 +79,7 @@ func (bi *BoostIndexer) fetchExternalProtocols() error {
 			alias = extProtocols[i].Hash[:8]
 		}
 		protocols = append(protocols, &models.Protocol{
-			ID:         helpers.GenerateID(),
+			ID:         protocolID(bi.Network, extProtocols[i].Hash),
 			Hash:       extProtocols[i].Hash,
 			Alias:      alias,
 			StartLevel: extProtocols[i].StartLevel,
@@ -86,24 +96,34 @@ func (bi *BoostIndexer) fetchExternalProtocols() error {
 // NewBoostIndexer -
 func NewBoostIndexer(cfg config.Config, network string, opts ...BoostIndexerOption) (*BoostIndexer, error) {
 	logger.Info("[%s] Creating indexer object...", network)
-	es := elastic.WaitNew([]string{cfg.Elastic.URI})
+	es := config.NewStorage(cfg.Storage, elastic.WaitNew([]string{cfg.Elastic.URI}))
 	rpcProvider, ok := cfg.RPC[network]
 	if !ok {
 		return nil, fmt.Errorf("Unknown network %s", network)
 	}
-	rpc := noderpc.NewWaitNode(rpcProvider.URI, time.Duration(rpcProvider.Timeout)*time.Second)
+	rpc, err := rpcProvider.NewPool(network, true)
+	if err != nil {
+		return nil, err
+	}
 
 	messageQueue, err := mq.New(cfg.RabbitMQ.URI, cfg.RabbitMQ.Queues)
 	if err != nil {
 		return nil, err
 	}
+	return newBoostIndexer(network, rpc, es, messageQueue, cfg.Share.Path, opts...)
+}
 
+func newBoostIndexer(network string, rpc noderpc.Pool, es elastic.IElastic, messageQueue mq.Publisher, filesDirectory string, opts ...BoostIndexerOption) (*BoostIndexer, error) {
 	bi := &BoostIndexer{
 		Network:        network,
 		rpc:            rpc,
 		es:             es,
 		messageQueue:   messageQueue,
-		filesDirectory: cfg.Share.Path,
+		relay:          outbox.NewRelay(network, es, messageQueue),
+		filesDirectory: filesDirectory,
+		concurrency:    defaultConcurrency,
+		window:         defaultWindow,
+		policy:         parsers.DefaultPolicy(),
 		stop:           make(chan struct{}),
 	}
 
@@ -122,6 +142,13 @@ func (bi *BoostIndexer) init() error {
 		return err
 	}
 
+	if err := bi.importInterfaces(); err != nil {
+		return err
+	}
+	if _, err := bi.registerInterfaces(); err != nil {
+		return err
+	}
+
 	if bi.boost {
 		if err := bi.fetchExternalProtocols(); err != nil {
 			return err
@@ -167,6 +194,11 @@ func (bi *BoostIndexer) Sync(wg *sync.WaitGroup) error {
 	localSentry := helpers.GetLocalSentry()
 	helpers.SetLocalTagSentry(localSentry, "network", bi.Network)
 
+	stopRelay := make(chan struct{})
+	defer close(stopRelay)
+	go bi.relay.Run(outboxPeriod, stopRelay)
+	go bi.runInterfaces(interfacesPeriod, stopRelay)
+
 	// First tick
 	if err := bi.process(); err != nil {
 		logger.Error(err)
@@ -212,12 +244,61 @@ func (bi *BoostIndexer) Stop() {
 	bi.stop <- struct{}{}
 }
 
-// Index -
+// Index - levels are fetched and parsed concurrently and committed in level order
 func (bi *BoostIndexer) Index(levels []int64) error {
 	if len(levels) == 0 {
 		return nil
 	}
-	for _, level := range levels {
+
+	p := newPipeline(bi.rpc, bi.concurrency, bi.window)
+	quit := make(chan struct{})
+	var wg sync.WaitGroup
+	defer func() {
+		p.Close()
+		close(quit)
+		wg.Wait()
+	}()
+
+	s := &scheduler{
+		boost:    bi.boost,
+		protocol: bi.currentProtocol.Hash,
+	}
+	if bi.state.Level > 0 {
+		s.hash = bi.state.Hash
+	}
+
+	tasks := make(chan *parseTask, bi.window)
+	slots := make(chan struct{}, bi.concurrency)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(tasks)
+		for block := range p.Run(levels) {
+			task := newParseTask(block)
+			err := block.err
+			if err == nil {
+				err = s.schedule(task)
+			}
+			if err != nil {
+				task.err = err
+				close(task.parsed)
+			} else {
+				wg.Add(1)
+				go bi.parse(task, slots, quit, &wg)
+			}
+
+			select {
+			case tasks <- task:
+			case <-quit:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for task := range tasks {
 		select {
 		case <-bi.stop:
 			bi.stopped = true
@@ -226,46 +307,65 @@ func (bi *BoostIndexer) Index(levels []int64) error {
 		default:
 		}
 
-		currentHead, err := bi.rpc.GetHeader(level)
-		if err != nil {
-			return err
+		<-task.parsed
+		if task.err != nil {
+			return task.err
 		}
 
-		if bi.state.Level > 0 && currentHead.Predecessor != bi.state.Hash && !bi.boost {
-			return fmt.Errorf("rollback")
+		logger.Info("[%s] indexing %d block", bi.Network, task.block.level)
+		if err := bi.commit(task.block.header, task.data); err != nil {
+			return err
 		}
-
-		logger.Info("[%s] indexing %d block", bi.Network, level)
-
-		if currentHead.Protocol != bi.currentProtocol.Hash {
-			log.Printf("[%s] New protocol detected: %s -> %s", bi.Network, bi.currentProtocol.Hash, currentHead.Protocol)
-			if err := bi.migrate(currentHead); err != nil {
-				return err
-			}
+		if task.migrate {
+			bi.currentProtocol = task.protocol
+			logger.Info("[%s] Migration to %s is completed", bi.Network, bi.currentProtocol.Alias)
 		}
+		close(task.committed)
+	}
+	return nil
+}
 
-		operations, contracts, migrations, err := bi.getDataFromBlock(bi.Network, currentHead)
-		if err != nil {
-			return err
-		}
+// commit - saves all data of the block and notifications about it (outbox) by one bulk request and only then saves the block itself,
+// which moves the indexer state. All documents have deterministic IDs, so if the process dies before the block is saved
+// the level is indexed again without duplicates.
+func (bi *BoostIndexer) commit(head noderpc.Header, data blockData) error {
+	delegates, err := bi.delegateNotifications(head.Level, data)
+	if err != nil {
+		return err
+	}
 
-		if err := bi.saveModels(contracts, mq.QueueContracts); err != nil {
-			return err
-		}
-		if err := bi.saveModels(operations, mq.QueueOperations); err != nil {
-			return err
-		}
-		if err := bi.saveModels(migrations, mq.QueueMigrations); err != nil {
-			return err
-		}
+	items := make([]elastic.Model, 0, 2*(len(data.contracts)+len(data.operations)+len(data.migrations))+len(data.delegations)+len(data.reveals)+len(data.balances)+len(data.bigMapDiffs)+len(data.bigMaps)+len(data.protocols))
+	items = append(items, data.protocols...)
+	items = append(items, data.contracts...)
+	items = append(items, data.bigMapDiffs...)
+	items = append(items, data.bigMaps...)
+	items = append(items, data.operations...)
+	items = append(items, data.migrations...)
+	items = append(items, data.delegations...)
+	items = append(items, data.reveals...)
+	items = append(items, data.balances...)
+	items = append(items, bi.notifications(head.Level, data.contracts, mq.QueueContracts)...)
+	items = append(items, bi.notifications(head.Level, data.operations, mq.QueueOperations)...)
+	items = append(items, bi.notifications(head.Level, data.migrations, mq.QueueMigrations)...)
+	items = append(items, delegates...)
+	if err := bi.es.BulkInsert(items); err != nil {
+		return err
+	}
 
-		if err := bi.createAndSaveBlock(currentHead); err != nil {
-			return err
-		}
+	if err := bi.createAndSaveBlock(head); err != nil {
+		return err
 	}
+	bi.deliver()
 	return nil
 }
 
+// deliver - notifications are already saved, so delivery errors are retried by the relay later
+func (bi *BoostIndexer) deliver() {
+	if _, err := bi.relay.Deliver(); err != nil {
+		logger.Errorf("[%s] Outbox: %s", bi.Network, err)
+	}
+}
+
 // Rollback -
 func (bi *BoostIndexer) Rollback() error {
 	logger.Warning("[%s] Rollback from %d", bi.Network, bi.state.Level)
@@ -416,7 +516,7 @@ func (bi *BoostIndexer) getContracts() (map[string]struct{}, map[string]struct{}
 
 func (bi *BoostIndexer) createAndSaveBlock(head noderpc.Header) error {
 	newBlock := models.Block{
-		ID:          helpers.GenerateID(),
+		ID:          helpers.GenerateIDFrom(bi.Network, head.Level),
 		Network:     bi.Network,
 		Hash:        head.Hash,
 		Predecessor: head.Predecessor,
@@ -434,93 +534,167 @@ func (bi *BoostIndexer) createAndSaveBlock(head noderpc.Header) error {
 	return nil
 }
 
-func (bi *BoostIndexer) saveModels(items []elastic.Model, queue string) error {
+func (bi *BoostIndexer) notifications(level int64, items []elastic.Model, queue string) []elastic.Model {
 	logger.Info("[%s] Found %d new %s", bi.Network, len(items), queue)
-	if err := bi.es.BulkInsert(items); err != nil {
-		return err
+	notifications := make([]elastic.Model, len(items))
+	for i := range items {
+		notifications[i] = models.NewNotification(bi.Network, level, queue, items[i].GetID())
 	}
+	return notifications
+}
 
-	for j := range items {
-		if err := bi.messageQueue.Send(mq.ChannelNew, queue, items[j].GetID()); err != nil {
-			return err
-		}
-	}
-	return nil
+// blockData - models found in operations of the block
+type blockData struct {
+	operations  []elastic.Model
+	contracts   []elastic.Model
+	migrations  []elastic.Model
+	delegations []elastic.Model
+	reveals     []elastic.Model
+	balances    []elastic.Model
+	bigMapDiffs []elastic.Model
+	bigMaps     []elastic.Model
+	protocols   []elastic.Model
 }
 
-func (bi *BoostIndexer) getDataFromBlock(network string, head noderpc.Header) ([]elastic.Model, []elastic.Model, []elastic.Model, error) {
-	data, err := bi.rpc.GetOperations(head.Level)
-	if err != nil {
-		return nil, nil, nil, err
-	}
-	defaultParser := parsers.NewDefaultParser(bi.rpc, bi.es, bi.filesDirectory)
+// add - appends models of `other` to the block data
+func (data *blockData) add(other blockData) {
+	data.operations = append(data.operations, other.operations...)
+	data.contracts = append(data.contracts, other.contracts...)
+	data.migrations = append(data.migrations, other.migrations...)
+	data.delegations = append(data.delegations, other.delegations...)
+	data.reveals = append(data.reveals, other.reveals...)
+	data.balances = append(data.balances, other.balances...)
+	data.bigMapDiffs = append(data.bigMapDiffs, other.bigMapDiffs...)
+	data.bigMaps = append(data.bigMaps, other.bigMaps...)
+	data.protocols = append(data.protocols, other.protocols...)
+}
+
+func (bi *BoostIndexer) getDataFromBlock(network string, head noderpc.Header, groups []gjson.Result) (data blockData, err error) {
+	defaultParser := parsers.NewDefaultParser(bi.rpc, bi.es, bi.filesDirectory, parsers.WithPolicy(bi.policy))
 
-	operations := make([]elastic.Model, 0)
-	contracts := make([]elastic.Model, 0)
-	migrations := make([]elastic.Model, 0)
-	for _, opg := range data.Array() {
+	data.operations = make([]elastic.Model, 0)
+	data.contracts = make([]elastic.Model, 0)
+	data.migrations = make([]elastic.Model, 0)
+	data.delegations = make([]elastic.Model, 0)
+	data.reveals = make([]elastic.Model, 0)
+	data.balances = make([]elastic.Model, 0)
+	for _, opg := range groups {
 		newOps, newContracts, newMigrations, err := defaultParser.Parse(opg, network, head)
 		if err != nil {
-			return nil, nil, nil, err
+			return data, err
 		}
 		for i := range newOps {
-			operations = append(operations, newOps[i])
+			data.operations = append(data.operations, newOps[i])
+			for _, change := range models.NewBalanceChanges(*newOps[i]) {
+				data.balances = append(data.balances, change)
+			}
 		}
 		for i := range newContracts {
-			contracts = append(contracts, newContracts[i])
+			data.contracts = append(data.contracts, newContracts[i])
 		}
 		for i := range newMigrations {
-			migrations = append(migrations, newMigrations[i])
+			data.migrations = append(data.migrations, newMigrations[i])
+		}
+
+		newDelegations, newReveals := defaultParser.ParseDelegationsAndReveals(opg, network, head)
+		for i := range newDelegations {
+			data.delegations = append(data.delegations, newDelegations[i])
+		}
+		for i := range newReveals {
+			data.reveals = append(data.reveals, newReveals[i])
 		}
 	}
+	data.bigMapDiffs = defaultParser.BigMapDiffs()
+	data.bigMaps = defaultParser.BigMaps()
 
-	return operations, contracts, migrations, nil
+	return data, nil
 }
 
-func (bi *BoostIndexer) migrate(head noderpc.Header) error {
-	if bi.currentProtocol.EndLevel == 0 && head.Level > 1 {
-		logger.Info("[%s] Finalizing the previous protocol: %s", bi.Network, bi.currentProtocol.Alias)
-		bi.currentProtocol.EndLevel = head.Level - 1
-		_, err := bi.es.UpdateDoc(elastic.DocProtocol, bi.currentProtocol.ID, bi.currentProtocol)
-		if err != nil {
-			return err
+// delegateNotifications - the `metrics` service updates delegates of contracts which made delegations by recalculation of the contracts
+func (bi *BoostIndexer) delegateNotifications(level int64, data blockData) ([]elastic.Model, error) {
+	originated := make(map[string]struct{})
+	for i := range data.contracts {
+		if contract, ok := data.contracts[i].(*models.Contract); ok {
+			originated[contract.Address] = struct{}{}
+		}
+	}
+
+	notifications := make([]elastic.Model, 0)
+	exists := make(map[string]struct{})
+	for i := range data.delegations {
+		delegation, ok := data.delegations[i].(*models.Delegation)
+		if !ok || delegation.Kind != consts.Delegation || delegation.Status != consts.Applied || !strings.HasPrefix(delegation.Source, "KT") {
+			continue
+		}
+		if _, ok := exists[delegation.Source]; ok {
+			continue
+		}
+		if _, ok := originated[delegation.Source]; !ok {
+			known, err := bi.es.IsKnownContract(bi.Network, delegation.Source)
+			if err != nil {
+				return nil, err
+			}
+			if !known {
+				continue
+			}
 		}
+		exists[delegation.Source] = struct{}{}
+		notifications = append(notifications, models.NewNotification(bi.Network, level, mq.QueueRecalc, helpers.GenerateIDFrom(bi.Network, delegation.Source)))
+	}
+	return notifications, nil
+}
+
+// migrate - collects documents of the protocol migration into the block data, so they are committed with the block.
+// Metadata and entrypoints of migrated contracts are updated in place: operations of the level are parsed with them
+// and they are derived from the scripts, so replay of the level writes the same values.
+func (bi *BoostIndexer) migrate(head noderpc.Header, data *blockData) (models.Protocol, error) {
+	prevProtocol := bi.currentProtocol
+	if prevProtocol.EndLevel == 0 && head.Level > 1 {
+		logger.Info("[%s] Finalizing the previous protocol: %s", bi.Network, prevProtocol.Alias)
+		prevProtocol.EndLevel = head.Level - 1
+		finalized := prevProtocol
+		data.protocols = append(data.protocols, &finalized)
 	}
 
 	newProtocol, err := bi.es.GetProtocol(bi.Network, head.Protocol, head.Level)
 	if err != nil {
 		logger.Warning("%s", err)
-		newProtocol, err = createProtocol(bi.es, bi.Network, head.Protocol, head.Level)
+		newProtocol, err = buildProtocol(bi.Network, head.Protocol, head.Level)
 		if err != nil {
-			return err
+			return newProtocol, err
 		}
+		created := newProtocol
+		data.protocols = append(data.protocols, &created)
 	}
 
 	if bi.Network == consts.Mainnet && head.Level == 1 {
-		if err := bi.vestingMigration(head); err != nil {
-			return err
+		if err := bi.vestingMigration(head, data); err != nil {
+			return newProtocol, err
 		}
 	} else {
-		if bi.currentProtocol.SymLink == "" {
-			return fmt.Errorf("[%s] Protocol should be initialized", bi.Network)
+		if prevProtocol.SymLink == "" {
+			return newProtocol, fmt.Errorf("[%s] Protocol should be initialized", bi.Network)
 		}
-		if newProtocol.SymLink != bi.currentProtocol.SymLink {
-			if err := bi.standartMigration(newProtocol); err != nil {
-				return err
+		if newProtocol.SymLink != prevProtocol.SymLink {
+			if err := bi.standartMigration(prevProtocol, newProtocol, data); err != nil {
+				return newProtocol, err
 			}
 		} else {
 			logger.Info("[%s] Same symlink %s for %s / %s",
-				bi.Network, newProtocol.SymLink, bi.currentProtocol.Alias, newProtocol.Alias)
+				bi.Network, newProtocol.SymLink, prevProtocol.Alias, newProtocol.Alias)
+		}
+		if err := bi.balanceMigration(head, data); err != nil {
+			return newProtocol, err
 		}
 	}
+	return newProtocol, nil
+}
 
-	bi.currentProtocol = newProtocol
-	logger.Info("[%s] Migration to %s is completed", bi.Network, bi.currentProtocol.Alias)
-	return nil
+func protocolID(network, hash string) string {
+	return helpers.GenerateIDFrom(network, hash)
 }
 
-func createProtocol(es *elastic.Elastic, network, hash string, level int64) (protocol models.Protocol, err error) {
-	logger.Info("[%s] Creating new protocol %s starting at %d", network, hash, level)
+func buildProtocol(network, hash string, level int64) (protocol models.Protocol, err error) {
 	protocol.SymLink, err = meta.GetProtoSymLink(hash)
 	if err != nil {
 		return
@@ -530,16 +704,22 @@ func createProtocol(es *elastic.Elastic, network, hash string, level int64) (pro
 	protocol.Network = network
 	protocol.Hash = hash
 	protocol.StartLevel = level
-	protocol.ID = helpers.GenerateID()
+	protocol.ID = protocolID(network, hash)
+	return
+}
 
-	_, err = es.AddDocumentWithID(protocol, elastic.DocProtocol, protocol.ID)
+func createProtocol(es elastic.IElastic, network, hash string, level int64) (protocol models.Protocol, err error) {
+	logger.Info("[%s] Creating new protocol %s starting at %d", network, hash, level)
+	protocol, err = buildProtocol(network, hash, level)
 	if err != nil {
 		return
 	}
+
+	_, err = es.AddDocumentWithID(protocol, elastic.DocProtocol, protocol.ID)
 	return
 }
 
-func (bi *BoostIndexer) standartMigration(newProtocol models.Protocol) error {
+func (bi *BoostIndexer) standartMigration(prevProtocol, newProtocol models.Protocol, data *blockData) error {
 	log.Printf("[%s] Try to find migrations...", bi.Network)
 	contracts, err := bi.es.GetContracts(map[string]interface{}{
 		"network": bi.Network,
@@ -550,7 +730,6 @@ func (bi *BoostIndexer) standartMigration(newProtocol models.Protocol) error {
 	log.Printf("[%s] Now %d contracts are indexed", bi.Network, len(contracts))
 
 	p := parsers.NewMigrationParser(bi.rpc, bi.es, bi.filesDirectory)
-	migrations := make([]elastic.Model, 0)
 	for i := range contracts {
 		logger.Info("Migrate %s...", contracts[i].Address)
 		script, err := bi.rpc.GetScriptJSON(contracts[i].Address, newProtocol.StartLevel)
@@ -558,22 +737,19 @@ func (bi *BoostIndexer) standartMigration(newProtocol models.Protocol) error {
 			return err
 		}
 
-		migration, err := p.Parse(script, contracts[i], bi.currentProtocol, newProtocol)
+		migration, err := p.Parse(script, contracts[i], prevProtocol, newProtocol)
 		if err != nil {
 			return err
 		}
 
 		if migration != nil {
-			migrations = append(migrations, migration)
+			data.migrations = append(data.migrations, migration)
 		}
 	}
-	if err := bi.saveModels(migrations, mq.QueueMigrations); err != nil {
-		return err
-	}
 	return nil
 }
 
-func (bi *BoostIndexer) vestingMigration(head noderpc.Header) error {
+func (bi *BoostIndexer) vestingMigration(head noderpc.Header, data *blockData) error {
 	addresses, err := bi.rpc.GetContractsByBlock(head.Level)
 	if err != nil {
 		return err
@@ -581,33 +757,76 @@ func (bi *BoostIndexer) vestingMigration(head noderpc.Header) error {
 
 	p := parsers.NewVestingParser(bi.rpc, bi.es, bi.filesDirectory)
 
-	migrations := make([]elastic.Model, 0)
-	contracts := make([]elastic.Model, 0)
 	for _, address := range addresses {
 		if !strings.HasPrefix(address, "KT") {
 			continue
 		}
 
-		data, err := bi.rpc.GetContractJSON(address, head.Level)
+		script, err := bi.rpc.GetContractJSON(address, head.Level)
 		if err != nil {
 			return err
 		}
 
-		migration, contract, err := p.Parse(data, head, bi.Network, address)
+		migration, contract, err := p.Parse(script, head, bi.Network, address)
 		if err != nil {
 			return err
 		}
-		migrations = append(migrations, migration)
+		data.migrations = append(data.migrations, migration)
 		if contract != nil {
-			contracts = append(contracts, contract)
+			data.contracts = append(data.contracts, contract)
+			if contract.Balance != 0 {
+				data.balances = append(data.balances, &models.BalanceChange{
+					ID:        models.BalanceChangeID(bi.Network, contract.Address, head.Level, consts.Migration),
+					Network:   bi.Network,
+					Address:   contract.Address,
+					Level:     head.Level,
+					Timestamp: head.Timestamp,
+					Kind:      consts.Migration,
+					Change:    contract.Balance,
+				})
+			}
 		}
 	}
 
-	if err := bi.saveModels(contracts, mq.QueueContracts); err != nil {
+	return nil
+}
+
+// balanceMigration - protocol migration may change balances of contracts without operations.
+// The node reports such changes in balance updates of the first block of the new protocol.
+func (bi *BoostIndexer) balanceMigration(head noderpc.Header, data *blockData) error {
+	metadata, err := bi.rpc.GetBlockMetadata(head.Level)
+	if err != nil {
 		return err
 	}
-	if err := bi.saveModels(migrations, mq.QueueMigrations); err != nil {
-		return err
+
+	index := make(map[string]*models.BalanceChange)
+	for _, update := range metadata.Get(`balance_updates.#(kind=="contract")#`).Array() {
+		// protocols before 008 have no `origin` and report only baking rewards of implicit accounts besides migration
+		if origin := update.Get("origin").String(); origin != "" && origin != consts.Migration {
+			continue
+		}
+		address := update.Get("contract").String()
+		if !strings.HasPrefix(address, "KT") {
+			continue
+		}
+		change, ok := index[address]
+		if !ok {
+			change = &models.BalanceChange{
+				ID:        models.BalanceChangeID(bi.Network, address, head.Level, consts.Migration),
+				Network:   bi.Network,
+				Address:   address,
+				Level:     head.Level,
+				Timestamp: head.Timestamp,
+				Kind:      consts.Migration,
+			}
+			index[address] = change
+			data.balances = append(data.balances, change)
+		}
+		change.Change += update.Get("change").Int()
+	}
+
+	for address, change := range index {
+		logger.Info("[%s] Balance of %s is changed by migration: %d", bi.Network, address, change.Change)
 	}
 	return nil
 }
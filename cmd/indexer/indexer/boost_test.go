@@ -0,0 +1,252 @@
+package indexer
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/baking-bad/bcdhub/cmd/indexer/parsers"
+	"github.com/baking-bad/bcdhub/internal/elastic/memory"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/baking-bad/bcdhub/internal/noderpc"
+)
+
+const (
+	testNetwork  = "babylonnet"
+	testContract = "KT1BUKeJTemAaVBfRz6cqxeUBQGQqMxfG19A"
+	testManager  = "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx"
+	testBaker    = "tz1VQnqCCqX4K5sP3FNkVSNKTdCAMJDd3E1n"
+)
+
+// TestMain - contract interfaces are loaded from `interfaces/` relative to the working directory of the indexer
+func TestMain(m *testing.M) {
+	if err := os.Chdir(".."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+type testPublisher struct {
+	mux      sync.Mutex
+	messages map[string][]interface{}
+}
+
+func (p *testPublisher) Send(channel, queue string, v interface{}) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.messages[queue] = append(p.messages[queue], v)
+	return nil
+}
+
+func (p *testPublisher) Close() {}
+
+func (p *testPublisher) count(queue string) int {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return len(p.messages[queue])
+}
+
+func newTestIndexer(t *testing.T, fixtures string, opts ...BoostIndexerOption) (*BoostIndexer, *memory.Storage, *testPublisher, func()) {
+	dir, err := ioutil.TempDir("", "bcd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	es := memory.New()
+	publisher := &testPublisher{messages: make(map[string][]interface{})}
+	bi, err := newBoostIndexer(testNetwork, noderpc.NewFixturePool(fixtures), es, publisher, dir, opts...)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return bi, es, publisher, func() { os.RemoveAll(dir) }
+}
+
+func bigMapValues(t *testing.T, es *memory.Storage) map[string]string {
+	diffs, err := es.GetBigMapDiffsForAddress(testNetwork, testContract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[string]string)
+	for i := range diffs {
+		values[diffs[i].KeyHash] = diffs[i].Value
+	}
+	return values
+}
+
+func TestBoostIndexer_Index(t *testing.T) {
+	bi, es, publisher, cleanup := newTestIndexer(t, "indexer/testdata/chain")
+	defer cleanup()
+
+	if err := bi.Index([]int64{1, 2, 3}); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+
+	state, err := es.CurrentState(testNetwork)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Level != 3 || state.Hash != "BL3" {
+		t.Errorf("state = %d %s, want 3 BL3", state.Level, state.Hash)
+	}
+
+	contract, err := es.GetContractByAddressAndNetwork(testNetwork, testContract)
+	if err != nil {
+		t.Fatalf("contract is not indexed: %v", err)
+	}
+	if contract.Level != 2 || contract.Manager != testManager {
+		t.Errorf("contract = %+v", contract)
+	}
+
+	var operations []models.Operation
+	if err := es.GetByNetwork(testNetwork, &operations); err != nil {
+		t.Fatal(err)
+	}
+	kinds := make(map[string]string)
+	for i := range operations {
+		kinds[operations[i].Kind] = operations[i].Entrypoint
+	}
+	if len(operations) != 3 || kinds["transaction"] != "default" {
+		t.Errorf("operations = %v", kinds)
+	}
+
+	// delegation of the contract is stored, operations of implicit accounts are skipped by the default policy
+	delegations, err := es.GetDelegations(testNetwork, testContract, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(delegations) != 1 || delegations[0].Delegate != testBaker || !delegations[0].Internal {
+		t.Errorf("delegations = %+v", delegations)
+	}
+	if _, err := es.GetReveal(testNetwork, testManager); err == nil {
+		t.Errorf("reveal of implicit account is indexed by the default policy")
+	}
+
+	// origination credit and transfer to the contract
+	changes, err := es.GetBalanceChanges(testNetwork, testContract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Change != 2000000 || changes[1].Change != 300000 {
+		t.Errorf("balance changes = %+v", changes)
+	}
+	if balance, err := es.GetBalanceAt(testNetwork, testContract, 2); err != nil || balance != 2000000 {
+		t.Errorf("GetBalanceAt(2) = %d %v, want 2000000", balance, err)
+	}
+
+	values := bigMapValues(t, es)
+	if len(values) != 2 || values["exprtzbxYSbdnkd7tUgDiExkFEQpaKwXHnEpLjJyZQPMr3sfrKqaXs"] == "" || values["exprv6UsC1sN3Fk2XfgcJCL8NCerP5rCGy1PRESZAqr7L2JdzX55EN"] == "" {
+		t.Errorf("big map = %v", values)
+	}
+
+	if publisher.count("contracts") != 1 || publisher.count("operations") != 3 || publisher.count("recalc") != 1 {
+		t.Errorf("notifications are not delivered: %v", publisher.messages)
+	}
+
+	// the same levels are indexed again without duplicates
+	bi.state = models.Block{}
+	if err := bi.Index([]int64{2, 3}); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	operations = nil
+	if err := es.GetByNetwork(testNetwork, &operations); err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 3 {
+		t.Errorf("reindex created duplicates: %d operations", len(operations))
+	}
+}
+
+func TestBoostIndexer_Rollback(t *testing.T) {
+	bi, es, _, cleanup := newTestIndexer(t, "indexer/testdata/chain")
+	defer cleanup()
+
+	if err := bi.Index([]int64{1, 2, 3}); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+
+	// node switched to another branch from level 3
+	bi.rpc = noderpc.NewFixturePool("indexer/testdata/fork")
+	if err := bi.Index([]int64{4}); err == nil || err.Error() != "rollback" {
+		t.Fatalf("Index() error = %v, want rollback", err)
+	}
+	if err := bi.Rollback(); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if bi.state.Level != 2 {
+		t.Errorf("state after rollback = %d, want 2", bi.state.Level)
+	}
+	if values := bigMapValues(t, es); len(values) != 1 {
+		t.Errorf("big map after rollback = %v", values)
+	}
+	if delegations, err := es.GetDelegations(testNetwork, testContract, 0, 0); err != nil || len(delegations) != 0 {
+		t.Errorf("delegations after rollback = %v %v", delegations, err)
+	}
+	if balance, err := es.GetBalanceAt(testNetwork, testContract, 3); err != nil || balance != 2000000 {
+		t.Errorf("balance after rollback = %d %v, want 2000000", balance, err)
+	}
+
+	if err := bi.Index([]int64{3, 4}); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	if bi.state.Level != 4 || bi.state.Hash != "BL4f" {
+		t.Errorf("state = %d %s, want 4 BL4f", bi.state.Level, bi.state.Hash)
+	}
+
+	// balance changed by the protocol migration at level 4 is corrected
+	if balance, err := es.GetBalanceAt(testNetwork, testContract, 4); err != nil || balance != 2000050 {
+		t.Errorf("balance after migration = %d %v, want 2000050", balance, err)
+	}
+
+	// protocols are switched with the commit of the migration level
+	if bi.currentProtocol.Hash != "PsCARTHAGazKbHtnKfLzQg3kms52kSRpgnDY982a9oYsSXRLQEb" || bi.currentProtocol.StartLevel != 4 {
+		t.Errorf("current protocol = %+v", bi.currentProtocol)
+	}
+	prev, err := es.GetProtocol(testNetwork, "PsBabyM1eUXZseaJdmXFApDSBqj8YBfwELoxZHHW77EMcAbbwAS", 3)
+	if err != nil || prev.EndLevel != 3 {
+		t.Errorf("previous protocol = %+v %v, want end level 3", prev, err)
+	}
+}
+
+func TestBoostIndexer_Policy(t *testing.T) {
+	policy, err := parsers.NewPolicy([]string{"transaction", "origination", "delegation", "reveal"}, []string{"contract", "implicit"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bi, es, _, cleanup := newTestIndexer(t, "indexer/testdata/chain", WithPolicy(policy))
+	defer cleanup()
+
+	if err := bi.Index([]int64{1, 2, 3}); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+
+	reveal, err := es.GetReveal(testNetwork, testManager)
+	if err != nil {
+		t.Fatalf("reveal is not indexed: %v", err)
+	}
+	if reveal.Level != 1 || reveal.PublicKey != "edpkuBknW28nW72KG6RoHtYW7p12T6GKc7nAbwYX5m8Wd9sDVC9yav" {
+		t.Errorf("reveal = %+v", reveal)
+	}
+
+	delegation, err := es.GetLastDelegation(testNetwork, testManager)
+	if err != nil {
+		t.Fatalf("delegation is not indexed: %v", err)
+	}
+	if delegation.Level != 1 || delegation.Delegate != testBaker || delegation.Internal {
+		t.Errorf("delegation = %+v", delegation)
+	}
+
+	var operations []models.Operation
+	if err := es.GetByNetwork(testNetwork, &operations); err != nil {
+		t.Fatal(err)
+	}
+	var transfers int
+	for i := range operations {
+		if operations[i].Source == testManager && operations[i].Destination == testBaker {
+			transfers++
+		}
+	}
+	if len(operations) != 4 || transfers != 1 {
+		t.Errorf("operations = %d, transfers between implicit accounts = %d", len(operations), transfers)
+	}
+}
@@ -1,6 +1,9 @@
 package indexer
 
 import (
+	"fmt"
+
+	"github.com/baking-bad/bcdhub/cmd/indexer/parsers"
 	"github.com/baking-bad/bcdhub/internal/config"
 	"github.com/baking-bad/bcdhub/internal/contractparser/cerrors"
 )
@@ -13,7 +16,15 @@ func CreateIndexers(cfg config.Config) ([]Indexer, error) {
 
 	indexers := make([]Indexer, 0)
 	for network, options := range cfg.Indexer.Networks {
-		boostOptions := make([]BoostIndexerOption, 0)
+		policy, err := parsers.NewPolicy(options.Operations.Kinds, options.Operations.Accounts)
+		if err != nil {
+			return nil, fmt.Errorf("[%s] %s", network, err)
+		}
+		boostOptions := []BoostIndexerOption{
+			WithPipeline(options.Concurrency, options.Prefetch),
+			WithPolicy(policy),
+			WithInterfaces(cfg.Indexer.Interfaces),
+		}
 		if options.Boost != "" {
 			boostOptions = append(boostOptions, WithBoost(options.Boost, network, cfg))
 		}
@@ -0,0 +1,237 @@
+package indexer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser"
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/contractparser/meta"
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/helpers"
+	"github.com/baking-bad/bcdhub/internal/logger"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/tidwall/gjson"
+)
+
+// interfacesPeriod - how often user-defined interfaces added through API are picked up from the storage
+const interfacesPeriod = time.Minute
+
+// retaggedMux - `retagged` list of the interface is updated by indexers of all networks
+var retaggedMux sync.Mutex
+
+// importInterfaces - saves interfaces from the config directory which are newer than the stored ones
+func (bi *BoostIndexer) importInterfaces() error {
+	if bi.interfacesDirectory == "" {
+		return nil
+	}
+	files, err := ioutil.ReadDir(bi.interfacesDirectory)
+	if err != nil {
+		if os.IsNotExist(err) {
+			logger.Warning("[%s] Interfaces directory %s doesn't exist", bi.Network, bi.interfacesDirectory)
+			return nil
+		}
+		return err
+	}
+
+	var stored []models.Interface
+	if err := bi.es.GetAll(&stored); err != nil {
+		return err
+	}
+	versions := make(map[string]int64)
+	for i := range stored {
+		if stored[i].Version > versions[stored[i].Name] {
+			versions[stored[i].Name] = stored[i].Version
+		}
+	}
+
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(bi.interfacesDirectory, f.Name()))
+		if err != nil {
+			return err
+		}
+		item, err := parseInterfaceFile(data)
+		if err != nil {
+			return fmt.Errorf("%s: %v", f.Name(), err)
+		}
+		if version, ok := versions[item.Name]; ok && version >= item.Version {
+			continue
+		}
+		versions[item.Name] = item.Version
+		// indexers of all networks import the same files, the version is saved by the first one
+		if err := bi.es.CreateDocument(item); err != nil {
+			if elastic.IsDocumentExists(err) {
+				continue
+			}
+			return err
+		}
+		logger.Info("[%s] Interface %s version %d is imported from %s", bi.Network, item.Name, item.Version, f.Name())
+	}
+	return nil
+}
+
+// parseInterfaceFile - file contains `{"name": ..., "version": ..., "entrypoints": [{"name": ..., "type": <Micheline type>}]}`. Version is 1 if it's omitted.
+func parseInterfaceFile(data []byte) (*models.Interface, error) {
+	file := gjson.ParseBytes(data)
+	name := file.Get("name").String()
+	version := file.Get("version").Int()
+	if version <= 0 {
+		version = 1
+	}
+	entrypoints := file.Get("entrypoints")
+	if _, err := contractparser.NewInterface(name, version, entrypoints); err != nil {
+		return nil, err
+	}
+	return models.NewInterface(name, version, entrypoints.Raw), nil
+}
+
+// registerInterfaces - adds stored interfaces to the registry used for tagging of new contracts
+func (bi *BoostIndexer) registerInterfaces() ([]models.Interface, error) {
+	var items []models.Interface
+	if err := bi.es.GetAll(&items); err != nil {
+		return nil, err
+	}
+	for i := range items {
+		iface, err := contractparser.NewInterface(items[i].Name, items[i].Version, gjson.Parse(items[i].Entrypoints))
+		if err != nil {
+			logger.Errorf("[%s] Interface %s version %d: %s", bi.Network, items[i].Name, items[i].Version, err)
+			continue
+		}
+		if contractparser.RegisterInterface(iface) {
+			logger.Info("[%s] Interface %s version %d is registered", bi.Network, iface.Name, iface.Version)
+		}
+	}
+	return items, nil
+}
+
+// syncInterfaces - registers new interfaces and re-tags existing contracts of the network by them
+func (bi *BoostIndexer) syncInterfaces() error {
+	items, err := bi.registerInterfaces()
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		if helpers.StringInArray(bi.Network, items[i].Retagged) {
+			continue
+		}
+		// only the registered version is applied, older ones are just marked as processed
+		if iface, ok := contractparser.GetInterface(items[i].Name); ok && iface.Version == items[i].Version {
+			count, err := bi.retag(iface)
+			if err != nil {
+				return err
+			}
+			logger.Info("[%s] %d contracts are re-tagged by interface %s version %d", bi.Network, count, iface.Name, iface.Version)
+		}
+		if err := bi.markRetagged(items[i].ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runInterfaces - synchronizes interfaces at start and every `period` until `stop` is closed
+func (bi *BoostIndexer) runInterfaces(period time.Duration, stop <-chan struct{}) {
+	if err := bi.syncInterfaces(); err != nil {
+		logger.Errorf("[%s] Interfaces: %s", bi.Network, err)
+	}
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := bi.syncInterfaces(); err != nil {
+				logger.Errorf("[%s] Interfaces: %s", bi.Network, err)
+			}
+		}
+	}
+}
+
+// retag - updates tags of the network contracts by the interface. Returns count of changed contracts.
+func (bi *BoostIndexer) retag(iface contractparser.Interface) (int, error) {
+	protocol, err := bi.es.GetProtocol(bi.Network, "", -1)
+	if err != nil {
+		return 0, err
+	}
+	contracts, err := bi.es.GetContracts(map[string]interface{}{
+		"network": bi.Network,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	for i := range contracts {
+		metadata, err := meta.GetMetadata(bi.es, contracts[i].Address, consts.PARAMETER, protocol.Hash)
+		if err != nil {
+			logger.Errorf("[%s] Re-tagging %s: %s", bi.Network, contracts[i].Address, err)
+			continue
+		}
+		if !retagContract(&contracts[i], iface, iface.Match(metadata)) {
+			continue
+		}
+		if err := bi.es.UpdateFields(elastic.DocContracts, contracts[i].ID, contracts[i], "Tags", "TagVersions"); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// retagContract - sets or removes the interface tag. Returns true if the contract was changed.
+func retagContract(contract *models.Contract, iface contractparser.Interface, matched bool) bool {
+	hasTag := helpers.StringInArray(iface.Name, contract.Tags)
+	versions := make(map[string]int64)
+	for _, tv := range contract.TagVersions {
+		versions[tv.Tag] = tv.Version
+	}
+	version, hasVersion := versions[iface.Name]
+
+	switch {
+	case matched && hasTag && hasVersion && version == iface.Version:
+		return false
+	case matched:
+		if !hasTag {
+			contract.Tags = append(contract.Tags, iface.Name)
+		}
+		versions[iface.Name] = iface.Version
+	case hasTag || hasVersion:
+		tags := make([]string, 0, len(contract.Tags))
+		for _, tag := range contract.Tags {
+			if tag != iface.Name {
+				tags = append(tags, tag)
+			}
+		}
+		contract.Tags = tags
+		delete(versions, iface.Name)
+	default:
+		return false
+	}
+	contract.TagVersions = models.NewTagVersions(versions)
+	return true
+}
+
+func (bi *BoostIndexer) markRetagged(id string) error {
+	retaggedMux.Lock()
+	defer retaggedMux.Unlock()
+
+	item := models.Interface{ID: id}
+	if err := bi.es.GetByID(&item); err != nil {
+		return err
+	}
+	if helpers.StringInArray(bi.Network, item.Retagged) {
+		return nil
+	}
+	item.Retagged = append(item.Retagged, bi.Network)
+	return bi.es.UpdateFields(elastic.DocInterfaces, id, item, "Retagged")
+}
@@ -0,0 +1,105 @@
+package indexer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser"
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+func Test_retagContract(t *testing.T) {
+	iface := contractparser.Interface{Name: "custom", Version: 2}
+	tests := []struct {
+		name         string
+		contract     models.Contract
+		matched      bool
+		want         bool
+		wantTags     []string
+		wantVersions []models.TagVersion
+	}{
+		{
+			name:         "new tag",
+			contract:     models.Contract{Tags: []string{"fa12"}, TagVersions: []models.TagVersion{{Tag: "fa12", Version: 0}}},
+			matched:      true,
+			want:         true,
+			wantTags:     []string{"fa12", "custom"},
+			wantVersions: []models.TagVersion{{Tag: "custom", Version: 2}, {Tag: "fa12", Version: 0}},
+		}, {
+			name:         "new version",
+			contract:     models.Contract{Tags: []string{"custom"}, TagVersions: []models.TagVersion{{Tag: "custom", Version: 1}}},
+			matched:      true,
+			want:         true,
+			wantTags:     []string{"custom"},
+			wantVersions: []models.TagVersion{{Tag: "custom", Version: 2}},
+		}, {
+			name:         "same version",
+			contract:     models.Contract{Tags: []string{"custom"}, TagVersions: []models.TagVersion{{Tag: "custom", Version: 2}}},
+			matched:      true,
+			want:         false,
+			wantTags:     []string{"custom"},
+			wantVersions: []models.TagVersion{{Tag: "custom", Version: 2}},
+		}, {
+			name:         "not matched by new version",
+			contract:     models.Contract{Tags: []string{"custom", "delegatable"}, TagVersions: []models.TagVersion{{Tag: "custom", Version: 1}}},
+			matched:      false,
+			want:         true,
+			wantTags:     []string{"delegatable"},
+			wantVersions: []models.TagVersion{},
+		}, {
+			name:     "not matched",
+			contract: models.Contract{Tags: []string{"delegatable"}},
+			matched:  false,
+			want:     false,
+			wantTags: []string{"delegatable"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retagContract(&tt.contract, iface, tt.matched); got != tt.want {
+				t.Errorf("retagContract() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(tt.contract.Tags, tt.wantTags) {
+				t.Errorf("Tags = %v, want %v", tt.contract.Tags, tt.wantTags)
+			}
+			if !reflect.DeepEqual(tt.contract.TagVersions, tt.wantVersions) {
+				t.Errorf("TagVersions = %v, want %v", tt.contract.TagVersions, tt.wantVersions)
+			}
+		})
+	}
+}
+
+func Test_parseInterfaceFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		wantVersion int64
+		wantErr     bool
+	}{
+		{
+			name:        "default version",
+			data:        `{"name":"custom","entrypoints":[{"name":"ping","type":{"prim":"unit"}}]}`,
+			wantVersion: 1,
+		}, {
+			name:        "version",
+			data:        `{"name":"custom","version":3,"entrypoints":[{"name":"ping","type":{"prim":"unit"}}]}`,
+			wantVersion: 3,
+		}, {
+			name:    "no entrypoints",
+			data:    `{"name":"custom"}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseInterfaceFile([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseInterfaceFile() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got.Version != tt.wantVersion {
+				t.Errorf("parseInterfaceFile() version = %v, want %v", got.Version, tt.wantVersion)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/baking-bad/bcdhub/cmd/indexer/parsers"
 	"github.com/baking-bad/bcdhub/internal/config"
 	"github.com/baking-bad/bcdhub/internal/index"
 )
@@ -28,3 +29,29 @@ func WithBoost(externalType, network string, cfg config.Config) BoostIndexerOpti
 		}
 	}
 }
+
+// WithPipeline - count of workers fetching and parsing blocks and count of levels which may be fetched ahead of committing
+func WithPipeline(concurrency, window int) BoostIndexerOption {
+	return func(bi *BoostIndexer) {
+		if concurrency > 0 {
+			bi.concurrency = concurrency
+		}
+		if window > 0 {
+			bi.window = window
+		}
+	}
+}
+
+// WithPolicy - operation kinds and account types which are indexed
+func WithPolicy(policy parsers.Policy) BoostIndexerOption {
+	return func(bi *BoostIndexer) {
+		bi.policy = policy
+	}
+}
+
+// WithInterfaces - directory with user-defined interfaces which are imported to the storage at start
+func WithInterfaces(directory string) BoostIndexerOption {
+	return func(bi *BoostIndexer) {
+		bi.interfacesDirectory = directory
+	}
+}
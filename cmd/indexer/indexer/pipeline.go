@@ -0,0 +1,128 @@
+package indexer
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/baking-bad/bcdhub/internal/noderpc"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	defaultConcurrency = 4
+	defaultWindow      = 16
+)
+
+var errPipelineClosed = fmt.Errorf("pipeline is closed")
+
+// fetchedBlock - header and operation groups of the level received from the node
+type fetchedBlock struct {
+	level  int64
+	header noderpc.Header
+	groups []gjson.Result
+	err    error
+}
+
+// pipeline - fetches blocks of upcoming levels by `concurrency` workers and returns them strictly in level order.
+// Not more than `window` levels are fetched ahead of the consumer. Fetched levels are parsed concurrently by `scheduler`.
+type pipeline struct {
+	rpc         noderpc.Pool
+	concurrency int
+	window      int
+
+	done chan struct{}
+	once sync.Once
+}
+
+func newPipeline(rpc noderpc.Pool, concurrency, window int) *pipeline {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if window < concurrency {
+		window = concurrency
+	}
+	return &pipeline{
+		rpc:         rpc,
+		concurrency: concurrency,
+		window:      window,
+		done:        make(chan struct{}),
+	}
+}
+
+type fetchJob struct {
+	level  int64
+	result chan *fetchedBlock
+}
+
+// Run - starts fetching of `levels`. The returned channel is closed when all levels are sent or the pipeline is closed.
+func (p *pipeline) Run(levels []int64) <-chan *fetchedBlock {
+	jobs := make(chan fetchJob, p.window)
+	ordered := make(chan fetchJob, p.window)
+	results := make(chan *fetchedBlock)
+
+	go func() {
+		defer close(jobs)
+		defer close(ordered)
+		for _, level := range levels {
+			job := fetchJob{level, make(chan *fetchedBlock, 1)}
+			select {
+			case <-p.done:
+				return
+			case ordered <- job:
+			}
+			jobs <- job
+		}
+	}()
+
+	for i := 0; i < p.concurrency; i++ {
+		go func() {
+			for job := range jobs {
+				select {
+				case <-p.done:
+					job.result <- &fetchedBlock{level: job.level, err: errPipelineClosed}
+				default:
+					job.result <- p.fetch(job.level)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(results)
+		for job := range ordered {
+			select {
+			case <-p.done:
+				return
+			case block := <-job.result:
+				select {
+				case <-p.done:
+					return
+				case results <- block:
+				}
+			}
+		}
+	}()
+	return results
+}
+
+// Close - stops fetching. It's safe to call it several times.
+func (p *pipeline) Close() {
+	p.once.Do(func() {
+		close(p.done)
+	})
+}
+
+func (p *pipeline) fetch(level int64) *fetchedBlock {
+	block := &fetchedBlock{level: level}
+	block.header, block.err = p.rpc.GetHeader(level)
+	if block.err != nil {
+		return block
+	}
+	data, err := p.rpc.GetOperations(level)
+	if err != nil {
+		block.err = err
+		return block
+	}
+	block.groups = data.Array()
+	return block
+}
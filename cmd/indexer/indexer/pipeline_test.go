@@ -0,0 +1,87 @@
+package indexer
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/noderpc"
+)
+
+func newTestNode() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// responses come back out of order
+		time.Sleep(time.Duration(rand.Intn(5)) * time.Millisecond)
+
+		var level int64
+		if _, err := fmt.Sscanf(r.URL.Path, "/chains/main/blocks/%d/header", &level); err == nil {
+			fmt.Fprintf(w, `{"level": %d, "hash": "B%d"}`, level, level)
+			return
+		}
+		if _, err := fmt.Sscanf(r.URL.Path, "/chains/main/blocks/%d/operations/3", &level); err == nil {
+			fmt.Fprintf(w, `[{"hash": "o%d"}]`, level)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+}
+
+func TestPipeline(t *testing.T) {
+	server := newTestNode()
+	defer server.Close()
+
+	levels := make([]int64, 50)
+	for i := range levels {
+		levels[i] = int64(i + 1)
+	}
+
+	tests := []struct {
+		name        string
+		concurrency int
+		window      int
+	}{
+		{"sequential", 1, 1},
+		{"concurrent", 4, 8},
+		{"window less than concurrency", 8, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPipeline(noderpc.NewPool([]string{server.URL}, time.Second), tt.concurrency, tt.window)
+			defer p.Close()
+
+			var count int
+			for block := range p.Run(levels) {
+				if block.err != nil {
+					t.Fatalf("fetch %d: %s", block.level, block.err)
+				}
+				want := levels[count]
+				if block.level != want || block.header.Level != want {
+					t.Errorf("got level %d (header %d), want %d", block.level, block.header.Level, want)
+				}
+				if len(block.groups) != 1 || block.groups[0].Get("hash").String() != fmt.Sprintf("o%d", want) {
+					t.Errorf("level %d: unexpected operations %v", want, block.groups)
+				}
+				count++
+			}
+			if count != len(levels) {
+				t.Errorf("got %d blocks, want %d", count, len(levels))
+			}
+		})
+	}
+}
+
+func TestPipeline_Close(t *testing.T) {
+	server := newTestNode()
+	defer server.Close()
+
+	p := newPipeline(noderpc.NewPool([]string{server.URL}, time.Second), 2, 4)
+	results := p.Run([]int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+	<-results
+	p.Close()
+
+	for range results {
+	}
+}
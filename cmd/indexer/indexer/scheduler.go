@@ -0,0 +1,166 @@
+package indexer
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/tidwall/gjson"
+)
+
+// parseTask - parsing of the fetched level. The level is parsed when all levels it depends on are committed.
+// Levels are committed strictly in level order.
+type parseTask struct {
+	block *fetchedBlock
+
+	contracts map[string]struct{}
+	exclusive bool
+	migrate   bool
+	deps      []*parseTask
+
+	data     blockData
+	protocol models.Protocol
+	err      error
+
+	parsed    chan struct{}
+	committed chan struct{}
+}
+
+func newParseTask(block *fetchedBlock) *parseTask {
+	return &parseTask{
+		block:     block,
+		parsed:    make(chan struct{}),
+		committed: make(chan struct{}),
+	}
+}
+
+func (t *parseTask) isCommitted() bool {
+	select {
+	case <-t.committed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (t *parseTask) conflicts(other *parseTask) bool {
+	if t.exclusive || other.exclusive {
+		return true
+	}
+	for address := range t.contracts {
+		if _, ok := other.contracts[address]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// blockContracts - contracts which are called, make calls or are originated by operations of the level.
+// Parsing of the operation reads only state of these contracts, so levels with disjoint contracts are parsed concurrently.
+// Originations also read code of other contracts to find similar ones, so levels with originations are parsed exclusively.
+func blockContracts(groups []gjson.Result) (contracts map[string]struct{}, originates bool) {
+	contracts = make(map[string]struct{})
+	add := func(content gjson.Result, resultPath string) {
+		for _, field := range []string{"source", "destination"} {
+			if address := content.Get(field).String(); strings.HasPrefix(address, "KT") {
+				contracts[address] = struct{}{}
+			}
+		}
+		if content.Get("kind").String() == consts.Origination {
+			originates = true
+		}
+		for _, address := range content.Get(resultPath + ".originated_contracts").Array() {
+			contracts[address.String()] = struct{}{}
+			originates = true
+		}
+	}
+
+	for _, group := range groups {
+		for _, content := range group.Get("contents").Array() {
+			add(content, "metadata.operation_result")
+			for _, internal := range content.Get("metadata.internal_operation_results").Array() {
+				add(internal, "result")
+			}
+		}
+	}
+	return
+}
+
+// scheduler - decides which already fetched levels may be parsed before the previous levels are committed
+type scheduler struct {
+	boost    bool
+	protocol string
+	hash     string
+
+	pending []*parseTask
+}
+
+// schedule - sets dependencies of the task. Returns error if the chain of blocks is broken.
+func (s *scheduler) schedule(task *parseTask) error {
+	header := task.block.header
+	if !s.boost && s.hash != "" && header.Predecessor != s.hash {
+		return fmt.Errorf("rollback")
+	}
+	s.hash = header.Hash
+
+	task.migrate = header.Protocol != s.protocol
+	s.protocol = header.Protocol
+
+	var originates bool
+	task.contracts, originates = blockContracts(task.block.groups)
+	task.exclusive = task.migrate || originates
+
+	pending := make([]*parseTask, 0, len(s.pending)+1)
+	for _, prev := range s.pending {
+		if prev.isCommitted() {
+			continue
+		}
+		pending = append(pending, prev)
+		if task.conflicts(prev) {
+			task.deps = append(task.deps, prev)
+		}
+	}
+	s.pending = append(pending, task)
+	return nil
+}
+
+// parse - waits for dependencies and parses the level. `slots` limits count of levels parsed at the same time.
+func (bi *BoostIndexer) parse(task *parseTask, slots chan struct{}, quit chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer close(task.parsed)
+
+	for _, dep := range task.deps {
+		select {
+		case <-dep.committed:
+		case <-quit:
+			task.err = errPipelineClosed
+			return
+		}
+	}
+
+	select {
+	case slots <- struct{}{}:
+	case <-quit:
+		task.err = errPipelineClosed
+		return
+	}
+	defer func() { <-slots }()
+
+	// the protocol of the indexer is switched only after the level of the migration is committed,
+	// so the migration is detected again if the commit fails
+	header := task.block.header
+	var migration blockData
+	if task.migrate {
+		log.Printf("[%s] New protocol detected: %s -> %s", bi.Network, bi.currentProtocol.Hash, header.Protocol)
+		if task.protocol, task.err = bi.migrate(header, &migration); task.err != nil {
+			return
+		}
+	}
+	if task.data, task.err = bi.getDataFromBlock(bi.Network, header, task.block.groups); task.err != nil {
+		return
+	}
+	task.data.add(migration)
+}
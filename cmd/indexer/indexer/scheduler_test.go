@@ -0,0 +1,81 @@
+package indexer
+
+import (
+	"testing"
+
+	"github.com/baking-bad/bcdhub/internal/noderpc"
+	"github.com/tidwall/gjson"
+)
+
+func testBlock(level int64, protocol, operations string) *fetchedBlock {
+	return &fetchedBlock{
+		level: level,
+		header: noderpc.Header{
+			Level:       level,
+			Hash:        "B" + string(rune('0'+level)),
+			Predecessor: "B" + string(rune('0'+level-1)),
+			Protocol:    protocol,
+		},
+		groups: gjson.Parse(operations).Array(),
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	const (
+		callA      = `[{"contents":[{"kind":"transaction","source":"tz1X","destination":"KT1A"}]}]`
+		callB      = `[{"contents":[{"kind":"transaction","source":"tz1X","destination":"KT1B"}]}]`
+		internalA  = `[{"contents":[{"kind":"transaction","source":"tz1X","destination":"KT1C","metadata":{"internal_operation_results":[{"kind":"transaction","source":"KT1C","destination":"KT1A"}]}}]}]`
+		originateD = `[{"contents":[{"kind":"origination","source":"tz1X","metadata":{"operation_result":{"originated_contracts":["KT1D"]}}}]}]`
+	)
+
+	s := &scheduler{protocol: "P1", hash: "B0"}
+	blocks := []*fetchedBlock{
+		testBlock(1, "P1", callA),
+		testBlock(2, "P1", callB),
+		testBlock(3, "P1", internalA),
+		testBlock(4, "P1", originateD),
+		testBlock(5, "P1", callB),
+		testBlock(6, "P2", callB),
+	}
+	// indexes of the levels each level waits for
+	// the first level is committed before the origination is scheduled
+	want := [][]int{{}, {}, {0}, {1, 2}, {1, 3}, {1, 2, 3, 4}}
+
+	tasks := make([]*parseTask, len(blocks))
+	for i, block := range blocks {
+		if i == 3 {
+			close(tasks[0].committed)
+		}
+		tasks[i] = newParseTask(block)
+		if err := s.schedule(tasks[i]); err != nil {
+			t.Fatalf("schedule(%d) error = %v", block.level, err)
+		}
+	}
+
+	for i, task := range tasks {
+		if i == 0 {
+			continue
+		}
+		deps := make(map[*parseTask]bool)
+		for _, dep := range task.deps {
+			deps[dep] = true
+		}
+		if len(deps) != len(want[i]) {
+			t.Errorf("level %d: %d dependencies, want %d", task.block.level, len(deps), len(want[i]))
+			continue
+		}
+		for _, idx := range want[i] {
+			if !deps[tasks[idx]] {
+				t.Errorf("level %d must wait for level %d", task.block.level, tasks[idx].block.level)
+			}
+		}
+	}
+	if !tasks[5].migrate || tasks[4].migrate {
+		t.Errorf("only the level of the new protocol has to be migrated")
+	}
+
+	broken := newParseTask(testBlock(8, "P2", callA))
+	if err := s.schedule(broken); err == nil {
+		t.Errorf("schedule() of the broken chain must fail")
+	}
+}
@@ -0,0 +1,83 @@
+package parsers
+
+import (
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+// blockStorage - storage which sees big map diffs and registry entries of the block being parsed before they are saved.
+// They are saved with other data of the block by the single commit.
+type blockStorage struct {
+	elastic.IElastic
+
+	bigMapDiffs []*models.BigMapDiff
+	bigMaps     []*models.BigMap
+}
+
+func newBlockStorage(es elastic.IElastic) *blockStorage {
+	return &blockStorage{
+		IElastic:    es,
+		bigMapDiffs: make([]*models.BigMapDiff, 0),
+		bigMaps:     make([]*models.BigMap, 0),
+	}
+}
+
+func (s *blockStorage) addBigMapDiffs(diffs []*models.BigMapDiff) {
+	s.bigMapDiffs = append(s.bigMapDiffs, diffs...)
+}
+
+// addBigMaps - the registry entry is replaced if the big map was already changed in the block
+func (s *blockStorage) addBigMaps(bigMaps []*models.BigMap) {
+	for _, bigMap := range bigMaps {
+		if idx := s.findBigMap(bigMap.ID); idx >= 0 {
+			s.bigMaps[idx] = bigMap
+		} else {
+			s.bigMaps = append(s.bigMaps, bigMap)
+		}
+	}
+}
+
+func (s *blockStorage) findBigMap(id string) int {
+	for i := range s.bigMaps {
+		if s.bigMaps[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetByID -
+func (s *blockStorage) GetByID(output elastic.Model) error {
+	if bigMap, ok := output.(*models.BigMap); ok {
+		if idx := s.findBigMap(bigMap.ID); idx >= 0 {
+			*bigMap = *s.bigMaps[idx]
+			return nil
+		}
+	}
+	return s.IElastic.GetByID(output)
+}
+
+// GetAllBigMapDiffByPtr - stored state of the big map updated by diffs of the block
+func (s *blockStorage) GetAllBigMapDiffByPtr(address, network string, ptr int64) ([]models.BigMapDiff, error) {
+	stored, err := s.IElastic.GetAllBigMapDiffByPtr(address, network, ptr)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int, len(stored))
+	for i := range stored {
+		index[stored[i].KeyHash] = i
+	}
+	for _, bmd := range s.bigMapDiffs {
+		if bmd.Network != network || bmd.Address != address || bmd.Ptr != ptr {
+			continue
+		}
+		if idx, ok := index[bmd.KeyHash]; ok {
+			stored[idx] = *bmd
+		} else {
+			index[bmd.KeyHash] = len(stored)
+			stored = append(stored, *bmd)
+		}
+	}
+	return stored, nil
+}
@@ -0,0 +1,62 @@
+package parsers
+
+import (
+	"testing"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/elastic/memory"
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+func TestBlockStorage(t *testing.T) {
+	es := memory.New()
+	if err := es.BulkInsert([]elastic.Model{
+		&models.BigMapDiff{ID: "stored_a", Network: "mainnet", Address: "KT1A", Ptr: 1, KeyHash: "a", Value: "1", IndexedTime: 1},
+		&models.BigMapDiff{ID: "stored_b", Network: "mainnet", Address: "KT1A", Ptr: 1, KeyHash: "b", Value: "2", IndexedTime: 1},
+		&models.BigMap{ID: models.BigMapID("mainnet", 1), Network: "mainnet", Ptr: 1, Address: "KT1A"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	block := newBlockStorage(es)
+	block.addBigMapDiffs([]*models.BigMapDiff{
+		{ID: "block_b", Network: "mainnet", Address: "KT1A", Ptr: 1, KeyHash: "b", Value: "3"},
+		{ID: "block_c", Network: "mainnet", Address: "KT1A", Ptr: 1, KeyHash: "c", Value: "4"},
+		{ID: "other", Network: "mainnet", Address: "KT1A", Ptr: 2, KeyHash: "a", Value: "5"},
+	})
+	block.addBigMaps([]*models.BigMap{
+		{ID: models.BigMapID("mainnet", 2), Network: "mainnet", Ptr: 2, Address: "KT1A"},
+	})
+
+	diffs, err := block.GetAllBigMapDiffByPtr("KT1A", "mainnet", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"a": "1", "b": "3", "c": "4"}
+	if len(diffs) != len(want) {
+		t.Errorf("GetAllBigMapDiffByPtr() = %d diffs, want %d", len(diffs), len(want))
+	}
+	for _, bmd := range diffs {
+		if want[bmd.KeyHash] != bmd.Value {
+			t.Errorf("GetAllBigMapDiffByPtr() %s = %s, want %s", bmd.KeyHash, bmd.Value, want[bmd.KeyHash])
+		}
+	}
+
+	for _, ptr := range []int64{1, 2} {
+		bigMap := models.BigMap{ID: models.BigMapID("mainnet", ptr)}
+		if err := block.GetByID(&bigMap); err != nil {
+			t.Errorf("GetByID(%d) error = %v", ptr, err)
+		}
+		if bigMap.Ptr != ptr {
+			t.Errorf("GetByID(%d) ptr = %d", ptr, bigMap.Ptr)
+		}
+	}
+
+	stored, err := es.GetAllBigMapDiffByPtr("KT1A", "mainnet", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 2 {
+		t.Errorf("block data must not be saved before commit: %d diffs", len(stored))
+	}
+}
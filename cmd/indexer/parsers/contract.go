@@ -14,12 +14,12 @@ import (
 	"github.com/tidwall/gjson"
 )
 
-func createNewContract(es *elastic.Elastic, operation models.Operation, filesDirectory, protoSymLink string) (*models.Contract, error) {
+func createNewContract(es elastic.IElastic, operation models.Operation, filesDirectory, protoSymLink string) (*models.Contract, error) {
 	if operation.Kind != consts.Origination && operation.Kind != consts.Migration {
 		return nil, fmt.Errorf("Invalid operation kind in computeContractMetrics: %s", operation.Kind)
 	}
 	contract := &models.Contract{
-		ID:         helpers.GenerateID(),
+		ID:         helpers.GenerateIDFrom(operation.Network, operation.Destination),
 		Network:    operation.Network,
 		Level:      operation.Level,
 		Timestamp:  operation.Timestamp,
@@ -35,7 +35,7 @@ func createNewContract(es *elastic.Elastic, operation models.Operation, filesDir
 	return contract, err
 }
 
-func computeMetrics(es *elastic.Elastic, operation models.Operation, filesDirectory, protoSymLink string, contract *models.Contract) error {
+func computeMetrics(es elastic.IElastic, operation models.Operation, filesDirectory, protoSymLink string, contract *models.Contract) error {
 	script, err := contractparser.New(operation.Script)
 	if err != nil {
 		return fmt.Errorf("contractparser.New: %v", err)
@@ -52,6 +52,9 @@ func computeMetrics(es *elastic.Elastic, operation models.Operation, filesDirect
 	contract.FailStrings = script.Code.FailStrings.Values()
 	contract.Annotations = script.Annotations.Values()
 	contract.Tags = script.Tags.Values()
+	if len(script.Code.Parameter.Interfaces) > 0 {
+		contract.TagVersions = models.NewTagVersions(script.Code.Parameter.Interfaces)
+	}
 	contract.Hardcoded = script.HardcodedAddresses.Values()
 
 	if err := metrics.SetFingerprint(operation.Script, contract); err != nil {
@@ -21,19 +21,39 @@ import (
 // DefaultParser -
 type DefaultParser struct {
 	rpc            noderpc.Pool
-	es             *elastic.Elastic
+	es             elastic.IElastic
+	block          *blockStorage
 	filesDirectory string
+	policy         Policy
 
 	updates map[int64][]*models.BigMapDiff
+	bigMaps map[int64]*models.BigMap
 }
 
-// NewDefaultParser -
-func NewDefaultParser(rpc noderpc.Pool, es *elastic.Elastic, filesDirectory string) *DefaultParser {
-	return &DefaultParser{
+// DefaultParserOption -
+type DefaultParserOption func(*DefaultParser)
+
+// WithPolicy - operation kinds and account types which are indexed
+func WithPolicy(policy Policy) DefaultParserOption {
+	return func(p *DefaultParser) {
+		p.policy = policy
+	}
+}
+
+// NewDefaultParser - parser of operations of the single block
+func NewDefaultParser(rpc noderpc.Pool, es elastic.IElastic, filesDirectory string, opts ...DefaultParserOption) *DefaultParser {
+	block := newBlockStorage(es)
+	p := &DefaultParser{
 		rpc:            rpc,
-		es:             es,
+		es:             block,
+		block:          block,
 		filesDirectory: filesDirectory,
+		policy:         DefaultPolicy(),
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 // Parse -
@@ -44,6 +64,7 @@ func (p *DefaultParser) Parse(opg gjson.Result, network string, head noderpc.Hea
 
 	// New OPG -> new temporary storage
 	p.updates = make(map[int64][]*models.BigMapDiff)
+	p.bigMaps = make(map[int64]*models.BigMap)
 
 	for idx, item := range opg.Get("contents").Array() {
 		need, err := p.needParse(item, network, idx)
@@ -55,7 +76,7 @@ func (p *DefaultParser) Parse(opg gjson.Result, network string, head noderpc.Hea
 		}
 
 		hash := opg.Get("hash").String()
-		op, contract, migration, err := p.parseContent(item, network, hash, head)
+		op, contract, migration, err := p.parseContent(item, network, hash, head, operationID(network, hash, item.Get("counter").Int(), 0))
 		if err != nil {
 			return nil, nil, nil, err
 		}
@@ -79,19 +100,42 @@ func (p *DefaultParser) Parse(opg gjson.Result, network string, head noderpc.Hea
 	return operations, contracts, migrations, nil
 }
 
-func (p *DefaultParser) parseContent(data gjson.Result, network, hash string, head noderpc.Header) (models.Operation, *models.Contract, *models.Migration, error) {
+// BigMapDiffs - big map diffs of all parsed operations. They are not saved by the parser.
+func (p *DefaultParser) BigMapDiffs() []elastic.Model {
+	items := make([]elastic.Model, len(p.block.bigMapDiffs))
+	for i := range p.block.bigMapDiffs {
+		items[i] = p.block.bigMapDiffs[i]
+	}
+	return items
+}
+
+// BigMaps - registry entries of big maps changed by all parsed operations. They are not saved by the parser.
+func (p *DefaultParser) BigMaps() []elastic.Model {
+	items := make([]elastic.Model, len(p.block.bigMaps))
+	for i := range p.block.bigMaps {
+		items[i] = p.block.bigMaps[i]
+	}
+	return items
+}
+
+// operationID - operation is identified by its group hash, counter and index among internal operations (0 for the external one)
+func operationID(network, hash string, counter, internalIndex int64) string {
+	return helpers.GenerateIDFrom(network, hash, counter, internalIndex)
+}
+
+func (p *DefaultParser) parseContent(data gjson.Result, network, hash string, head noderpc.Header, id string) (models.Operation, *models.Contract, *models.Migration, error) {
 	kind := data.Get("kind").String()
 	switch kind {
 	case consts.Origination:
-		return p.parseOrigination(data, network, hash, head)
+		return p.parseOrigination(data, network, hash, head, id)
 	default:
-		op, migration, err := p.parseTransaction(data, network, hash, head)
+		op, migration, err := p.parseTransaction(data, network, hash, head, id)
 		return op, nil, migration, err
 	}
 }
 
-func (p *DefaultParser) parseTransaction(data gjson.Result, network, hash string, head noderpc.Header) (op models.Operation, migration *models.Migration, err error) {
-	op.ID = helpers.GenerateID()
+func (p *DefaultParser) parseTransaction(data gjson.Result, network, hash string, head noderpc.Header, id string) (op models.Operation, migration *models.Migration, err error) {
+	op.ID = id
 	op.Network = network
 	op.Hash = hash
 	op.Protocol = head.Protocol
@@ -122,9 +166,9 @@ func (p *DefaultParser) parseTransaction(data gjson.Result, network, hash string
 	return
 }
 
-func (p *DefaultParser) parseOrigination(data gjson.Result, network, hash string, head noderpc.Header) (models.Operation, *models.Contract, *models.Migration, error) {
+func (p *DefaultParser) parseOrigination(data gjson.Result, network, hash string, head noderpc.Header, id string) (models.Operation, *models.Contract, *models.Migration, error) {
 	op := models.Operation{
-		ID:             helpers.GenerateID(),
+		ID:             id,
 		Network:        network,
 		Hash:           hash,
 		Protocol:       head.Protocol,
@@ -231,11 +275,8 @@ func (p *DefaultParser) finishParseOperation(item gjson.Result, op *models.Opera
 		}
 		op.DeffatedStorage = rs.DeffatedStorage
 
-		if len(rs.BigMapDiffs) > 0 {
-			if err := p.es.BulkInsert(rs.GetBigMapDiffModels()); err != nil {
-				return nil, err
-			}
-		}
+		p.block.addBigMapDiffs(rs.BigMapDiffs)
+		p.block.addBigMaps(rs.BigMaps)
 
 		if op.Kind == consts.Transaction {
 			if migration, err = p.findMigration(item, op); err != nil {
@@ -266,7 +307,7 @@ func (p *DefaultParser) findMigration(item gjson.Result, op *models.Operation) (
 		if contractparser.HasLambda(value) {
 			logger.Info("[%s] Migration detected: %s", op.Network, op.Destination)
 			return &models.Migration{
-				ID:          helpers.GenerateID(),
+				ID:          helpers.GenerateIDFrom(op.ID, consts.MigrationLambda),
 				IndexedTime: time.Now().UnixNano() / 1000,
 
 				Network:   op.Network,
@@ -306,20 +347,29 @@ func (p *DefaultParser) getEntrypoint(item gjson.Result, metadata *meta.Contract
 	return nil
 }
 
-func (p *DefaultParser) parseInternalOperations(item gjson.Result, main models.Operation, head noderpc.Header) ([]*models.Operation, []*models.Contract, []*models.Migration, error) {
+func getInternalOperations(item gjson.Result) []gjson.Result {
 	path := fmt.Sprintf("metadata.internal_operation_results")
 	if !item.Get(path).Exists() {
 		path = fmt.Sprintf("metadata.internal_operations")
 		if !item.Get(path).Exists() {
-			return nil, nil, nil, nil
+			return nil
 		}
 	}
+	return item.Get(path).Array()
+}
+
+func (p *DefaultParser) parseInternalOperations(item gjson.Result, main models.Operation, head noderpc.Header) ([]*models.Operation, []*models.Contract, []*models.Migration, error) {
+	internals := getInternalOperations(item)
+	if len(internals) == 0 {
+		return nil, nil, nil, nil
+	}
 
 	operations := make([]*models.Operation, 0)
 	contracts := make([]*models.Contract, 0)
 	migrations := make([]*models.Migration, 0)
-	for i, op := range item.Get(path).Array() {
-		internalOperation, contract, migration, err := p.parseContent(op, main.Network, main.Hash, head)
+	for i, op := range internals {
+		internalIndex := int64(i + 1)
+		internalOperation, contract, migration, err := p.parseContent(op, main.Network, main.Hash, head, operationID(main.Network, main.Hash, main.Counter, internalIndex))
 		if err != nil {
 			return nil, nil, nil, err
 		}
@@ -335,7 +385,7 @@ func (p *DefaultParser) parseInternalOperations(item gjson.Result, main models.O
 		internalOperation.Level = main.Level
 		internalOperation.Timestamp = main.Timestamp
 		internalOperation.Internal = true
-		internalOperation.InternalIndex = int64(i + 1)
+		internalOperation.InternalIndex = internalIndex
 		operations = append(operations, &internalOperation)
 	}
 	return operations, contracts, migrations, nil
@@ -343,15 +393,24 @@ func (p *DefaultParser) parseInternalOperations(item gjson.Result, main models.O
 
 func (p *DefaultParser) needParse(item gjson.Result, network string, idx int) (bool, error) {
 	kind := item.Get("kind").String()
-	source := item.Get("source").String()
-	destination := item.Get("destination").String()
-	prefixCondition := strings.HasPrefix(source, "KT") || strings.HasPrefix(destination, "KT")
-	transactionCondition := kind == consts.Transaction && prefixCondition
-	if transactionCondition {
-		return p.es.NeedParseOperation(network, source, destination)
+	switch kind {
+	case consts.Transaction:
+		source := item.Get("source").String()
+		destination := item.Get("destination").String()
+		account := AccountType(source, destination)
+		if !p.policy.Allows(kind, account) {
+			return false, nil
+		}
+		if account == AccountContract {
+			return p.es.NeedParseOperation(network, source, destination)
+		}
+		return true, nil
+	case consts.Origination:
+		return p.policy.Allows(kind, AccountContract) && item.Get("script").Exists(), nil
+	default:
+		// delegations and reveals are stored by `ParseDelegationsAndReveals`
+		return false, nil
 	}
-	originationCondition := kind == consts.Origination && item.Get("script").Exists()
-	return originationCondition, nil
 }
 
 func (p *DefaultParser) getRichStorage(data gjson.Result, metadata *meta.ContractMetadata, op *models.Operation) (storage.RichStorage, error) {
@@ -372,6 +431,7 @@ func (p *DefaultParser) getRichStorage(data gjson.Result, metadata *meta.Contrac
 
 	// Init parser by current context
 	parser.SetUpdates(p.updates)
+	parser.SetBigMaps(p.bigMaps)
 
 	switch op.Kind {
 	case consts.Transaction:
@@ -0,0 +1,107 @@
+package parsers
+
+import (
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/baking-bad/bcdhub/internal/noderpc"
+	"github.com/tidwall/gjson"
+)
+
+// ParseDelegationsAndReveals - returns delegations and reveals of the operation group which are allowed by the policy.
+// Contracts change their delegates by internal operations, so internal operations are inspected too.
+// Delegate set by origination of a contract is stored as the first delegation of the contract.
+func (p *DefaultParser) ParseDelegationsAndReveals(opg gjson.Result, network string, head noderpc.Header) ([]*models.Delegation, []*models.Reveal) {
+	delegations := make([]*models.Delegation, 0)
+	reveals := make([]*models.Reveal, 0)
+
+	hash := opg.Get("hash").String()
+	for _, item := range opg.Get("contents").Array() {
+		counter := item.Get("counter").Int()
+		if item.Get("kind").String() == consts.Reveal {
+			if reveal := p.parseReveal(item, network, hash, head); reveal != nil {
+				reveals = append(reveals, reveal)
+			}
+			continue
+		}
+
+		if delegation := p.parseDelegation(item, network, hash, head, counter, 0); delegation != nil {
+			delegations = append(delegations, delegation)
+		}
+		for i, internal := range getInternalOperations(item) {
+			if delegation := p.parseDelegation(internal, network, hash, head, counter, int64(i+1)); delegation != nil {
+				delegations = append(delegations, delegation)
+			}
+		}
+	}
+	return delegations, reveals
+}
+
+func (p *DefaultParser) parseDelegation(item gjson.Result, network, hash string, head noderpc.Header, counter, internalIndex int64) *models.Delegation {
+	result, _ := p.parseMetadata(item)
+	if result == nil {
+		return nil
+	}
+
+	delegation := models.Delegation{
+		ID:            operationID(network, hash, counter, internalIndex),
+		IndexedTime:   time.Now().UnixNano() / 1000,
+		Network:       network,
+		Protocol:      head.Protocol,
+		Hash:          hash,
+		Counter:       counter,
+		Internal:      internalIndex > 0,
+		InternalIndex: internalIndex,
+		Level:         head.Level,
+		Timestamp:     head.Timestamp,
+		Kind:          item.Get("kind").String(),
+		Status:        result.Status,
+		Source:        item.Get("source").String(),
+		Delegate:      item.Get("delegate").String(),
+		Fee:           item.Get("fee").Int(),
+	}
+
+	switch delegation.Kind {
+	case consts.Delegation:
+	case consts.Origination:
+		if result.Status != consts.Applied || result.Originated == "" || delegation.Delegate == "" {
+			return nil
+		}
+		delegation.Source = result.Originated
+		delegation.Fee = 0
+	default:
+		return nil
+	}
+
+	if !p.policy.Allows(consts.Delegation, AccountType(delegation.Source)) {
+		return nil
+	}
+	return &delegation
+}
+
+func (p *DefaultParser) parseReveal(item gjson.Result, network, hash string, head noderpc.Header) *models.Reveal {
+	source := item.Get("source").String()
+	if !p.policy.Allows(consts.Reveal, AccountType(source)) {
+		return nil
+	}
+	result, _ := p.parseMetadata(item)
+	if result == nil {
+		return nil
+	}
+
+	return &models.Reveal{
+		ID:          operationID(network, hash, item.Get("counter").Int(), 0),
+		IndexedTime: time.Now().UnixNano() / 1000,
+		Network:     network,
+		Protocol:    head.Protocol,
+		Hash:        hash,
+		Counter:     item.Get("counter").Int(),
+		Level:       head.Level,
+		Timestamp:   head.Timestamp,
+		Status:      result.Status,
+		Source:      source,
+		PublicKey:   item.Get("public_key").String(),
+		Fee:         item.Get("fee").Int(),
+	}
+}
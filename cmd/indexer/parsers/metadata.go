@@ -21,7 +21,7 @@ func getMetadata(script gjson.Result, tag, protoSymLink string, c *models.Contra
 	return res, nil
 }
 
-func updateMetadata(es *elastic.Elastic, script gjson.Result, protoSymLink string, c *models.Contract) error {
+func updateMetadata(es elastic.IElastic, script gjson.Result, protoSymLink string, c *models.Contract) error {
 	metadata := models.Metadata{ID: c.Address}
 	if err := es.GetByID(&metadata); err != nil {
 		return err
@@ -69,7 +69,7 @@ func createMetadata(script gjson.Result, tag string, c *models.Contract) (string
 	return "", fmt.Errorf("[createMetadata] Unknown tag '%s' contract %s", tag, c.Address)
 }
 
-func saveMetadata(es *elastic.Elastic, script gjson.Result, protoSymLink string, c *models.Contract) error {
+func saveMetadata(es elastic.IElastic, script gjson.Result, protoSymLink string, c *models.Contract) error {
 	storage, err := getMetadata(script, consts.STORAGE, protoSymLink, c)
 	if err != nil {
 		return err
@@ -15,12 +15,12 @@ import (
 // MigrationParser -
 type MigrationParser struct {
 	rpc            noderpc.Pool
-	es             *elastic.Elastic
+	es             elastic.IElastic
 	filesDirectory string
 }
 
 // NewMigrationParser -
-func NewMigrationParser(rpc noderpc.Pool, es *elastic.Elastic, filesDirectory string) *MigrationParser {
+func NewMigrationParser(rpc noderpc.Pool, es elastic.IElastic, filesDirectory string) *MigrationParser {
 	return &MigrationParser{
 		rpc:            rpc,
 		es:             es,
@@ -47,7 +47,7 @@ func (p *MigrationParser) Parse(data gjson.Result, old models.Contract, prevProt
 	}
 
 	op := models.Migration{
-		ID:          helpers.GenerateID(),
+		ID:          helpers.GenerateIDFrom(old.Network, old.Address, prevProtocol.EndLevel, consts.MigrationUpdate),
 		IndexedTime: time.Now().UnixNano() / 1000,
 
 		Network:      old.Network,
@@ -0,0 +1,83 @@
+package parsers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/helpers"
+)
+
+// Account types
+const (
+	AccountContract = "contract"
+	AccountImplicit = "implicit"
+)
+
+// Policy - operation kinds and account types which are indexed. An operation belongs to contracts if any of its accounts
+// (source or destination) is a contract, otherwise it belongs to implicit accounts.
+type Policy struct {
+	kinds    map[string]struct{}
+	accounts map[string]struct{}
+}
+
+var (
+	defaultKinds    = []string{consts.Transaction, consts.Origination, consts.Delegation}
+	defaultAccounts = []string{AccountContract}
+
+	supportedKinds    = []string{consts.Transaction, consts.Origination, consts.Delegation, consts.Reveal}
+	supportedAccounts = []string{AccountContract, AccountImplicit}
+)
+
+// DefaultPolicy - transactions, originations and delegations of contracts
+func DefaultPolicy() Policy {
+	policy, _ := NewPolicy(nil, nil)
+	return policy
+}
+
+// NewPolicy - empty `kinds` or `accounts` are replaced by default ones
+func NewPolicy(kinds, accounts []string) (Policy, error) {
+	if len(kinds) == 0 {
+		kinds = defaultKinds
+	}
+	if len(accounts) == 0 {
+		accounts = defaultAccounts
+	}
+
+	policy := Policy{
+		kinds:    make(map[string]struct{}),
+		accounts: make(map[string]struct{}),
+	}
+	for _, kind := range kinds {
+		if !helpers.StringInArray(kind, supportedKinds) {
+			return policy, fmt.Errorf("Unsupported operation kind: %s", kind)
+		}
+		policy.kinds[kind] = struct{}{}
+	}
+	for _, account := range accounts {
+		if !helpers.StringInArray(account, supportedAccounts) {
+			return policy, fmt.Errorf("Unsupported account type: %s", account)
+		}
+		policy.accounts[account] = struct{}{}
+	}
+	return policy, nil
+}
+
+// Allows - returns true if operation of `kind` made by `account` type has to be indexed
+func (p Policy) Allows(kind, account string) bool {
+	if _, ok := p.kinds[kind]; !ok {
+		return false
+	}
+	_, ok := p.accounts[account]
+	return ok
+}
+
+// AccountType - returns `contract` if any of `addresses` is a contract and `implicit` otherwise
+func AccountType(addresses ...string) string {
+	for i := range addresses {
+		if strings.HasPrefix(addresses[i], "KT") {
+			return AccountContract
+		}
+	}
+	return AccountImplicit
+}
@@ -0,0 +1,72 @@
+package parsers
+
+import "testing"
+
+func TestPolicy_Allows(t *testing.T) {
+	implicit, err := NewPolicy([]string{"delegation", "reveal"}, []string{"implicit"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		policy  Policy
+		kind    string
+		account string
+		want    bool
+	}{
+		{"default: contract transaction", DefaultPolicy(), "transaction", AccountContract, true},
+		{"default: contract delegation", DefaultPolicy(), "delegation", AccountContract, true},
+		{"default: implicit transaction", DefaultPolicy(), "transaction", AccountImplicit, false},
+		{"default: reveal", DefaultPolicy(), "reveal", AccountImplicit, false},
+		{"implicit: reveal", implicit, "reveal", AccountImplicit, true},
+		{"implicit: contract delegation", implicit, "delegation", AccountContract, false},
+		{"implicit: transaction", implicit, "transaction", AccountImplicit, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Allows(tt.kind, tt.account); got != tt.want {
+				t.Errorf("Allows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		kinds    []string
+		accounts []string
+		wantErr  bool
+	}{
+		{"default", nil, nil, false},
+		{"all", []string{"transaction", "origination", "delegation", "reveal"}, []string{"contract", "implicit"}, false},
+		{"unknown kind", []string{"endorsement"}, nil, true},
+		{"unknown account", nil, []string{"baker"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPolicy(tt.kinds, tt.accounts); (err != nil) != tt.wantErr {
+				t.Errorf("NewPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAccountType(t *testing.T) {
+	tests := []struct {
+		name      string
+		addresses []string
+		want      string
+	}{
+		{"implicit", []string{"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx", "tz1VQnqCCqX4K5sP3FNkVSNKTdCAMJDd3E1n"}, AccountImplicit},
+		{"contract destination", []string{"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx", "KT1BUKeJTemAaVBfRz6cqxeUBQGQqMxfG19A"}, AccountContract},
+		{"no destination", []string{"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx", ""}, AccountImplicit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AccountType(tt.addresses...); got != tt.want {
+				t.Errorf("AccountType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -16,12 +16,12 @@ import (
 // VestingParser -
 type VestingParser struct {
 	rpc            noderpc.Pool
-	es             *elastic.Elastic
+	es             elastic.IElastic
 	filesDirectory string
 }
 
 // NewVestingParser -
-func NewVestingParser(rpc noderpc.Pool, es *elastic.Elastic, filesDirectory string) *VestingParser {
+func NewVestingParser(rpc noderpc.Pool, es elastic.IElastic, filesDirectory string) *VestingParser {
 	return &VestingParser{
 		rpc:            rpc,
 		es:             es,
@@ -32,7 +32,7 @@ func NewVestingParser(rpc noderpc.Pool, es *elastic.Elastic, filesDirectory stri
 // Parse -
 func (p *VestingParser) Parse(data gjson.Result, head noderpc.Header, network, address string) (*models.Migration, *models.Contract, error) {
 	migration := &models.Migration{
-		ID:          helpers.GenerateID(),
+		ID:          helpers.GenerateIDFrom(network, address, head.Level, consts.MigrationBootstrap),
 		IndexedTime: time.Now().UnixNano() / 1000,
 
 		Level:     head.Level,
@@ -38,6 +38,7 @@ func handler(data amqp.Delivery) error {
 			return err
 		}
 	}
+	markProcessed(data)
 
 	if err := data.Ack(false); err != nil {
 		return fmt.Errorf("Error acknowledging message: %s", err)
@@ -83,6 +84,7 @@ func main() {
 
 	ctx = config.NewContext(
 		config.WithElasticSearch(cfg.Elastic),
+		config.WithStorage(cfg.Storage),
 		config.WithRPC(cfg.RPC),
 		config.WithDatabase(cfg.DB),
 		config.WithRabbit(cfg.RabbitMQ),
@@ -59,6 +59,19 @@ func parseOperation(operation models.Operation) error {
 		}
 	}
 
+	// failure of token steps is not retried: the message would be redelivered forever if the operation can't be decoded
+	if err := h.SetTokenBalances(operation); err != nil {
+		logger.Errorf("[parseOperation] Compute token balances of %s error: %s", operation.ID, err)
+	}
+
+	if err := h.SetTokenTransfers(operation); err != nil {
+		logger.Errorf("[parseOperation] Compute token transfers of %s error: %s", operation.ID, err)
+	}
+
+	if err := h.SetTokenMetadata(operation); err != nil {
+		logger.Errorf("[parseOperation] Compute token metadata of %s error: %s", operation.ID, err)
+	}
+
 	logger.Info("Operation %s processed", operation.ID)
 	return nil
 }
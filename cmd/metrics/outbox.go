@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/logger"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/baking-bad/bcdhub/internal/mq"
+	"github.com/streadway/amqp"
+)
+
+// markProcessed - confirms notification of the indexer outbox, so it's not sent again by reconciliation
+func markProcessed(data amqp.Delivery) {
+	switch data.RoutingKey {
+	case mq.QueueContracts, mq.QueueOperations, mq.QueueMigrations:
+	default:
+		return
+	}
+
+	var entityID string
+	if err := json.Unmarshal(data.Body, &entityID); err != nil {
+		logger.Errorf("[markProcessed] Unmarshal message body error: %s", err)
+		return
+	}
+
+	id := models.NotificationID(data.RoutingKey, entityID)
+	notification := models.Notification{Status: models.NotificationProcessed}
+	if err := ctx.ES.UpdateFields(elastic.DocNotifications, id, notification, "Status"); err != nil {
+		// notifications are absent for data indexed before the outbox was introduced
+		logger.Warning("[markProcessed] %s %s: %s", data.RoutingKey, entityID, err)
+	}
+}
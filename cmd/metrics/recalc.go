@@ -46,9 +46,13 @@ func recalc(contract models.Contract) error {
 		}
 	}
 
+	delegate := contract.Delegate
 	if err := h.UpdateContractStats(&contract); err != nil {
 		return fmt.Errorf("[recalc] Compute contract stats error message: %s", err)
 	}
+	if contract.Delegate != delegate {
+		contract.DelegateAlias = ctx.Aliases[contract.Delegate]
+	}
 
 	if _, err := ctx.ES.UpdateDoc(elastic.DocContracts, contract.ID, contract); err != nil {
 		return err
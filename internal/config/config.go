@@ -17,6 +17,7 @@ type Config struct {
 	Elastic  ElasticSearchConfig `yaml:"elastic"`
 	RabbitMQ RabbitConfig        `yaml:"rabbitmq"`
 	DB       DatabaseConfig      `yaml:"db"`
+	Storage  StorageConfig       `yaml:"storage"`
 	OAuth    OAuthConfig         `yaml:"oauth"`
 
 	Share struct {
@@ -39,6 +40,9 @@ type Config struct {
 			Project string `yaml:"project"`
 		} `yaml:"sentry"`
 		Networks []string `yaml:"networks"`
+		Admin    struct {
+			Token string `yaml:"token"`
+		} `yaml:"admin"`
 	} `yaml:"api"`
 
 	Indexer struct {
@@ -46,8 +50,12 @@ type Config struct {
 			Enabled bool   `yaml:"enabled"`
 			Project string `yaml:"project"`
 		} `yaml:"sentry"`
-		Networks map[string]struct {
-			Boost string `yaml:"boost"`
+		Interfaces string `yaml:"interfaces"`
+		Networks   map[string]struct {
+			Boost       string           `yaml:"boost"`
+			Concurrency int              `yaml:"concurrency"`
+			Prefetch    int              `yaml:"prefetch"`
+			Operations  OperationsConfig `yaml:"operations"`
 		} `yaml:"networks"`
 	} `yaml:"indexer"`
 
@@ -72,8 +80,31 @@ type Config struct {
 
 // RPCConfig -
 type RPCConfig struct {
-	URI     string `yaml:"uri"`
-	Timeout int    `yaml:"timeout"`
+	URI      string            `yaml:"uri"`
+	Nodes    []string          `yaml:"nodes"`
+	Timeout  int               `yaml:"timeout"`
+	Cache    RPCCacheConfig    `yaml:"cache"`
+	Fixtures RPCFixturesConfig `yaml:"fixtures"`
+}
+
+// RPCFixturesConfig - in `record` mode node responses are saved to `dir`, in `replay` mode they are served from `dir` without the node
+type RPCFixturesConfig struct {
+	Dir  string `yaml:"dir"`
+	Mode string `yaml:"mode"`
+}
+
+// RPCCacheConfig - disk cache of node responses for finalized levels. Cache is disabled if `dir` is empty.
+type RPCCacheConfig struct {
+	Dir           string `yaml:"dir"`
+	SizeMB        int64  `yaml:"size_mb"`
+	FinalityDepth int64  `yaml:"finality_depth"`
+}
+
+// OperationsConfig - operation kinds (`transaction`, `origination`, `delegation`, `reveal`) and account types (`contract`, `implicit`)
+// which are indexed. Transactions, originations and delegations of contracts are indexed if lists are empty.
+type OperationsConfig struct {
+	Kinds    []string `yaml:"kinds"`
+	Accounts []string `yaml:"accounts"`
 }
 
 // ElasticSearchConfig -
@@ -86,6 +117,12 @@ type DatabaseConfig struct {
 	ConnString string `yaml:"conn_string"`
 }
 
+// StorageConfig - backend for chain data. Elasticsearch is used if `backend` is empty.
+type StorageConfig struct {
+	Backend    string `yaml:"backend"`
+	ConnString string `yaml:"conn_string"`
+}
+
 // RabbitConfig -
 type RabbitConfig struct {
 	URI    string   `yaml:"uri"`
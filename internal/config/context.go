@@ -14,7 +14,7 @@ import (
 // Context -
 type Context struct {
 	DB           database.DB
-	ES           *elastic.Elastic
+	ES           elastic.IElastic
 	MQ           *mq.MQ
 	RPC          map[string]noderpc.Pool
 	TzKTServices map[string]*tzkt.ServicesTzKT
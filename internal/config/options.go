@@ -23,7 +23,11 @@ func WithRPC(rpcConfig map[string]RPCConfig) ContextOption {
 		}
 		rpc := make(map[string]noderpc.Pool)
 		for network, rpcProvider := range rpcConfig {
-			rpc[network] = noderpc.NewPool([]string{rpcProvider.URI}, time.Second*time.Duration(rpcProvider.Timeout))
+			pool, err := rpcProvider.NewPool(network, false)
+			if err != nil {
+				panic(err)
+			}
+			rpc[network] = pool
 		}
 		ctx.RPC = rpc
 	}
@@ -36,6 +40,13 @@ func WithElasticSearch(esConfig ElasticSearchConfig) ContextOption {
 	}
 }
 
+// WithStorage - moves chain data to the storage backend. Has to be applied after `WithElasticSearch`.
+func WithStorage(storageConfig StorageConfig) ContextOption {
+	return func(ctx *Context) {
+		ctx.ES = NewStorage(storageConfig, ctx.ES)
+	}
+}
+
 // WithDatabase -
 func WithDatabase(dbConfig DatabaseConfig) ContextOption {
 	return func(ctx *Context) {
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/noderpc"
+)
+
+// Fixtures modes
+const (
+	FixturesRecord = "record"
+	FixturesReplay = "replay"
+)
+
+// URIs - main node and additional nodes of the pool
+func (cfg RPCConfig) URIs() []string {
+	return append([]string{cfg.URI}, cfg.Nodes...)
+}
+
+// NewPool - creates node pool of the `network`. If cache is configured, responses are cached in the network subdirectory.
+func (cfg RPCConfig) NewPool(network string, wait bool) (noderpc.Pool, error) {
+	opts := make([]noderpc.PoolOption, 0)
+	if cfg.Cache.Dir != "" {
+		cacheOpts := make([]noderpc.CacheOption, 0)
+		if cfg.Cache.FinalityDepth > 0 {
+			cacheOpts = append(cacheOpts, noderpc.WithFinalityDepth(cfg.Cache.FinalityDepth))
+		}
+		cache, err := noderpc.NewCache(filepath.Join(cfg.Cache.Dir, network), cfg.Cache.SizeMB*1024*1024, cacheOpts...)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, noderpc.WithCache(cache))
+	}
+
+	switch cfg.Fixtures.Mode {
+	case "":
+	case FixturesRecord:
+		opts = append(opts, noderpc.WithRecorder(filepath.Join(cfg.Fixtures.Dir, network)))
+	case FixturesReplay:
+		return noderpc.NewFixturePool(filepath.Join(cfg.Fixtures.Dir, network), opts...), nil
+	default:
+		return nil, fmt.Errorf("Unknown fixtures mode: %s", cfg.Fixtures.Mode)
+	}
+
+	timeout := time.Second * time.Duration(cfg.Timeout)
+	if wait {
+		return noderpc.NewWaitPool(cfg.URIs(), timeout, opts...), nil
+	}
+	return noderpc.NewPool(cfg.URIs(), timeout, opts...), nil
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"log"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/elastic/postgres"
+)
+
+// Storage backends
+const (
+	StorageElastic  = "elastic"
+	StoragePostgres = "postgres"
+)
+
+// NewStorage - wraps Elasticsearch by the backend from config. Elasticsearch keeps full-text search in any case.
+func NewStorage(storageConfig StorageConfig, es elastic.IElastic) elastic.IElastic {
+	switch storageConfig.Backend {
+	case "", StorageElastic:
+		return es
+	case StoragePostgres:
+		return postgres.WaitNew(storageConfig.ConnString, es)
+	default:
+		log.Panicf("Unknown storage backend: %s", storageConfig.Backend)
+	}
+	return nil
+}
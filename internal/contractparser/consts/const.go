@@ -17,6 +17,7 @@ const (
 	Transaction = "transaction"
 	Origination = "origination"
 	Delegation  = "delegation"
+	Reveal      = "reveal"
 	Migration   = "migration"
 )
 
@@ -0,0 +1,377 @@
+package filter
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/contractparser/meta"
+	"github.com/baking-bad/bcdhub/internal/contractparser/newmiguel"
+	"github.com/tidwall/gjson"
+)
+
+// Operators
+const (
+	OpEq  = "eq"
+	OpNe  = "ne"
+	OpGt  = "gt"
+	OpGte = "gte"
+	OpLt  = "lt"
+	OpLte = "lte"
+)
+
+const valueField = "@value"
+
+type kind int
+
+const (
+	kindNumber kind = iota
+	kindTimestamp
+	kindBool
+	kindString
+)
+
+var kinds = map[string]kind{
+	consts.INT:       kindNumber,
+	consts.NAT:       kindNumber,
+	consts.MUTEZ:     kindNumber,
+	consts.TIMESTAMP: kindTimestamp,
+	consts.BOOL:      kindBool,
+	consts.STRING:    kindString,
+	consts.BYTES:     kindString,
+	consts.ADDRESS:   kindString,
+	consts.CONTRACT:  kindString,
+	consts.KEYHASH:   kindString,
+	consts.KEY:       kindString,
+	consts.SIGNATURE: kindString,
+	consts.CHAINID:   kindString,
+}
+
+// Filter - condition on the field of the big map value. `Field` is the dot-separated path of field names, `@value` is the value itself.
+type Filter struct {
+	Field    string
+	Operator string
+	Value    string
+}
+
+// Parse - parses filter in form `field:operator:value`, e.g. `balance:gt:100`
+func Parse(s string) (Filter, error) {
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) != 3 || parts[0] == "" {
+		return Filter{}, fmt.Errorf("Invalid filter: %s. Expected `field:operator:value`", s)
+	}
+	return Filter{
+		Field:    parts[0],
+		Operator: parts[1],
+		Value:    parts[2],
+	}, nil
+}
+
+type condition struct {
+	path     string
+	parts    []string
+	kind     kind
+	operator string
+
+	number    *big.Int
+	timestamp int64
+	boolean   bool
+	str       string
+}
+
+// Matcher - checks big map values against filters. All filters have to match.
+type Matcher struct {
+	metadata   meta.Metadata
+	valuePath  string
+	conditions []condition
+}
+
+// New - resolves filter fields by metadata of the big map located at `binPath`
+func New(metadata meta.Metadata, binPath string, filters []Filter) (*Matcher, error) {
+	m := &Matcher{
+		metadata:   metadata,
+		valuePath:  binPath + "/v",
+		conditions: make([]condition, len(filters)),
+	}
+	if _, ok := metadata[m.valuePath]; !ok {
+		return nil, fmt.Errorf("Unknown big map path: %s", binPath)
+	}
+
+	for i := range filters {
+		c, err := m.compile(filters[i])
+		if err != nil {
+			return nil, err
+		}
+		m.conditions[i] = c
+	}
+	return m, nil
+}
+
+// Fields - returns field paths of the big map value which can be used in filters
+func Fields(metadata meta.Metadata, binPath string) []string {
+	fields := make([]string, 0)
+	collectFields(metadata, binPath+"/v", "", &fields)
+	sort.Strings(fields)
+	return fields
+}
+
+func collectFields(metadata meta.Metadata, path, prefix string, fields *[]string) {
+	path = unwrapOption(metadata, path)
+	nm, ok := metadata[path]
+	if !ok {
+		return
+	}
+	if _, ok := kinds[nm.Prim]; ok {
+		if prefix == "" {
+			prefix = valueField
+		}
+		*fields = append(*fields, prefix)
+		return
+	}
+	for _, arg := range nm.Args {
+		name := metadata[arg].Name
+		if name == "" || !isStructural(metadata, path, arg) {
+			continue
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		collectFields(metadata, arg, name, fields)
+	}
+}
+
+func (m *Matcher) compile(f Filter) (c condition, err error) {
+	path, err := m.resolve(f.Field)
+	if err != nil {
+		return
+	}
+	nm := m.metadata[path]
+	k, ok := kinds[nm.Prim]
+	if !ok {
+		return c, fmt.Errorf("Field %s has type %s which can not be filtered", f.Field, nm.Prim)
+	}
+
+	c.path = path
+	c.parts = strings.Split(strings.TrimPrefix(strings.TrimPrefix(path, m.valuePath), "/"), "/")
+	if c.parts[0] == "" {
+		c.parts = nil
+	}
+	c.kind = k
+	c.operator = f.Operator
+
+	switch f.Operator {
+	case OpEq, OpNe:
+	case OpGt, OpGte, OpLt, OpLte:
+		if k != kindNumber && k != kindTimestamp {
+			return c, fmt.Errorf("Operator %s is not allowed for field %s of type %s", f.Operator, f.Field, nm.Prim)
+		}
+	default:
+		return c, fmt.Errorf("Unknown operator: %s", f.Operator)
+	}
+
+	switch k {
+	case kindNumber:
+		number, ok := new(big.Int).SetString(f.Value, 10)
+		if !ok {
+			return c, fmt.Errorf("Invalid %s value of field %s: %s", nm.Prim, f.Field, f.Value)
+		}
+		c.number = number
+	case kindTimestamp:
+		if c.timestamp, err = parseTimestamp(f.Value); err != nil {
+			return c, fmt.Errorf("Invalid timestamp value of field %s: %s", f.Field, f.Value)
+		}
+	case kindBool:
+		if c.boolean, err = strconv.ParseBool(f.Value); err != nil {
+			return c, fmt.Errorf("Invalid bool value of field %s: %s", f.Field, f.Value)
+		}
+	default:
+		c.str = f.Value
+	}
+	return c, nil
+}
+
+// resolve - returns binary path of the field. Only fields of pairs, options and unions can be filtered.
+func (m *Matcher) resolve(field string) (string, error) {
+	path := m.valuePath
+	if field == valueField {
+		return unwrapOption(m.metadata, path), nil
+	}
+
+	for _, name := range strings.Split(field, ".") {
+		path = unwrapOption(m.metadata, path)
+		found := false
+		for _, arg := range m.metadata[path].Args {
+			if m.metadata[arg].Name != name {
+				continue
+			}
+			if !isStructural(m.metadata, path, arg) {
+				return "", fmt.Errorf("Field %s is inside a collection", field)
+			}
+			path = arg
+			found = true
+			break
+		}
+		if !found {
+			return "", fmt.Errorf("Unknown field %s. Available fields: %s", field, strings.Join(Fields(m.metadata, strings.TrimSuffix(m.valuePath, "/v")), ", "))
+		}
+	}
+	return unwrapOption(m.metadata, path), nil
+}
+
+// unwrapOption - optional value is filtered by the value under `Some`
+func unwrapOption(metadata meta.Metadata, path string) string {
+	for {
+		nm, ok := metadata[path]
+		if !ok || nm.Prim != consts.OPTION {
+			return path
+		}
+		path += "/o"
+	}
+}
+
+func isStructural(metadata meta.Metadata, parent, path string) bool {
+	prefix := parent
+	for _, part := range strings.Split(strings.TrimPrefix(path, parent+"/"), "/") {
+		nm, ok := metadata[prefix]
+		if !ok {
+			return false
+		}
+		switch nm.Prim {
+		case consts.PAIR, consts.OR:
+			if part != "0" && part != "1" {
+				return false
+			}
+		case consts.OPTION:
+			if part != "o" {
+				return false
+			}
+		default:
+			return false
+		}
+		prefix += "/" + part
+	}
+	return true
+}
+
+// Match - returns true if `value` satisfies all filters. Value without the field (e.g. `None` or other union branch) does not match.
+func (m *Matcher) Match(value gjson.Result) (bool, error) {
+	for i := range m.conditions {
+		ok, err := m.match(m.conditions[i], value)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (m *Matcher) match(c condition, value gjson.Result) (bool, error) {
+	data, ok := m.extract(c, value)
+	if !ok {
+		return false, nil
+	}
+
+	var cmp int
+	switch c.kind {
+	case kindNumber:
+		number, ok := new(big.Int).SetString(data.Get(consts.INT).String(), 10)
+		if !ok {
+			return false, fmt.Errorf("Invalid number: %s", data.Raw)
+		}
+		cmp = number.Cmp(c.number)
+	case kindTimestamp:
+		var timestamp int64
+		if data.Get(consts.INT).Exists() {
+			timestamp = data.Get(consts.INT).Int()
+		} else {
+			t, err := parseTimestamp(data.Get(consts.STRING).String())
+			if err != nil {
+				return false, err
+			}
+			timestamp = t
+		}
+		cmp = compareInt(timestamp, c.timestamp)
+	case kindBool:
+		if (data.Get("prim").String() == "True") != c.boolean {
+			cmp = 1
+		}
+	default:
+		node, err := newmiguel.BigMapToMiguel(data, c.path, m.metadata)
+		if err != nil {
+			return false, err
+		}
+		if node == nil || fmt.Sprintf("%v", node.Value) != c.str {
+			cmp = 1
+		}
+	}
+
+	switch c.operator {
+	case OpEq:
+		return cmp == 0, nil
+	case OpNe:
+		return cmp != 0, nil
+	case OpGt:
+		return cmp > 0, nil
+	case OpGte:
+		return cmp >= 0, nil
+	case OpLt:
+		return cmp < 0, nil
+	case OpLte:
+		return cmp <= 0, nil
+	}
+	return false, nil
+}
+
+func (m *Matcher) extract(c condition, value gjson.Result) (gjson.Result, bool) {
+	path := m.valuePath
+	for _, part := range c.parts {
+		switch m.metadata[path].Prim {
+		case consts.OPTION:
+			if value.Get("prim").String() != "Some" {
+				return value, false
+			}
+			value = value.Get("args.0")
+		case consts.OR:
+			branch := "Left"
+			if part == "1" {
+				branch = "Right"
+			}
+			if value.Get("prim").String() != branch {
+				return value, false
+			}
+			value = value.Get("args.0")
+		default:
+			value = value.Get("args." + part)
+		}
+		if !value.Exists() {
+			return value, false
+		}
+		path += "/" + part
+	}
+	return value, true
+}
+
+func parseTimestamp(value string) (int64, error) {
+	if ts, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return ts, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Unix(), nil
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
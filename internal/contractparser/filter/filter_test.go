@@ -0,0 +1,78 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser/meta"
+	"github.com/tidwall/gjson"
+)
+
+const testStorageType = `{"prim":"big_map","args":[{"prim":"address"},{"prim":"pair","args":[{"prim":"nat","annots":["%balance"]},{"prim":"pair","args":[{"prim":"option","args":[{"prim":"address"}],"annots":["%owner"]},{"prim":"timestamp","annots":["%updated"]}]}]}]}`
+
+func testMetadata(t *testing.T) meta.Metadata {
+	metadata, err := meta.ParseMetadata(gjson.Parse(testStorageType))
+	if err != nil {
+		t.Fatalf("ParseMetadata error: %v", err)
+	}
+	return metadata
+}
+
+func TestFields(t *testing.T) {
+	want := []string{"balance", "owner", "updated"}
+	if got := Fields(testMetadata(t), "0"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Fields got %v, want %v", got, want)
+	}
+}
+
+func TestMatcher_Match(t *testing.T) {
+	metadata := testMetadata(t)
+	value := gjson.Parse(`{"prim":"Pair","args":[{"int":"1500"},{"prim":"Pair","args":[{"prim":"Some","args":[{"string":"tz1eLWfccL46VAUjtyz9kEKgzuKnwyZH4rTA"}]},{"string":"2020-06-01T00:00:00Z"}]}]}`)
+	none := gjson.Parse(`{"prim":"Pair","args":[{"int":"10"},{"prim":"Pair","args":[{"prim":"None"},{"int":"1590969600"}]}]}`)
+
+	tests := []struct {
+		name    string
+		filter  string
+		value   gjson.Result
+		want    bool
+		wantErr bool
+	}{
+		{"balance gt", "balance:gt:1000", value, true, false},
+		{"balance lte", "balance:lte:1000", value, false, false},
+		{"balance eq", "balance:eq:1500", value, true, false},
+		{"owner eq", "owner:eq:tz1eLWfccL46VAUjtyz9kEKgzuKnwyZH4rTA", value, true, false},
+		{"owner ne", "owner:ne:tz1eLWfccL46VAUjtyz9kEKgzuKnwyZH4rTA", value, false, false},
+		{"owner is none", "owner:eq:tz1eLWfccL46VAUjtyz9kEKgzuKnwyZH4rTA", none, false, false},
+		{"updated gte RFC3339", "updated:gte:2020-06-01T00:00:00Z", value, true, false},
+		{"updated lt unix", "updated:lt:1590969601", none, true, false},
+		{"unknown field", "amount:eq:1", value, false, true},
+		{"order on address", "owner:gt:tz1", value, false, true},
+		{"invalid number", "balance:eq:abc", value, false, true},
+		{"unknown operator", "balance:like:1", value, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse(tt.filter)
+			if err != nil {
+				t.Errorf("Parse error: %v", err)
+				return
+			}
+			m, err := New(metadata, "0", []Filter{f})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			got, err := m.Match(tt.value)
+			if err != nil {
+				t.Errorf("Match error: %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Match got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
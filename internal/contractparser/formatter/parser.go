@@ -0,0 +1,470 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// ParseError - error of Michelson parsing. Line and column are 1-based.
+type ParseError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+// Error -
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// MichelsonToMicheline - parses Michelson text to Micheline. Top level expressions separated by `;` (e.g. sections
+// of the script) are returned as sequence, a single expression is returned as is. Macros are kept as primitives.
+func MichelsonToMicheline(text string) (gjson.Result, error) {
+	tokens, err := tokenize(text)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	p := &parser{tokens: tokens}
+	node, err := p.parseRoot()
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	data, err := json.Marshal(node)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	return gjson.ParseBytes(data), nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPrim
+	tokenAnnot
+	tokenInt
+	tokenString
+	tokenBytes
+	tokenOpenBrace
+	tokenCloseBrace
+	tokenOpenParen
+	tokenCloseParen
+	tokenSemicolon
+)
+
+var tokenNames = map[tokenKind]string{
+	tokenEOF:        "end of input",
+	tokenPrim:       "primitive",
+	tokenAnnot:      "annotation",
+	tokenInt:        "int",
+	tokenString:     "string",
+	tokenBytes:      "bytes",
+	tokenOpenBrace:  "`{`",
+	tokenCloseBrace: "`}`",
+	tokenOpenParen:  "`(`",
+	tokenCloseParen: "`)`",
+	tokenSemicolon:  "`;`",
+}
+
+var punctuation = map[rune]tokenKind{
+	'{': tokenOpenBrace,
+	'}': tokenCloseBrace,
+	'(': tokenOpenParen,
+	')': tokenCloseParen,
+	';': tokenSemicolon,
+}
+
+type token struct {
+	kind   tokenKind
+	value  string
+	line   int
+	column int
+}
+
+func (t token) String() string {
+	if t.value == "" {
+		return tokenNames[t.kind]
+	}
+	return fmt.Sprintf("%s `%s`", tokenNames[t.kind], t.value)
+}
+
+type lexer struct {
+	text   []rune
+	pos    int
+	line   int
+	column int
+}
+
+func tokenize(text string) ([]token, error) {
+	l := &lexer{
+		text:   []rune(text),
+		line:   1,
+		column: 1,
+	}
+	tokens := make([]token, 0)
+	for {
+		if err := l.skipSpaceAndComments(); err != nil {
+			return nil, err
+		}
+		t, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+		if t.kind == tokenEOF {
+			return tokens, nil
+		}
+	}
+}
+
+func (l *lexer) peek(offset int) rune {
+	if l.pos+offset >= len(l.text) {
+		return 0
+	}
+	return l.text[l.pos+offset]
+}
+
+func (l *lexer) advance() rune {
+	r := l.text[l.pos]
+	l.pos++
+	if r == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
+	return r
+}
+
+func (l *lexer) errorf(line, column int, format string, args ...interface{}) error {
+	return &ParseError{
+		Line:    line,
+		Column:  column,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func (l *lexer) skipSpaceAndComments() error {
+	for l.pos < len(l.text) {
+		r := l.peek(0)
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			l.advance()
+		case r == '#':
+			for l.pos < len(l.text) && l.peek(0) != '\n' {
+				l.advance()
+			}
+		case r == '/' && l.peek(1) == '*':
+			line, column := l.line, l.column
+			l.advance()
+			l.advance()
+			for !(l.peek(0) == '*' && l.peek(1) == '/') {
+				if l.pos >= len(l.text) {
+					return l.errorf(line, column, "unterminated comment")
+				}
+				l.advance()
+			}
+			l.advance()
+			l.advance()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (l *lexer) next() (token, error) {
+	t := token{line: l.line, column: l.column}
+	if l.pos >= len(l.text) {
+		t.kind = tokenEOF
+		return t, nil
+	}
+
+	r := l.peek(0)
+	switch {
+	case punctuation[r] != tokenEOF:
+		l.advance()
+		t.kind = punctuation[r]
+	case r == '"':
+		value, err := l.readString()
+		if err != nil {
+			return t, err
+		}
+		t.kind = tokenString
+		t.value = value
+	case r == '0' && l.peek(1) == 'x':
+		l.advance()
+		l.advance()
+		t.kind = tokenBytes
+		t.value = l.readWhile(isHexDigit)
+		if len(t.value)%2 != 0 {
+			return t, l.errorf(t.line, t.column, "odd length of bytes: 0x%s", t.value)
+		}
+	case r == '-' || isDigit(r):
+		t.kind = tokenInt
+		if r == '-' {
+			l.advance()
+			t.value = "-"
+		}
+		digits := l.readWhile(isDigit)
+		if digits == "" {
+			return t, l.errorf(t.line, t.column, "digit expected after `-`")
+		}
+		t.value += digits
+	case r == '@' || r == ':' || r == '%':
+		t.kind = tokenAnnot
+		t.value = l.readWhile(isAnnotChar)
+	case isLetter(r) || r == '_':
+		t.kind = tokenPrim
+		t.value = l.readWhile(isPrimChar)
+	default:
+		return t, l.errorf(t.line, t.column, "unexpected character `%c`", r)
+	}
+
+	if next := l.peek(0); t.kind != tokenString && (isPrimChar(next) || next == '"') && !isDelimiter(t.kind) {
+		return t, l.errorf(l.line, l.column, "unexpected character `%c`", next)
+	}
+	return t, nil
+}
+
+func (l *lexer) readWhile(predicate func(r rune) bool) string {
+	var sb strings.Builder
+	for l.pos < len(l.text) && predicate(l.peek(0)) {
+		sb.WriteRune(l.advance())
+	}
+	return sb.String()
+}
+
+// readString - supports escapes of Michelson strings and `\uXXXX` which appears in Micheline JSON
+func (l *lexer) readString() (string, error) {
+	line, column := l.line, l.column
+	l.advance()
+
+	var sb strings.Builder
+	for {
+		if l.pos >= len(l.text) {
+			return "", l.errorf(line, column, "unterminated string")
+		}
+		r := l.advance()
+		switch r {
+		case '"':
+			return sb.String(), nil
+		case '\n':
+			return "", l.errorf(line, column, "unterminated string")
+		case '\\':
+			escLine, escColumn := l.line, l.column-1
+			if l.pos >= len(l.text) {
+				return "", l.errorf(line, column, "unterminated string")
+			}
+			switch esc := l.advance(); esc {
+			case '"', '\\', '/':
+				sb.WriteRune(esc)
+			case 'n':
+				sb.WriteRune('\n')
+			case 't':
+				sb.WriteRune('\t')
+			case 'r':
+				sb.WriteRune('\r')
+			case 'b':
+				sb.WriteRune('\b')
+			case 'f':
+				sb.WriteRune('\f')
+			case 'u':
+				var hex strings.Builder
+				for i := 0; i < 4 && l.pos < len(l.text) && isHexDigit(l.peek(0)); i++ {
+					hex.WriteRune(l.advance())
+				}
+				code, err := strconv.ParseUint(hex.String(), 16, 32)
+				if err != nil || hex.Len() != 4 {
+					return "", l.errorf(escLine, escColumn, "invalid unicode escape")
+				}
+				sb.WriteRune(rune(code))
+			default:
+				return "", l.errorf(escLine, escColumn, "invalid escape sequence `\\%c`", esc)
+			}
+		default:
+			sb.WriteRune(r)
+		}
+	}
+}
+
+func isDelimiter(kind tokenKind) bool {
+	switch kind {
+	case tokenOpenBrace, tokenCloseBrace, tokenOpenParen, tokenCloseParen, tokenSemicolon:
+		return true
+	default:
+		return false
+	}
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isHexDigit(r rune) bool {
+	return isDigit(r) || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+}
+
+func isLetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+func isPrimChar(r rune) bool {
+	return isLetter(r) || isDigit(r) || r == '_'
+}
+
+func isAnnotChar(r rune) bool {
+	return isPrimChar(r) || r == '.' || r == '@' || r == '%' || r == ':'
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) advance() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) unexpected(t token) error {
+	return &ParseError{
+		Line:    t.line,
+		Column:  t.column,
+		Message: fmt.Sprintf("unexpected %s", t.String()),
+	}
+}
+
+func (p *parser) expect(kind tokenKind) error {
+	if t := p.advance(); t.kind != kind {
+		return &ParseError{
+			Line:    t.line,
+			Column:  t.column,
+			Message: fmt.Sprintf("%s expected, got %s", tokenNames[kind], t.String()),
+		}
+	}
+	return nil
+}
+
+func (p *parser) parseRoot() (interface{}, error) {
+	items, separated, err := p.parseItems(tokenEOF)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, p.unexpected(p.peek())
+	}
+	if len(items) == 1 && !separated {
+		return items[0], nil
+	}
+	return items, nil
+}
+
+// parseItems - parses expressions separated by `;` up to the closing token. Trailing `;` is allowed.
+func (p *parser) parseItems(closing tokenKind) ([]interface{}, bool, error) {
+	items := make([]interface{}, 0)
+	var separated bool
+	for p.peek().kind != closing {
+		item, err := p.parseExpr()
+		if err != nil {
+			return nil, false, err
+		}
+		items = append(items, item)
+
+		if p.peek().kind == closing {
+			break
+		}
+		if err := p.expect(tokenSemicolon); err != nil {
+			return nil, false, err
+		}
+		separated = true
+	}
+	p.advance()
+	return items, separated, nil
+}
+
+// parseExpr - parses primitive application or argument
+func (p *parser) parseExpr() (interface{}, error) {
+	t := p.peek()
+	if t.kind != tokenPrim {
+		return p.parseArg()
+	}
+	p.advance()
+	node := map[string]interface{}{
+		"prim": t.value,
+	}
+	if annots := p.parseAnnots(); len(annots) > 0 {
+		node["annots"] = annots
+	}
+
+	args := make([]interface{}, 0)
+	for {
+		switch p.peek().kind {
+		case tokenSemicolon, tokenCloseBrace, tokenCloseParen, tokenEOF:
+			if len(args) > 0 {
+				node["args"] = args
+			}
+			return node, nil
+		case tokenAnnot:
+			// annotations follow the primitive, annotated arguments must be parenthesized: `pair (chain_id %a) nat`
+			return nil, p.unexpected(p.peek())
+		default:
+			arg, err := p.parseArg()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+	}
+}
+
+func (p *parser) parseAnnots() []string {
+	annots := make([]string, 0)
+	for p.peek().kind == tokenAnnot {
+		annots = append(annots, p.advance().value)
+	}
+	return annots
+}
+
+// parseArg - parses literal, sequence, parenthesized expression or primitive without arguments
+func (p *parser) parseArg() (interface{}, error) {
+	t := p.advance()
+	switch t.kind {
+	case tokenInt:
+		return map[string]interface{}{"int": t.value}, nil
+	case tokenString:
+		return map[string]interface{}{"string": t.value}, nil
+	case tokenBytes:
+		return map[string]interface{}{"bytes": t.value}, nil
+	case tokenPrim:
+		return map[string]interface{}{"prim": t.value}, nil
+	case tokenOpenBrace:
+		items, _, err := p.parseItems(tokenCloseBrace)
+		if err != nil {
+			return nil, err
+		}
+		return items, nil
+	case tokenOpenParen:
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenCloseParen); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	default:
+		return nil, p.unexpected(t)
+	}
+}
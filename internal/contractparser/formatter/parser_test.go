@@ -0,0 +1,140 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestMichelsonToMicheline(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr string
+	}{
+		{
+			name:  "int",
+			input: "-42",
+			want:  `{"int":"-42"}`,
+		}, {
+			name:  "string with escapes",
+			input: `"a \"b\"\n\\"`,
+			want:  `{"string":"a \"b\"\n\\"}`,
+		}, {
+			name:  "bytes",
+			input: "0x00FF",
+			want:  `{"bytes":"00FF"}`,
+		}, {
+			name:  "data",
+			input: `Pair (Some 1) {Elt "a" True; Elt "b" False}`,
+			want:  `{"prim":"Pair","args":[{"prim":"Some","args":[{"int":"1"}]},[{"prim":"Elt","args":[{"string":"a"},{"prim":"True"}]},{"prim":"Elt","args":[{"string":"b"},{"prim":"False"}]}]]}`,
+		}, {
+			name:  "annotations",
+			input: "pair (nat %a :b) (string @c)",
+			want:  `{"prim":"pair","args":[{"prim":"nat","annots":["%a",":b"]},{"prim":"string","annots":["@c"]}]}`,
+		}, {
+			name:    "annotations of argument without parentheses",
+			input:   "pair chain_id %a nat",
+			wantErr: "1:15: unexpected annotation `%a`",
+		}, {
+			name:  "script with comments and macros",
+			input: "parameter unit; # comment\nstorage unit;\n/* multiline\ncomment */\ncode { CDR ; NIL operation ; PAIR ; DIIP { UNPAIR } ; IF_SOME {} { FAIL } ; };",
+			want:  `[{"prim":"parameter","args":[{"prim":"unit"}]},{"prim":"storage","args":[{"prim":"unit"}]},{"prim":"code","args":[[{"prim":"CDR"},{"prim":"NIL","args":[{"prim":"operation"}]},{"prim":"PAIR"},{"prim":"DIIP","args":[[{"prim":"UNPAIR"}]]},{"prim":"IF_SOME","args":[[],[{"prim":"FAIL"}]]}]]}]`,
+		}, {
+			name:  "sequence",
+			input: "{ 1 ; 2 }",
+			want:  `[{"int":"1"},{"int":"2"}]`,
+		}, {
+			name:    "unbalanced brace",
+			input:   "{ PUSH nat 1 }\n}",
+			wantErr: "2:1: `;` expected, got `}`",
+		}, {
+			name:    "unclosed sequence",
+			input:   "code { DUP ;",
+			wantErr: "1:13: unexpected end of input",
+		}, {
+			name:    "unterminated string",
+			input:   "PUSH string \"abc",
+			wantErr: "1:13: unterminated string",
+		}, {
+			name:    "invalid character",
+			input:   "PUSH nat 1;\nDROP $",
+			wantErr: "2:6: unexpected character `$`",
+		}, {
+			name:    "odd bytes",
+			input:   "0xabc",
+			wantErr: "1:1: odd length of bytes: 0xabc",
+		}, {
+			name:    "unterminated comment",
+			input:   "DUP /* comment",
+			wantErr: "1:5: unterminated comment",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MichelsonToMicheline(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("MichelsonToMicheline() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("MichelsonToMicheline() error = %v", err)
+				return
+			}
+			if !equalJSON(got.Raw, tt.want) {
+				t.Errorf("MichelsonToMicheline() = %v, want %v", got.Raw, tt.want)
+			}
+		})
+	}
+}
+
+func TestMichelsonToMicheline_RoundTrip(t *testing.T) {
+	files, err := ioutil.ReadDir("./formatter_tests")
+	if err != nil {
+		t.Errorf("ioutil.ReadDir error: %v", err)
+		return
+	}
+	for _, file := range files {
+		address := file.Name()
+		t.Run(address, func(t *testing.T) {
+			data, err := ioutil.ReadFile(fmt.Sprintf("./formatter_tests/%v/code_%v.json", address, address[:6]))
+			if err != nil {
+				t.Errorf("ioutil.ReadFile code.json error: %v", err)
+				return
+			}
+			for _, inline := range []bool{true, false} {
+				michelson, err := MichelineToMichelson(gjson.ParseBytes(data), inline, DefLineSize)
+				if err != nil {
+					t.Errorf("MichelineToMichelson error: %v", err)
+					return
+				}
+				result, err := MichelsonToMicheline(michelson)
+				if err != nil {
+					t.Errorf("MichelsonToMicheline(inline=%v) error: %v", inline, err)
+					return
+				}
+				if !equalJSON(result.Raw, string(data)) {
+					t.Errorf("MichelsonToMicheline(inline=%v) result differs from source", inline)
+				}
+			}
+		})
+	}
+}
+
+func equalJSON(a, b string) bool {
+	var x, y interface{}
+	if err := json.Unmarshal([]byte(a), &x); err != nil {
+		return false
+	}
+	if err := json.Unmarshal([]byte(b), &y); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(x, y)
+}
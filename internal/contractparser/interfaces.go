@@ -0,0 +1,175 @@
+package contractparser
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/contractparser/meta"
+	"github.com/baking-bad/bcdhub/internal/jsonload"
+	"github.com/tidwall/gjson"
+)
+
+// builtinVersion - version of interfaces shipped in `interfaces/` directory. Any registered version overrides them.
+const builtinVersion = 0
+
+// Interface - entrypoints which contract has to implement to be tagged by `Name`.
+// Several versions of the interface may be registered, the highest one is used for tagging.
+type Interface struct {
+	Name        string
+	Version     int64
+	Entrypoints []Entrypoint
+}
+
+var (
+	interfaces       = map[string]Interface{}
+	interfacesLoaded bool
+	interfacesMux    sync.RWMutex
+)
+
+// NewInterface - creates interface from array of entrypoints `{"name": "transfer", "type": <Micheline type>}`
+func NewInterface(name string, version int64, entrypoints gjson.Result) (Interface, error) {
+	i := Interface{
+		Name:    name,
+		Version: version,
+	}
+	if name == "" {
+		return i, fmt.Errorf("Empty interface name")
+	}
+	if !entrypoints.IsArray() || len(entrypoints.Array()) == 0 {
+		return i, fmt.Errorf("Interface %s has no entrypoints", name)
+	}
+
+	for _, item := range entrypoints.Array() {
+		entrypointName := item.Get("name").String()
+		if entrypointName == "" {
+			return i, fmt.Errorf("Interface %s: empty entrypoint name", name)
+		}
+		e, err := entrypointFromMicheline(item.Get("type"))
+		if err != nil {
+			return i, fmt.Errorf("Interface %s: entrypoint %s: %v", name, entrypointName, err)
+		}
+		e.Name = entrypointName
+		i.Entrypoints = append(i.Entrypoints, e)
+	}
+	return i, nil
+}
+
+// entrypointFromMicheline - converts Micheline type to the form used by `compareEntrypoints`. Callback type of `contract` is not compared.
+func entrypointFromMicheline(typ gjson.Result) (Entrypoint, error) {
+	prim := typ.Get("prim").String()
+	if prim == "" {
+		return Entrypoint{}, fmt.Errorf("Invalid Micheline type: %s", typ.Raw)
+	}
+	e := Entrypoint{
+		Prim: strings.ToLower(prim),
+	}
+
+	args := typ.Get("args").Array()
+	for _, arg := range args {
+		argEntrypoint, err := entrypointFromMicheline(arg)
+		if err != nil {
+			return e, err
+		}
+		if e.Prim == consts.CONTRACT {
+			e.Parameter = argEntrypoint
+			continue
+		}
+		e.Args = append(e.Args, argEntrypoint)
+	}
+	return e, nil
+}
+
+// RegisterInterface - adds interface to the registry. Returns false if the same or higher version of the interface is already registered.
+func RegisterInterface(i Interface) bool {
+	interfacesMux.Lock()
+	defer interfacesMux.Unlock()
+
+	if current, ok := interfaces[i.Name]; ok && current.Version >= i.Version {
+		return false
+	}
+	interfaces[i.Name] = i
+	return true
+}
+
+// GetInterface - returns registered version of the interface
+func GetInterface(name string) (Interface, bool) {
+	if err := loadInterfaces(); err != nil {
+		return Interface{}, false
+	}
+
+	interfacesMux.RLock()
+	defer interfacesMux.RUnlock()
+
+	i, ok := interfaces[name]
+	return i, ok
+}
+
+// Match - returns true if contract with parameter `metadata` implements the interface. Interface without entrypoints matches nothing.
+func (i Interface) Match(metadata meta.Metadata) bool {
+	if len(i.Entrypoints) == 0 {
+		return false
+	}
+	return findInterface(metadata, i.Entrypoints)
+}
+
+// loadInterfaces - registers interfaces from `interfaces/` directory once. File name is used as interface name.
+func loadInterfaces() error {
+	interfacesMux.Lock()
+	defer interfacesMux.Unlock()
+
+	if interfacesLoaded {
+		return nil
+	}
+
+	files, err := ioutil.ReadDir("interfaces/")
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		path := fmt.Sprintf("interfaces/%s", f.Name())
+		var e []Entrypoint
+		if err := jsonload.StructFromFile(path, &e); err != nil {
+			return err
+		}
+		name := strings.Split(f.Name(), ".")[0]
+		if _, ok := interfaces[name]; ok {
+			continue
+		}
+		interfaces[name] = Interface{
+			Name:        name,
+			Version:     builtinVersion,
+			Entrypoints: e,
+		}
+	}
+	interfacesLoaded = true
+	return nil
+}
+
+// findInterfaces - returns names of interfaces implemented by contract with their versions
+func findInterfaces(metadata meta.Metadata) (map[string]int64, error) {
+	if err := loadInterfaces(); err != nil {
+		return nil, err
+	}
+
+	interfacesMux.RLock()
+	defer interfacesMux.RUnlock()
+
+	names := make([]string, 0, len(interfaces))
+	for name := range interfaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	res := make(map[string]int64)
+	for _, name := range names {
+		if interfaces[name].Match(metadata) {
+			res[name] = interfaces[name].Version
+		}
+	}
+	return res, nil
+}
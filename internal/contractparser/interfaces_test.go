@@ -0,0 +1,147 @@
+package contractparser
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestNewInterface(t *testing.T) {
+	tests := []struct {
+		name        string
+		iface       string
+		entrypoints string
+		want        []Entrypoint
+		wantErr     bool
+	}{
+		{
+			name:        "transfer",
+			iface:       "transferable",
+			entrypoints: `[{"name":"transfer","type":{"prim":"pair","args":[{"prim":"address","annots":[":from"]},{"prim":"pair","args":[{"prim":"address"},{"prim":"nat"}]}]}}]`,
+			want: []Entrypoint{
+				{
+					Name: "transfer",
+					Prim: "pair",
+					Args: []Entrypoint{
+						{Prim: "address"},
+						{Prim: "pair", Args: []Entrypoint{{Prim: "address"}, {Prim: "nat"}}},
+					},
+				},
+			},
+		}, {
+			name:        "callback",
+			iface:       "view",
+			entrypoints: `[{"name":"getBalance","type":{"prim":"pair","args":[{"prim":"address"},{"prim":"contract","args":[{"prim":"nat"}]}]}}]`,
+			want: []Entrypoint{
+				{
+					Name: "getBalance",
+					Prim: "pair",
+					Args: []Entrypoint{
+						{Prim: "address"},
+						{Prim: "contract", Parameter: Entrypoint{Prim: "nat"}},
+					},
+				},
+			},
+		}, {
+			name:        "empty name",
+			entrypoints: `[{"name":"transfer","type":{"prim":"unit"}}]`,
+			wantErr:     true,
+		}, {
+			name:        "no entrypoints",
+			iface:       "empty",
+			entrypoints: `[]`,
+			wantErr:     true,
+		}, {
+			name:        "no entrypoint name",
+			iface:       "unnamed",
+			entrypoints: `[{"type":{"prim":"unit"}}]`,
+			wantErr:     true,
+		}, {
+			name:        "invalid type",
+			iface:       "invalid",
+			entrypoints: `[{"name":"transfer","type":{"int":"1"}}]`,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewInterface(tt.iface, 1, gjson.Parse(tt.entrypoints))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewInterface() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got.Entrypoints, tt.want) {
+				t.Errorf("NewInterface() = %+v, want %+v", got.Entrypoints, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterInterface(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int64
+		want    bool
+	}{
+		{"first", 2, true},
+		{"lower", 1, false},
+		{"same", 2, false},
+		{"higher", 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RegisterInterface(Interface{Name: "test_register", Version: tt.version}); got != tt.want {
+				t.Errorf("RegisterInterface() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if i, ok := GetInterface("test_register"); !ok || i.Version != 3 {
+		t.Errorf("GetInterface() = %v %v, want version 3", i.Version, ok)
+	}
+}
+
+func TestRegisteredInterfaceTags(t *testing.T) {
+	iface, err := NewInterface("test_transfer", 5, gjson.Parse(`[{"name":"transfer","type":{"prim":"pair","args":[{"prim":"address"},{"prim":"pair","args":[{"prim":"address"},{"prim":"nat"}]}]}}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterInterface(iface)
+
+	tests := []struct {
+		name string
+		path string
+		want map[string]int64
+	}{
+		{
+			name: "fa12",
+			path: "testdata/tags/fa12-babylonnet-KT1Q3XGrpbqhF6ny4qLhuiKekmk86hiAnmhh.json",
+			want: map[string]int64{"fa12": builtinVersion, "test_transfer": 5},
+		}, {
+			name: "fa2",
+			path: "testdata/tags/fa2-tzip12.json",
+			want: map[string]int64{"fa2": builtinVersion},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := ioutil.ReadFile(tt.path)
+			if err != nil {
+				t.Errorf("ioutil.ReadFile %v error %v", tt.path, err)
+				return
+			}
+			p, err := newParameter(gjson.ParseBytes(file))
+			if err != nil {
+				t.Errorf("newParameter error %v", err)
+				return
+			}
+			if !reflect.DeepEqual(p.Interfaces, tt.want) {
+				t.Errorf("Interfaces = %v, want %v", p.Interfaces, tt.want)
+			}
+			if _, ok := p.Tags["test_transfer"]; ok != (tt.want["test_transfer"] > 0) {
+				t.Errorf("Wrong tags %v", p.Tags)
+			}
+		})
+	}
+}
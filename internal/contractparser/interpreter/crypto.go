@@ -0,0 +1,245 @@
+package interpreter
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser/pack"
+	"github.com/baking-bad/bcdhub/internal/contractparser/unpack"
+	"github.com/baking-bad/bcdhub/internal/tzbase58"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/ed25519"
+)
+
+const addressBytesLength = 22
+
+type base58Prefix struct {
+	prefix []byte
+	tag    byte
+}
+
+var keyPrefixes = map[string]base58Prefix{
+	"edpk": {[]byte{13, 15, 37, 217}, 0x00},
+	"sppk": {[]byte{3, 254, 226, 86}, 0x01},
+	"p2pk": {[]byte{3, 178, 139, 127}, 0x02},
+}
+
+var keyHashPrefixes = map[string]base58Prefix{
+	"tz1": {[]byte{6, 161, 159}, 0x00},
+	"tz2": {[]byte{6, 161, 161}, 0x01},
+	"tz3": {[]byte{6, 161, 164}, 0x02},
+}
+
+var signaturePrefixes = map[string]int{
+	"edsig":  5,
+	"spsig1": 5,
+	"p2sig":  4,
+	"sig":    3,
+}
+
+var (
+	prefixKT1     = []byte{2, 90, 121}
+	prefixChainID = []byte{87, 82, 0}
+	prefixExpr    = []byte{13, 44, 64, 27}
+)
+
+func decodeBase58(value string, prefixLen int) ([]byte, error) {
+	payload, err := tzbase58.DecodeFromHex(value, prefixLen)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid base58 value %s: %v", value, err)
+	}
+	return hex.DecodeString(payload)
+}
+
+// addressToBytes - binary form of the address, entrypoint is appended as is
+func addressToBytes(address string) ([]byte, error) {
+	address, entrypoint := splitAddress(address)
+	packed, err := pack.Address(address)
+	if err != nil {
+		return nil, err
+	}
+	data, err := hex.DecodeString(packed)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, []byte(entrypoint)...), nil
+}
+
+func bytesToAddress(data []byte) (string, error) {
+	if len(data) < addressBytesLength {
+		return "", fmt.Errorf("Invalid address bytes: %x", data)
+	}
+	address, err := unpack.Address(hex.EncodeToString(data[:addressBytesLength]))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > addressBytesLength {
+		address += "%" + string(data[addressBytesLength:])
+	}
+	return address, nil
+}
+
+// splitAddress - splits `KT1...%entrypoint` to address and entrypoint
+func splitAddress(value string) (string, string) {
+	if idx := strings.Index(value, "%"); idx >= 0 {
+		return value[:idx], value[idx+1:]
+	}
+	return value, ""
+}
+
+func isAddress(value string) bool {
+	address, _ := splitAddress(value)
+	if len(address) != 36 {
+		return false
+	}
+	switch address[:3] {
+	case "KT1", "tz1", "tz2", "tz3":
+		_, err := decodeBase58(address, 3)
+		return err == nil
+	default:
+		return false
+	}
+}
+
+func keyToBytes(key string) ([]byte, error) {
+	if len(key) < 4 {
+		return nil, fmt.Errorf("Invalid key: %s", key)
+	}
+	prefix, ok := keyPrefixes[key[:4]]
+	if !ok {
+		return nil, fmt.Errorf("Invalid key: %s", key)
+	}
+	payload, err := decodeBase58(key, len(prefix.prefix))
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{prefix.tag}, payload...), nil
+}
+
+func bytesToKey(data []byte) (string, error) {
+	return unpack.PublicKey(hex.EncodeToString(data))
+}
+
+func keyHashToBytes(keyHash string) ([]byte, error) {
+	if len(keyHash) < 3 {
+		return nil, fmt.Errorf("Invalid key hash: %s", keyHash)
+	}
+	prefix, ok := keyHashPrefixes[keyHash[:3]]
+	if !ok {
+		return nil, fmt.Errorf("Invalid key hash: %s", keyHash)
+	}
+	payload, err := decodeBase58(keyHash, len(prefix.prefix))
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{prefix.tag}, payload...), nil
+}
+
+func bytesToKeyHash(data []byte) (string, error) {
+	return unpack.KeyHash(hex.EncodeToString(data))
+}
+
+func signatureToBytes(signature string) ([]byte, error) {
+	for prefix, length := range signaturePrefixes {
+		if strings.HasPrefix(signature, prefix) {
+			return decodeBase58(signature, length)
+		}
+	}
+	return nil, fmt.Errorf("Invalid signature: %s", signature)
+}
+
+func bytesToSignature(data []byte) (string, error) {
+	return unpack.Signature(hex.EncodeToString(data))
+}
+
+func chainIDToBytes(chainID string) ([]byte, error) {
+	return decodeBase58(chainID, len(prefixChainID))
+}
+
+func bytesToChainID(data []byte) (string, error) {
+	return unpack.ChainID(hex.EncodeToString(data))
+}
+
+// hashKey - key hash is BLAKE2B-160 of the public key
+func hashKey(key string) (string, error) {
+	data, err := keyToBytes(key)
+	if err != nil {
+		return "", err
+	}
+	hash, err := blake2b.New(20, nil)
+	if err != nil {
+		return "", err
+	}
+	hash.Write(data[1:])
+	for _, prefix := range keyHashPrefixes {
+		if prefix.tag == data[0] {
+			return tzbase58.EncodeFromBytes(hash.Sum(nil), prefix.prefix), nil
+		}
+	}
+	return "", fmt.Errorf("Invalid key: %s", key)
+}
+
+// originatedAddress - address of the contract created by the interpreter. Real address depends on the operation hash
+// which is unknown before injection, so it is derived from the creator and the nonce.
+func originatedAddress(creator string, nonce int) string {
+	hash, _ := blake2b.New(20, nil)
+	hash.Write([]byte(fmt.Sprintf("%s:%d", creator, nonce)))
+	return tzbase58.EncodeFromBytes(hash.Sum(nil), prefixKT1)
+}
+
+// checkSignature - message is hashed by BLAKE2B-256 before signing
+func checkSignature(key, signature string, message []byte) (bool, error) {
+	keyBytes, err := keyToBytes(key)
+	if err != nil {
+		return false, err
+	}
+	sig, err := signatureToBytes(signature)
+	if err != nil {
+		return false, err
+	}
+	if len(sig) != 64 {
+		return false, fmt.Errorf("Invalid signature length: %d", len(sig))
+	}
+	digest := blake2b.Sum256(message)
+
+	switch keyBytes[0] {
+	case 0x00:
+		return ed25519.Verify(ed25519.PublicKey(keyBytes[1:]), digest[:], sig), nil
+	case 0x02:
+		x, y, err := decompressP256(keyBytes[1:])
+		if err != nil {
+			return false, err
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		return ecdsa.Verify(pub, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])), nil
+	default:
+		return false, fmt.Errorf("secp256k1 signatures are not supported")
+	}
+}
+
+func decompressP256(data []byte) (*big.Int, *big.Int, error) {
+	if len(data) != 33 || (data[0] != 2 && data[0] != 3) {
+		return nil, nil, fmt.Errorf("Invalid p256 public key")
+	}
+	params := elliptic.P256().Params()
+	x := new(big.Int).SetBytes(data[1:])
+
+	// y² = x³ - 3x + b
+	y2 := new(big.Int).Exp(x, big.NewInt(3), params.P)
+	y2.Sub(y2, new(big.Int).Mul(x, big.NewInt(3)))
+	y2.Add(y2, params.B)
+	y2.Mod(y2, params.P)
+
+	y := new(big.Int).ModSqrt(y2, params.P)
+	if y == nil {
+		return nil, nil, fmt.Errorf("Invalid p256 public key")
+	}
+	if y.Bit(0) != uint(data[0]&1) {
+		y.Sub(params.P, y)
+	}
+	return x, y, nil
+}
@@ -0,0 +1,272 @@
+package interpreter
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/tidwall/gjson"
+)
+
+func (i *Interpreter) domainOp(ins *instruction, s *stack) error {
+	switch ins.prim {
+	case "SELF":
+		entrypoint := ins.fieldAnnot()
+		t, ok := i.parameterType.Entrypoint(entrypoint)
+		if !ok {
+			return fmt.Errorf("Unknown entrypoint: %s", entrypoint)
+		}
+		s.push(&Value{Type: newType(consts.CONTRACT, t), Str: withEntrypoint(i.self, entrypoint)})
+	case "NOW":
+		s.push(newInt(newType(consts.TIMESTAMP), big.NewInt(i.now)))
+	case "AMOUNT":
+		s.push(newInt(newType(consts.MUTEZ), big.NewInt(i.amount)))
+	case "BALANCE":
+		s.push(newInt(newType(consts.MUTEZ), big.NewInt(i.balance)))
+	case "SOURCE":
+		s.push(&Value{Type: newType(consts.ADDRESS), Str: i.source})
+	case "SENDER":
+		s.push(&Value{Type: newType(consts.ADDRESS), Str: i.sender})
+	case "CHAIN_ID":
+		s.push(&Value{Type: newType(consts.CHAINID), Str: i.chainID})
+	case "CONTRACT":
+		return i.contract(ins, s)
+	case "TRANSFER_TOKENS":
+		return i.transfer(s)
+	case "CREATE_CONTRACT":
+		return i.createContract(ins, s)
+	case "CHECK_SIGNATURE":
+		values, err := s.popN(3)
+		if err != nil {
+			return err
+		}
+		key, signature, message := values[0], values[1], values[2]
+		if err := expectType(key, consts.KEY); err != nil {
+			return err
+		}
+		if err := expectType(signature, consts.SIGNATURE); err != nil {
+			return err
+		}
+		if err := expectType(message, consts.BYTES); err != nil {
+			return err
+		}
+		ok, err := checkSignature(key.Str, signature.Str, message.Bytes)
+		if err != nil {
+			return err
+		}
+		s.push(newBool(ok))
+	default:
+		top, err := s.pop()
+		if err != nil {
+			return err
+		}
+		return i.unaryDomainOp(ins, top, s)
+	}
+	return nil
+}
+
+func (i *Interpreter) unaryDomainOp(ins *instruction, top *Value, s *stack) error {
+	switch ins.prim {
+	case "IMPLICIT_ACCOUNT":
+		if err := expectType(top, consts.KEYHASH); err != nil {
+			return err
+		}
+		s.push(&Value{Type: newType(consts.CONTRACT, newType(consts.UNIT)), Str: top.Str})
+	case "ADDRESS":
+		if err := expectType(top, consts.CONTRACT); err != nil {
+			return err
+		}
+		s.push(&Value{Type: newType(consts.ADDRESS), Str: top.Str})
+	case "HASH_KEY":
+		if err := expectType(top, consts.KEY); err != nil {
+			return err
+		}
+		keyHash, err := hashKey(top.Str)
+		if err != nil {
+			return err
+		}
+		s.push(&Value{Type: newType(consts.KEYHASH), Str: keyHash})
+	case "SET_DELEGATE":
+		if err := expectType(top, consts.OPTION); err != nil {
+			return err
+		}
+		operation := map[string]interface{}{
+			"kind":   "delegation",
+			"source": i.self,
+		}
+		if top.IsSome() {
+			operation["delegate"] = top.Args[0].Str
+		}
+		s.push(&Value{Type: newType(consts.OPERATION), Operation: operation})
+	}
+	return nil
+}
+
+// contract - implicit accounts have the only `default` entrypoint of type unit
+func (i *Interpreter) contract(ins *instruction, s *stack) error {
+	t, err := typeArg(ins)
+	if err != nil {
+		return err
+	}
+	top, err := s.pop()
+	if err != nil {
+		return err
+	}
+	if err := expectType(top, consts.ADDRESS); err != nil {
+		return err
+	}
+	address, entrypoint := splitAddress(top.Str)
+	if annot := ins.fieldAnnot(); annot != "" {
+		if entrypoint != "" {
+			s.push(newNone(newType(consts.CONTRACT, t)))
+			return nil
+		}
+		entrypoint = annot
+	}
+	if entrypoint == "" {
+		entrypoint = defaultEntrypoint
+	}
+
+	var found bool
+	if strings.HasPrefix(address, "KT1") {
+		found, err = i.hasEntrypoint(address, entrypoint, t)
+		if err != nil {
+			return err
+		}
+	} else {
+		found = entrypoint == defaultEntrypoint && t.Prim == consts.UNIT
+	}
+
+	if !found {
+		s.push(newNone(newType(consts.CONTRACT, t)))
+		return nil
+	}
+	s.push(newSome(&Value{Type: newType(consts.CONTRACT, t), Str: withEntrypoint(address, entrypoint)}))
+	return nil
+}
+
+func (i *Interpreter) hasEntrypoint(address, entrypoint string, t *Type) (bool, error) {
+	var parameter *Type
+	if address == i.self {
+		parameter = i.parameterType
+	} else {
+		if i.getContract == nil {
+			return false, fmt.Errorf("Contract %s is not available offline", address)
+		}
+		data, err := i.getContract(address)
+		if err != nil {
+			return false, err
+		}
+		if !data.Exists() {
+			return false, nil
+		}
+		if parameter, err = NewType(data); err != nil {
+			return false, err
+		}
+	}
+	entrypointType, ok := parameter.Entrypoint(entrypoint)
+	return ok && entrypointType.Equal(t), nil
+}
+
+func (i *Interpreter) transfer(s *stack) error {
+	values, err := s.popN(3)
+	if err != nil {
+		return err
+	}
+	parameter, amount, contract := values[0], values[1], values[2]
+	if err := expectType(amount, consts.MUTEZ); err != nil {
+		return err
+	}
+	if err := expectType(contract, consts.CONTRACT); err != nil {
+		return err
+	}
+	if !contract.Type.Args[0].Equal(parameter.Type) {
+		return fmt.Errorf("Parameter %s does not match contract %s", parameter.Type.String(), contract.Type.String())
+	}
+	value, err := parameter.Micheline(false)
+	if err != nil {
+		return err
+	}
+	destination, entrypoint := splitAddress(contract.Str)
+	if entrypoint == "" {
+		entrypoint = defaultEntrypoint
+	}
+	s.push(&Value{
+		Type: newType(consts.OPERATION),
+		Operation: map[string]interface{}{
+			"kind":        "transaction",
+			"source":      i.self,
+			"destination": destination,
+			"amount":      amount.Int.String(),
+			"parameters": map[string]interface{}{
+				"entrypoint": entrypoint,
+				"value":      value,
+			},
+		},
+	})
+	return nil
+}
+
+func (i *Interpreter) createContract(ins *instruction, s *stack) error {
+	if len(ins.raw) != 1 {
+		return fmt.Errorf("Invalid arguments count: %d", len(ins.raw))
+	}
+	values, err := s.popN(3)
+	if err != nil {
+		return err
+	}
+	delegate, amount, storage := values[0], values[1], values[2]
+	if err := expectType(delegate, consts.OPTION); err != nil {
+		return err
+	}
+	if err := expectType(amount, consts.MUTEZ); err != nil {
+		return err
+	}
+	storageType, err := sectionType(ins.raw[0], consts.STORAGE)
+	if err != nil {
+		return err
+	}
+	if !storageType.Equal(storage.Type) {
+		return fmt.Errorf("Storage %s does not match %s", storage.Type.String(), storageType.String())
+	}
+	initial, err := storage.Micheline(false)
+	if err != nil {
+		return err
+	}
+
+	i.nonce++
+	address := originatedAddress(i.self, i.nonce)
+	operation := map[string]interface{}{
+		"kind":    "origination",
+		"source":  i.self,
+		"balance": amount.Int.String(),
+		"script": map[string]interface{}{
+			"code":    ins.raw[0].Value(),
+			"storage": initial,
+		},
+		"originated_contract": address,
+	}
+	if delegate.IsSome() {
+		operation["delegate"] = delegate.Args[0].Str
+	}
+	s.push(&Value{Type: newType(consts.ADDRESS), Str: address})
+	s.push(&Value{Type: newType(consts.OPERATION), Operation: operation})
+	return nil
+}
+
+func sectionType(script gjson.Result, name string) (*Type, error) {
+	for _, section := range script.Array() {
+		if section.Get(consts.KeyPrim).String() == name {
+			return NewType(section.Get("args.0"))
+		}
+	}
+	return nil, fmt.Errorf("Section %s is not found", name)
+}
+
+func withEntrypoint(address, entrypoint string) string {
+	if entrypoint == "" || entrypoint == defaultEntrypoint {
+		return address
+	}
+	return address + "%" + entrypoint
+}
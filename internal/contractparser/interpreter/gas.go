@@ -0,0 +1,128 @@
+package interpreter
+
+import (
+	"math/bits"
+
+	"github.com/tidwall/gjson"
+)
+
+// Gas costs are approximation of the Carthage gas model: every instruction has a base cost
+// and instructions working with collections, big numbers or bytes pay for the size of arguments.
+const (
+	baseCost         = 10
+	nodeCost         = 5
+	bigMapReadCost   = 1000
+	signatureCost    = 1000
+	hashCostPerByte  = 2
+	bytesCostPerWord = 1
+)
+
+func (i *Interpreter) consume(cost int64) error {
+	i.gas -= cost
+	if i.gas < 0 {
+		i.gas = 0
+		return &GasExhaustedError{}
+	}
+	return nil
+}
+
+// deserializationCost - cost of reading Micheline is proportional to the nodes count
+func deserializationCost(data gjson.Result) int64 {
+	var count int64
+	var walk func(node gjson.Result)
+	walk = func(node gjson.Result) {
+		count++
+		switch {
+		case node.IsArray():
+			for _, item := range node.Array() {
+				walk(item)
+			}
+		case node.IsObject():
+			for _, arg := range node.Get("args").Array() {
+				walk(arg)
+			}
+		}
+	}
+	walk(data)
+	return count * nodeCost
+}
+
+func instructionCost(ins *instruction, s stack) int64 {
+	cost := int64(baseCost)
+	top := s.item(0)
+	second := s.item(1)
+
+	switch ins.prim {
+	case "MEM", "GET", "UPDATE":
+		collection := s.item(1)
+		if ins.prim == "UPDATE" {
+			collection = s.item(2)
+		}
+		if collection != nil {
+			cost += logSize(len(collection.Args) + len(collection.Elts))
+			if collection.Ptr != nil {
+				cost += bigMapReadCost
+			}
+		}
+	case "ADD", "SUB", "COMPARE":
+		cost += intWords(top) + intWords(second)
+	case "MUL", "EDIV", "LSL", "LSR":
+		cost += intWords(top) * intWords(second)
+	case "CONCAT":
+		if top != nil {
+			cost += int64(len(top.Str)+len(top.Bytes)+len(top.Args)) * bytesCostPerWord
+		}
+		if second != nil {
+			cost += int64(len(second.Str)+len(second.Bytes)) * bytesCostPerWord
+		}
+	case "BLAKE2B", "SHA256", "SHA512":
+		if top != nil {
+			cost += int64(len(top.Bytes)) * hashCostPerByte
+		}
+	case "PACK", "UNPACK":
+		cost += valueSize(top) * nodeCost
+	case "CHECK_SIGNATURE":
+		cost += signatureCost
+		if third := s.item(2); third != nil {
+			cost += int64(len(third.Bytes)) * hashCostPerByte
+		}
+	case "HASH_KEY":
+		cost += signatureCost / 10
+	case "CONTRACT":
+		cost += bigMapReadCost
+	}
+	return cost
+}
+
+func (s stack) item(depth int) *Value {
+	if depth >= len(s) {
+		return nil
+	}
+	return s[len(s)-1-depth]
+}
+
+func logSize(size int) int64 {
+	return int64(bits.Len(uint(size)))
+}
+
+// intWords - size of the number in 64-bit words
+func intWords(v *Value) int64 {
+	if v == nil || v.Int == nil {
+		return 1
+	}
+	return int64(len(v.Int.Bits())) + 1
+}
+
+func valueSize(v *Value) int64 {
+	if v == nil {
+		return 0
+	}
+	size := int64(1)
+	for _, arg := range v.Args {
+		size += valueSize(arg)
+	}
+	for _, elt := range v.Elts {
+		size += valueSize(elt.Key) + valueSize(elt.Value)
+	}
+	return size
+}
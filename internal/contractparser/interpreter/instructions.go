@@ -0,0 +1,975 @@
+package interpreter
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/contractparser/pack"
+	"github.com/baking-bad/bcdhub/internal/contractparser/unpack/rawbytes"
+	"github.com/tidwall/gjson"
+	"golang.org/x/crypto/blake2b"
+)
+
+const maxShift = 256
+
+var maxMutez = big.NewInt(9223372036854775807)
+
+func (i *Interpreter) exec(ins *instruction, s *stack) error {
+	if ins.isSeq() {
+		for _, item := range ins.args {
+			if err := i.exec(item, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	idx := i.begin(ins, *s)
+	if err := i.consume(instructionCost(ins, *s)); err != nil {
+		return &GasExhaustedError{Location: ins.location}
+	}
+	if err := i.step(ins, s); err != nil {
+		switch err.(type) {
+		case *ScriptRejectedError, *GasExhaustedError, *RuntimeError:
+			return err
+		default:
+			return &RuntimeError{Location: ins.location, Prim: ins.prim, Err: err}
+		}
+	}
+	i.end(idx, *s)
+	return nil
+}
+
+func (i *Interpreter) step(ins *instruction, s *stack) error {
+	switch ins.prim {
+	case "DROP", "DUP", "SWAP", "DIG", "DUG", "PUSH", "UNIT", "LAMBDA", "CAST", "RENAME":
+		return i.stackOp(ins, s)
+	case "DIP", "IF", "IF_NONE", "IF_LEFT", "IF_CONS", "LOOP", "LOOP_LEFT", "EXEC", "APPLY", "FAILWITH":
+		return i.controlOp(ins, s)
+	case "PAIR", "CAR", "CDR", "SOME", "NONE", "LEFT", "RIGHT", "NIL", "CONS":
+		return dataOp(ins, s)
+	case "EMPTY_SET", "EMPTY_MAP", "EMPTY_BIG_MAP", "SIZE", "MEM", "GET", "UPDATE", "ITER", "MAP":
+		return i.collectionOp(ins, s)
+	case "CONCAT", "SLICE", "PACK", "UNPACK", "BLAKE2B", "SHA256", "SHA512":
+		return bytesOp(ins, s)
+	case "ADD", "SUB", "MUL", "EDIV", "ABS", "ISNAT", "INT", "NEG", "LSL", "LSR", "OR", "AND", "XOR", "NOT":
+		return arithmeticOp(ins, s)
+	case "COMPARE", "EQ", "NEQ", "LT", "GT", "LE", "GE":
+		return compareOp(ins, s)
+	case "SELF", "CONTRACT", "IMPLICIT_ACCOUNT", "ADDRESS", "TRANSFER_TOKENS", "SET_DELEGATE", "CREATE_CONTRACT",
+		"NOW", "AMOUNT", "BALANCE", "SOURCE", "SENDER", "CHAIN_ID", "CHECK_SIGNATURE", "HASH_KEY":
+		return i.domainOp(ins, s)
+	default:
+		return fmt.Errorf("Unsupported instruction: %s", ins.prim)
+	}
+}
+
+func (i *Interpreter) stackOp(ins *instruction, s *stack) error {
+	switch ins.prim {
+	case "DROP":
+		n, err := ins.intArg(1)
+		if err != nil {
+			return err
+		}
+		_, err = s.popN(n)
+		return err
+	case "DUP":
+		v, err := s.peek()
+		if err != nil {
+			return err
+		}
+		s.push(v)
+	case "SWAP":
+		values, err := s.popN(2)
+		if err != nil {
+			return err
+		}
+		s.push(values[0], values[1])
+	case "DIG":
+		n, err := ins.intArg(0)
+		if err != nil {
+			return err
+		}
+		values, err := s.popN(n + 1)
+		if err != nil {
+			return err
+		}
+		for idx := n - 1; idx >= 0; idx-- {
+			s.push(values[idx])
+		}
+		s.push(values[n])
+	case "DUG":
+		n, err := ins.intArg(0)
+		if err != nil {
+			return err
+		}
+		values, err := s.popN(n + 1)
+		if err != nil {
+			return err
+		}
+		s.push(values[0])
+		for idx := n; idx > 0; idx-- {
+			s.push(values[idx])
+		}
+	case "PUSH":
+		if ins.value != nil {
+			s.push(ins.value)
+			return nil
+		}
+		if len(ins.raw) != 2 {
+			return fmt.Errorf("Invalid arguments count: %d", len(ins.raw))
+		}
+		t, err := NewType(ins.raw[0])
+		if err != nil {
+			return err
+		}
+		v, err := ParseValue(t, ins.raw[1])
+		if err != nil {
+			return err
+		}
+		s.push(v)
+	case "UNIT":
+		s.push(&Value{Type: newType(consts.UNIT)})
+	case "LAMBDA":
+		if len(ins.raw) != 2 || len(ins.args) != 1 {
+			return fmt.Errorf("Invalid arguments count")
+		}
+		param, err := NewType(ins.raw[0])
+		if err != nil {
+			return err
+		}
+		ret, err := NewType(ins.raw[1])
+		if err != nil {
+			return err
+		}
+		s.push(&Value{
+			Type: newType(consts.LAMBDA, param, ret),
+			Code: ins.args[0].node,
+			body: ins.args[0],
+		})
+	case "CAST", "RENAME":
+		_, err := s.peek()
+		return err
+	}
+	return nil
+}
+
+func (i *Interpreter) controlOp(ins *instruction, s *stack) error {
+	if ins.prim == "EXEC" || ins.prim == "APPLY" {
+		return i.lambdaOp(ins, s)
+	}
+
+	if ins.prim == "DIP" {
+		n, err := ins.intArg(1)
+		if err != nil {
+			return err
+		}
+		protected, err := s.popN(n)
+		if err != nil {
+			return err
+		}
+		if err := i.exec(ins.args[0], s); err != nil {
+			return err
+		}
+		for idx := len(protected) - 1; idx >= 0; idx-- {
+			s.push(protected[idx])
+		}
+		return nil
+	}
+
+	top, err := s.pop()
+	if err != nil {
+		return err
+	}
+	switch ins.prim {
+	case "FAILWITH":
+		with, err := top.Micheline(false)
+		if err != nil {
+			return err
+		}
+		return &ScriptRejectedError{Location: ins.location, With: with}
+	case "IF":
+		if err := expectType(top, consts.BOOL); err != nil {
+			return err
+		}
+		if top.Bool {
+			return i.exec(ins.args[0], s)
+		}
+		return i.exec(ins.args[1], s)
+	case "IF_NONE":
+		if err := expectType(top, consts.OPTION); err != nil {
+			return err
+		}
+		if !top.IsSome() {
+			return i.exec(ins.args[0], s)
+		}
+		s.push(top.Args[0])
+		return i.exec(ins.args[1], s)
+	case "IF_LEFT":
+		if err := expectType(top, consts.OR); err != nil {
+			return err
+		}
+		s.push(top.Args[0])
+		if top.Bool {
+			return i.exec(ins.args[0], s)
+		}
+		return i.exec(ins.args[1], s)
+	case "IF_CONS":
+		if err := expectType(top, consts.LIST); err != nil {
+			return err
+		}
+		if len(top.Args) == 0 {
+			return i.exec(ins.args[1], s)
+		}
+		s.push(&Value{Type: top.Type, Args: top.Args[1:]}, top.Args[0])
+		return i.exec(ins.args[0], s)
+	case "LOOP":
+		for {
+			if err := expectType(top, consts.BOOL); err != nil {
+				return err
+			}
+			if !top.Bool {
+				return nil
+			}
+			if err := i.exec(ins.args[0], s); err != nil {
+				return err
+			}
+			if top, err = s.pop(); err != nil {
+				return err
+			}
+		}
+	case "LOOP_LEFT":
+		for {
+			if err := expectType(top, consts.OR); err != nil {
+				return err
+			}
+			s.push(top.Args[0])
+			if !top.Bool {
+				return nil
+			}
+			if err := i.exec(ins.args[0], s); err != nil {
+				return err
+			}
+			if top, err = s.pop(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (i *Interpreter) lambdaOp(ins *instruction, s *stack) error {
+	values, err := s.popN(2)
+	if err != nil {
+		return err
+	}
+	arg, lambda := values[0], values[1]
+	if err := expectType(lambda, consts.LAMBDA); err != nil {
+		return err
+	}
+	body, err := compileLambda(lambda)
+	if err != nil {
+		return err
+	}
+
+	if ins.prim == "APPLY" {
+		paramType := lambda.Type.Args[0]
+		if paramType.Prim != consts.PAIR || !paramType.Args[0].Equal(arg.Type) {
+			return fmt.Errorf("Can not apply %s to lambda with parameter %s", arg.Type.String(), paramType.String())
+		}
+		optimized, err := arg.Micheline(true)
+		if err != nil {
+			return err
+		}
+		code := []interface{}{
+			map[string]interface{}{
+				consts.KeyPrim: "PUSH",
+				consts.KeyArgs: []interface{}{paramType.Args[0].Micheline(), optimized},
+			},
+			prim("PAIR"),
+		}
+		for _, item := range lambda.Code.Array() {
+			code = append(code, item.Value())
+		}
+		applied := &instruction{
+			location: -1,
+			args: []*instruction{
+				{location: -1, prim: "PUSH", value: arg},
+				{location: -1, prim: "PAIR"},
+				body,
+			},
+		}
+		raw, err := json.Marshal(code)
+		if err != nil {
+			return err
+		}
+		s.push(&Value{
+			Type: newType(consts.LAMBDA, paramType.Args[1], lambda.Type.Args[1]),
+			Code: gjson.ParseBytes(raw),
+			body: applied,
+		})
+		return nil
+	}
+
+	inner := &stack{arg}
+	if err := i.exec(body, inner); err != nil {
+		return err
+	}
+	if len(*inner) != 1 {
+		return fmt.Errorf("Lambda returned %d values", len(*inner))
+	}
+	s.push((*inner)[0])
+	return nil
+}
+
+func dataOp(ins *instruction, s *stack) error {
+	switch ins.prim {
+	case "NONE", "NIL":
+		t, err := typeArg(ins)
+		if err != nil {
+			return err
+		}
+		if ins.prim == "NONE" {
+			s.push(newNone(t))
+		} else {
+			s.push(&Value{Type: newType(consts.LIST, t), Args: make([]*Value, 0)})
+		}
+		return nil
+	case "PAIR", "CONS":
+		values, err := s.popN(2)
+		if err != nil {
+			return err
+		}
+		if ins.prim == "PAIR" {
+			s.push(newPair(values[0], values[1]))
+			return nil
+		}
+		if err := expectType(values[1], consts.LIST); err != nil {
+			return err
+		}
+		list := &Value{Type: values[1].Type, Args: []*Value{values[0]}}
+		list.Args = append(list.Args, values[1].Args...)
+		s.push(list)
+		return nil
+	}
+
+	top, err := s.pop()
+	if err != nil {
+		return err
+	}
+	switch ins.prim {
+	case "CAR", "CDR":
+		if err := expectType(top, consts.PAIR); err != nil {
+			return err
+		}
+		if ins.prim == "CAR" {
+			s.push(top.Args[0])
+		} else {
+			s.push(top.Args[1])
+		}
+	case "SOME":
+		s.push(newSome(top))
+	case "LEFT", "RIGHT":
+		t, err := typeArg(ins)
+		if err != nil {
+			return err
+		}
+		v := &Value{Args: []*Value{top}, Bool: ins.prim == "LEFT"}
+		if v.Bool {
+			v.Type = newType(consts.OR, top.Type, t)
+		} else {
+			v.Type = newType(consts.OR, t, top.Type)
+		}
+		s.push(v)
+	}
+	return nil
+}
+
+func (i *Interpreter) collectionOp(ins *instruction, s *stack) error {
+	switch ins.prim {
+	case "EMPTY_SET":
+		t, err := typeArg(ins)
+		if err != nil {
+			return err
+		}
+		s.push(&Value{Type: newType(consts.SET, t), Args: make([]*Value, 0)})
+		return nil
+	case "EMPTY_MAP", "EMPTY_BIG_MAP":
+		if len(ins.raw) != 2 {
+			return fmt.Errorf("Invalid arguments count: %d", len(ins.raw))
+		}
+		key, err := NewType(ins.raw[0])
+		if err != nil {
+			return err
+		}
+		value, err := NewType(ins.raw[1])
+		if err != nil {
+			return err
+		}
+		prim := consts.MAP
+		if ins.prim == "EMPTY_BIG_MAP" {
+			prim = consts.BIGMAP
+		}
+		s.push(&Value{Type: newType(prim, key, value), Elts: make([]*Elt, 0)})
+		return nil
+	case "SIZE":
+		top, err := s.pop()
+		if err != nil {
+			return err
+		}
+		var size int
+		switch top.Type.Prim {
+		case consts.STRING:
+			size = len(top.Str)
+		case consts.BYTES:
+			size = len(top.Bytes)
+		case consts.LIST, consts.SET:
+			size = len(top.Args)
+		case consts.MAP:
+			size = len(top.Elts)
+		default:
+			return fmt.Errorf("SIZE is not defined for %s", top.Type.Prim)
+		}
+		s.push(newInt(newType(consts.NAT), big.NewInt(int64(size))))
+		return nil
+	case "MEM", "GET":
+		values, err := s.popN(2)
+		if err != nil {
+			return err
+		}
+		key, collection := values[0], values[1]
+		if collection.Type.Prim == consts.SET {
+			_, ok := collection.findItem(key)
+			s.push(newBool(ok))
+			return nil
+		}
+		if collection.Type.Prim != consts.MAP && collection.Type.Prim != consts.BIGMAP {
+			return fmt.Errorf("%s is not defined for %s", ins.prim, collection.Type.Prim)
+		}
+		value, err := i.getValue(collection, key)
+		if err != nil {
+			return err
+		}
+		switch {
+		case ins.prim == "MEM":
+			s.push(newBool(value != nil))
+		case value == nil:
+			s.push(newNone(collection.Type.Args[1]))
+		default:
+			s.push(newSome(value))
+		}
+		return nil
+	case "UPDATE":
+		values, err := s.popN(3)
+		if err != nil {
+			return err
+		}
+		updated, err := update(values[2], values[0], values[1])
+		if err != nil {
+			return err
+		}
+		s.push(updated)
+		return nil
+	default:
+		return i.iterate(ins, s)
+	}
+}
+
+func (i *Interpreter) getValue(collection, key *Value) (*Value, error) {
+	idx, ok := collection.findElt(key)
+	if ok {
+		return collection.Elts[idx].Value, nil
+	}
+	if collection.Ptr == nil {
+		return nil, nil
+	}
+	if i.getBigMap == nil {
+		return nil, fmt.Errorf("Big map %d is not available offline", *collection.Ptr)
+	}
+	data, err := i.getBigMap(*collection.Ptr, key)
+	if err != nil || !data.Exists() {
+		return nil, err
+	}
+	return ParseValue(collection.Type.Args[1], data)
+}
+
+func update(collection, key, value *Value) (*Value, error) {
+	result := &Value{Type: collection.Type, Ptr: collection.Ptr}
+	switch collection.Type.Prim {
+	case consts.SET:
+		if err := expectType(value, consts.BOOL); err != nil {
+			return nil, err
+		}
+		idx, ok := collection.findItem(key)
+		result.Args = append(result.Args, collection.Args[:idx]...)
+		if value.Bool {
+			result.Args = append(result.Args, key)
+		}
+		if ok {
+			idx++
+		}
+		result.Args = append(result.Args, collection.Args[idx:]...)
+	case consts.MAP, consts.BIGMAP:
+		if err := expectType(value, consts.OPTION); err != nil {
+			return nil, err
+		}
+		idx, ok := collection.findElt(key)
+		result.Elts = append(make([]*Elt, 0), collection.Elts[:idx]...)
+		switch {
+		case value.IsSome():
+			result.Elts = append(result.Elts, &Elt{Key: key, Value: value.Args[0]})
+		case collection.Ptr != nil:
+			result.Elts = append(result.Elts, &Elt{Key: key})
+		}
+		if ok {
+			idx++
+		}
+		result.Elts = append(result.Elts, collection.Elts[idx:]...)
+	default:
+		return nil, fmt.Errorf("UPDATE is not defined for %s", collection.Type.Prim)
+	}
+	return result, nil
+}
+
+func (i *Interpreter) iterate(ins *instruction, s *stack) error {
+	collection, err := s.pop()
+	if err != nil {
+		return err
+	}
+
+	var items []*Value
+	switch collection.Type.Prim {
+	case consts.LIST, consts.SET:
+		if ins.prim == "MAP" && collection.Type.Prim == consts.SET {
+			return fmt.Errorf("MAP is not defined for set")
+		}
+		items = collection.Args
+	case consts.MAP:
+		items = make([]*Value, len(collection.Elts))
+		for idx, elt := range collection.Elts {
+			items[idx] = newPair(elt.Key, elt.Value)
+		}
+	default:
+		return fmt.Errorf("%s is not defined for %s", ins.prim, collection.Type.Prim)
+	}
+
+	results := make([]*Value, 0, len(items))
+	for _, item := range items {
+		s.push(item)
+		if err := i.exec(ins.args[0], s); err != nil {
+			return err
+		}
+		if ins.prim == "MAP" {
+			value, err := s.pop()
+			if err != nil {
+				return err
+			}
+			results = append(results, value)
+		}
+	}
+	if ins.prim == "ITER" {
+		return nil
+	}
+
+	if collection.Type.Prim == consts.LIST {
+		itemType := collection.Type.Args[0]
+		if len(results) > 0 {
+			itemType = results[0].Type
+		}
+		s.push(&Value{Type: newType(consts.LIST, itemType), Args: results})
+		return nil
+	}
+	valueType := collection.Type.Args[1]
+	if len(results) > 0 {
+		valueType = results[0].Type
+	}
+	mapped := &Value{Type: newType(consts.MAP, collection.Type.Args[0], valueType), Elts: make([]*Elt, len(results))}
+	for idx := range results {
+		mapped.Elts[idx] = &Elt{Key: collection.Elts[idx].Key, Value: results[idx]}
+	}
+	s.push(mapped)
+	return nil
+}
+
+func bytesOp(ins *instruction, s *stack) error {
+	switch ins.prim {
+	case "CONCAT":
+		return concat(s)
+	case "SLICE":
+		values, err := s.popN(3)
+		if err != nil {
+			return err
+		}
+		offset, length, value := values[0], values[1], values[2]
+		if err := expectType(offset, consts.NAT); err != nil {
+			return err
+		}
+		if err := expectType(length, consts.NAT); err != nil {
+			return err
+		}
+		var size int
+		switch value.Type.Prim {
+		case consts.STRING:
+			size = len(value.Str)
+		case consts.BYTES:
+			size = len(value.Bytes)
+		default:
+			return fmt.Errorf("SLICE is not defined for %s", value.Type.Prim)
+		}
+		end := new(big.Int).Add(offset.Int, length.Int)
+		if end.Cmp(big.NewInt(int64(size))) > 0 {
+			s.push(newNone(value.Type))
+			return nil
+		}
+		from, to := int(offset.Int.Int64()), int(end.Int64())
+		if value.Type.Prim == consts.STRING {
+			s.push(newSome(&Value{Type: value.Type, Str: value.Str[from:to]}))
+		} else {
+			s.push(newSome(&Value{Type: value.Type, Bytes: value.Bytes[from:to]}))
+		}
+		return nil
+	}
+
+	top, err := s.pop()
+	if err != nil {
+		return err
+	}
+	switch ins.prim {
+	case "PACK":
+		packed, err := packValue(top)
+		if err != nil {
+			return err
+		}
+		s.push(&Value{Type: newType(consts.BYTES), Bytes: packed})
+	case "UNPACK":
+		t, err := typeArg(ins)
+		if err != nil {
+			return err
+		}
+		if err := expectType(top, consts.BYTES); err != nil {
+			return err
+		}
+		s.push(unpackValue(t, top.Bytes))
+	default:
+		if err := expectType(top, consts.BYTES); err != nil {
+			return err
+		}
+		var digest []byte
+		switch ins.prim {
+		case "BLAKE2B":
+			hash := blake2b.Sum256(top.Bytes)
+			digest = hash[:]
+		case "SHA256":
+			hash := sha256.Sum256(top.Bytes)
+			digest = hash[:]
+		case "SHA512":
+			hash := sha512.Sum512(top.Bytes)
+			digest = hash[:]
+		}
+		s.push(&Value{Type: newType(consts.BYTES), Bytes: digest})
+	}
+	return nil
+}
+
+func concat(s *stack) error {
+	top, err := s.pop()
+	if err != nil {
+		return err
+	}
+	var items []*Value
+	var t *Type
+	if top.Type.Prim == consts.LIST {
+		items = top.Args
+		t = top.Type.Args[0]
+	} else {
+		second, err := s.pop()
+		if err != nil {
+			return err
+		}
+		items = []*Value{top, second}
+		t = top.Type
+	}
+	result := &Value{Type: t}
+	for _, item := range items {
+		switch {
+		case item.Type.Prim != t.Prim:
+			return fmt.Errorf("CONCAT of %s and %s", t.Prim, item.Type.Prim)
+		case t.Prim == consts.STRING:
+			result.Str += item.Str
+		case t.Prim == consts.BYTES:
+			result.Bytes = append(result.Bytes, item.Bytes...)
+		default:
+			return fmt.Errorf("CONCAT is not defined for %s", t.Prim)
+		}
+	}
+	if t.Prim == consts.BYTES && result.Bytes == nil {
+		result.Bytes = make([]byte, 0)
+	}
+	s.push(result)
+	return nil
+}
+
+func packValue(v *Value) ([]byte, error) {
+	micheline, err := v.Micheline(true)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(micheline)
+	if err != nil {
+		return nil, err
+	}
+	forged, err := pack.Forge(gjson.ParseBytes(raw))
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{0x05}, forged...), nil
+}
+
+// unpackValue - returns `None` if bytes are not packed data of the type
+func unpackValue(t *Type, data []byte) *Value {
+	if len(data) < 2 || data[0] != 0x05 {
+		return newNone(t)
+	}
+	micheline, err := rawbytes.ToMicheline(hex.EncodeToString(data[1:]))
+	if err != nil {
+		return newNone(t)
+	}
+	v, err := ParseValue(t, gjson.Parse(micheline))
+	if err != nil {
+		return newNone(t)
+	}
+	return newSome(v)
+}
+
+func arithmeticOp(ins *instruction, s *stack) error {
+	switch ins.prim {
+	case "ABS", "ISNAT", "INT", "NEG", "NOT":
+		top, err := s.pop()
+		if err != nil {
+			return err
+		}
+		result, err := unaryOp(ins.prim, top)
+		if err != nil {
+			return err
+		}
+		s.push(result)
+		return nil
+	}
+
+	values, err := s.popN(2)
+	if err != nil {
+		return err
+	}
+	result, err := binaryOp(ins.prim, values[0], values[1])
+	if err != nil {
+		return err
+	}
+	s.push(result)
+	return nil
+}
+
+func unaryOp(op string, v *Value) (*Value, error) {
+	switch op {
+	case "ABS":
+		if err := expectType(v, consts.INT); err != nil {
+			return nil, err
+		}
+		return newInt(newType(consts.NAT), new(big.Int).Abs(v.Int)), nil
+	case "ISNAT":
+		if err := expectType(v, consts.INT); err != nil {
+			return nil, err
+		}
+		if v.Int.Sign() < 0 {
+			return newNone(newType(consts.NAT)), nil
+		}
+		return newSome(newInt(newType(consts.NAT), v.Int)), nil
+	case "INT":
+		if err := expectType(v, consts.NAT); err != nil {
+			return nil, err
+		}
+		return newInt(newType(consts.INT), v.Int), nil
+	case "NEG":
+		if v.Type.Prim != consts.INT && v.Type.Prim != consts.NAT {
+			return nil, fmt.Errorf("NEG is not defined for %s", v.Type.Prim)
+		}
+		return newInt(newType(consts.INT), new(big.Int).Neg(v.Int)), nil
+	default:
+		switch v.Type.Prim {
+		case consts.BOOL:
+			return newBool(!v.Bool), nil
+		case consts.INT, consts.NAT:
+			return newInt(newType(consts.INT), new(big.Int).Not(v.Int)), nil
+		default:
+			return nil, fmt.Errorf("NOT is not defined for %s", v.Type.Prim)
+		}
+	}
+}
+
+func binaryOp(op string, a, b *Value) (*Value, error) {
+	signature := a.Type.Prim + " " + b.Type.Prim
+	undefined := fmt.Errorf("%s is not defined for %s", op, signature)
+	nat, integer, mutez, timestamp := newType(consts.NAT), newType(consts.INT), newType(consts.MUTEZ), newType(consts.TIMESTAMP)
+
+	switch op {
+	case "ADD", "SUB", "MUL":
+		var t *Type
+		switch signature {
+		case "nat nat":
+			t = nat
+			if op == "SUB" {
+				t = integer
+			}
+		case "int int", "int nat", "nat int":
+			t = integer
+		case "timestamp int", "int timestamp":
+			if op == "MUL" || (op == "SUB" && a.Type.Prim == consts.INT) {
+				return nil, undefined
+			}
+			t = timestamp
+		case "timestamp timestamp":
+			if op != "SUB" {
+				return nil, undefined
+			}
+			t = integer
+		case "mutez mutez":
+			if op == "MUL" {
+				return nil, undefined
+			}
+			t = mutez
+		case "mutez nat", "nat mutez":
+			if op != "MUL" {
+				return nil, undefined
+			}
+			t = mutez
+		default:
+			return nil, undefined
+		}
+		result := new(big.Int)
+		switch op {
+		case "ADD":
+			result.Add(a.Int, b.Int)
+		case "SUB":
+			result.Sub(a.Int, b.Int)
+		default:
+			result.Mul(a.Int, b.Int)
+		}
+		if t.Prim == consts.MUTEZ && (result.Sign() < 0 || result.Cmp(maxMutez) > 0) {
+			return nil, fmt.Errorf("Mutez overflow: %s", result.String())
+		}
+		return newInt(t, result), nil
+	case "EDIV":
+		var quotient, remainder *Type
+		switch signature {
+		case "nat nat":
+			quotient, remainder = nat, nat
+		case "int int", "int nat", "nat int":
+			quotient, remainder = integer, nat
+		case "mutez nat":
+			quotient, remainder = mutez, mutez
+		case "mutez mutez":
+			quotient, remainder = nat, mutez
+		default:
+			return nil, undefined
+		}
+		resultType := newType(consts.PAIR, quotient, remainder)
+		if b.Int.Sign() == 0 {
+			return newNone(resultType), nil
+		}
+		q, r := new(big.Int), new(big.Int)
+		q.DivMod(a.Int, b.Int, r)
+		return newSome(newPair(newInt(quotient, q), newInt(remainder, r))), nil
+	case "LSL", "LSR":
+		if signature != "nat nat" {
+			return nil, undefined
+		}
+		if b.Int.Cmp(big.NewInt(maxShift)) > 0 {
+			return nil, fmt.Errorf("Shift overflow: %s", b.Int.String())
+		}
+		if op == "LSL" {
+			return newInt(nat, new(big.Int).Lsh(a.Int, uint(b.Int.Uint64()))), nil
+		}
+		return newInt(nat, new(big.Int).Rsh(a.Int, uint(b.Int.Uint64()))), nil
+	default:
+		switch signature {
+		case "bool bool":
+			switch op {
+			case "OR":
+				return newBool(a.Bool || b.Bool), nil
+			case "AND":
+				return newBool(a.Bool && b.Bool), nil
+			default:
+				return newBool(a.Bool != b.Bool), nil
+			}
+		case "nat nat":
+			switch op {
+			case "OR":
+				return newInt(nat, new(big.Int).Or(a.Int, b.Int)), nil
+			case "AND":
+				return newInt(nat, new(big.Int).And(a.Int, b.Int)), nil
+			default:
+				return newInt(nat, new(big.Int).Xor(a.Int, b.Int)), nil
+			}
+		default:
+			return nil, undefined
+		}
+	}
+}
+
+func compareOp(ins *instruction, s *stack) error {
+	if ins.prim == "COMPARE" {
+		values, err := s.popN(2)
+		if err != nil {
+			return err
+		}
+		if !isComparable(values[0].Type) || !values[0].Type.Equal(values[1].Type) {
+			return fmt.Errorf("COMPARE is not defined for %s and %s", values[0].Type.String(), values[1].Type.String())
+		}
+		s.push(newInt(newType(consts.INT), big.NewInt(int64(Compare(values[0], values[1])))))
+		return nil
+	}
+
+	top, err := s.pop()
+	if err != nil {
+		return err
+	}
+	if err := expectType(top, consts.INT); err != nil {
+		return err
+	}
+	sign := top.Int.Sign()
+	var result bool
+	switch ins.prim {
+	case "EQ":
+		result = sign == 0
+	case "NEQ":
+		result = sign != 0
+	case "LT":
+		result = sign < 0
+	case "GT":
+		result = sign > 0
+	case "LE":
+		result = sign <= 0
+	case "GE":
+		result = sign >= 0
+	}
+	s.push(newBool(result))
+	return nil
+}
+
+func expectType(v *Value, prim string) error {
+	if v.Type.Prim != prim {
+		return fmt.Errorf("Expected %s, got %s", prim, v.Type.Prim)
+	}
+	return nil
+}
+
+func typeArg(ins *instruction) (*Type, error) {
+	if len(ins.raw) != 1 {
+		return nil, fmt.Errorf("Invalid arguments count: %d", len(ins.raw))
+	}
+	return NewType(ins.raw[0])
+}
@@ -0,0 +1,601 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	formattererror "github.com/baking-bad/bcdhub/internal/contractparser/formatter_error"
+	"github.com/baking-bad/bcdhub/internal/contractparser/macros"
+	"github.com/tidwall/gjson"
+)
+
+// Defaults of the execution context. Self, source and sender are the dummy contract used by `run_code` of the node.
+const (
+	DefaultGasLimit = 1040000
+	DefaultChainID  = "NetXdQprcVkpaWU"
+	DummyContract   = "KT1BEqzn5Wx8uJrZNvuS9DVHmLvG9td3fDLi"
+)
+
+// BigMapGetter - returns value of the key from the big map stored on chain. Not existing result means the key is absent.
+type BigMapGetter func(ptr int64, key *Value) (gjson.Result, error)
+
+// ContractGetter - returns parameter type of the originated contract. Not existing result means the contract is unknown.
+type ContractGetter func(address string) (gjson.Result, error)
+
+// Interpreter - executes Michelson code of the Carthage protocol without a node. Macros are expanded before execution.
+type Interpreter struct {
+	script        gjson.Result
+	parameterType *Type
+	storageType   *Type
+	code          *instruction
+
+	gasLimit  int64
+	gas       int64
+	positions map[int]position
+	nonce     int
+
+	traceLimit     int
+	trace          []Step
+	traceTruncated bool
+
+	self    string
+	source  string
+	sender  string
+	amount  int64
+	balance int64
+	now     int64
+	chainID string
+
+	getBigMap   BigMapGetter
+	getContract ContractGetter
+}
+
+// Option -
+type Option func(*Interpreter)
+
+// WithGasLimit - execution stops when gas is exhausted
+func WithGasLimit(limit int64) Option {
+	return func(i *Interpreter) {
+		if limit > 0 {
+			i.gasLimit = limit
+		}
+	}
+}
+
+// WithTrace - collects stacks of the first `limit` executed instructions. Execution continues after the limit without tracing.
+func WithTrace(limit int) Option {
+	return func(i *Interpreter) {
+		if limit > 0 {
+			i.traceLimit = limit
+		}
+	}
+}
+
+// WithContract - address of the executed contract and its balance
+func WithContract(address string, balance int64) Option {
+	return func(i *Interpreter) {
+		i.self = address
+		i.balance = balance
+	}
+}
+
+// WithTransaction - source, sender and amount of the call. Empty addresses keep defaults.
+func WithTransaction(source, sender string, amount int64) Option {
+	return func(i *Interpreter) {
+		if source != "" {
+			i.source = source
+		}
+		if sender != "" {
+			i.sender = sender
+		}
+		i.amount = amount
+	}
+}
+
+// WithChain - chain id and timestamp returned by CHAIN_ID and NOW
+func WithChain(chainID string, now time.Time) Option {
+	return func(i *Interpreter) {
+		if chainID != "" {
+			i.chainID = chainID
+		}
+		i.now = now.UTC().Unix()
+	}
+}
+
+// WithBigMapGetter - big maps passed by pointer are read by the getter
+func WithBigMapGetter(getter BigMapGetter) Option {
+	return func(i *Interpreter) {
+		i.getBigMap = getter
+	}
+}
+
+// WithContractGetter - parameter types of originated contracts for CONTRACT instruction
+func WithContractGetter(getter ContractGetter) Option {
+	return func(i *Interpreter) {
+		i.getContract = getter
+	}
+}
+
+// New - compiles the script. `script` is the array of `parameter`, `storage` and `code` sections.
+func New(script gjson.Result, opts ...Option) (*Interpreter, error) {
+	i := &Interpreter{
+		script:    script,
+		gasLimit:  DefaultGasLimit,
+		positions: make(map[int]position),
+		self:      DummyContract,
+		source:    DummyContract,
+		sender:    DummyContract,
+		chainID:   DefaultChainID,
+		now:       time.Now().UTC().Unix(),
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	expanded, err := macros.Expand(script)
+	if err != nil {
+		return nil, err
+	}
+	i.script = expanded
+	if err := i.compile(); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func (i *Interpreter) compile() error {
+	if !i.script.IsArray() {
+		return fmt.Errorf("Invalid script: array of sections expected")
+	}
+	c := &compiler{inScript: true}
+	c.next() // root sequence
+	for _, section := range i.script.Array() {
+		c.next()
+		args := section.Get(consts.KeyArgs).Array()
+		if len(args) != 1 {
+			return fmt.Errorf("Invalid section: %s", section.Raw)
+		}
+		var err error
+		switch section.Get(consts.KeyPrim).String() {
+		case consts.PARAMETER:
+			i.parameterType, err = NewType(args[0])
+			c.skip(args[0])
+		case consts.STORAGE:
+			i.storageType, err = NewType(args[0])
+			c.skip(args[0])
+		case consts.CODE:
+			i.code, err = c.compileSeq(args[0])
+		default:
+			err = fmt.Errorf("Unknown section: %s", section.Get(consts.KeyPrim).String())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if i.parameterType == nil || i.storageType == nil || i.code == nil {
+		return fmt.Errorf("Invalid script: parameter, storage and code sections are required")
+	}
+	return nil
+}
+
+// Result - result of the execution. Micheline of storage and operations is in readable form.
+// Big maps received by pointer stay pointers in storage and their changes are returned as `BigMapDiff`.
+type Result struct {
+	Storage     interface{}   `json:"storage,omitempty"`
+	Operations  []interface{} `json:"operations"`
+	BigMapDiff  []BigMapDiff  `json:"big_map_diff,omitempty"`
+	ConsumedGas int64         `json:"consumed_gas"`
+	Trace       []Step        `json:"trace,omitempty"`
+
+	TraceTruncated bool `json:"trace_truncated,omitempty"`
+}
+
+// BigMapDiff - change of the big map received by pointer. Nil value means the key is removed.
+type BigMapDiff struct {
+	Ptr   int64       `json:"big_map"`
+	Key   interface{} `json:"key"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Step - executed instruction. Steps are ordered by the start of execution, so blocks of IF, DIP, LOOP, etc.
+// precede their bodies. Stacks are top first, `Gas` is the remaining gas after the instruction. If the instruction failed,
+// `After` is empty and `Gas` is the remaining gas before it. Position in the formatted script is known for instructions of the script and is not known
+// for lambdas passed as data.
+type Step struct {
+	Location    int      `json:"location"`
+	Prim        string   `json:"prim"`
+	Gas         int64    `json:"gas"`
+	Before      []*Value `json:"-"`
+	After       []*Value `json:"-"`
+	Row         int      `json:"row,omitempty"`
+	StartColumn int      `json:"start_col,omitempty"`
+	EndColumn   int      `json:"end_col,omitempty"`
+}
+
+type position struct {
+	row, start, end int
+}
+
+// Run - executes the script with the parameter of the entrypoint and the storage. Result is returned on error too:
+// it contains the trace and gas consumed before the failure.
+func (i *Interpreter) Run(entrypoint string, parameter, storage gjson.Result) (*Result, error) {
+	i.gas = i.gasLimit
+	i.trace = make([]Step, 0)
+	i.traceTruncated = false
+	i.nonce = 0
+	result := &Result{
+		Operations: make([]interface{}, 0),
+	}
+
+	output, err := i.run(entrypoint, parameter, storage)
+	result.ConsumedGas = i.gasLimit - i.gas
+	result.Trace = i.trace
+	result.TraceTruncated = i.traceTruncated
+	if err != nil {
+		return result, err
+	}
+
+	if output.Type.Prim != consts.PAIR || output.Args[0].Type.Prim != consts.LIST {
+		return result, fmt.Errorf("Invalid result of the code: %s", output.Type.String())
+	}
+	for _, operation := range output.Args[0].Args {
+		result.Operations = append(result.Operations, operation.Operation)
+	}
+	if result.Storage, err = output.Args[1].Micheline(false); err != nil {
+		return result, err
+	}
+	result.BigMapDiff, err = collectBigMapDiff(output.Args[1])
+	return result, err
+}
+
+func (i *Interpreter) run(entrypoint string, parameter, storage gjson.Result) (*Value, error) {
+	if entrypoint == "" {
+		entrypoint = defaultEntrypoint
+	}
+	entrypointType, ok := i.parameterType.Entrypoint(entrypoint)
+	if !ok {
+		return nil, fmt.Errorf("Unknown entrypoint: %s", entrypoint)
+	}
+	param, err := ParseValue(entrypointType, parameter)
+	if err != nil {
+		return nil, err
+	}
+	if param, err = wrapEntrypoint(i.parameterType, entrypointType, param); err != nil {
+		return nil, err
+	}
+	store, err := ParseValue(i.storageType, storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := i.consume(deserializationCost(i.script) + deserializationCost(parameter) + deserializationCost(storage)); err != nil {
+		return nil, err
+	}
+
+	s := &stack{newPair(param, store)}
+	if err := i.exec(i.code, s); err != nil {
+		return nil, err
+	}
+	if len(*s) != 1 {
+		return nil, fmt.Errorf("Invalid stack length after execution: %d", len(*s))
+	}
+	return s.pop()
+}
+
+// wrapEntrypoint - wraps the entrypoint value by `Left` and `Right` up to the root of parameter
+func wrapEntrypoint(root, entrypoint *Type, value *Value) (*Value, error) {
+	if root == entrypoint {
+		return value, nil
+	}
+	if root.Prim != consts.OR {
+		return nil, fmt.Errorf("Entrypoint is not found in the parameter")
+	}
+	for idx, arg := range root.Args {
+		wrapped, err := wrapEntrypoint(arg, entrypoint, value)
+		if err != nil {
+			continue
+		}
+		return &Value{
+			Type: root,
+			Bool: idx == 0,
+			Args: []*Value{wrapped},
+		}, nil
+	}
+	return nil, fmt.Errorf("Entrypoint is not found in the parameter")
+}
+
+func collectBigMapDiff(storage *Value) ([]BigMapDiff, error) {
+	diff := make([]BigMapDiff, 0)
+	var walk func(v *Value) error
+	walk = func(v *Value) error {
+		if v.Type.Prim == consts.BIGMAP && v.Ptr != nil {
+			for _, elt := range v.Elts {
+				key, err := elt.Key.Micheline(false)
+				if err != nil {
+					return err
+				}
+				item := BigMapDiff{Ptr: *v.Ptr, Key: key}
+				if elt.Value != nil {
+					if item.Value, err = elt.Value.Micheline(false); err != nil {
+						return err
+					}
+				}
+				diff = append(diff, item)
+			}
+			return nil
+		}
+		for _, arg := range v.Args {
+			if err := walk(arg); err != nil {
+				return err
+			}
+		}
+		for _, elt := range v.Elts {
+			if elt.Value != nil {
+				if err := walk(elt.Value); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	err := walk(storage)
+	return diff, err
+}
+
+// begin - appends the step with the stack before the instruction and returns its index
+func (i *Interpreter) begin(ins *instruction, s stack) int {
+	if i.traceLimit == 0 {
+		return -1
+	}
+	if len(i.trace) >= i.traceLimit {
+		i.traceTruncated = true
+		return -1
+	}
+	step := Step{
+		Location: ins.location,
+		Prim:     ins.prim,
+		Gas:      i.gas,
+		Before:   s.top(),
+	}
+	if ins.inScript {
+		pos, ok := i.positions[ins.location]
+		if !ok {
+			row, start, end, err := formattererror.LocateContractError(i.script, ins.location)
+			if err == nil {
+				pos = position{row + 1, start, end}
+			}
+			i.positions[ins.location] = pos
+		}
+		step.Row, step.StartColumn, step.EndColumn = pos.row, pos.start, pos.end
+	}
+	i.trace = append(i.trace, step)
+	return len(i.trace) - 1
+}
+
+// end - sets the stack after the instruction to the step
+func (i *Interpreter) end(idx int, s stack) {
+	if idx < 0 {
+		return
+	}
+	i.trace[idx].Gas = i.gas
+	i.trace[idx].After = s.top()
+}
+
+// ScriptRejectedError - FAILWITH was executed
+type ScriptRejectedError struct {
+	Location int
+	With     interface{}
+}
+
+// Error -
+func (e *ScriptRejectedError) Error() string {
+	return fmt.Sprintf("Script rejected at location %d with %v", e.Location, e.With)
+}
+
+// GasExhaustedError - gas limit is reached
+type GasExhaustedError struct {
+	Location int
+}
+
+// Error -
+func (e *GasExhaustedError) Error() string {
+	return fmt.Sprintf("Gas exhausted at location %d", e.Location)
+}
+
+// RuntimeError - instruction failed, e.g. because of overflow or ill-typed stack
+type RuntimeError struct {
+	Location int
+	Prim     string
+	Err      error
+}
+
+// Error -
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("%s at location %d: %v", e.Prim, e.Location, e.Err)
+}
+
+type instruction struct {
+	location int
+	prim     string
+	annots   []string
+	raw      []gjson.Result
+	args     []*instruction
+	node     gjson.Result
+	inScript bool
+
+	value *Value // constant pushed by the lambda built by APPLY
+}
+
+func (ins *instruction) isSeq() bool {
+	return ins.prim == ""
+}
+
+// fieldAnnot - returns entrypoint annotation of SELF and CONTRACT
+func (ins *instruction) fieldAnnot() string {
+	for _, annot := range ins.annots {
+		if strings.HasPrefix(annot, "%") {
+			return annot[1:]
+		}
+	}
+	return ""
+}
+
+func (ins *instruction) intArg(defaultValue int) (int, error) {
+	if len(ins.raw) == 0 {
+		return defaultValue, nil
+	}
+	if !ins.raw[0].Get(consts.KeyInt).Exists() {
+		return 0, fmt.Errorf("Invalid argument: %s", ins.raw[0].Raw)
+	}
+	return int(ins.raw[0].Get(consts.KeyInt).Int()), nil
+}
+
+// compiler - numbers nodes in prefix order as the node does, so locations of instructions match node locations
+type compiler struct {
+	counter  int
+	inScript bool
+}
+
+func (c *compiler) next() int {
+	location := c.counter
+	c.counter++
+	return location
+}
+
+func (c *compiler) skip(node gjson.Result) {
+	c.next()
+	switch {
+	case node.IsArray():
+		for _, item := range node.Array() {
+			c.skip(item)
+		}
+	case node.IsObject():
+		for _, arg := range node.Get(consts.KeyArgs).Array() {
+			c.skip(arg)
+		}
+	}
+}
+
+func (c *compiler) compileSeq(node gjson.Result) (*instruction, error) {
+	if !node.IsArray() {
+		return nil, fmt.Errorf("Sequence of instructions expected: %s", node.Raw)
+	}
+	seq := &instruction{
+		location: c.next(),
+		node:     node,
+		inScript: c.inScript,
+	}
+	for _, item := range node.Array() {
+		ins, err := c.compileInstruction(item)
+		if err != nil {
+			return nil, err
+		}
+		seq.args = append(seq.args, ins)
+	}
+	return seq, nil
+}
+
+func (c *compiler) compileInstruction(node gjson.Result) (*instruction, error) {
+	if node.IsArray() {
+		return c.compileSeq(node)
+	}
+	if !node.Get(consts.KeyPrim).Exists() {
+		return nil, fmt.Errorf("Instruction expected: %s", node.Raw)
+	}
+	ins := &instruction{
+		location: c.next(),
+		prim:     node.Get(consts.KeyPrim).String(),
+		node:     node,
+		inScript: c.inScript,
+	}
+	for _, annot := range node.Get(consts.KeyAnnots).Array() {
+		ins.annots = append(ins.annots, annot.String())
+	}
+	args := node.Get(consts.KeyArgs).Array()
+	for idx := range args {
+		if !isCodeArg(ins.prim, idx, len(args)) {
+			ins.raw = append(ins.raw, args[idx])
+			c.skip(args[idx])
+			continue
+		}
+		body, err := c.compileSeq(args[idx])
+		if err != nil {
+			return nil, err
+		}
+		ins.args = append(ins.args, body)
+	}
+	return ins, nil
+}
+
+func isCodeArg(prim string, idx, count int) bool {
+	switch prim {
+	case "IF", "IF_NONE", "IF_LEFT", "IF_CONS":
+		return true
+	case "LOOP", "LOOP_LEFT", "ITER", "MAP":
+		return idx == 0
+	case "DIP":
+		return idx == count-1
+	case "LAMBDA":
+		return idx == 2
+	default:
+		return false
+	}
+}
+
+// compileLambda - lambdas passed as data have own locations and are not linked to the script
+func compileLambda(v *Value) (*instruction, error) {
+	if v.body != nil {
+		return v.body, nil
+	}
+	body, err := (&compiler{}).compileSeq(v.Code)
+	if err != nil {
+		return nil, err
+	}
+	v.body = body
+	return body, nil
+}
+
+type stack []*Value
+
+func (s *stack) push(values ...*Value) {
+	*s = append(*s, values...)
+}
+
+func (s *stack) pop() (*Value, error) {
+	if len(*s) == 0 {
+		return nil, fmt.Errorf("Stack is empty")
+	}
+	v := (*s)[len(*s)-1]
+	*s = (*s)[:len(*s)-1]
+	return v, nil
+}
+
+// popN - returns values top first
+func (s *stack) popN(n int) ([]*Value, error) {
+	if len(*s) < n {
+		return nil, fmt.Errorf("Stack is too short: %d < %d", len(*s), n)
+	}
+	values := make([]*Value, n)
+	for idx := 0; idx < n; idx++ {
+		values[idx] = (*s)[len(*s)-1-idx]
+	}
+	*s = (*s)[:len(*s)-n]
+	return values, nil
+}
+
+// top - returns copy of the stack top first
+func (s stack) top() []*Value {
+	values := make([]*Value, len(s))
+	for idx := range s {
+		values[idx] = s[len(s)-1-idx]
+	}
+	return values
+}
+
+func (s *stack) peek() (*Value, error) {
+	if len(*s) == 0 {
+		return nil, fmt.Errorf("Stack is empty")
+	}
+	return (*s)[len(*s)-1], nil
+}
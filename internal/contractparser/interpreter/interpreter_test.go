@@ -0,0 +1,241 @@
+package interpreter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func script(parameter, storage, code string) gjson.Result {
+	return gjson.Parse(`[{"prim":"parameter","args":[` + parameter + `]},{"prim":"storage","args":[` + storage + `]},{"prim":"code","args":[` + code + `]}]`)
+}
+
+func TestInterpreter_Run(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     gjson.Result
+		entrypoint string
+		parameter  string
+		storage    string
+		opts       []Option
+		want       string
+		wantOps    string
+		wantDiff   string
+		wantErr    string
+	}{
+		{
+			name:      "add",
+			script:    script(`{"prim":"int"}`, `{"prim":"int"}`, `[{"prim":"DUP"},{"prim":"CAR"},{"prim":"DIP","args":[[{"prim":"CDR"}]]},{"prim":"ADD"},{"prim":"NIL","args":[{"prim":"operation"}]},{"prim":"PAIR"}]`),
+			parameter: `{"int":"2"}`,
+			storage:   `{"int":"40"}`,
+			want:      `{"int":"42"}`,
+		}, {
+			name:       "entrypoint",
+			script:     script(`{"prim":"or","args":[{"prim":"int","annots":["%inc"]},{"prim":"int","annots":["%dec"]}]}`, `{"prim":"int"}`, `[{"prim":"DUP"},{"prim":"CAR"},{"prim":"DIP","args":[[{"prim":"CDR"}]]},{"prim":"IF_LEFT","args":[[{"prim":"ADD"}],[{"prim":"SWAP"},{"prim":"SUB"}]]},{"prim":"NIL","args":[{"prim":"operation"}]},{"prim":"PAIR"}]`),
+			entrypoint: "dec",
+			parameter:  `{"int":"2"}`,
+			storage:    `{"int":"40"}`,
+			want:       `{"int":"38"}`,
+		}, {
+			name:      "failwith",
+			script:    script(`{"prim":"string"}`, `{"prim":"unit"}`, `[{"prim":"CAR"},{"prim":"FAILWITH"}]`),
+			parameter: `{"string":"error"}`,
+			storage:   `{"prim":"Unit"}`,
+			wantErr:   `Script rejected at location 8 with map[string:error]`,
+		}, {
+			name:      "gas exhausted",
+			script:    script(`{"prim":"unit"}`, `{"prim":"unit"}`, `[{"prim":"PUSH","args":[{"prim":"bool"},{"prim":"True"}]},{"prim":"LOOP","args":[[{"prim":"PUSH","args":[{"prim":"bool"},{"prim":"True"}]}]]}]`),
+			parameter: `{"prim":"Unit"}`,
+			storage:   `{"prim":"Unit"}`,
+			opts:      []Option{WithGasLimit(1000)},
+			wantErr:   `Gas exhausted at location 12`,
+		}, {
+			name:      "mutez underflow",
+			script:    script(`{"prim":"mutez"}`, `{"prim":"mutez"}`, `[{"prim":"DUP"},{"prim":"CDR"},{"prim":"DIP","args":[[{"prim":"CAR"}]]},{"prim":"SUB"},{"prim":"NIL","args":[{"prim":"operation"}]},{"prim":"PAIR"}]`),
+			parameter: `{"int":"2"}`,
+			storage:   `{"int":"1"}`,
+			wantErr:   `SUB at location 12: Mutez overflow: -1`,
+		}, {
+			name:      "map update",
+			script:    script(`{"prim":"string"}`, `{"prim":"map","args":[{"prim":"string"},{"prim":"nat"}]}`, `[{"prim":"DUP"},{"prim":"CDR"},{"prim":"SWAP"},{"prim":"CAR"},{"prim":"DIP","args":[[{"prim":"PUSH","args":[{"prim":"nat"},{"int":"1"}]},{"prim":"SOME"}]]},{"prim":"UPDATE"},{"prim":"NIL","args":[{"prim":"operation"}]},{"prim":"PAIR"}]`),
+			parameter: `{"string":"b"}`,
+			storage:   `[{"prim":"Elt","args":[{"string":"a"},{"int":"0"}]},{"prim":"Elt","args":[{"string":"c"},{"int":"2"}]}]`,
+			want:      `[{"args":[{"string":"a"},{"int":"0"}],"prim":"Elt"},{"args":[{"string":"b"},{"int":"1"}],"prim":"Elt"},{"args":[{"string":"c"},{"int":"2"}],"prim":"Elt"}]`,
+		}, {
+			name:      "big map by pointer",
+			script:    script(`{"prim":"string"}`, `{"prim":"big_map","args":[{"prim":"string"},{"prim":"nat"}]}`, `[{"prim":"DUP"},{"prim":"CDR"},{"prim":"SWAP"},{"prim":"CAR"},{"prim":"DUP"},{"prim":"DIP","args":[[{"prim":"DIP","args":[[{"prim":"DUP"}]]},{"prim":"GET"},{"prim":"IF_NONE","args":[[{"prim":"PUSH","args":[{"prim":"string"},{"string":"no key"}]},{"prim":"FAILWITH"}],[{"prim":"PUSH","args":[{"prim":"nat"},{"int":"1"}]},{"prim":"ADD"},{"prim":"SOME"}]]}]]},{"prim":"UPDATE"},{"prim":"NIL","args":[{"prim":"operation"}]},{"prim":"PAIR"}]`),
+			parameter: `{"string":"a"}`,
+			storage:   `{"int":"17"}`,
+			opts: []Option{
+				WithBigMapGetter(func(ptr int64, key *Value) (gjson.Result, error) {
+					if ptr == 17 && key.Str == "a" {
+						return gjson.Parse(`{"int":"41"}`), nil
+					}
+					return gjson.Result{}, nil
+				}),
+			},
+			want:     `{"int":"17"}`,
+			wantDiff: `[{"big_map":17,"key":{"string":"a"},"value":{"int":"42"}}]`,
+		}, {
+			name:      "apply and exec",
+			script:    script(`{"prim":"nat"}`, `{"prim":"nat"}`, `[{"prim":"CAR"},{"prim":"LAMBDA","args":[{"prim":"pair","args":[{"prim":"nat"},{"prim":"nat"}]},{"prim":"nat"},[{"prim":"DUP"},{"prim":"CAR"},{"prim":"DIP","args":[[{"prim":"CDR"}]]},{"prim":"MUL"}]]},{"prim":"PUSH","args":[{"prim":"nat"},{"int":"3"}]},{"prim":"APPLY"},{"prim":"SWAP"},{"prim":"EXEC"},{"prim":"NIL","args":[{"prim":"operation"}]},{"prim":"PAIR"}]`),
+			parameter: `{"int":"5"}`,
+			storage:   `{"int":"0"}`,
+			want:      `{"int":"15"}`,
+		}, {
+			name:      "pack",
+			script:    script(`{"prim":"unit"}`, `{"prim":"bytes"}`, `[{"prim":"DROP"},{"prim":"PUSH","args":[{"prim":"pair","args":[{"prim":"int"},{"prim":"string"}]},{"prim":"Pair","args":[{"int":"1"},{"string":"a"}]}]},{"prim":"PACK"},{"prim":"NIL","args":[{"prim":"operation"}]},{"prim":"PAIR"}]`),
+			parameter: `{"prim":"Unit"}`,
+			storage:   `{"bytes":""}`,
+			want:      `{"bytes":"05070700010100000001` + `61"}`,
+		}, {
+			name:      "transfer to implicit account",
+			script:    script(`{"prim":"key_hash"}`, `{"prim":"unit"}`, `[{"prim":"DUP"},{"prim":"CAR"},{"prim":"IMPLICIT_ACCOUNT"},{"prim":"PUSH","args":[{"prim":"mutez"},{"int":"100"}]},{"prim":"UNIT"},{"prim":"TRANSFER_TOKENS"},{"prim":"DIP","args":[[{"prim":"CDR"},{"prim":"NIL","args":[{"prim":"operation"}]}]]},{"prim":"CONS"},{"prim":"PAIR"}]`),
+			parameter: `{"string":"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx"}`,
+			storage:   `{"prim":"Unit"}`,
+			opts:      []Option{WithContract("KT1BEqzn5Wx8uJrZNvuS9DVHmLvG9td3fDLi", 1000)},
+			want:      `{"prim":"Unit"}`,
+			wantOps:   `[{"amount":"100","destination":"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx","kind":"transaction","parameters":{"entrypoint":"default","value":{"prim":"Unit"}},"source":"KT1BEqzn5Wx8uJrZNvuS9DVHmLvG9td3fDLi"}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i, err := New(tt.script, tt.opts...)
+			if err != nil {
+				t.Errorf("New() error = %v", err)
+				return
+			}
+			result, err := i.Run(tt.entrypoint, gjson.Parse(tt.parameter), gjson.Parse(tt.storage))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Run() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if result.ConsumedGas == 0 {
+					t.Errorf("Run() consumed gas is not counted on error")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Run() error = %v", err)
+				return
+			}
+			assertJSON(t, "storage", result.Storage, tt.want)
+			if tt.wantOps != "" {
+				assertJSON(t, "operations", result.Operations, tt.wantOps)
+			}
+			if tt.wantDiff != "" {
+				assertJSON(t, "big_map_diff", result.BigMapDiff, tt.wantDiff)
+			}
+		})
+	}
+}
+
+func TestInterpreter_Trace(t *testing.T) {
+	i, err := New(script(`{"prim":"unit"}`, `{"prim":"unit"}`, `[{"prim":"CDR"},{"prim":"NIL","args":[{"prim":"operation"}]},{"prim":"PAIR"}]`), WithTrace(10))
+	if err != nil {
+		t.Errorf("New() error = %v", err)
+		return
+	}
+	result, err := i.Run("", gjson.Parse(`{"prim":"Unit"}`), gjson.Parse(`{"prim":"Unit"}`))
+	if err != nil {
+		t.Errorf("Run() error = %v", err)
+		return
+	}
+
+	want := []struct {
+		location int
+		prim     string
+		before   int
+		after    int
+	}{
+		{7, "CDR", 1, 1},
+		{8, "NIL", 1, 2},
+		{10, "PAIR", 2, 1},
+	}
+	if len(result.Trace) != len(want) {
+		t.Errorf("Trace length = %d, want %d", len(result.Trace), len(want))
+		return
+	}
+	var gas int64 = DefaultGasLimit
+	for idx, step := range result.Trace {
+		if step.Location != want[idx].location || step.Prim != want[idx].prim || len(step.Before) != want[idx].before || len(step.After) != want[idx].after {
+			t.Errorf("Step %d = %d %s %d %d, want %v", idx, step.Location, step.Prim, len(step.Before), len(step.After), want[idx])
+		}
+		if step.Row == 0 {
+			t.Errorf("Step %d has no position", idx)
+		}
+		if step.Gas >= gas {
+			t.Errorf("Step %d gas %d is not decreased", idx, step.Gas)
+		}
+		gas = step.Gas
+	}
+}
+
+func TestInterpreter_TraceLimit(t *testing.T) {
+	i, err := New(script(`{"prim":"unit"}`, `{"prim":"unit"}`, `[{"prim":"CDR"},{"prim":"NIL","args":[{"prim":"operation"}]},{"prim":"PAIR"}]`), WithTrace(2))
+	if err != nil {
+		t.Errorf("New() error = %v", err)
+		return
+	}
+	result, err := i.Run("", gjson.Parse(`{"prim":"Unit"}`), gjson.Parse(`{"prim":"Unit"}`))
+	if err != nil {
+		t.Errorf("Run() error = %v", err)
+		return
+	}
+	if len(result.Trace) != 2 || !result.TraceTruncated {
+		t.Errorf("Trace length = %d, truncated = %v, want 2 and truncated", len(result.Trace), result.TraceTruncated)
+	}
+	if result.Storage == nil {
+		t.Errorf("Execution must continue after the trace limit")
+	}
+}
+
+func assertJSON(t *testing.T, name string, value interface{}, want string) {
+	got, err := json.Marshal(value)
+	if err != nil {
+		t.Errorf("%s: %v", name, err)
+		return
+	}
+	if string(got) != want {
+		t.Errorf("%s = %s, want %s", name, got, want)
+	}
+}
+
+func TestValue_KeyHash(t *testing.T) {
+	tests := []struct {
+		name  string
+		typ   string
+		value string
+		want  string
+	}{
+		{
+			name:  "nat",
+			typ:   `{"prim":"nat"}`,
+			value: `{"int":"0"}`,
+			want:  "exprtZBwZUeYYYfUs9B9Rg2ywHezVHnCCnmF9WsDQVrs582dSK63dC",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typ, err := NewType(gjson.Parse(tt.typ))
+			if err != nil {
+				t.Errorf("NewType() error = %v", err)
+				return
+			}
+			value, err := ParseValue(typ, gjson.Parse(tt.value))
+			if err != nil {
+				t.Errorf("ParseValue() error = %v", err)
+				return
+			}
+			got, err := value.KeyHash()
+			if err != nil {
+				t.Errorf("KeyHash() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("KeyHash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
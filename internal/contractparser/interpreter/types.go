@@ -0,0 +1,156 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/tidwall/gjson"
+)
+
+const defaultEntrypoint = "default"
+
+// Type - Michelson type. Primitives are lower case, annotations are kept for entrypoints lookup.
+type Type struct {
+	Prim   string
+	Args   []*Type
+	Annots []string
+}
+
+// NewType - parses Micheline type
+func NewType(data gjson.Result) (*Type, error) {
+	if !data.IsObject() || !data.Get("prim").Exists() {
+		return nil, fmt.Errorf("Invalid type: %s", data.Raw)
+	}
+	t := &Type{
+		Prim:   strings.ToLower(data.Get("prim").String()),
+		Args:   make([]*Type, 0),
+		Annots: make([]string, 0),
+	}
+	for _, annot := range data.Get("annots").Array() {
+		t.Annots = append(t.Annots, annot.String())
+	}
+	for _, arg := range data.Get("args").Array() {
+		argType, err := NewType(arg)
+		if err != nil {
+			return nil, err
+		}
+		t.Args = append(t.Args, argType)
+	}
+	if len(t.Args) != typeArgsCount(t.Prim) {
+		return nil, fmt.Errorf("Invalid arguments count of type %s: %d", t.Prim, len(t.Args))
+	}
+	return t, nil
+}
+
+func typeArgsCount(prim string) int {
+	switch prim {
+	case consts.PAIR, consts.OR, consts.MAP, consts.BIGMAP, consts.LAMBDA:
+		return 2
+	case consts.OPTION, consts.LIST, consts.SET, consts.CONTRACT:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func newType(prim string, args ...*Type) *Type {
+	return &Type{
+		Prim:   prim,
+		Args:   args,
+		Annots: make([]string, 0),
+	}
+}
+
+// Equal - compares types ignoring annotations
+func (t *Type) Equal(other *Type) bool {
+	if t.Prim != other.Prim || len(t.Args) != len(other.Args) {
+		return false
+	}
+	for i := range t.Args {
+		if !t.Args[i].Equal(other.Args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// FieldAnnot - returns field annotation without `%`
+func (t *Type) FieldAnnot() string {
+	for _, annot := range t.Annots {
+		if strings.HasPrefix(annot, "%") {
+			return annot[1:]
+		}
+	}
+	return ""
+}
+
+// Micheline - returns JSON-ready Micheline of the type
+func (t *Type) Micheline() map[string]interface{} {
+	result := map[string]interface{}{
+		"prim": t.Prim,
+	}
+	if len(t.Args) > 0 {
+		args := make([]interface{}, len(t.Args))
+		for i := range t.Args {
+			args[i] = t.Args[i].Micheline()
+		}
+		result["args"] = args
+	}
+	if len(t.Annots) > 0 {
+		result["annots"] = t.Annots
+	}
+	return result
+}
+
+// String -
+func (t *Type) String() string {
+	if len(t.Args) == 0 {
+		return t.Prim
+	}
+	args := make([]string, len(t.Args))
+	for i := range t.Args {
+		args[i] = t.Args[i].String()
+	}
+	return fmt.Sprintf("(%s %s)", t.Prim, strings.Join(args, " "))
+}
+
+// Entrypoint - returns type of the entrypoint. `default` is the root unless some branch is annotated as `%default`.
+func (t *Type) Entrypoint(name string) (*Type, bool) {
+	if name == "" {
+		name = defaultEntrypoint
+	}
+	if found := t.findEntrypoint(name); found != nil {
+		return found, true
+	}
+	if name == defaultEntrypoint {
+		return t, true
+	}
+	return nil, false
+}
+
+func (t *Type) findEntrypoint(name string) *Type {
+	if t.FieldAnnot() == name {
+		return t
+	}
+	if t.Prim != consts.OR {
+		return nil
+	}
+	for _, arg := range t.Args {
+		if found := arg.findEntrypoint(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func isComparable(t *Type) bool {
+	switch t.Prim {
+	case consts.INT, consts.NAT, consts.STRING, consts.BYTES, consts.MUTEZ, consts.BOOL, consts.KEYHASH, consts.TIMESTAMP, consts.ADDRESS:
+		return true
+	case consts.PAIR:
+		return isComparable(t.Args[0]) && isComparable(t.Args[1])
+	default:
+		return false
+	}
+}
@@ -0,0 +1,463 @@
+package interpreter
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/tzbase58"
+	"github.com/tidwall/gjson"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Value - typed Michelson value. Which fields are set depends on the type.
+type Value struct {
+	Type *Type
+
+	Int   *big.Int // int, nat, mutez, timestamp
+	Str   string   // string and domain types (address, contract, key, key_hash, signature, chain_id) in readable form
+	Bytes []byte
+	Bool  bool     // bool; `Left` for or
+	Args  []*Value // pair items; or value; option value (empty for `None`); list and set items (sets are sorted)
+	Elts  []*Elt   // map and big_map items sorted by key. Removed keys of big map received by pointer have nil value.
+	Ptr   *int64   // pointer of big map
+	Code  gjson.Result
+
+	Operation map[string]interface{}
+
+	body *instruction // compiled lambda
+}
+
+// Elt - map item
+type Elt struct {
+	Key   *Value
+	Value *Value
+}
+
+func newInt(t *Type, value *big.Int) *Value {
+	return &Value{Type: t, Int: value}
+}
+
+func newBool(value bool) *Value {
+	return &Value{Type: newType(consts.BOOL), Bool: value}
+}
+
+func newPair(left, right *Value) *Value {
+	return &Value{
+		Type: newType(consts.PAIR, left.Type, right.Type),
+		Args: []*Value{left, right},
+	}
+}
+
+func newSome(value *Value) *Value {
+	return &Value{
+		Type: newType(consts.OPTION, value.Type),
+		Args: []*Value{value},
+	}
+}
+
+func newNone(t *Type) *Value {
+	return &Value{
+		Type: newType(consts.OPTION, t),
+		Args: make([]*Value, 0),
+	}
+}
+
+// IsSome - option is `Some`
+func (v *Value) IsSome() bool {
+	return len(v.Args) == 1
+}
+
+// ParseValue - parses Micheline data of the type. Both readable and optimized forms are accepted.
+func ParseValue(t *Type, data gjson.Result) (*Value, error) {
+	v := &Value{Type: t}
+	switch t.Prim {
+	case consts.INT, consts.NAT, consts.MUTEZ:
+		number, ok := new(big.Int).SetString(data.Get(consts.KeyInt).String(), 10)
+		if !ok {
+			return nil, invalidData(t, data)
+		}
+		if t.Prim != consts.INT && number.Sign() < 0 {
+			return nil, invalidData(t, data)
+		}
+		if t.Prim == consts.MUTEZ && !number.IsInt64() {
+			return nil, invalidData(t, data)
+		}
+		v.Int = number
+	case consts.TIMESTAMP:
+		if data.Get(consts.KeyInt).Exists() {
+			number, ok := new(big.Int).SetString(data.Get(consts.KeyInt).String(), 10)
+			if !ok {
+				return nil, invalidData(t, data)
+			}
+			v.Int = number
+		} else if data.Get(consts.KeyString).Exists() {
+			ts, err := time.Parse(time.RFC3339, data.Get(consts.KeyString).String())
+			if err != nil {
+				return nil, invalidData(t, data)
+			}
+			v.Int = big.NewInt(ts.Unix())
+		} else {
+			return nil, invalidData(t, data)
+		}
+	case consts.STRING:
+		if !data.Get(consts.KeyString).Exists() {
+			return nil, invalidData(t, data)
+		}
+		v.Str = data.Get(consts.KeyString).String()
+	case consts.BYTES:
+		b, err := hex.DecodeString(data.Get(consts.KeyBytes).String())
+		if err != nil || !data.Get(consts.KeyBytes).Exists() {
+			return nil, invalidData(t, data)
+		}
+		v.Bytes = b
+	case consts.BOOL:
+		switch data.Get(consts.KeyPrim).String() {
+		case "True":
+			v.Bool = true
+		case "False":
+		default:
+			return nil, invalidData(t, data)
+		}
+	case consts.UNIT:
+		if data.Get(consts.KeyPrim).String() != "Unit" {
+			return nil, invalidData(t, data)
+		}
+	case consts.ADDRESS, consts.CONTRACT, consts.KEY, consts.KEYHASH, consts.SIGNATURE, consts.CHAINID:
+		s, err := parseDomainValue(t, data)
+		if err != nil {
+			return nil, err
+		}
+		v.Str = s
+	case consts.PAIR:
+		if data.Get(consts.KeyPrim).String() != consts.Pair || len(data.Get(consts.KeyArgs).Array()) != 2 {
+			return nil, invalidData(t, data)
+		}
+		for i, arg := range data.Get(consts.KeyArgs).Array() {
+			value, err := ParseValue(t.Args[i], arg)
+			if err != nil {
+				return nil, err
+			}
+			v.Args = append(v.Args, value)
+		}
+	case consts.OR:
+		var argType *Type
+		switch data.Get(consts.KeyPrim).String() {
+		case "Left":
+			v.Bool = true
+			argType = t.Args[0]
+		case "Right":
+			argType = t.Args[1]
+		default:
+			return nil, invalidData(t, data)
+		}
+		value, err := ParseValue(argType, data.Get("args.0"))
+		if err != nil {
+			return nil, err
+		}
+		v.Args = []*Value{value}
+	case consts.OPTION:
+		v.Args = make([]*Value, 0)
+		switch data.Get(consts.KeyPrim).String() {
+		case consts.Some:
+			value, err := ParseValue(t.Args[0], data.Get("args.0"))
+			if err != nil {
+				return nil, err
+			}
+			v.Args = append(v.Args, value)
+		case consts.None:
+		default:
+			return nil, invalidData(t, data)
+		}
+	case consts.LIST, consts.SET:
+		if !data.IsArray() {
+			return nil, invalidData(t, data)
+		}
+		v.Args = make([]*Value, 0)
+		for _, item := range data.Array() {
+			value, err := ParseValue(t.Args[0], item)
+			if err != nil {
+				return nil, err
+			}
+			if t.Prim == consts.SET && len(v.Args) > 0 && Compare(v.Args[len(v.Args)-1], value) >= 0 {
+				return nil, fmt.Errorf("Set values have to be unique and sorted: %s", data.Raw)
+			}
+			v.Args = append(v.Args, value)
+		}
+	case consts.MAP, consts.BIGMAP:
+		if t.Prim == consts.BIGMAP && data.Get(consts.KeyInt).Exists() {
+			ptr := data.Get(consts.KeyInt).Int()
+			v.Ptr = &ptr
+			v.Elts = make([]*Elt, 0)
+			return v, nil
+		}
+		if !data.IsArray() {
+			return nil, invalidData(t, data)
+		}
+		v.Elts = make([]*Elt, 0)
+		for _, item := range data.Array() {
+			if !strings.EqualFold(item.Get(consts.KeyPrim).String(), consts.ELT) || len(item.Get(consts.KeyArgs).Array()) != 2 {
+				return nil, invalidData(t, item)
+			}
+			key, err := ParseValue(t.Args[0], item.Get("args.0"))
+			if err != nil {
+				return nil, err
+			}
+			value, err := ParseValue(t.Args[1], item.Get("args.1"))
+			if err != nil {
+				return nil, err
+			}
+			if len(v.Elts) > 0 && Compare(v.Elts[len(v.Elts)-1].Key, key) >= 0 {
+				return nil, fmt.Errorf("Map keys have to be unique and sorted: %s", data.Raw)
+			}
+			v.Elts = append(v.Elts, &Elt{Key: key, Value: value})
+		}
+	case consts.LAMBDA:
+		if !data.IsArray() {
+			return nil, invalidData(t, data)
+		}
+		v.Code = data
+	default:
+		return nil, fmt.Errorf("Values of type %s can not be parsed", t.Prim)
+	}
+	return v, nil
+}
+
+func parseDomainValue(t *Type, data gjson.Result) (string, error) {
+	if s := data.Get(consts.KeyString); s.Exists() {
+		value := s.String()
+		var err error
+		switch t.Prim {
+		case consts.ADDRESS, consts.CONTRACT:
+			if !isAddress(value) {
+				return "", invalidData(t, data)
+			}
+		case consts.KEY:
+			_, err = keyToBytes(value)
+		case consts.KEYHASH:
+			_, err = keyHashToBytes(value)
+		case consts.SIGNATURE:
+			_, err = signatureToBytes(value)
+		case consts.CHAINID:
+			_, err = chainIDToBytes(value)
+		}
+		if err != nil {
+			return "", invalidData(t, data)
+		}
+		return value, nil
+	}
+
+	b, err := hex.DecodeString(data.Get(consts.KeyBytes).String())
+	if err != nil || !data.Get(consts.KeyBytes).Exists() {
+		return "", invalidData(t, data)
+	}
+	var value string
+	switch t.Prim {
+	case consts.ADDRESS, consts.CONTRACT:
+		value, err = bytesToAddress(b)
+	case consts.KEY:
+		value, err = bytesToKey(b)
+	case consts.KEYHASH:
+		value, err = bytesToKeyHash(b)
+	case consts.SIGNATURE:
+		value, err = bytesToSignature(b)
+	case consts.CHAINID:
+		value, err = bytesToChainID(b)
+	}
+	if err != nil {
+		return "", invalidData(t, data)
+	}
+	return value, nil
+}
+
+func invalidData(t *Type, data gjson.Result) error {
+	return fmt.Errorf("Invalid data of type %s: %s", t.String(), data.Raw)
+}
+
+// Micheline - returns JSON-ready Micheline of the value. Optimized form uses binary representation of domain types
+// and integer timestamps as PACK does.
+func (v *Value) Micheline(optimized bool) (interface{}, error) {
+	switch v.Type.Prim {
+	case consts.INT, consts.NAT, consts.MUTEZ:
+		return map[string]interface{}{consts.KeyInt: v.Int.String()}, nil
+	case consts.TIMESTAMP:
+		if optimized || !v.Int.IsInt64() {
+			return map[string]interface{}{consts.KeyInt: v.Int.String()}, nil
+		}
+		return map[string]interface{}{consts.KeyString: time.Unix(v.Int.Int64(), 0).UTC().Format(time.RFC3339)}, nil
+	case consts.STRING:
+		return map[string]interface{}{consts.KeyString: v.Str}, nil
+	case consts.BYTES:
+		return map[string]interface{}{consts.KeyBytes: hex.EncodeToString(v.Bytes)}, nil
+	case consts.BOOL:
+		if v.Bool {
+			return prim("True"), nil
+		}
+		return prim("False"), nil
+	case consts.UNIT:
+		return prim("Unit"), nil
+	case consts.ADDRESS, consts.CONTRACT, consts.KEY, consts.KEYHASH, consts.SIGNATURE, consts.CHAINID:
+		if !optimized {
+			return map[string]interface{}{consts.KeyString: v.Str}, nil
+		}
+		b, err := domainBytes(v)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{consts.KeyBytes: hex.EncodeToString(b)}, nil
+	case consts.PAIR, consts.OR, consts.OPTION:
+		name := consts.Pair
+		switch {
+		case v.Type.Prim == consts.OR && v.Bool:
+			name = "Left"
+		case v.Type.Prim == consts.OR:
+			name = "Right"
+		case v.Type.Prim == consts.OPTION && v.IsSome():
+			name = consts.Some
+		case v.Type.Prim == consts.OPTION:
+			return prim(consts.None), nil
+		}
+		args, err := michelineList(v.Args, optimized)
+		if err != nil {
+			return nil, err
+		}
+		return prim(name, args...), nil
+	case consts.LIST, consts.SET:
+		return michelineList(v.Args, optimized)
+	case consts.MAP, consts.BIGMAP:
+		if v.Ptr != nil {
+			return map[string]interface{}{consts.KeyInt: fmt.Sprintf("%d", *v.Ptr)}, nil
+		}
+		items := make([]interface{}, 0)
+		for _, elt := range v.Elts {
+			if elt.Value == nil {
+				continue
+			}
+			key, err := elt.Key.Micheline(optimized)
+			if err != nil {
+				return nil, err
+			}
+			value, err := elt.Value.Micheline(optimized)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, prim("Elt", key, value))
+		}
+		return items, nil
+	case consts.LAMBDA:
+		return v.Code.Value(), nil
+	case consts.OPERATION:
+		return v.Operation, nil
+	default:
+		return nil, fmt.Errorf("Values of type %s can not be converted to Micheline", v.Type.Prim)
+	}
+}
+
+func michelineList(values []*Value, optimized bool) ([]interface{}, error) {
+	result := make([]interface{}, len(values))
+	for i := range values {
+		item, err := values[i].Micheline(optimized)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = item
+	}
+	return result, nil
+}
+
+func prim(name string, args ...interface{}) map[string]interface{} {
+	result := map[string]interface{}{
+		consts.KeyPrim: name,
+	}
+	if len(args) > 0 {
+		result[consts.KeyArgs] = args
+	}
+	return result
+}
+
+func domainBytes(v *Value) ([]byte, error) {
+	switch v.Type.Prim {
+	case consts.ADDRESS, consts.CONTRACT:
+		return addressToBytes(v.Str)
+	case consts.KEY:
+		return keyToBytes(v.Str)
+	case consts.KEYHASH:
+		return keyHashToBytes(v.Str)
+	case consts.SIGNATURE:
+		return signatureToBytes(v.Str)
+	case consts.CHAINID:
+		return chainIDToBytes(v.Str)
+	default:
+		return nil, fmt.Errorf("%s is not a domain type", v.Type.Prim)
+	}
+}
+
+// KeyHash - script expression hash of the value as the node computes big map key hashes
+func (v *Value) KeyHash() (string, error) {
+	packed, err := packValue(v)
+	if err != nil {
+		return "", err
+	}
+	hash := blake2b.Sum256(packed)
+	return tzbase58.EncodeFromBytes(hash[:], prefixExpr), nil
+}
+
+// Compare - compares values of the same comparable type
+func Compare(a, b *Value) int {
+	switch a.Type.Prim {
+	case consts.INT, consts.NAT, consts.MUTEZ, consts.TIMESTAMP:
+		return a.Int.Cmp(b.Int)
+	case consts.STRING:
+		return strings.Compare(a.Str, b.Str)
+	case consts.BYTES:
+		return bytes.Compare(a.Bytes, b.Bytes)
+	case consts.BOOL:
+		switch {
+		case a.Bool == b.Bool:
+			return 0
+		case a.Bool:
+			return 1
+		default:
+			return -1
+		}
+	case consts.ADDRESS, consts.KEYHASH:
+		aBytes, errA := domainBytes(a)
+		bBytes, errB := domainBytes(b)
+		if errA != nil || errB != nil {
+			return strings.Compare(a.Str, b.Str)
+		}
+		return bytes.Compare(aBytes, bBytes)
+	case consts.PAIR:
+		if cmp := Compare(a.Args[0], b.Args[0]); cmp != 0 {
+			return cmp
+		}
+		return Compare(a.Args[1], b.Args[1])
+	default:
+		return 0
+	}
+}
+
+// find - returns index of the key in the sorted items and true if the key exists
+func find(count int, key *Value, keyAt func(i int) *Value) (int, bool) {
+	idx := sort.Search(count, func(i int) bool {
+		return Compare(keyAt(i), key) >= 0
+	})
+	return idx, idx < count && Compare(keyAt(idx), key) == 0
+}
+
+func (v *Value) findElt(key *Value) (int, bool) {
+	return find(len(v.Elts), key, func(i int) *Value {
+		return v.Elts[i].Key
+	})
+}
+
+func (v *Value) findItem(key *Value) (int, bool) {
+	return find(len(v.Args), key, func(i int) *Value {
+		return v.Args[i]
+	})
+}
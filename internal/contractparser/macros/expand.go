@@ -0,0 +1,165 @@
+package macros
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/valyala/fastjson"
+)
+
+type expander func(prim string, annots []string, args []string) (string, error)
+
+var (
+	regCompare   = regexp.MustCompile(`^CMP(EQ|NEQ|LT|GT|LE|GE)$`)
+	regIf        = regexp.MustCompile(`^IF(EQ|NEQ|LT|GT|LE|GE)$`)
+	regIfCompare = regexp.MustCompile(`^IFCMP(EQ|NEQ|LT|GT|LE|GE)$`)
+	regAssert    = regexp.MustCompile(`^ASSERT_(CMP)?(EQ|NEQ|LT|GT|LE|GE)$`)
+	regCadr      = regexp.MustCompile(`^C[AD]{2,}R$`)
+	regSetCadr   = regexp.MustCompile(`^SET_C[AD]+R$`)
+	regMapCadr   = regexp.MustCompile(`^MAP_C[AD]+R$`)
+	regDip       = regexp.MustCompile(`^DI{2,}P$`)
+	regDup       = regexp.MustCompile(`^DU{2,}P$`)
+	regPair      = regexp.MustCompile(`^P[AIP]{3,}R$`)
+	regUnpair    = regexp.MustCompile(`^UNP[AIP]{2,}R$`)
+)
+
+var expanders = []struct {
+	re     *regexp.Regexp
+	expand expander
+}{
+	{regexp.MustCompile(`^FAIL$`), expandFail},
+	{regexp.MustCompile(`^ASSERT(_NONE|_SOME|_LEFT|_RIGHT)?$`), expandAssert},
+	{regAssert, expandAssertCompare},
+	{regCompare, expandCompare},
+	{regIf, expandIf},
+	{regIfCompare, expandIfCompare},
+	{regexp.MustCompile(`^IF_(SOME|RIGHT)$`), expandIfSome},
+	{regCadr, expandCadr},
+	{regSetCadr, expandSetCadr},
+	{regMapCadr, expandMapCadr},
+	{regDip, expandDip},
+	{regDup, expandDup},
+	{regPair, expandPair},
+	{regUnpair, expandUnpair},
+}
+
+// Expand - replaces macros by primitive instructions as the client does before injection.
+// Macros are expanded to the same forms which are recognized by `Collapse`.
+func Expand(tree gjson.Result) (gjson.Result, error) {
+	val, err := fastjson.Parse(tree.String())
+	if err != nil {
+		return tree, err
+	}
+
+	if err := expand(val); err != nil {
+		return tree, err
+	}
+
+	return gjson.Parse(val.String()), nil
+}
+
+func expand(tree *fastjson.Value) error {
+	switch tree.Type() {
+	case fastjson.TypeArray:
+		arr, err := tree.Array()
+		if err != nil {
+			return err
+		}
+		for i := range arr {
+			if err := expand(arr[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case fastjson.TypeObject:
+		return expandObject(tree)
+	default:
+		return fmt.Errorf("Invalid fastjson.Type: %v", tree.Type())
+	}
+}
+
+func expandObject(tree *fastjson.Value) error {
+	args := getArgs(tree)
+	for i := range args {
+		if err := expand(args[i]); err != nil {
+			return err
+		}
+	}
+
+	prim := strings.Trim(getPrim(tree), `"`)
+	if prim == "" {
+		return nil
+	}
+
+	for _, e := range expanders {
+		if !e.re.MatchString(prim) {
+			continue
+		}
+		annots := make([]string, 0)
+		for _, annot := range tree.GetArray("annots") {
+			annots = append(annots, string(annot.GetStringBytes()))
+		}
+		rawArgs := make([]string, len(args))
+		for i := range args {
+			rawArgs[i] = args[i].String()
+		}
+
+		expanded, err := e.expand(prim, annots, rawArgs)
+		if err != nil {
+			return err
+		}
+		val, err := fastjson.Parse(expanded)
+		if err != nil {
+			return err
+		}
+		// expansion may contain other macros, e.g. FAIL or nested SET_C[AD]+R
+		if err := expand(val); err != nil {
+			return err
+		}
+		*tree = *val
+		return nil
+	}
+	return nil
+}
+
+func checkArgs(prim string, args []string, count int) error {
+	if len(args) != count {
+		return fmt.Errorf("Invalid arguments count of %s: %d", prim, len(args))
+	}
+	return nil
+}
+
+// primJSON - Micheline of the primitive. Annotations and arguments are already encoded JSON.
+func primJSON(prim string, annots []string, args ...string) string {
+	var sb strings.Builder
+	sb.WriteString(`{"prim":"`)
+	sb.WriteString(prim)
+	sb.WriteString(`"`)
+	if len(annots) > 0 {
+		sb.WriteString(`,"annots":[`)
+		for i := range annots {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString(fmt.Sprintf("%q", annots[i]))
+		}
+		sb.WriteString("]")
+	}
+	if len(args) > 0 {
+		sb.WriteString(`,"args":[`)
+		sb.WriteString(strings.Join(args, ","))
+		sb.WriteString("]")
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+func seqJSON(items ...string) string {
+	return fmt.Sprintf("[%s]", strings.Join(items, ","))
+}
+
+func intJSON(value int) string {
+	return fmt.Sprintf(`{"int":"%d"}`, value)
+}
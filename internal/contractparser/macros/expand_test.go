@@ -0,0 +1,137 @@
+package macros
+
+import (
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		name    string
+		tree    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "FAIL",
+			tree: `[{"prim":"FAIL"}]`,
+			want: `[[{"prim":"UNIT"},{"prim":"FAILWITH"}]]`,
+		}, {
+			name: "ASSERT_SOME",
+			tree: `{"prim":"ASSERT_SOME","annots":["@x"]}`,
+			want: `[{"prim":"IF_NONE","args":[[[{"prim":"UNIT"},{"prim":"FAILWITH"}]],[{"prim":"RENAME","annots":["@x"]}]]}]`,
+		}, {
+			name: "ASSERT_LEFT",
+			tree: `{"prim":"ASSERT_LEFT"}`,
+			want: `[{"prim":"IF_LEFT","args":[[],[[{"prim":"UNIT"},{"prim":"FAILWITH"}]]]}]`,
+		}, {
+			name: "ASSERT_CMPLT",
+			tree: `{"prim":"ASSERT_CMPLT"}`,
+			want: `[[{"prim":"COMPARE"},{"prim":"LT"}],{"prim":"IF","args":[[],[[{"prim":"UNIT"},{"prim":"FAILWITH"}]]]}]`,
+		}, {
+			name: "CMPGE",
+			tree: `{"prim":"CMPGE","annots":["@ge"]}`,
+			want: `[{"prim":"COMPARE"},{"prim":"GE","annots":["@ge"]}]`,
+		}, {
+			name: "IFCMPLT with FAIL in branch",
+			tree: `{"prim":"IFCMPLT","args":[[],[{"prim":"FAIL"}]]}`,
+			want: `[[{"prim":"COMPARE"},{"prim":"LT"}],{"prim":"IF","args":[[],[[{"prim":"UNIT"},{"prim":"FAILWITH"}]]]}]`,
+		}, {
+			name: "IF_SOME",
+			tree: `{"prim":"IF_SOME","args":[[{"prim":"DROP"}],[]]}`,
+			want: `[{"prim":"IF_NONE","args":[[],[{"prim":"DROP"}]]}]`,
+		}, {
+			name: "CDAR",
+			tree: `{"prim":"CDAR","annots":["@test"]}`,
+			want: `[{"prim":"CDR"},{"prim":"CAR","annots":["@test"]}]`,
+		}, {
+			name: "SET_CDR with field annotation",
+			tree: `{"prim":"SET_CDR","annots":["%b"]}`,
+			want: `[{"prim":"DUP"},{"prim":"CDR","annots":["%b"]},{"prim":"DROP"},{"prim":"CAR"},{"prim":"PAIR"}]`,
+		}, {
+			name: "SET_CADR",
+			tree: `{"prim":"SET_CADR"}`,
+			want: `[{"prim":"DUP"},{"prim":"DIP","args":[[{"prim":"CAR"},[{"prim":"CAR"},{"prim":"PAIR"}]]]},{"prim":"CDR"},{"prim":"SWAP"},{"prim":"PAIR"}]`,
+		}, {
+			name: "MAP_CDAR",
+			tree: `{"prim":"MAP_CDAR","args":[[{"prim":"AND"}]]}`,
+			want: `[{"prim":"DUP"},{"prim":"DIP","args":[[{"prim":"CDR"},[{"prim":"DUP"},{"prim":"CDR"},{"prim":"DIP","args":[[{"prim":"CAR"},[{"prim":"AND"}]]]},{"prim":"SWAP"},{"prim":"PAIR"}]]]},{"prim":"CAR"},{"prim":"PAIR"}]`,
+		}, {
+			name: "DIIIP",
+			tree: `{"prim":"DIIIP","args":[[{"prim":"DROP"}]]}`,
+			want: `{"prim":"DIP","args":[{"int":"3"},[{"prim":"DROP"}]]}`,
+		}, {
+			name: "DUUUP",
+			tree: `{"prim":"DUUUP","annots":["@x"]}`,
+			want: `[{"prim":"DIP","args":[{"int":"2"},[{"prim":"DUP","annots":["@x"]}]]},{"prim":"DIG","args":[{"int":"3"}]}]`,
+		}, {
+			name: "PAPPAIIR",
+			tree: `{"prim":"PAPPAIIR","annots":["%a","%b","%c","%d","@p"]}`,
+			want: `[{"prim":"DIP","args":[[{"prim":"PAIR","annots":["%b","%c"]},{"prim":"PAIR","annots":["%","%d"]}]]},{"prim":"PAIR","annots":["%a","@p"]}]`,
+		}, {
+			name: "UNPAPAIR",
+			tree: `{"prim":"UNPAPAIR","annots":["@a","@b","@c"]}`,
+			want: `[{"prim":"DUP"},{"prim":"CAR","annots":["@a"]},{"prim":"DIP","args":[[{"prim":"CDR"}]]},{"prim":"DIP","args":[[{"prim":"DUP"},{"prim":"CAR","annots":["@b"]},{"prim":"DIP","args":[[{"prim":"CDR","annots":["@c"]}]]}]]}]`,
+		}, {
+			name: "nested macros",
+			tree: `[{"prim":"DIP","args":[[{"prim":"CAAR"}]]},{"prim":"PAIR"}]`,
+			want: `[{"prim":"DIP","args":[[[{"prim":"CAR"},{"prim":"CAR"}]]]},{"prim":"PAIR"}]`,
+		}, {
+			name:    "invalid PAIR macro",
+			tree:    `{"prim":"PAPAR"}`,
+			wantErr: true,
+		}, {
+			name:    "invalid arguments count",
+			tree:    `{"prim":"IFEQ","args":[[]]}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Expand(gjson.Parse(tt.tree))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expand() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got.String() != tt.want {
+				t.Errorf("Expand() = %v, want %v", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandCollapse(t *testing.T) {
+	tests := []string{
+		`{"prim":"ASSERT"}`,
+		`{"prim":"ASSERT_NONE"}`,
+		`{"prim":"ASSERT_RIGHT","annots":["@r"]}`,
+		`{"prim":"ASSERT_EQ"}`,
+		`{"prim":"ASSERT_CMPNEQ"}`,
+		`{"prim":"IFGT","args":[[{"prim":"DROP"}],[]]}`,
+		`{"prim":"CADR","annots":["@test"]}`,
+		`{"prim":"SET_CAR"}`,
+		`{"prim":"SET_CDR","annots":["%b"]}`,
+		`{"prim":"MAP_CAR","args":[[{"prim":"AND"}]],"annots":["%a"]}`,
+		`{"prim":"MAP_CDR","args":[[{"prim":"AND"}]],"annots":["%a"]}`,
+		`{"prim":"UNPAIR","annots":["%a","%b"]}`,
+		`[{"prim":"CAR"},{"prim":"ASSERT_CMPGE"},{"prim":"UNIT"}]`,
+	}
+	for _, tree := range tests {
+		t.Run(tree, func(t *testing.T) {
+			expanded, err := Expand(gjson.Parse(tree))
+			if err != nil {
+				t.Errorf("Expand() error = %v", err)
+				return
+			}
+			got, err := Collapse(expanded)
+			if err != nil {
+				t.Errorf("Collapse() error = %v", err)
+				return
+			}
+			if got.String() != tree {
+				t.Errorf("Collapse(Expand()) = %v, want %v", got.String(), tree)
+			}
+		})
+	}
+}
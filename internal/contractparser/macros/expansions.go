@@ -0,0 +1,345 @@
+package macros
+
+import (
+	"fmt"
+	"strings"
+)
+
+var failJSON = primJSON("FAIL", nil)
+
+func expandFail(prim string, annots []string, args []string) (string, error) {
+	if err := checkArgs(prim, args, 0); err != nil {
+		return "", err
+	}
+	return seqJSON(primJSON("UNIT", nil), primJSON("FAILWITH", nil)), nil
+}
+
+// rename - RENAME is required only if the macro has annotations
+func rename(annots []string) string {
+	if len(annots) == 0 {
+		return seqJSON()
+	}
+	return seqJSON(primJSON("RENAME", annots))
+}
+
+func expandAssert(prim string, annots []string, args []string) (string, error) {
+	if err := checkArgs(prim, args, 0); err != nil {
+		return "", err
+	}
+	fail := seqJSON(failJSON)
+	switch prim {
+	case "ASSERT_NONE":
+		return seqJSON(primJSON("IF_NONE", nil, seqJSON(), fail)), nil
+	case "ASSERT_SOME":
+		return seqJSON(primJSON("IF_NONE", nil, fail, rename(annots))), nil
+	case "ASSERT_LEFT":
+		return seqJSON(primJSON("IF_LEFT", nil, rename(annots), fail)), nil
+	case "ASSERT_RIGHT":
+		return seqJSON(primJSON("IF_LEFT", nil, fail, rename(annots))), nil
+	default:
+		return seqJSON(primJSON("IF", nil, seqJSON(), fail)), nil
+	}
+}
+
+func expandAssertCompare(prim string, annots []string, args []string) (string, error) {
+	if err := checkArgs(prim, args, 0); err != nil {
+		return "", err
+	}
+	match := regAssert.FindStringSubmatch(prim)
+	check := primJSON(match[2], nil)
+	if match[1] != "" {
+		check = seqJSON(primJSON("COMPARE", nil), check)
+	}
+	return seqJSON(check, primJSON("IF", nil, seqJSON(), seqJSON(failJSON))), nil
+}
+
+func expandCompare(prim string, annots []string, args []string) (string, error) {
+	if err := checkArgs(prim, args, 0); err != nil {
+		return "", err
+	}
+	return seqJSON(primJSON("COMPARE", nil), primJSON(strings.TrimPrefix(prim, "CMP"), annots)), nil
+}
+
+func expandIf(prim string, annots []string, args []string) (string, error) {
+	if err := checkArgs(prim, args, 2); err != nil {
+		return "", err
+	}
+	return seqJSON(primJSON(strings.TrimPrefix(prim, "IF"), annots), primJSON("IF", nil, args...)), nil
+}
+
+func expandIfCompare(prim string, annots []string, args []string) (string, error) {
+	if err := checkArgs(prim, args, 2); err != nil {
+		return "", err
+	}
+	compare := seqJSON(primJSON("COMPARE", nil), primJSON(strings.TrimPrefix(prim, "IFCMP"), annots))
+	return seqJSON(compare, primJSON("IF", nil, args...)), nil
+}
+
+func expandIfSome(prim string, annots []string, args []string) (string, error) {
+	if err := checkArgs(prim, args, 2); err != nil {
+		return "", err
+	}
+	instruction := "IF_NONE"
+	if prim == "IF_RIGHT" {
+		instruction = "IF_LEFT"
+	}
+	return seqJSON(primJSON(instruction, annots, args[1], args[0])), nil
+}
+
+func expandCadr(prim string, annots []string, args []string) (string, error) {
+	if err := checkArgs(prim, args, 0); err != nil {
+		return "", err
+	}
+	path := prim[1 : len(prim)-1]
+	items := make([]string, len(path))
+	for i := range path {
+		var itemAnnots []string
+		if i == len(path)-1 {
+			itemAnnots = annots
+		}
+		items[i] = primJSON(accessor(path[i]), itemAnnots)
+	}
+	return seqJSON(items...), nil
+}
+
+func accessor(letter byte) string {
+	if letter == 'A' {
+		return "CAR"
+	}
+	return "CDR"
+}
+
+func fieldAnnots(annots []string) []string {
+	fields := make([]string, 0)
+	for _, annot := range annots {
+		if strings.HasPrefix(annot, "%") {
+			fields = append(fields, annot)
+		}
+	}
+	return fields
+}
+
+func expandSetCadr(prim string, annots []string, args []string) (string, error) {
+	if err := checkArgs(prim, args, 0); err != nil {
+		return "", err
+	}
+	path := strings.TrimSuffix(strings.TrimPrefix(prim, "SET_C"), "R")
+	if len(path) > 1 {
+		inner := primJSON(fmt.Sprintf("SET_C%sR", path[1:]), annots)
+		if path[0] == 'A' {
+			return seqJSON(primJSON("DUP", nil), primJSON("DIP", nil, seqJSON(primJSON("CAR", nil), inner)), primJSON("CDR", nil), primJSON("SWAP", nil), primJSON("PAIR", nil)), nil
+		}
+		return seqJSON(primJSON("DUP", nil), primJSON("DIP", nil, seqJSON(primJSON("CDR", nil), inner)), primJSON("CAR", nil), primJSON("PAIR", nil)), nil
+	}
+
+	// field annotation checks the name of the replaced field
+	fields := fieldAnnots(annots)
+	var check []string
+	if len(fields) > 0 {
+		check = []string{primJSON("DUP", nil), primJSON(accessor(path[0]), fields[:1]), primJSON("DROP", nil)}
+	}
+	if path[0] == 'A' {
+		return seqJSON(append(check, primJSON("CDR", nil), primJSON("SWAP", nil), primJSON("PAIR", nil))...), nil
+	}
+	return seqJSON(append(check, primJSON("CAR", nil), primJSON("PAIR", nil))...), nil
+}
+
+func expandMapCadr(prim string, annots []string, args []string) (string, error) {
+	if err := checkArgs(prim, args, 1); err != nil {
+		return "", err
+	}
+	path := strings.TrimSuffix(strings.TrimPrefix(prim, "MAP_C"), "R")
+	if len(path) > 1 {
+		inner := primJSON(fmt.Sprintf("MAP_C%sR", path[1:]), annots, args[0])
+		if path[0] == 'A' {
+			return seqJSON(primJSON("DUP", nil), primJSON("DIP", nil, seqJSON(primJSON("CAR", nil), inner)), primJSON("CDR", nil), primJSON("SWAP", nil), primJSON("PAIR", nil)), nil
+		}
+		return seqJSON(primJSON("DUP", nil), primJSON("DIP", nil, seqJSON(primJSON("CDR", nil), inner)), primJSON("CAR", nil), primJSON("PAIR", nil)), nil
+	}
+
+	var pairAnnots []string
+	if fields := fieldAnnots(annots); len(fields) > 0 {
+		if path[0] == 'A' {
+			pairAnnots = []string{fields[0], "%@"}
+		} else {
+			pairAnnots = []string{"%@", fields[0]}
+		}
+	}
+	if path[0] == 'A' {
+		return seqJSON(primJSON("DUP", nil), primJSON("CDR", nil), primJSON("DIP", nil, seqJSON(primJSON("CAR", nil), args[0])), primJSON("SWAP", nil), primJSON("PAIR", pairAnnots)), nil
+	}
+	return seqJSON(primJSON("DUP", nil), primJSON("CDR", nil), args[0], primJSON("SWAP", nil), primJSON("CAR", nil), primJSON("PAIR", pairAnnots)), nil
+}
+
+func expandDip(prim string, annots []string, args []string) (string, error) {
+	if err := checkArgs(prim, args, 1); err != nil {
+		return "", err
+	}
+	return primJSON("DIP", annots, intJSON(len(prim)-2), args[0]), nil
+}
+
+func expandDup(prim string, annots []string, args []string) (string, error) {
+	if err := checkArgs(prim, args, 0); err != nil {
+		return "", err
+	}
+	depth := len(prim) - 2
+	return seqJSON(primJSON("DIP", nil, intJSON(depth-1), seqJSON(primJSON("DUP", annots))), primJSON("DIG", nil, intJSON(depth))), nil
+}
+
+// pairTree - structure of PAPPAIIR-like macros: `P` is a node, `A` is the left leaf and `I` is the right leaf
+type pairTree struct {
+	left, right *pairTree
+	annot       string
+	variable    string
+	extra       []string // type and variable annotations of the root PAIR
+}
+
+func (t *pairTree) isLeaf() bool {
+	return t.left == nil
+}
+
+func parsePairTree(prim string, start int) (*pairTree, error) {
+	var parse func(pos int, leaf byte) (*pairTree, int, error)
+	parse = func(pos int, leaf byte) (*pairTree, int, error) {
+		if pos >= len(prim)-1 {
+			return nil, pos, fmt.Errorf("Invalid macro: %s", prim)
+		}
+		switch prim[pos] {
+		case leaf:
+			return &pairTree{}, pos + 1, nil
+		case 'P':
+			left, next, err := parse(pos+1, 'A')
+			if err != nil {
+				return nil, next, err
+			}
+			right, next, err := parse(next, 'I')
+			if err != nil {
+				return nil, next, err
+			}
+			return &pairTree{left: left, right: right}, next, nil
+		default:
+			return nil, pos, fmt.Errorf("Invalid macro: %s", prim)
+		}
+	}
+	tree, next, err := parse(start, 0)
+	if err != nil {
+		return nil, err
+	}
+	if next != len(prim)-1 || tree.isLeaf() {
+		return nil, fmt.Errorf("Invalid macro: %s", prim)
+	}
+	return tree, nil
+}
+
+// leaves - leaves of the tree from left to right
+func (t *pairTree) leaves() []*pairTree {
+	if t.isLeaf() {
+		return []*pairTree{t}
+	}
+	return append(t.left.leaves(), t.right.leaves()...)
+}
+
+// setLeafAnnots - field and variable annotations are distributed to leaves from left to right
+func (t *pairTree) setLeafAnnots(annots []string) {
+	leaves := t.leaves()
+	var fields, variables int
+	for _, annot := range annots {
+		switch {
+		case strings.HasPrefix(annot, "%") && fields < len(leaves):
+			leaves[fields].annot = annot
+			fields++
+		case strings.HasPrefix(annot, "@") && variables < len(leaves):
+			leaves[variables].variable = annot
+			variables++
+		}
+	}
+}
+
+func (t *pairTree) leafAnnots() []string {
+	annots := make([]string, 0)
+	if t.variable != "" {
+		annots = append(annots, t.variable)
+	}
+	if t.annot != "" {
+		annots = append(annots, t.annot)
+	}
+	return annots
+}
+
+// pairAnnots - field annotations of PAIR are annotations of its leaves
+func (t *pairTree) pairAnnots() []string {
+	result := make([]string, 0)
+	for _, child := range []*pairTree{t.left, t.right} {
+		annot := "%"
+		if child.isLeaf() && child.annot != "" {
+			annot = child.annot
+		}
+		result = append(result, annot)
+	}
+	for len(result) > 0 && result[len(result)-1] == "%" {
+		result = result[:len(result)-1]
+	}
+	return result
+}
+
+func (t *pairTree) pair() []string {
+	items := make([]string, 0)
+	if !t.left.isLeaf() {
+		items = append(items, t.left.pair()...)
+	}
+	if !t.right.isLeaf() {
+		items = append(items, primJSON("DIP", nil, seqJSON(t.right.pair()...)))
+	}
+	return append(items, primJSON("PAIR", append(t.pairAnnots(), t.extra...)))
+}
+
+func (t *pairTree) unpair() []string {
+	var car, cdr []string
+	if t.left.isLeaf() {
+		car = t.left.leafAnnots()
+	}
+	if t.right.isLeaf() {
+		cdr = t.right.leafAnnots()
+	}
+	items := []string{
+		primJSON("DUP", nil),
+		primJSON("CAR", car),
+		primJSON("DIP", nil, seqJSON(primJSON("CDR", cdr))),
+	}
+	if !t.right.isLeaf() {
+		items = append(items, primJSON("DIP", nil, seqJSON(t.right.unpair()...)))
+	}
+	if !t.left.isLeaf() {
+		items = append(items, t.left.unpair()...)
+	}
+	return items
+}
+
+func expandPair(prim string, annots []string, args []string) (string, error) {
+	if err := checkArgs(prim, args, 0); err != nil {
+		return "", err
+	}
+	tree, err := parsePairTree(prim, 0)
+	if err != nil {
+		return "", err
+	}
+	tree.setLeafAnnots(fieldAnnots(annots))
+	for _, annot := range annots {
+		if !strings.HasPrefix(annot, "%") {
+			tree.extra = append(tree.extra, annot)
+		}
+	}
+	return seqJSON(tree.pair()...), nil
+}
+
+func expandUnpair(prim string, annots []string, args []string) (string, error) {
+	if err := checkArgs(prim, args, 0); err != nil {
+		return "", err
+	}
+	tree, err := parsePairTree(prim, 2)
+	if err != nil {
+		return "", err
+	}
+	tree.setLeafAnnots(annots)
+	return seqJSON(tree.unpair()...), nil
+}
@@ -9,7 +9,6 @@ import (
 	"github.com/baking-bad/bcdhub/internal/contractparser/node"
 	"github.com/baking-bad/bcdhub/internal/elastic"
 	"github.com/baking-bad/bcdhub/internal/helpers"
-	"github.com/baking-bad/bcdhub/internal/models"
 	"github.com/tidwall/gjson"
 )
 
@@ -331,13 +330,13 @@ func getNodeType(n internalNode, metadata Metadata) (string, []string) {
 }
 
 // GetContractMetadata -
-func GetContractMetadata(es *elastic.Elastic, address string) (*ContractMetadata, error) {
+func GetContractMetadata(es elastic.IElastic, address string) (*ContractMetadata, error) {
 	if address == "" {
 		return nil, fmt.Errorf("[GetContractMetadata] Empty address")
 	}
 
-	data := models.Metadata{ID: address}
-	if err := es.GetByID(&data); err != nil {
+	data, err := es.GetMetadata(address)
+	if err != nil {
 		return nil, err
 	}
 
@@ -365,13 +364,13 @@ func GetContractMetadata(es *elastic.Elastic, address string) (*ContractMetadata
 }
 
 // GetMetadata -
-func GetMetadata(es *elastic.Elastic, address, part, protocol string) (Metadata, error) {
+func GetMetadata(es elastic.IElastic, address, part, protocol string) (Metadata, error) {
 	if address == "" {
 		return nil, fmt.Errorf("[GetMetadata] Empty address")
 	}
 
-	data := models.Metadata{ID: address}
-	if err := es.GetByID(&data); err != nil {
+	data, err := es.GetMetadata(address)
+	if err != nil {
 		return nil, err
 	}
 
@@ -25,6 +25,17 @@ func (m Metadata) BuildEntrypointMicheline(binaryPath string, data map[string]in
 	return gjson.Parse(wrapped), nil
 }
 
+// BuildMicheline - builds Micheline value of the type located at `binaryPath`, e.g. initial storage of the contract
+func (m Metadata) BuildMicheline(binaryPath string, data map[string]interface{}) (gjson.Result, error) {
+	binaryPath = prepareData(binaryPath, data)
+
+	micheline, err := build(m, binaryPath, data)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	return gjson.Parse(micheline), nil
+}
+
 func prepareData(binaryPath string, data map[string]interface{}) string {
 	if strings.HasSuffix(binaryPath, "/o") { // Hack for high-level option
 		binaryPath = strings.TrimSuffix(binaryPath, "/o")
@@ -63,6 +74,11 @@ func build(metadata Metadata, path string, data map[string]interface{}) (string,
 		return optionBuilder(metadata, nm, path, data)
 	case consts.MAP:
 		return mapBuilder(metadata, nm, path, data)
+	case consts.BIGMAP:
+		if value, ok := data[path]; ok && value != nil && reflect.TypeOf(value).Kind() == reflect.Slice { // big map literal, e.g. in initial storage
+			return mapBuilder(metadata, nm, path, data)
+		}
+		return defaultBuilder(metadata, nm, path, data)
 	default:
 		return defaultBuilder(metadata, nm, path, data)
 	}
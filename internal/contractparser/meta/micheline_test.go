@@ -460,3 +460,74 @@ func TestMetadata_BuildEntrypointMicheline(t *testing.T) {
 		})
 	}
 }
+
+func TestMetadata_BuildMicheline(t *testing.T) {
+	tests := []struct {
+		name       string
+		metadata   string
+		binaryPath string
+		data       map[string]interface{}
+		want       string
+		wantErr    bool
+	}{
+		{
+			name:       "pair of big map and nat",
+			metadata:   `{"0":{"prim":"pair","args":["0/0","0/1"],"type":"namedtuple"},"0/0":{"fieldname":"ledger","prim":"big_map","type":"big_map","name":"ledger"},"0/0/k":{"prim":"address","type":"address"},"0/0/v":{"prim":"nat","type":"nat"},"0/1":{"fieldname":"total","prim":"nat","type":"nat","name":"total"}}`,
+			binaryPath: "0",
+			data: map[string]interface{}{
+				"0/0": []interface{}{},
+				"0/1": 100,
+			},
+			want: `{"prim": "Pair", "args":[[], {"int": "100"}]}`,
+		}, {
+			name:       "big map literal",
+			metadata:   `{"0":{"prim":"big_map","type":"big_map"},"0/k":{"prim":"address","type":"address"},"0/v":{"prim":"nat","type":"nat"}}`,
+			binaryPath: "0",
+			data: map[string]interface{}{
+				"0": []interface{}{
+					map[string]interface{}{"0/k": "tz1eLWfccL46VAUjtyz9kEKgzuKnwyZH4rTA", "0/v": 5},
+				},
+			},
+			want: `[{"prim": "Elt", "args":[{"string": "tz1eLWfccL46VAUjtyz9kEKgzuKnwyZH4rTA"}, {"int": "5"}]}]`,
+		}, {
+			name:       "big map pointer",
+			metadata:   `{"0":{"prim":"big_map","type":"big_map"},"0/k":{"prim":"address","type":"address"},"0/v":{"prim":"nat","type":"nat"}}`,
+			binaryPath: "0",
+			data: map[string]interface{}{
+				"0": 12,
+			},
+			want: `{"int": "12"}`,
+		}, {
+			name:       "missing field",
+			metadata:   `{"0":{"prim":"nat","type":"nat","name":"total"}}`,
+			binaryPath: "0",
+			data:       map[string]interface{}{},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var metadata Metadata
+			if err := json.Unmarshal([]byte(tt.metadata), &metadata); err != nil {
+				t.Errorf("Unmarshal error: %v", err)
+				return
+			}
+			got, err := metadata.BuildMicheline(tt.binaryPath, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Metadata.BuildMicheline() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			var want interface{}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Errorf("Unmarshal error: %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got.Value(), want) {
+				t.Errorf("Metadata.BuildMicheline() = %v, want %v", got.Value(), want)
+			}
+		})
+	}
+}
@@ -53,6 +53,11 @@ func (node *Node) Diff(prev *Node) {
 	node.compareChildren(prev)
 }
 
+// MarkDeleted - marks the node and all its children as deleted
+func (node *Node) MarkDeleted() {
+	node.setDiffType(delete)
+}
+
 func (node *Node) compareFields(second *Node) bool {
 	if second == nil {
 		return false
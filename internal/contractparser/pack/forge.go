@@ -0,0 +1,116 @@
+package pack
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Forge - encodes Micheline to the binary format of the protocol. Result of PACK is `05` followed by forged data.
+// Unlike `Micheline` nested nodes are encoded without the prefix, so it works with any expression.
+func Forge(node gjson.Result) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := forge(node, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func forge(node gjson.Result, buf *bytes.Buffer) error {
+	switch {
+	case node.IsArray():
+		var items bytes.Buffer
+		for _, item := range node.Array() {
+			if err := forge(item, &items); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(0x02)
+		buf.Write(packArrayWithLength(items.Bytes()))
+	case !node.IsObject():
+		return fmt.Errorf("[Forge] Invalid micheline: %s", node.Raw)
+	case node.Get("prim").Exists():
+		return forgePrim(node, buf)
+	case node.Get("int").Exists():
+		value, ok := new(big.Int).SetString(node.Get("int").String(), 10)
+		if !ok {
+			return fmt.Errorf("[Forge] Invalid int: %s", node.Get("int").String())
+		}
+		buf.WriteByte(0x00)
+		buf.Write(forgeInt(value))
+	case node.Get("string").Exists():
+		buf.WriteByte(0x01)
+		buf.Write(packArrayWithLength([]byte(node.Get("string").String())))
+	case node.Get("bytes").Exists():
+		data, err := hex.DecodeString(node.Get("bytes").String())
+		if err != nil {
+			return err
+		}
+		buf.WriteByte(0x0A)
+		buf.Write(packArrayWithLength(data))
+	default:
+		return fmt.Errorf("[Forge] Invalid micheline: %s", node.Raw)
+	}
+	return nil
+}
+
+func forgePrim(node gjson.Result, buf *bytes.Buffer) error {
+	prim := node.Get("prim").String()
+	tag, ok := primTags[prim]
+	if !ok {
+		return fmt.Errorf("[Forge] Unknown primitive: %s", prim)
+	}
+	args := node.Get("args").Array()
+	annots := make([]string, 0)
+	for _, annot := range node.Get("annots").Array() {
+		annots = append(annots, annot.String())
+	}
+
+	argsLen := len(args)
+	if argsLen > 3 {
+		argsLen = 3
+	}
+	buf.WriteByte(lenTags[argsLen][len(annots) > 0])
+	buf.WriteByte(tag)
+
+	var forgedArgs bytes.Buffer
+	for i := range args {
+		if err := forge(args[i], &forgedArgs); err != nil {
+			return err
+		}
+	}
+	if len(args) < 3 {
+		buf.Write(forgedArgs.Bytes())
+		if len(annots) > 0 {
+			buf.Write(packArrayWithLength([]byte(strings.Join(annots, " "))))
+		}
+		return nil
+	}
+	buf.Write(packArrayWithLength(forgedArgs.Bytes()))
+	buf.Write(packArrayWithLength([]byte(strings.Join(annots, " "))))
+	return nil
+}
+
+// forgeInt - zarith encoding: the first byte keeps the sign and 6 bits, next bytes keep 7 bits each
+func forgeInt(value *big.Int) []byte {
+	abs := new(big.Int).Abs(value)
+	mask := big.NewInt(0x7F)
+
+	first := byte(new(big.Int).And(abs, big.NewInt(0x3F)).Int64())
+	if value.Sign() < 0 {
+		first |= 0x40
+	}
+	abs.Rsh(abs, 6)
+
+	result := []byte{first}
+	for abs.Sign() > 0 {
+		result[len(result)-1] |= 0x80
+		result = append(result, byte(new(big.Int).And(abs, mask).Int64()))
+		abs.Rsh(abs, 7)
+	}
+	return result
+}
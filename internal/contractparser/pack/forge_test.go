@@ -0,0 +1,68 @@
+package pack
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestForge(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "int",
+			input:    `{"int": "505506"}`,
+			expected: "00a2da3d",
+		}, {
+			name:     "negative int",
+			input:    `{"int": "-1"}`,
+			expected: "0041",
+		}, {
+			name:     "int 64",
+			input:    `{"int": "64"}`,
+			expected: "008001",
+		}, {
+			name:     "unit",
+			input:    `{"prim": "Unit"}`,
+			expected: "030b",
+		}, {
+			name:     "pair",
+			input:    `{"prim": "Pair", "args": [{"int": "1"}, {"string": "a"}]}`,
+			expected: "070700010100000001" + "61",
+		}, {
+			name:     "sequence",
+			input:    `[{"int": "1"}, {"int": "2"}]`,
+			expected: "020000000400010002",
+		}, {
+			name:     "annotated type",
+			input:    `{"prim": "nat", "annots": [":a"]}`,
+			expected: "0462000000023a61",
+		}, {
+			name:     "bytes",
+			input:    `{"bytes": "00ff"}`,
+			expected: "0a0000000200ff",
+		}, {
+			name:    "unknown primitive",
+			input:   `{"prim": "UNKNOWN"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Forge(gjson.Parse(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Forge() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if fmt.Sprintf("%x", result) != tt.expected {
+				t.Errorf("Forge() = %x, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
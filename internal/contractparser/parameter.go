@@ -28,6 +28,7 @@ type Parameter struct {
 	Language    string
 	Tags        helpers.Set
 	Annotations helpers.Set
+	Interfaces  map[string]int64
 }
 
 func newParameter(v gjson.Result) (Parameter, error) {
@@ -51,11 +52,14 @@ func newParameter(v gjson.Result) (Parameter, error) {
 	}
 	p.Metadata = m
 
-	tags, err := endpointsTags(m)
+	interfaces, err := findInterfaces(m)
 	if err != nil {
 		return p, err
 	}
-	p.Tags.Append(tags...)
+	p.Interfaces = interfaces
+	for tag := range interfaces {
+		p.Tags.Append(tag)
+	}
 
 	return p, err
 }
@@ -52,7 +52,7 @@ func (p *parser) parse(v gjson.Result) error {
 }
 
 // MakeStorageParser -
-func MakeStorageParser(rpc noderpc.Pool, es *elastic.Elastic, protocol string) (parser storage.Parser, err error) {
+func MakeStorageParser(rpc noderpc.Pool, es elastic.IElastic, protocol string) (parser storage.Parser, err error) {
 	protoSymLink, err := meta.GetProtoSymLink(protocol)
 	if err != nil {
 		return nil, err
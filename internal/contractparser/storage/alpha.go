@@ -8,7 +8,6 @@ import (
 	"github.com/baking-bad/bcdhub/internal/contractparser/newmiguel"
 	"github.com/baking-bad/bcdhub/internal/contractparser/storage/hash"
 	"github.com/baking-bad/bcdhub/internal/contractparser/stringer"
-	"github.com/baking-bad/bcdhub/internal/helpers"
 	"github.com/baking-bad/bcdhub/internal/models"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
@@ -61,7 +60,7 @@ func (a *Alpha) ParseOrigination(content gjson.Result, metadata meta.Metadata, o
 				return RichStorage{Empty: true}, err
 			}
 			bmd = append(bmd, &models.BigMapDiff{
-				ID:          helpers.GenerateID(),
+				ID:          bigMapDiffID(operation.ID, "update", 0, keyHash),
 				BinPath:     "0/0",
 				Key:         item.Get("args.0").Value(),
 				KeyHash:     keyHash,
@@ -132,7 +131,7 @@ func (a *Alpha) getBigMapDiff(result gjson.Result, address string, m meta.Metada
 	bmd := make([]*models.BigMapDiff, 0)
 	for _, item := range result.Get("big_map_diff").Array() {
 		bmd = append(bmd, &models.BigMapDiff{
-			ID:          helpers.GenerateID(),
+			ID:          bigMapDiffID(operation.ID, "update", 0, item.Get("key_hash").String()),
 			BinPath:     "0/0",
 			Key:         item.Get("key").Value(),
 			KeyHash:     item.Get("key_hash").String(),
@@ -153,3 +152,8 @@ func (a *Alpha) getBigMapDiff(result gjson.Result, address string, m meta.Metada
 func (a *Alpha) SetUpdates(temp map[int64][]*models.BigMapDiff) {
 	return
 }
+
+// SetBigMaps -
+func (a *Alpha) SetBigMaps(bigMaps map[int64]*models.BigMap) {
+	return
+}
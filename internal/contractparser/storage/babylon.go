@@ -11,7 +11,6 @@ import (
 	"github.com/baking-bad/bcdhub/internal/contractparser/newmiguel"
 	"github.com/baking-bad/bcdhub/internal/contractparser/stringer"
 	"github.com/baking-bad/bcdhub/internal/elastic"
-	"github.com/baking-bad/bcdhub/internal/helpers"
 	"github.com/baking-bad/bcdhub/internal/models"
 	"github.com/baking-bad/bcdhub/internal/noderpc"
 	"github.com/tidwall/gjson"
@@ -21,18 +20,22 @@ import (
 // Babylon -
 type Babylon struct {
 	rpc noderpc.Pool
-	es  *elastic.Elastic
+	es  elastic.IElastic
 
 	updates map[int64][]*models.BigMapDiff
+	bigMaps map[int64]*models.BigMap
+
+	registry []*models.BigMap
 }
 
 // NewBabylon -
-func NewBabylon(rpc noderpc.Pool, es *elastic.Elastic) *Babylon {
+func NewBabylon(rpc noderpc.Pool, es elastic.IElastic) *Babylon {
 	return &Babylon{
 		rpc: rpc,
 		es:  es,
 
 		updates: make(map[int64][]*models.BigMapDiff),
+		bigMaps: make(map[int64]*models.BigMap),
 	}
 }
 
@@ -44,18 +47,20 @@ func (b *Babylon) ParseTransaction(content gjson.Result, metadata meta.Metadata,
 		return RichStorage{Empty: true}, err
 	}
 	var bm []*models.BigMapDiff
+	var registry []*models.BigMap
 	if result.Get("big_map_diff.#").Int() > 0 {
 		ptrMap, err := b.binPathToPtrMap(metadata, result.Get("storage"))
 		if err != nil {
 			return RichStorage{Empty: true}, err
 		}
 
-		if bm, err = b.handleBigMapDiff(result, ptrMap, address, operation); err != nil {
+		if bm, registry, err = b.handleBigMapDiff(result, ptrMap, address, operation); err != nil {
 			return RichStorage{Empty: true}, err
 		}
 	}
 	return RichStorage{
 		BigMapDiffs:     bm,
+		BigMaps:         registry,
 		DeffatedStorage: result.Get("storage").String(),
 	}, nil
 }
@@ -73,20 +78,23 @@ func (b *Babylon) ParseOrigination(content gjson.Result, metadata meta.Metadata,
 		return RichStorage{Empty: true}, err
 	}
 
+	ptrToBin, err := b.binPathToPtrMap(metadata, storage)
+	if err != nil {
+		return RichStorage{Empty: true}, err
+	}
+
 	var bm []*models.BigMapDiff
+	registry := make([]*models.BigMap, 0)
 	if result.Get("big_map_diff.#").Int() > 0 {
-		ptrToBin, err := b.binPathToPtrMap(metadata, storage)
-		if err != nil {
-			return RichStorage{Empty: true}, err
-		}
-
-		if bm, err = b.handleBigMapDiff(result, ptrToBin, address, operation); err != nil {
+		if bm, registry, err = b.handleBigMapDiff(result, ptrToBin, address, operation); err != nil {
 			return RichStorage{Empty: true}, err
 		}
 	}
+	registry = append(registry, b.adoptBigMaps(ptrToBin, address, registry)...)
 
 	return RichStorage{
 		BigMapDiffs:     bm,
+		BigMaps:         registry,
 		DeffatedStorage: storage.String(),
 	}, nil
 }
@@ -160,13 +168,16 @@ func (b *Babylon) Enrich(storage string, bmd []models.BigMapDiff, skipEmpty bool
 	return data, nil
 }
 
-func (b *Babylon) handleBigMapDiff(result gjson.Result, ptrMap map[int64]string, address string, operation models.Operation) ([]*models.BigMapDiff, error) {
+// handleBigMapDiff - returns big map diffs and registry entries of big maps which were allocated, copied or removed by the operation
+func (b *Babylon) handleBigMapDiff(result gjson.Result, ptrMap map[int64]string, address string, operation models.Operation) ([]*models.BigMapDiff, []*models.BigMap, error) {
 	bmd := make([]*models.BigMapDiff, 0)
+	b.registry = make([]*models.BigMap, 0)
 
 	handlers := map[string]func(gjson.Result, map[int64]string, string, models.Operation) ([]*models.BigMapDiff, error){
 		"update": b.handleBigMapDiffUpdate,
 		"copy":   b.handleBigMapDiffCopy,
 		"remove": b.handleBigMapDiffRemove,
+		"alloc":  b.handleBigMapDiffAlloc,
 	}
 
 	for _, item := range result.Get("big_map_diff").Array() {
@@ -177,20 +188,20 @@ func (b *Babylon) handleBigMapDiff(result gjson.Result, ptrMap map[int64]string,
 		}
 		data, err := handler(item, ptrMap, address, operation)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		if len(data) > 0 {
 			bmd = append(bmd, data...)
 		}
 	}
-	return bmd, nil
+	return bmd, b.registry, nil
 }
 
 func (b *Babylon) handleBigMapDiffUpdate(item gjson.Result, ptrMap map[int64]string, address string, operation models.Operation) ([]*models.BigMapDiff, error) {
 	ptr := item.Get("big_map").Int()
 
 	bmd := models.BigMapDiff{
-		ID:          helpers.GenerateID(),
+		ID:          bigMapDiffID(operation.ID, "update", ptr, item.Get("key_hash").String()),
 		Ptr:         ptr,
 		Key:         item.Get("key").Value(),
 		KeyHash:     item.Get("key_hash").String(),
@@ -222,6 +233,10 @@ func (b *Babylon) handleBigMapDiffCopy(item gjson.Result, ptrMap map[int64]strin
 	sourcePtr := item.Get("source_big_map").Int()
 	destinationPtr := item.Get("destination_big_map").Int()
 
+	if err := b.copyBigMap(sourcePtr, destinationPtr, ptrMap, address, operation); err != nil {
+		return nil, err
+	}
+
 	if sourcePtr >= 0 {
 		bmd, err := b.es.GetAllBigMapDiffByPtr(address, operation.Network, sourcePtr)
 		if err != nil {
@@ -238,7 +253,7 @@ func (b *Babylon) handleBigMapDiffCopy(item gjson.Result, ptrMap map[int64]strin
 
 		newUpdates := make([]*models.BigMapDiff, len(bmd))
 		for i := range bmd {
-			bmd[i].ID = helpers.GenerateID()
+			bmd[i].ID = bigMapDiffID(operation.ID, "copy", destinationPtr, bmd[i].KeyHash)
 			bmd[i].OperationID = operation.ID
 			bmd[i].Level = operation.Level
 			bmd[i].IndexedTime = time.Now().UnixNano() / 1000
@@ -272,7 +287,7 @@ func (b *Babylon) handleBigMapDiffCopy(item gjson.Result, ptrMap map[int64]strin
 
 		newUpdates := make([]*models.BigMapDiff, len(bmd))
 		for i := range bmd {
-			bmd[i].ID = helpers.GenerateID()
+			bmd[i].ID = bigMapDiffID(operation.ID, "copy", destinationPtr, bmd[i].KeyHash)
 			bmd[i].Ptr = destinationPtr
 			bmd[i].Address = address
 			bmd[i].Level = operation.Level
@@ -297,6 +312,9 @@ func (b *Babylon) handleBigMapDiffCopy(item gjson.Result, ptrMap map[int64]strin
 
 func (b *Babylon) handleBigMapDiffRemove(item gjson.Result, ptrMap map[int64]string, address string, operation models.Operation) ([]*models.BigMapDiff, error) {
 	ptr := item.Get("big_map").Int()
+	if err := b.removeBigMap(ptr, address, operation); err != nil {
+		return nil, err
+	}
 	if ptr < 0 {
 		delete(b.updates, ptr)
 		return nil, nil
@@ -307,7 +325,7 @@ func (b *Babylon) handleBigMapDiffRemove(item gjson.Result, ptrMap map[int64]str
 	}
 	newUpdates := make([]*models.BigMapDiff, len(bmd))
 	for i := range bmd {
-		bmd[i].ID = helpers.GenerateID()
+		bmd[i].ID = bigMapDiffID(operation.ID, "remove", ptr, bmd[i].KeyHash)
 		bmd[i].OperationID = operation.ID
 		bmd[i].Level = operation.Level
 		bmd[i].IndexedTime = time.Now().UnixNano() / 1000
@@ -320,6 +338,148 @@ func (b *Babylon) handleBigMapDiffRemove(item gjson.Result, ptrMap map[int64]str
 	return newUpdates, nil
 }
 
+func (b *Babylon) handleBigMapDiffAlloc(item gjson.Result, ptrMap map[int64]string, address string, operation models.Operation) ([]*models.BigMapDiff, error) {
+	ptr := item.Get("big_map").Int()
+	if _, ok := b.updates[ptr]; !ok {
+		b.updates[ptr] = []*models.BigMapDiff{}
+	}
+
+	bigMap := b.newBigMap(ptr, ptrMap, address, operation)
+	bigMap.KeyType = item.Get("key_type").String()
+	bigMap.ValueType = item.Get("value_type").String()
+	b.register(bigMap)
+	return nil, nil
+}
+
+// newBigMap - the big map is owned by `address` only if its storage keeps the pointer.
+// Otherwise the big map belongs to a contract originated in the operation group and the origination adopts it.
+func (b *Babylon) newBigMap(ptr int64, ptrMap map[int64]string, address string, operation models.Operation) *models.BigMap {
+	bigMap := &models.BigMap{
+		ID:          models.BigMapID(operation.Network, ptr),
+		Network:     operation.Network,
+		Ptr:         ptr,
+		OperationID: operation.ID,
+		Level:       operation.Level,
+		Timestamp:   operation.Timestamp,
+		Protocol:    operation.Protocol,
+	}
+	if binPath, ok := ptrMap[ptr]; ok {
+		bigMap.Address = address
+		bigMap.BinPath = binPath
+	}
+	return bigMap
+}
+
+// adoptBigMaps - sets the originated contract as the owner of its big maps registered earlier in the operation group,
+// e.g. copied by the caller of `CREATE_CONTRACT`. Returns adopted big maps which are not in `registry` already.
+func (b *Babylon) adoptBigMaps(ptrMap map[int64]string, address string, registry []*models.BigMap) []*models.BigMap {
+	adopted := make([]*models.BigMap, 0)
+	for ptr, binPath := range ptrMap {
+		bigMap, ok := b.bigMaps[ptr]
+		if !ok || bigMap.Address == address {
+			continue
+		}
+		bigMap.Address = address
+		bigMap.BinPath = binPath
+
+		registered := false
+		for i := range registry {
+			registered = registered || registry[i].Ptr == ptr
+		}
+		if !registered {
+			adopted = append(adopted, bigMap)
+		}
+	}
+	return adopted
+}
+
+// copyBigMap - registers the destination big map. The copy of the temporary big map keeps the lineage of the temporary one.
+func (b *Babylon) copyBigMap(sourcePtr, destinationPtr int64, ptrMap map[int64]string, address string, operation models.Operation) error {
+	source, err := b.getBigMap(operation.Network, sourcePtr)
+	if err != nil {
+		return err
+	}
+
+	bigMap := b.newBigMap(destinationPtr, ptrMap, address, operation)
+	if allocated, ok := b.bigMaps[destinationPtr]; ok {
+		bigMap.KeyType = allocated.KeyType
+		bigMap.ValueType = allocated.ValueType
+	}
+	if source != nil {
+		bigMap.KeyType = source.KeyType
+		bigMap.ValueType = source.ValueType
+	}
+
+	if sourcePtr >= 0 {
+		bigMap.SourcePtr = &sourcePtr
+	} else {
+		bigMap.Temporary = sourcePtr
+		if source != nil {
+			bigMap.SourcePtr = source.SourcePtr
+		}
+	}
+	b.register(bigMap)
+	return nil
+}
+
+func (b *Babylon) removeBigMap(ptr int64, address string, operation models.Operation) error {
+	if ptr < 0 {
+		delete(b.bigMaps, ptr)
+		return nil
+	}
+
+	bigMap, err := b.getBigMap(operation.Network, ptr)
+	if err != nil {
+		return err
+	}
+	if bigMap == nil {
+		bigMap = &models.BigMap{
+			ID:      models.BigMapID(operation.Network, ptr),
+			Network: operation.Network,
+			Ptr:     ptr,
+			Address: address,
+		}
+	}
+	bigMap.RemovedOperationID = operation.ID
+	bigMap.RemovedLevel = operation.Level
+	b.register(bigMap)
+	return nil
+}
+
+// getBigMap - returns the registry entry known in the current operation group or the stored one. Returns nil if the big map is unknown.
+func (b *Babylon) getBigMap(network string, ptr int64) (*models.BigMap, error) {
+	if bigMap, ok := b.bigMaps[ptr]; ok {
+		return bigMap, nil
+	}
+	if ptr < 0 || b.es == nil {
+		return nil, nil
+	}
+
+	bigMap := models.BigMap{ID: models.BigMapID(network, ptr)}
+	if err := b.es.GetByID(&bigMap); err != nil {
+		if elastic.IsRecordNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &bigMap, nil
+}
+
+// register - temporary big maps live only in the operation group and are not stored
+func (b *Babylon) register(bigMap *models.BigMap) {
+	b.bigMaps[bigMap.Ptr] = bigMap
+	if bigMap.Ptr < 0 {
+		return
+	}
+	for i := range b.registry {
+		if b.registry[i].Ptr == bigMap.Ptr {
+			b.registry[i] = bigMap
+			return
+		}
+	}
+	b.registry = append(b.registry, bigMap)
+}
+
 func (b *Babylon) addToUpdates(bmd *models.BigMapDiff, ptr int64) {
 	if arr, ok := b.updates[bmd.Ptr]; !ok {
 		b.updates[bmd.Ptr] = []*models.BigMapDiff{bmd}
@@ -339,6 +499,21 @@ func (b *Babylon) addToUpdates(bmd *models.BigMapDiff, ptr int64) {
 	}
 }
 
+// FindBigMapPointers - returns pointers of big maps in the Babylon storage by their binary paths
+func FindBigMapPointers(m meta.Metadata, storage gjson.Result) (map[int64]string, error) {
+	var b Babylon
+	return b.binPathToPtrMap(m, storage)
+}
+
+// PointerJSONPath - returns GJSON path of big map pointer `ptr` located at `binPath` in the Babylon storage. Empty path is the storage itself.
+func PointerJSONPath(storage gjson.Result, ptr int64, binPath string) (string, error) {
+	if binPath == "0" {
+		return "", nil
+	}
+	var b Babylon
+	return b.findPtrJSONPath(ptr, newmiguel.GetGJSONPath(strings.TrimPrefix(binPath, "0/")), storage)
+}
+
 func (b *Babylon) binPathToPtrMap(m meta.Metadata, storage gjson.Result) (map[int64]string, error) {
 	key := make(map[int64]string)
 	keyInt := storage.Get("int")
@@ -476,3 +651,8 @@ func (b *Babylon) findPtrJSONPath(ptr int64, path string, data gjson.Result) (st
 func (b *Babylon) SetUpdates(temp map[int64][]*models.BigMapDiff) {
 	b.updates = temp
 }
+
+// SetBigMaps - sets big maps allocated, copied or removed in the current operation group
+func (b *Babylon) SetBigMaps(bigMaps map[int64]*models.BigMap) {
+	b.bigMaps = bigMaps
+}
@@ -2,8 +2,12 @@ package storage
 
 import (
 	"log"
+	"reflect"
 	"testing"
 
+	"github.com/baking-bad/bcdhub/internal/contractparser/meta"
+	"github.com/baking-bad/bcdhub/internal/elastic/memory"
+	"github.com/baking-bad/bcdhub/internal/models"
 	"github.com/tidwall/gjson"
 )
 
@@ -85,3 +89,134 @@ func TestBabylon_findPtrJSONPath(t *testing.T) {
 		})
 	}
 }
+
+func TestFindBigMapPointers(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata string
+		storage  string
+		want     map[int64]string
+	}{
+		{
+			name:     "root big map",
+			metadata: `{"prim":"big_map","args":[{"prim":"address"},{"prim":"nat"}]}`,
+			storage:  `{"int":"17"}`,
+			want:     map[int64]string{17: "0"},
+		}, {
+			name:     "big maps in pair",
+			metadata: `{"prim":"pair","args":[{"prim":"big_map","args":[{"prim":"address"},{"prim":"nat"}]},{"prim":"pair","args":[{"prim":"address"},{"prim":"big_map","args":[{"prim":"nat"},{"prim":"bytes"}]}]}]}`,
+			storage:  `{"prim":"Pair","args":[{"int":"5"},{"prim":"Pair","args":[{"string":"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx"},{"int":"6"}]}]}`,
+			want:     map[int64]string{5: "0/0", 6: "0/1/1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := meta.ParseMetadata(gjson.Parse(tt.metadata))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := FindBigMapPointers(m, gjson.Parse(tt.storage))
+			if err != nil {
+				t.Errorf("FindBigMapPointers() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindBigMapPointers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBabylon_handleBigMapDiffRegistry(t *testing.T) {
+	es := memory.New()
+	if err := es.CreateIndexes(); err != nil {
+		t.Fatalf("CreateIndexes error: %v", err)
+	}
+	b := NewBabylon(nil, es)
+
+	result := gjson.Parse(`{"big_map_diff":[
+		{"action":"alloc","big_map":"-1","key_type":{"prim":"address"},"value_type":{"prim":"nat"}},
+		{"action":"copy","source_big_map":"-1","destination_big_map":"7"},
+		{"action":"copy","source_big_map":"7","destination_big_map":"8"},
+		{"action":"remove","big_map":"5"}
+	]}`)
+	operation := models.Operation{ID: "op", Network: "mainnet", Level: 10}
+	_, registry, err := b.handleBigMapDiff(result, map[int64]string{7: "0/0", 8: "0/1"}, "KT1A", operation)
+	if err != nil {
+		t.Errorf("handleBigMapDiff error: %v", err)
+		return
+	}
+
+	source := int64(7)
+	want := []*models.BigMap{
+		{
+			ID: models.BigMapID("mainnet", 7), Network: "mainnet", Ptr: 7, Address: "KT1A", BinPath: "0/0", OperationID: "op", Level: 10,
+			KeyType: `{"prim":"address"}`, ValueType: `{"prim":"nat"}`, Temporary: -1,
+		},
+		{
+			ID: models.BigMapID("mainnet", 8), Network: "mainnet", Ptr: 8, Address: "KT1A", BinPath: "0/1", OperationID: "op", Level: 10,
+			KeyType: `{"prim":"address"}`, ValueType: `{"prim":"nat"}`, SourcePtr: &source,
+		},
+		{
+			ID: models.BigMapID("mainnet", 5), Network: "mainnet", Ptr: 5, Address: "KT1A", RemovedOperationID: "op", RemovedLevel: 10,
+		},
+	}
+	if !reflect.DeepEqual(registry, want) {
+		for i := range registry {
+			t.Logf("got %+v", *registry[i])
+		}
+		t.Errorf("handleBigMapDiff registry mismatch")
+	}
+}
+
+func TestBabylon_adoptBigMaps(t *testing.T) {
+	b := NewBabylon(nil, nil)
+
+	result := gjson.Parse(`{"big_map_diff":[
+		{"action":"alloc","big_map":"9","key_type":{"prim":"string"},"value_type":{"prim":"bytes"}}
+	]}`)
+	operation := models.Operation{ID: "op", Network: "mainnet", Level: 10}
+	_, registry, err := b.handleBigMapDiff(result, map[int64]string{7: "0"}, "KT1A", operation)
+	if err != nil {
+		t.Errorf("handleBigMapDiff error: %v", err)
+		return
+	}
+	if len(registry) != 1 || registry[0].Address != "" {
+		t.Errorf("big map of the originated contract must not be owned by the caller: %v", registry)
+		return
+	}
+
+	if adopted := b.adoptBigMaps(map[int64]string{9: "0/1"}, "KT1B", nil); len(adopted) != 1 {
+		t.Errorf("adoptBigMaps got %d big maps", len(adopted))
+		return
+	}
+	if registry[0].Address != "KT1B" || registry[0].BinPath != "0/1" {
+		t.Errorf("adoptBigMaps owner got %s %s", registry[0].Address, registry[0].BinPath)
+	}
+}
+
+func TestPointerJSONPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		storage string
+		ptr     int64
+		binPath string
+		want    string
+	}{
+		{"root", `{"int":"12"}`, 12, "0", ""},
+		{"pair", `{"prim":"Pair","args":[{"int":"12"},{"prim":"Unit"}]}`, 12, "0/0", "args.0"},
+		{"nested pair", `{"prim":"Pair","args":[{"prim":"Unit"},{"prim":"Pair","args":[{"string":"a"},{"int":"13"}]}]}`, 13, "0/1/1", "args.1.args.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PointerJSONPath(gjson.Parse(tt.storage), tt.ptr, tt.binPath)
+			if err != nil {
+				t.Errorf("PointerJSONPath error: %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("PointerJSONPath got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"fmt"
 
+	"github.com/baking-bad/bcdhub/internal/helpers"
 	"github.com/tidwall/gjson"
 )
 
@@ -16,3 +17,8 @@ func getResult(op gjson.Result) (gjson.Result, error) {
 	}
 	return result, nil
 }
+
+// bigMapDiffID - diff is identified by its operation, action, pointer and key hash, so re-indexing the same level produces the same IDs
+func bigMapDiffID(operationID, action string, ptr int64, keyHash string) string {
+	return helpers.GenerateIDFrom(operationID, action, ptr, keyHash)
+}
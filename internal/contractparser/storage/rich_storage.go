@@ -11,6 +11,7 @@ import (
 type RichStorage struct {
 	DeffatedStorage string
 	BigMapDiffs     []*models.BigMapDiff
+	BigMaps         []*models.BigMap
 	Empty           bool
 }
 
@@ -23,6 +24,15 @@ func (r RichStorage) GetBigMapDiffModels() []elastic.Model {
 	return result
 }
 
+// GetBigMapModels - returns registry entries of big maps changed by the operation
+func (r RichStorage) GetBigMapModels() []elastic.Model {
+	result := make([]elastic.Model, len(r.BigMaps))
+	for i := range r.BigMaps {
+		result[i] = r.BigMaps[i]
+	}
+	return result
+}
+
 // Parser -
 type Parser interface {
 	ParseTransaction(content gjson.Result, metadata meta.Metadata, operation models.Operation) (RichStorage, error)
@@ -30,4 +40,5 @@ type Parser interface {
 	Enrich(string, []models.BigMapDiff, bool) (gjson.Result, error)
 
 	SetUpdates(map[int64][]*models.BigMapDiff)
+	SetBigMaps(map[int64]*models.BigMap)
 }
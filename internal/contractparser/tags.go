@@ -2,18 +2,13 @@ package contractparser
 
 import (
 	"fmt"
-	"io/ioutil"
-	"strings"
 
 	"github.com/baking-bad/bcdhub/internal/contractparser/meta"
 
 	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
 	"github.com/baking-bad/bcdhub/internal/contractparser/node"
-	"github.com/baking-bad/bcdhub/internal/jsonload"
 )
 
-var interfaces = map[string][]Entrypoint{}
-
 func primTags(node node.Node) string {
 	switch node.Prim {
 	case consts.CREATECONTRACT:
@@ -28,39 +23,6 @@ func primTags(node node.Node) string {
 	return ""
 }
 
-func loadInterfaces() error {
-	files, err := ioutil.ReadDir("interfaces/")
-	if err != nil {
-		return err
-	}
-
-	for _, f := range files {
-		path := fmt.Sprintf("interfaces/%s", f.Name())
-		var e []Entrypoint
-		if err := jsonload.StructFromFile(path, &e); err != nil {
-			return err
-		}
-		name := strings.Split(f.Name(), ".")[0]
-		interfaces[name] = e
-	}
-	return nil
-}
-
-func endpointsTags(metadata meta.Metadata) ([]string, error) {
-	if len(interfaces) == 0 {
-		if err := loadInterfaces(); err != nil {
-			return nil, err
-		}
-	}
-	res := make([]string, 0)
-	for tag, i := range interfaces {
-		if findInterface(metadata, i) {
-			res = append(res, tag)
-		}
-	}
-	return res, nil
-}
-
 func findInterface(metadata meta.Metadata, i []Entrypoint) bool {
 	root := metadata["0"]
 
@@ -90,7 +52,7 @@ func compareEntrypoints(metadata meta.Metadata, in Entrypoint, en meta.NodeMetad
 	}
 
 	for i, inArg := range in.Args {
-		enPath := fmt.Sprintf("%s/%d", path, i)
+		enPath := argPath(path, in.Prim, i)
 		enMeta, ok := metadata[enPath]
 		if !ok {
 			return false
@@ -102,3 +64,22 @@ func compareEntrypoints(metadata meta.Metadata, in Entrypoint, en meta.NodeMetad
 
 	return true
 }
+
+// argPath - path of `idx` argument in metadata. Arguments of containers and options have special suffixes.
+func argPath(path, prim string, idx int) string {
+	switch prim {
+	case consts.LIST:
+		return path + "/l"
+	case consts.SET:
+		return path + "/s"
+	case consts.OPTION:
+		return path + "/o"
+	case consts.MAP, consts.BIGMAP:
+		if idx == 0 {
+			return path + "/k"
+		}
+		return path + "/v"
+	default:
+		return fmt.Sprintf("%s/%d", path, idx)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"testing"
 
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
 	"github.com/tidwall/gjson"
 )
 
@@ -69,3 +70,47 @@ func TestTagFA12(t *testing.T) {
 	}
 
 }
+
+func TestTagFA2(t *testing.T) {
+	testCases := []struct {
+		name string
+		path string
+		res  bool
+	}{
+		{
+			name: "TZIP-12",
+			path: "testdata/tags/fa2-tzip12.json",
+			res:  true,
+		},
+		{
+			name: "FA1.2 transfer [wrong]",
+			path: "testdata/tags/fa2-wrong-transfer.json",
+			res:  false,
+		},
+		{
+			name: "babylonnet/KT1Q3XGrpbqhF6ny4qLhuiKekmk86hiAnmhh [FA1.2]",
+			path: "testdata/tags/fa12-babylonnet-KT1Q3XGrpbqhF6ny4qLhuiKekmk86hiAnmhh.json",
+			res:  false,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := ioutil.ReadFile(tt.path)
+			if err != nil {
+				t.Errorf("ioutil.ReadFile %v error %v", tt.path, err)
+				return
+			}
+
+			p, err := newParameter(gjson.ParseBytes(file))
+			if err != nil {
+				t.Errorf("newParameter error %v", err)
+				return
+			}
+
+			if _, ok := p.Tags[consts.FA2Tag]; tt.res != ok {
+				t.Errorf("Wrong res. Got: %v", p.Tags)
+			}
+		})
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"net/http"
 
 	"github.com/elastic/go-elasticsearch/v8/esapi"
 )
@@ -58,3 +60,29 @@ func (e *Elastic) AddDocumentWithID(v interface{}, index, docID string) (string,
 	}
 	return r.Get("_id").String(), nil
 }
+
+// CreateDocument - saves the document only if there is no document with the same ID
+func (e *Elastic) CreateDocument(item Model) error {
+	b, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	req := esapi.CreateRequest{
+		Index:      item.GetIndex(),
+		Body:       bytes.NewReader(b),
+		Refresh:    "true",
+		DocumentID: item.GetID(),
+	}
+
+	res, err := req.Do(context.Background(), e)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusConflict {
+		return fmt.Errorf("%s: %s %s", DocumentExists, item.GetIndex(), item.GetID())
+	}
+	_, err = e.getResponse(res)
+	return err
+}
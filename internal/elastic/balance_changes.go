@@ -0,0 +1,49 @@
+package elastic
+
+import (
+	"reflect"
+
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+// GetBalanceChanges - returns all balance changes of the contract in order of levels
+func (e *Elastic) GetBalanceChanges(network, address string) ([]models.BalanceChange, error) {
+	changes := make([]models.BalanceChange, 0)
+	query := newQuery().Query(
+		boolQ(
+			filter(
+				matchPhrase("network", network),
+				matchPhrase("address", address),
+			),
+		),
+	).Sort("level", "asc")
+	if err := e.getByScroll(DocBalanceChanges, query, reflect.TypeOf(models.BalanceChange{}), &changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// GetBalanceAt - returns sum of balance changes of the contract up to `level` inclusive
+func (e *Elastic) GetBalanceAt(network, address string, level int64) (int64, error) {
+	query := newQuery().Query(
+		boolQ(
+			filter(
+				matchPhrase("network", network),
+				matchPhrase("address", address),
+				rangeQ("level", qItem{"lte": level}),
+			),
+		),
+	).Add(
+		qItem{
+			"aggs": qItem{
+				"balance": sum("change"),
+			},
+		},
+	).Zero()
+
+	res, err := e.query([]string{DocBalanceChanges}, query)
+	if err != nil {
+		return 0, err
+	}
+	return res.Get("aggregations.balance.value").Int(), nil
+}
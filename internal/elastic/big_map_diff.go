@@ -7,37 +7,13 @@ import (
 
 // GetUniqueBigMapDiffsByOperationID -
 func (e *Elastic) GetUniqueBigMapDiffsByOperationID(operationID string) ([]models.BigMapDiff, error) {
-	query := newQuery().
-		Query(
-			boolQ(
-				filter(
-					matchPhrase("operation_id", operationID),
-				),
+	return e.getLastBigMapDiffs(
+		boolQ(
+			filter(
+				matchPhrase("operation_id", operationID),
 			),
-		).Add(aggs(
-		"keys", qItem{
-			"terms": qItem{
-				"field": "key_hash.keyword",
-				"size":  maxQuerySize,
-			},
-			"aggs": qItem{
-				"top_key": topHits(1, "indexed_time", "desc"),
-			},
-		},
-	)).Zero()
-
-	res, err := e.query([]string{DocBigMapDiff}, query)
-	if err != nil {
-		return nil, err
-	}
-	response := make([]models.BigMapDiff, 0)
-	for _, item := range res.Get("aggregations.keys.buckets").Array() {
-		bmd := item.Get("top_key.hits.hits.0")
-		var b models.BigMapDiff
-		b.ParseElasticJSON(bmd)
-		response = append(response, b)
-	}
-	return response, nil
+		),
+	)
 }
 
 // GetPrevBigMapDiffs -
@@ -49,76 +25,34 @@ func (e *Elastic) GetPrevBigMapDiffs(filters []models.BigMapDiff, indexedTime in
 			matchPhrase("bin_path", filters[i].BinPath),
 		))
 	}
-	b := boolQ(
-		should(shouldData...),
-		filter(
-			matchPhrase("address", address),
-			rangeQ("indexed_time", qItem{"lt": indexedTime}),
+	return e.getLastBigMapDiffs(
+		boolQ(
+			should(shouldData...),
+			filter(
+				matchPhrase("address", address),
+				rangeQ("indexed_time", qItem{"lt": indexedTime}),
+			),
+			minimumShouldMatch(1),
 		),
-		minimumShouldMatch(1),
 	)
-
-	query := newQuery().Query(b).
-		Add(aggs(
-			"keys", qItem{
-				"terms": qItem{
-					"field": "key_hash.keyword",
-					"size":  maxQuerySize,
-				},
-				"aggs": qItem{
-					"top_key": topHits(1, "indexed_time", "desc"),
-				},
-			},
-		)).
-		Sort("indexed_time", "desc").Zero()
-
-	res, err := e.query([]string{DocBigMapDiff}, query)
-	if err != nil {
-		return nil, err
-	}
-
-	response := make([]models.BigMapDiff, 0)
-	for _, item := range res.Get("aggregations.keys.buckets").Array() {
-		bmd := item.Get("top_key.hits.hits.0")
-		var b models.BigMapDiff
-		b.ParseElasticJSON(bmd)
-		response = append(response, b)
-	}
-	return response, nil
 }
 
 // GetBigMapDiffsForAddress -
-func (e *Elastic) GetBigMapDiffsForAddress(address string) ([]models.BigMapDiff, error) {
-	query := newQuery().Query(
-		boolQ(
-			must(
-				matchPhrase("address", address),
-			),
-		),
-	).Add(aggs(
-		"keys", qItem{
-			"terms": qItem{
-				"field": "key_hash.keyword",
-				"size":  maxQuerySize, // TODO: arbitrary number of keys
-			},
-			"aggs": qItem{
-				"top_key": topHits(1, "indexed_time", "desc"),
-			},
-		},
-	)).Zero()
+func (e *Elastic) GetBigMapDiffsForAddress(network, address string) ([]models.BigMapDiff, error) {
+	return e.GetBigMapDiffsForAddressAtLevel(network, address, 0)
+}
 
-	res, err := e.query([]string{DocBigMapDiff}, query)
-	if err != nil {
-		return nil, err
+// GetBigMapDiffsForAddressAtLevel - returns the last diff of every key of every big map at or below `level`. Level 0 is the head.
+func (e *Elastic) GetBigMapDiffsForAddressAtLevel(network, address string, level int64) ([]models.BigMapDiff, error) {
+	filters := []qItem{
+		matchQ("network", network),
+		matchPhrase("address", address),
 	}
-	response := make([]models.BigMapDiff, 0)
-	for _, item := range res.Get("aggregations.keys.buckets").Array() {
-		bmd := item.Get("top_key.hits.hits.0")
-		var b models.BigMapDiff
-		b.ParseElasticJSON(bmd)
-		response = append(response, b)
+	if level > 0 {
+		filters = append(filters, rangeQ("level", qItem{"lte": level}))
 	}
-	return response, nil
+
+	return e.getLastBigMapDiffs(boolQ(filter(filters...)))
 }
 
 // GetBigMap -
@@ -149,7 +83,7 @@ func (e *Elastic) GetBigMap(address string, ptr int64, searchText string, size,
 	}
 
 	if size == 0 {
-		size = defaultSize
+		size = DefaultSize
 	}
 
 	to := size + offset
@@ -219,7 +153,7 @@ func (e *Elastic) GetBigMapDiffByPtrAndKeyHash(address string, ptr int64, keyHas
 	}
 
 	if size == 0 {
-		size = defaultSize
+		size = DefaultSize
 	}
 
 	query := newQuery().Query(b).Sort("level", "desc").Size(size).From(offset)
@@ -259,9 +193,7 @@ func (e *Elastic) GetBigMapDiffsJSONByOperationID(operationID string) ([]gjson.R
 
 // GetAllBigMapDiffByPtr -
 func (e *Elastic) GetAllBigMapDiffByPtr(address, network string, ptr int64) ([]models.BigMapDiff, error) {
-	bmd := make([]models.BigMapDiff, 0)
-
-	query := newQuery().Query(
+	return e.getLastBigMapDiffs(
 		boolQ(
 			filter(
 				matchQ("network", network),
@@ -269,28 +201,380 @@ func (e *Elastic) GetAllBigMapDiffByPtr(address, network string, ptr int64) ([]m
 				term("ptr", ptr),
 			),
 		),
+	)
+}
+
+// bigMapKeysPageSize - count of keys requested by one page of the composite aggregation
+const bigMapKeysPageSize = 1000
+
+// getLastBigMapDiffs - returns the last diff of every key matched by `query`
+func (e *Elastic) getLastBigMapDiffs(query qItem) ([]models.BigMapDiff, error) {
+	response := make([]models.BigMapDiff, 0)
+	err := e.eachLastBigMapDiff(query, func(hit gjson.Result, count int64) error {
+		var b models.BigMapDiff
+		b.ParseElasticJSON(hit)
+		response = append(response, b)
+		return nil
+	})
+	return response, err
+}
+
+// eachLastBigMapDiff - calls `handler` with the last diff of every key matched by `query` and the count of the key diffs
+func (e *Elastic) eachLastBigMapDiff(query qItem, handler func(hit gjson.Result, count int64) error) error {
+	return e.eachBigMapKeyBucket(query, qItem{
+		"top_key": topHits(1, "indexed_time", "desc"),
+	}, func(bucket gjson.Result) error {
+		return handler(bucket.Get("top_key.hits.hits.0"), bucket.Get("doc_count").Int())
+	})
+}
+
+// eachBigMapKeyBucket - calls `handler` with the bucket of every key matched by `query`. Buckets contain `subAggs`.
+// Keys are grouped by pointer and key hash.
+func (e *Elastic) eachBigMapKeyBucket(query, subAggs qItem, handler func(bucket gjson.Result) error) error {
+	return e.eachCompositeBucket(DocBigMapDiff, query, qList{
+		qItem{"ptr": qItem{"terms": qItem{"field": "ptr", "missing_bucket": true}}},
+		qItem{"key_hash": qItem{"terms": qItem{"field": "key_hash.keyword"}}},
+	}, subAggs, handler)
+}
+
+// eachCompositeBucket - calls `handler` with every bucket of the composite aggregation by `sources`. Buckets contain `subAggs`.
+// Buckets are paged by `bigMapKeysPageSize`, so their count is not limited.
+func (e *Elastic) eachCompositeBucket(index string, query qItem, sources qList, subAggs qItem, handler func(bucket gjson.Result) error) error {
+	var after interface{}
+	for {
+		composite := qItem{
+			"size":    bigMapKeysPageSize,
+			"sources": sources,
+		}
+		if after != nil {
+			composite["after"] = after
+		}
+
+		res, err := e.query([]string{index}, newQuery().Query(query).Add(
+			aggs("buckets", qItem{
+				"composite": composite,
+				"aggs":      subAggs,
+			}),
+		).Zero())
+		if err != nil {
+			return err
+		}
+
+		buckets := res.Get("aggregations.buckets.buckets").Array()
+		for _, item := range buckets {
+			if err := handler(item); err != nil {
+				return err
+			}
+		}
+
+		afterKey := res.Get("aggregations.buckets.after_key")
+		if len(buckets) < bigMapKeysPageSize || !afterKey.Exists() {
+			return nil
+		}
+		after = afterKey.Value()
+	}
+}
+
+// eachBigMapDiffDesc - calls `handler` with diffs matched by `query` from the newest to the oldest while it returns true.
+// Diffs are paged with `search_after`, so their count is not limited.
+func (e *Elastic) eachBigMapDiffDesc(query qItem, handler func(hit gjson.Result) (bool, error)) error {
+	var after qList
+	for {
+		q := newQuery().Query(query).Add(qItem{
+			"sort": qList{
+				sort("indexed_time", "desc"),
+				sort("key_hash.keyword", "asc"),
+			},
+		}).Size(bigMapKeysPageSize)
+		if after != nil {
+			q = q.Add(qItem{"search_after": after})
+		}
+
+		res, err := e.query([]string{DocBigMapDiff}, q)
+		if err != nil {
+			return err
+		}
+
+		hits := res.Get("hits.hits").Array()
+		for _, hit := range hits {
+			next, err := handler(hit)
+			if err != nil || !next {
+				return err
+			}
+		}
+		if len(hits) < bigMapKeysPageSize {
+			return nil
+		}
+		last := hits[len(hits)-1]
+		after = qList{last.Get("sort.0").Int(), last.Get("sort.1").String()}
+	}
+}
+
+func bigMapKeysQuery(address string, ptr, level int64, keyHash string) qItem {
+	filters := []qItem{
+		matchPhrase("address", address),
+	}
+	if ptr != 0 {
+		filters = append(filters, term("ptr", ptr))
+	}
+	if level > 0 {
+		filters = append(filters, rangeQ("level", qItem{"lte": level}))
+	}
+	if keyHash != "" {
+		filters = append(filters, matchPhrase("key_hash", keyHash))
+	}
+
+	b := boolQ(filter(filters...))
+	if ptr == 0 {
+		b.Get("bool").Extend(notMust(exists("ptr")))
+	}
+	return b
+}
+
+// removedValue - matches diffs which remove the key from the big map
+func removedValue() qItem {
+	return boolQ(
+		should(
+			term("value.keyword", ""),
+			boolQ(notMust(exists("value"))),
+		),
+		minimumShouldMatch(1),
+	)
+}
+
+// eachBigMapLiveKey - calls `handler` with keys present in the big map at the level while it returns true, recently changed keys go first.
+// Diffs are read from the newest one: the first diff of a key is its last value. Count of key diffs is not set.
+func (e *Elastic) eachBigMapLiveKey(ctx GetBigMapKeysContext, handler func(key BigMapDiff) (bool, error)) error {
+	seen := make(map[string]struct{})
+	return e.eachBigMapDiffDesc(bigMapKeysQuery(ctx.Address, ctx.Ptr, ctx.Level, ""), func(hit gjson.Result) (bool, error) {
+		keyHash := hit.Get("_source.key_hash").String()
+		if _, ok := seen[keyHash]; ok {
+			return true, nil
+		}
+		seen[keyHash] = struct{}{}
+
+		if hit.Get("_source.value").String() == "" {
+			return true, nil
+		}
+		var b BigMapDiff
+		b.ParseElasticJSON(hit)
+		return handler(b)
+	})
+}
+
+// GetBigMapKeys - returns page of keys which are present in the big map at the level, recently changed keys go first.
+// Keys are read until the page is filled.
+func (e *Elastic) GetBigMapKeys(ctx GetBigMapKeysContext) ([]BigMapDiff, error) {
+	if ctx.Size == 0 {
+		ctx.Size = DefaultSize
+	}
+
+	var skipped int64
+	result := make([]BigMapDiff, 0)
+	err := e.eachBigMapLiveKey(ctx, func(key BigMapDiff) (bool, error) {
+		if skipped < ctx.Offset {
+			skipped++
+			return true, nil
+		}
+		result = append(result, key)
+		return int64(len(result)) < ctx.Size, nil
+	})
+	if err != nil || len(result) == 0 {
+		return result, err
+	}
+	return result, e.setBigMapKeysCount(ctx, result)
+}
+
+// FilterBigMapKeys - returns page of keys which are present in the big map at the level and satisfy `match`, and the total count of such keys.
+// All keys are read once.
+func (e *Elastic) FilterBigMapKeys(ctx GetBigMapKeysContext, match func(key BigMapDiff) (bool, error)) ([]BigMapDiff, int64, error) {
+	if ctx.Size == 0 {
+		ctx.Size = DefaultSize
+	}
+
+	var total int64
+	result := make([]BigMapDiff, 0)
+	err := e.eachBigMapLiveKey(ctx, func(key BigMapDiff) (bool, error) {
+		ok, err := match(key)
+		if err != nil || !ok {
+			return err == nil, err
+		}
+		if total >= ctx.Offset && int64(len(result)) < ctx.Size {
+			result = append(result, key)
+		}
+		total++
+		return true, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(result) == 0 {
+		return result, total, nil
+	}
+	return result, total, e.setBigMapKeysCount(ctx, result)
+}
+
+// setBigMapKeysCount - sets count of diffs at the level to every key of the page
+func (e *Elastic) setBigMapKeysCount(ctx GetBigMapKeysContext, keys []BigMapDiff) error {
+	hashes := make([]string, len(keys))
+	for i := range keys {
+		hashes[i] = keys[i].KeyHash
+	}
+	query := newQuery().Query(
+		boolQ(
+			filter(
+				bigMapKeysQuery(ctx.Address, ctx.Ptr, ctx.Level, ""),
+				in("key_hash.keyword", hashes),
+			),
+		),
 	).Add(
 		aggs("keys", qItem{
 			"terms": qItem{
 				"field": "key_hash.keyword",
+				"size":  len(hashes),
+			},
+		}),
+	).Zero()
+
+	res, err := e.query([]string{DocBigMapDiff}, query)
+	if err != nil {
+		return err
+	}
+	counts := make(map[string]int64)
+	for _, item := range res.Get("aggregations.keys.buckets").Array() {
+		counts[item.Get("key").String()] = item.Get("doc_count").Int()
+	}
+	for i := range keys {
+		keys[i].Count = counts[keys[i].KeyHash]
+	}
+	return nil
+}
+
+// bigMapKeysPrecision - count of keys below which the cardinality aggregation is close to exact
+const bigMapKeysPrecision = 40000
+
+// GetBigMapKeysCount - returns count of keys which are present in the big map at the level.
+// It is the cardinality of all keys without keys removed by their last diff, so it is approximate for big maps above `bigMapKeysPrecision` keys.
+func (e *Elastic) GetBigMapKeysCount(address string, ptr, level int64) (int64, error) {
+	query := newQuery().Query(
+		bigMapKeysQuery(address, ptr, level, ""),
+	).Add(
+		aggs("keys", qItem{
+			"cardinality": qItem{
+				"field":               "key_hash.keyword",
+				"precision_threshold": bigMapKeysPrecision,
+			},
+		}),
+	).Zero()
+
+	res, err := e.query([]string{DocBigMapDiff}, query)
+	if err != nil {
+		return 0, err
+	}
+	total := res.Get("aggregations.keys.value").Int()
+
+	removed, err := e.getBigMapRemovedKeysCount(address, ptr, level)
+	if err != nil {
+		return 0, err
+	}
+	if total < removed {
+		return 0, nil
+	}
+	return total - removed, nil
+}
+
+// getBigMapRemovedKeysCount - returns count of keys which last diff at the level removes them.
+// Only keys having removals are aggregated.
+func (e *Elastic) getBigMapRemovedKeysCount(address string, ptr, level int64) (int64, error) {
+	removals := make(map[string]int64)
+	query := boolQ(filter(bigMapKeysQuery(address, ptr, level, ""), removedValue()))
+	if err := e.eachBigMapKeyBucket(query, qItem{
+		"last": max("indexed_time"),
+	}, func(bucket gjson.Result) error {
+		removals[bucket.Get("key.key_hash").String()] = bucket.Get("last.value").Int()
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	var count int64
+	hashes := make([]string, 0, bigMapKeysPageSize)
+	for keyHash := range removals {
+		hashes = append(hashes, keyHash)
+		if len(hashes) < bigMapKeysPageSize {
+			continue
+		}
+		removed, err := e.countRemovedKeys(address, ptr, level, hashes, removals)
+		if err != nil {
+			return 0, err
+		}
+		count += removed
+		hashes = hashes[:0]
+	}
+	if len(hashes) > 0 {
+		removed, err := e.countRemovedKeys(address, ptr, level, hashes, removals)
+		if err != nil {
+			return 0, err
+		}
+		count += removed
+	}
+	return count, nil
+}
+
+// countRemovedKeys - returns count of `hashes` which were not set after their last removal
+func (e *Elastic) countRemovedKeys(address string, ptr, level int64, hashes []string, removals map[string]int64) (int64, error) {
+	query := newQuery().Query(
+		boolQ(
+			filter(
+				bigMapKeysQuery(address, ptr, level, ""),
+				in("key_hash.keyword", hashes),
+			),
+			notMust(removedValue()),
+		),
+	).Add(
+		aggs("keys", qItem{
+			"terms": qItem{
+				"field": "key_hash.keyword",
+				"size":  len(hashes),
 			},
 			"aggs": qItem{
-				"top_key": topHits(1, "indexed_time", "desc"),
+				"last": max("indexed_time"),
 			},
 		}),
-	).Sort("indexed_time", "desc").Zero()
+	).Zero()
 
-	result, err := e.query([]string{DocBigMapDiff}, query)
+	res, err := e.query([]string{DocBigMapDiff}, query)
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+	updates := make(map[string]int64)
+	for _, item := range res.Get("aggregations.keys.buckets").Array() {
+		updates[item.Get("key").String()] = item.Get("last.value").Int()
 	}
-	buckets := result.Get("aggregations.keys.buckets").Array()
-	for _, item := range buckets {
-		hit := item.Get("top_key.hits.hits.0")
 
-		var b models.BigMapDiff
+	var count int64
+	for _, keyHash := range hashes {
+		if updates[keyHash] < removals[keyHash] {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetBigMapKeyHistory - returns all diffs of the key from the oldest to the newest. Diffs are paged, so their count is not limited.
+func (e *Elastic) GetBigMapKeyHistory(address string, ptr int64, keyHash string) ([]BigMapDiff, error) {
+	result := make([]BigMapDiff, 0)
+	if err := e.eachBigMapDiffDesc(bigMapKeysQuery(address, ptr, 0, keyHash), func(hit gjson.Result) (bool, error) {
+		var b BigMapDiff
 		b.ParseElasticJSON(hit)
-		bmd = append(bmd, b)
+		result = append(result, b)
+		return true, nil
+	}); err != nil {
+		return nil, err
 	}
-	return bmd, nil
+
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result, nil
 }
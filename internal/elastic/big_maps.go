@@ -0,0 +1,53 @@
+package elastic
+
+import (
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/tidwall/gjson"
+)
+
+// GetRemovedBigMaps - returns registry entries of big maps removed after `level`
+func (e *Elastic) GetRemovedBigMaps(network string, level int64) ([]models.BigMap, error) {
+	query := newQuery().Query(
+		boolQ(
+			filter(
+				matchPhrase("network", network),
+				rangeQ("removed_level", qItem{"gt": level}),
+			),
+		),
+	).All()
+
+	resp, err := e.query([]string{DocBigMaps}, query)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := resp.Get("hits.hits").Array()
+	bigMaps := make([]models.BigMap, len(hits))
+	for i := range hits {
+		bigMaps[i].ParseElasticJSON(hits[i])
+	}
+	return bigMaps, nil
+}
+
+// GetFirstBigMapDiffs - returns the oldest diff of every big map pointer in the network. Pre-Babylon big maps are skipped.
+func (e *Elastic) GetFirstBigMapDiffs(network string) ([]models.BigMapDiff, error) {
+	query := boolQ(
+		filter(
+			matchQ("network", network),
+			exists("ptr"),
+		),
+	)
+
+	response := make([]models.BigMapDiff, 0)
+	err := e.eachCompositeBucket(DocBigMapDiff, query, qList{
+		qItem{"ptr": qItem{"terms": qItem{"field": "ptr"}}},
+	}, qItem{
+		"first": topHits(1, "indexed_time", "asc"),
+	}, func(bucket gjson.Result) error {
+		var b models.BigMapDiff
+		b.ParseElasticJSON(bucket.Get("first.hits.hits.0"))
+		response = append(response, b)
+		return nil
+	})
+	return response, err
+}
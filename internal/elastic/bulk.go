@@ -27,7 +27,7 @@ func (e *Elastic) Bulk(index string, buf *bytes.Buffer) error {
 	return err
 }
 
-// BulkInsert -
+// BulkInsert - items may belong to different indices
 func (e *Elastic) BulkInsert(items []Model) error {
 	if len(items) == 0 {
 		return nil
@@ -35,7 +35,7 @@ func (e *Elastic) BulkInsert(items []Model) error {
 	index := items[0].GetIndex()
 	bulk := bytes.NewBuffer([]byte{})
 	for i := range items {
-		meta := []byte(fmt.Sprintf(`{ "index" : { "_id": "%s"} }%s`, items[i].GetID(), "\n"))
+		meta := []byte(fmt.Sprintf(`{ "index" : { "_index": "%s", "_id": "%s"} }%s`, items[i].GetIndex(), items[i].GetID(), "\n"))
 		data, err := json.Marshal(items[i])
 		if err != nil {
 			return err
@@ -6,9 +6,21 @@ const (
 	DocBlocks     = "block"
 	DocOperations = "operation"
 	DocBigMapDiff = "bigmapdiff"
+	DocBigMaps    = "bigmap"
 	DocMetadata   = "metadata"
 	DocMigrations = "migration"
 	DocProtocol   = "protocol"
+
+	DocDelegations    = "delegation"
+	DocReveals        = "reveal"
+	DocBalanceChanges = "balance_change"
+
+	DocTokenBalanceChanges = "token_balance_change"
+	DocTransfers           = "transfer"
+
+	DocNotifications = "notification"
+
+	DocInterfaces = "interface"
 )
 
 // Index names
@@ -20,4 +32,5 @@ const (
 const (
 	IndexNotFoundError = "index_not_found_exception"
 	RecordNotFound     = "Record is not found:"
+	DocumentExists     = "Document already exists:"
 )
@@ -215,7 +215,7 @@ func (e *Elastic) IsFAContract(network, address string) (bool, error) {
 			filter(
 				qItem{
 					"terms": qItem{
-						"tags": []string{"fa12", "fa1"},
+						"tags": []string{"fa12", "fa1", "fa2"},
 					},
 				},
 			),
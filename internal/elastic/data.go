@@ -99,12 +99,44 @@ type PageableOperations struct {
 	LastID     string             `json:"last_id"`
 }
 
+// PageableTransfers -
+type PageableTransfers struct {
+	Transfers []models.Transfer `json:"transfers"`
+	LastID    string            `json:"last_id"`
+}
+
+// GetTransfersContext - filters of token transfers. Empty fields are not applied. `Address` matches both sender and receiver.
+// `Start` and `End` are timestamps in milliseconds. `MinAmount` is a decimal string. `OperationIDs` limits transfers to ones made by the operations.
+// `LastID` is the cursor returned with the previous page.
+type GetTransfersContext struct {
+	Network      string
+	Contracts    []string
+	Address      string
+	Sender       string
+	Receiver     string
+	Start        int64
+	End          int64
+	MinAmount    string
+	OperationIDs []string
+	LastID       string
+	Size         int64
+}
+
 // SameContractsResponse -
 type SameContractsResponse struct {
 	Count     uint64            `json:"count"`
 	Contracts []models.Contract `json:"contracts"`
 }
 
+// GetBigMapKeysContext - keys of the big map `Ptr` of the contract `Address` at `Level`. Level 0 is the head, zero pointer is the pre-Babylon big map.
+type GetBigMapKeysContext struct {
+	Address string
+	Ptr     int64
+	Level   int64
+	Size    int64
+	Offset  int64
+}
+
 // BigMapDiff -
 type BigMapDiff struct {
 	Ptr         int64     `json:"ptr,omitempty"`
@@ -0,0 +1,93 @@
+package elastic
+
+import (
+	"fmt"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+// delegationsOrder - the latest delegation goes first. Internal operations are applied after the external one in their index order.
+func delegationsOrder() qItem {
+	return qItem{
+		"sort": qList{
+			sort("level", "desc"),
+			sort("counter", "desc"),
+			sort("internal", "desc"),
+			sort("internal_index", "desc"),
+		},
+	}
+}
+
+// GetDelegations - returns delegations made by `address` starting from the latest one
+func (e *Elastic) GetDelegations(network, address string, size, offset int64) ([]models.Delegation, error) {
+	if size == 0 {
+		size = DefaultSize
+	}
+	query := newQuery().Query(
+		boolQ(
+			filter(
+				matchPhrase("network", network),
+				matchPhrase("source", address),
+			),
+		),
+	).Add(delegationsOrder()).Size(size).From(offset)
+
+	resp, err := e.query([]string{DocDelegations}, query)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := resp.Get("hits.hits").Array()
+	delegations := make([]models.Delegation, len(hits))
+	for i := range hits {
+		delegations[i].ParseElasticJSON(hits[i])
+	}
+	return delegations, nil
+}
+
+// GetLastDelegation - returns the latest applied delegation made by `address`
+func (e *Elastic) GetLastDelegation(network, address string) (d models.Delegation, err error) {
+	query := newQuery().Query(
+		boolQ(
+			filter(
+				matchPhrase("network", network),
+				matchPhrase("source", address),
+				term("status", consts.Applied),
+			),
+		),
+	).Add(delegationsOrder()).One()
+
+	resp, err := e.query([]string{DocDelegations}, query)
+	if err != nil {
+		return
+	}
+	if resp.Get("hits.total.value").Int() < 1 {
+		return d, fmt.Errorf("%s: delegation of %s in %s", RecordNotFound, address, network)
+	}
+	d.ParseElasticJSON(resp.Get("hits.hits.0"))
+	return
+}
+
+// GetReveal - returns applied reveal of `address`
+func (e *Elastic) GetReveal(network, address string) (r models.Reveal, err error) {
+	query := newQuery().Query(
+		boolQ(
+			filter(
+				matchPhrase("network", network),
+				matchPhrase("source", address),
+				term("status", consts.Applied),
+			),
+		),
+	).One()
+
+	resp, err := e.query([]string{DocReveals}, query)
+	if err != nil {
+		return
+	}
+	if resp.Get("hits.total.value").Int() < 1 {
+		return r, fmt.Errorf("%s: reveal of %s in %s", RecordNotFound, address, network)
+	}
+	r.ParseElasticJSON(resp.Get("hits.hits.0"))
+	return
+}
@@ -232,6 +232,14 @@ func (e *Elastic) CreateIndexes() error {
 		DocMigrations,
 		DocProtocol,
 		DocBlocks,
+		DocNotifications,
+		DocDelegations,
+		DocReveals,
+		DocBalanceChanges,
+		DocTokenBalanceChanges,
+		DocTransfers,
+		DocBigMaps,
+		DocInterfaces,
 	} {
 		if err := e.CreateIndexIfNotExists(index); err != nil {
 			return err
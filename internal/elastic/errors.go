@@ -6,3 +6,8 @@ import "strings"
 func IsRecordNotFound(err error) bool {
 	return strings.Contains(err.Error(), RecordNotFound)
 }
+
+// IsDocumentExists -
+func IsDocumentExists(err error) bool {
+	return strings.Contains(err.Error(), DocumentExists)
+}
@@ -1,6 +1,11 @@
 package elastic
 
-import "github.com/tidwall/gjson"
+import (
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/tidwall/gjson"
+)
 
 // Model -
 type Model interface {
@@ -14,3 +19,169 @@ type Scorable interface {
 	GetScores(string) []string
 	FoundByName(gjson.Result) string
 }
+
+// IGeneral -
+type IGeneral interface {
+	CreateIndexes() error
+	DeleteByLevelAndNetwork([]string, string, int64) error
+	GetAll(interface{}) error
+	GetByID(Model) error
+	GetByIDs([]string, interface{}) error
+	GetByNetwork(string, interface{}) error
+	GetByNetworkWithSort(string, string, string, interface{}) error
+	AddDocumentWithID(interface{}, string, string) (string, error)
+	CreateDocument(Model) error
+	UpdateDoc(string, string, interface{}) (gjson.Result, error)
+	UpdateFields(string, string, interface{}, ...string) error
+}
+
+// IBulk -
+type IBulk interface {
+	BulkInsert([]Model) error
+	BulkUpdate([]Model) error
+	BulkDelete([]Model) error
+	BulkRemoveField(string, []Model) error
+}
+
+// IBalanceChanges -
+type IBalanceChanges interface {
+	GetBalanceChanges(string, string) ([]models.BalanceChange, error)
+	GetBalanceAt(string, string, int64) (int64, error)
+}
+
+// IBigMapDiff -
+type IBigMapDiff interface {
+	GetUniqueBigMapDiffsByOperationID(string) ([]models.BigMapDiff, error)
+	GetPrevBigMapDiffs([]models.BigMapDiff, int64, string) ([]models.BigMapDiff, error)
+	GetBigMapDiffsForAddress(string, string) ([]models.BigMapDiff, error)
+	GetBigMapDiffsForAddressAtLevel(string, string, int64) ([]models.BigMapDiff, error)
+	GetBigMap(string, int64, string, int64, int64) ([]BigMapDiff, error)
+	GetBigMapDiffByPtrAndKeyHash(string, int64, string, int64, int64) ([]BigMapDiff, int64, error)
+	GetBigMapDiffsJSONByOperationID(string) ([]gjson.Result, error)
+	GetAllBigMapDiffByPtr(string, string, int64) ([]models.BigMapDiff, error)
+	GetBigMapKeys(GetBigMapKeysContext) ([]BigMapDiff, error)
+	GetBigMapKeysCount(string, int64, int64) (int64, error)
+	FilterBigMapKeys(GetBigMapKeysContext, func(BigMapDiff) (bool, error)) ([]BigMapDiff, int64, error)
+	GetBigMapKeyHistory(string, int64, string) ([]BigMapDiff, error)
+	GetRemovedBigMaps(string, int64) ([]models.BigMap, error)
+	GetFirstBigMapDiffs(string) ([]models.BigMapDiff, error)
+}
+
+// IBlock -
+type IBlock interface {
+	CurrentState(string) (models.Block, error)
+	GetBlock(string, int64) (models.Block, error)
+	GetAllStates() ([]models.Block, error)
+}
+
+// IContract -
+type IContract interface {
+	GetContractByAddressAndNetwork(string, string) (models.Contract, error)
+	GetContract(map[string]interface{}) (models.Contract, error)
+	GetContractsByIDsWithSort([]string, string, string) ([]models.Contract, error)
+	GetContracts(map[string]interface{}) ([]models.Contract, error)
+	GetRandomContract() (models.Contract, error)
+	GetContractWithdrawn(string, string) (int64, error)
+	GetContractID(map[string]interface{}) (string, error)
+	Recommendations([]string, string, []string, int64) ([]models.Contract, error)
+	IsFAContract(string, string) (bool, error)
+	UpdateContractMigrationsCount(string, string) error
+	GetContractAddressesByNetworkAndLevel(string, int64) (gjson.Result, error)
+	NeedParseOperation(string, string, string) (bool, error)
+	IsKnownContract(string, string) (bool, error)
+	GetContractsIDByAddress([]string, string) ([]string, error)
+	RecalcContractStats(string, string) (ContractStats, error)
+	GetContractMigrationStats(string, string) (ContractMigrationsStats, error)
+}
+
+// IDelegations -
+type IDelegations interface {
+	GetDelegations(string, string, int64, int64) ([]models.Delegation, error)
+	GetLastDelegation(string, string) (models.Delegation, error)
+	GetReveal(string, string) (models.Reveal, error)
+}
+
+// IMetadata -
+type IMetadata interface {
+	GetMetadata(string) (models.Metadata, error)
+}
+
+// IMigrations -
+type IMigrations interface {
+	GetMigrations(string, string) ([]models.Migration, error)
+}
+
+// IOperations -
+type IOperations interface {
+	GetOperationByHash(string) ([]models.Operation, error)
+	GetContractOperations(string, string, uint64, map[string]interface{}) (PageableOperations, error)
+	GetLastStorage(string, string) (gjson.Result, error)
+	GetLastStorageAtLevel(string, string, int64) (gjson.Result, error)
+	GetPreviousOperation(string, string, int64) (models.Operation, error)
+	GetAllLevelsForNetwork(string) (map[int64]struct{}, error)
+	GetAffectedContracts(string, int64, int64) ([]string, error)
+	GetAllOperationsByStatus(string, string) ([]models.Operation, error)
+}
+
+// IOutbox -
+type IOutbox interface {
+	GetPendingNotifications(string, time.Time, int64) ([]models.Notification, error)
+	GetNotifications(string, int64, int64) ([]models.Notification, error)
+	GetDocumentLevels(string, string, int64, int64) (map[string]int64, error)
+}
+
+// IProjects -
+type IProjects interface {
+	GetLastProjectContracts() ([]models.Contract, error)
+	GetSameContracts(models.Contract, int64, int64) (SameContractsResponse, error)
+	GetSimilarContracts(models.Contract) ([]SimilarContract, error)
+	GetProjectsStats() ([]ProjectStats, error)
+	GetDiffTasks(int64) ([]DiffTask, error)
+}
+
+// IProtocol -
+type IProtocol interface {
+	GetProtocol(string, string, int64) (models.Protocol, error)
+	GetSymLinks(string, int64) (map[string]bool, error)
+}
+
+// ISearch -
+type ISearch interface {
+	SearchByText(string, int64, []string, map[string]interface{}, bool) (SearchResult, error)
+}
+
+// IStats -
+type IStats interface {
+	GetItemsCountForNetwork(string) (NetworkCountStats, error)
+	GetDateHistogram(string, string, string) ([][]int64, error)
+	GetTimeline([]string, int64, int64) ([]TimelineItem, error)
+}
+
+// ITokens -
+type ITokens interface {
+	GetTokens(string, int64, int64) ([]models.Contract, error)
+	GetTokenTransferOperations(string, string, string, int64) (PageableOperations, error)
+	GetTokenHolders(string, string, int64) ([]models.TokenBalance, error)
+	GetTokenBalance(string, string, string, int64) (string, error)
+	GetTransfers(GetTransfersContext) (PageableTransfers, error)
+}
+
+// IElastic - storage used by indexer, metrics and API. `Elastic` is the default implementation.
+type IElastic interface {
+	IGeneral
+	IBulk
+	IBalanceChanges
+	IBigMapDiff
+	IBlock
+	IContract
+	IDelegations
+	IMetadata
+	IMigrations
+	IOperations
+	IOutbox
+	IProjects
+	IProtocol
+	ISearch
+	IStats
+	ITokens
+}
@@ -0,0 +1,41 @@
+package memory
+
+import (
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+func byAddress(network, address string) func(d *document) bool {
+	return func(d *document) bool {
+		return d.get("network").String() == network && d.get("address").String() == address
+	}
+}
+
+// GetBalanceChanges -
+func (s *Storage) GetBalanceChanges(network, address string) ([]models.BalanceChange, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.find(elastic.DocBalanceChanges, byAddress(network, address))
+	sortDocs(docs, "level", "asc")
+
+	changes := make([]models.BalanceChange, len(docs))
+	for i := range docs {
+		changes[i].ParseElasticJSON(docs[i].hit())
+	}
+	return changes, nil
+}
+
+// GetBalanceAt -
+func (s *Storage) GetBalanceAt(network, address string, level int64) (int64, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	var balance int64
+	for _, doc := range s.find(elastic.DocBalanceChanges, byAddress(network, address)) {
+		if doc.get("level").Int() <= level {
+			balance += doc.get("change").Int()
+		}
+	}
+	return balance, nil
+}
@@ -0,0 +1,282 @@
+package memory
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/tidwall/gjson"
+)
+
+type keyBucket struct {
+	top   *document
+	count int64
+}
+
+// lastByKey - groups diffs by big map pointer and key hash and returns the latest diff of every group ordered by its `indexed_time` desc
+func lastByKey(docs []*document) []keyBucket {
+	buckets := make(map[string]*keyBucket)
+	for i := range docs {
+		key := docs[i].get("ptr").Raw + "_" + docs[i].get("key_hash").String()
+		bucket, ok := buckets[key]
+		if !ok {
+			buckets[key] = &keyBucket{docs[i], 1}
+			continue
+		}
+		bucket.count++
+		if docs[i].get("indexed_time").Int() > bucket.top.get("indexed_time").Int() {
+			bucket.top = docs[i]
+		}
+	}
+
+	result := make([]keyBucket, 0, len(buckets))
+	for _, bucket := range buckets {
+		result = append(result, *bucket)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].top.get("indexed_time").Int() > result[j].top.get("indexed_time").Int()
+	})
+	return result
+}
+
+func parseBigMapDiffBuckets(buckets []keyBucket) []models.BigMapDiff {
+	response := make([]models.BigMapDiff, len(buckets))
+	for i := range buckets {
+		response[i].ParseElasticJSON(buckets[i].top.hit())
+	}
+	return response
+}
+
+func byPtr(d *document, ptr int64) bool {
+	if ptr == 0 {
+		return !d.get("ptr").Exists()
+	}
+	return d.get("ptr").Int() == ptr
+}
+
+// GetUniqueBigMapDiffsByOperationID -
+func (s *Storage) GetUniqueBigMapDiffsByOperationID(operationID string) ([]models.BigMapDiff, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return parseBigMapDiffBuckets(lastByKey(s.find(elastic.DocBigMapDiff, func(d *document) bool {
+		return d.get("operation_id").String() == operationID
+	}))), nil
+}
+
+// GetPrevBigMapDiffs -
+func (s *Storage) GetPrevBigMapDiffs(filters []models.BigMapDiff, indexedTime int64, address string) ([]models.BigMapDiff, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return parseBigMapDiffBuckets(lastByKey(s.find(elastic.DocBigMapDiff, func(d *document) bool {
+		if d.get("address").String() != address || d.get("indexed_time").Int() >= indexedTime {
+			return false
+		}
+		for i := range filters {
+			if d.get("key_hash").String() == filters[i].KeyHash && d.get("bin_path").String() == filters[i].BinPath {
+				return true
+			}
+		}
+		return false
+	}))), nil
+}
+
+// GetBigMapDiffsForAddress -
+func (s *Storage) GetBigMapDiffsForAddress(network, address string) ([]models.BigMapDiff, error) {
+	return s.GetBigMapDiffsForAddressAtLevel(network, address, 0)
+}
+
+// GetBigMapDiffsForAddressAtLevel -
+func (s *Storage) GetBigMapDiffsForAddressAtLevel(network, address string, level int64) ([]models.BigMapDiff, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return parseBigMapDiffBuckets(lastByKey(s.find(elastic.DocBigMapDiff, func(d *document) bool {
+		if d.get("network").String() != network || d.get("address").String() != address {
+			return false
+		}
+		return level == 0 || d.get("level").Int() <= level
+	}))), nil
+}
+
+// GetBigMap -
+func (s *Storage) GetBigMap(address string, ptr int64, searchText string, size, offset int64) ([]elastic.BigMapDiff, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	buckets := lastByKey(s.find(elastic.DocBigMapDiff, func(d *document) bool {
+		if d.get("address").String() != address || !byPtr(d, ptr) {
+			return false
+		}
+		return searchText == "" || containsText(d, searchText, "key", "key_hash", "key_strings")
+	}))
+
+	if size == 0 {
+		size = elastic.DefaultSize
+	}
+	if int64(len(buckets)) < offset {
+		return nil, nil
+	}
+	end := offset + size
+	if int64(len(buckets)) < end {
+		end = int64(len(buckets))
+	}
+
+	result := make([]elastic.BigMapDiff, 0)
+	for _, bucket := range buckets[offset:end] {
+		var b elastic.BigMapDiff
+		b.ParseElasticJSON(bucket.top.hit())
+		b.Count = bucket.count
+		result = append(result, b)
+	}
+	return result, nil
+}
+
+func containsText(d *document, text string, fields ...string) bool {
+	text = strings.ToLower(text)
+	for i := range fields {
+		value := d.get(fields[i])
+		values := []gjson.Result{value}
+		if value.IsArray() {
+			values = value.Array()
+		}
+		for j := range values {
+			if strings.Contains(strings.ToLower(values[j].String()), text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetBigMapDiffByPtrAndKeyHash -
+func (s *Storage) GetBigMapDiffByPtrAndKeyHash(address string, ptr int64, keyHash string, size, offset int64) ([]elastic.BigMapDiff, int64, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.find(elastic.DocBigMapDiff, func(d *document) bool {
+		return d.get("address").String() == address && d.get("key_hash").String() == keyHash && byPtr(d, ptr)
+	})
+	sortDocs(docs, "level", "desc")
+
+	result := make([]elastic.BigMapDiff, 0)
+	for _, doc := range page(docs, size, offset) {
+		var b elastic.BigMapDiff
+		b.ParseElasticJSON(doc.hit())
+		result = append(result, b)
+	}
+	return result, int64(len(docs)), nil
+}
+
+// GetBigMapDiffsJSONByOperationID -
+func (s *Storage) GetBigMapDiffsJSONByOperationID(operationID string) ([]gjson.Result, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return hitsArray(s.find(elastic.DocBigMapDiff, func(d *document) bool {
+		return d.get("operation_id").String() == operationID
+	})).Array(), nil
+}
+
+// GetAllBigMapDiffByPtr -
+func (s *Storage) GetAllBigMapDiffByPtr(address, network string, ptr int64) ([]models.BigMapDiff, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return parseBigMapDiffBuckets(lastByKey(s.find(elastic.DocBigMapDiff, func(d *document) bool {
+		return d.get("network").String() == network && d.get("address").String() == address && d.get("ptr").Int() == ptr
+	}))), nil
+}
+
+func (s *Storage) bigMapLiveKeys(address string, ptr, level int64) []keyBucket {
+	buckets := lastByKey(s.find(elastic.DocBigMapDiff, func(d *document) bool {
+		return d.get("address").String() == address && byPtr(d, ptr) && (level == 0 || d.get("level").Int() <= level)
+	}))
+
+	live := make([]keyBucket, 0, len(buckets))
+	for i := range buckets {
+		if buckets[i].top.get("value").String() != "" {
+			live = append(live, buckets[i])
+		}
+	}
+	return live
+}
+
+// GetBigMapKeys -
+func (s *Storage) GetBigMapKeys(ctx elastic.GetBigMapKeysContext) ([]elastic.BigMapDiff, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	buckets := s.bigMapLiveKeys(ctx.Address, ctx.Ptr, ctx.Level)
+	docs := make([]*document, len(buckets))
+	for i := range buckets {
+		docs[i] = buckets[i].top
+	}
+
+	result := make([]elastic.BigMapDiff, 0)
+	for i, doc := range page(docs, ctx.Size, ctx.Offset) {
+		var b elastic.BigMapDiff
+		b.ParseElasticJSON(doc.hit())
+		b.Count = buckets[int(ctx.Offset)+i].count
+		result = append(result, b)
+	}
+	return result, nil
+}
+
+// FilterBigMapKeys -
+func (s *Storage) FilterBigMapKeys(ctx elastic.GetBigMapKeysContext, match func(elastic.BigMapDiff) (bool, error)) ([]elastic.BigMapDiff, int64, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	if ctx.Size == 0 {
+		ctx.Size = elastic.DefaultSize
+	}
+
+	var total int64
+	result := make([]elastic.BigMapDiff, 0)
+	for _, bucket := range s.bigMapLiveKeys(ctx.Address, ctx.Ptr, ctx.Level) {
+		var b elastic.BigMapDiff
+		b.ParseElasticJSON(bucket.top.hit())
+		b.Count = bucket.count
+
+		ok, err := match(b)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !ok {
+			continue
+		}
+		if total >= ctx.Offset && int64(len(result)) < ctx.Size {
+			result = append(result, b)
+		}
+		total++
+	}
+	return result, total, nil
+}
+
+// GetBigMapKeysCount -
+func (s *Storage) GetBigMapKeysCount(address string, ptr, level int64) (int64, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return int64(len(s.bigMapLiveKeys(address, ptr, level))), nil
+}
+
+// GetBigMapKeyHistory -
+func (s *Storage) GetBigMapKeyHistory(address string, ptr int64, keyHash string) ([]elastic.BigMapDiff, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.find(elastic.DocBigMapDiff, func(d *document) bool {
+		return d.get("address").String() == address && d.get("key_hash").String() == keyHash && byPtr(d, ptr)
+	})
+	sortDocs(docs, "indexed_time", "asc")
+
+	result := make([]elastic.BigMapDiff, len(docs))
+	for i := range docs {
+		result[i].ParseElasticJSON(docs[i].hit())
+	}
+	return result, nil
+}
@@ -0,0 +1,44 @@
+package memory
+
+import (
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+// GetRemovedBigMaps -
+func (s *Storage) GetRemovedBigMaps(network string, level int64) ([]models.BigMap, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.find(elastic.DocBigMaps, func(d *document) bool {
+		return d.get("network").String() == network && d.get("removed_level").Int() > level
+	})
+
+	bigMaps := make([]models.BigMap, len(docs))
+	for i := range docs {
+		bigMaps[i].ParseElasticJSON(docs[i].hit())
+	}
+	return bigMaps, nil
+}
+
+// GetFirstBigMapDiffs -
+func (s *Storage) GetFirstBigMapDiffs(network string) ([]models.BigMapDiff, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.find(elastic.DocBigMapDiff, func(d *document) bool {
+		return d.get("network").String() == network && d.get("ptr").Exists()
+	})
+	sortDocsBy(docs, "asc", "ptr", "indexed_time")
+
+	response := make([]models.BigMapDiff, 0)
+	for i := range docs {
+		if i > 0 && docs[i].get("ptr").Int() == docs[i-1].get("ptr").Int() {
+			continue
+		}
+		var b models.BigMapDiff
+		b.ParseElasticJSON(docs[i].hit())
+		response = append(response, b)
+	}
+	return response, nil
+}
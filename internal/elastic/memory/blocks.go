@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+// CurrentState - returns current indexer state for network
+func (s *Storage) CurrentState(network string) (block models.Block, err error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	block.Network = network
+	docs := s.find(elastic.DocBlocks, byNetwork(network))
+	if len(docs) == 0 {
+		return
+	}
+	sortDocs(docs, "level", "desc")
+	block.ParseElasticJSON(docs[0].hit())
+	return
+}
+
+// GetBlock -
+func (s *Storage) GetBlock(network string, level int64) (block models.Block, err error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	block.Network = network
+	doc, ok := s.findOne(elastic.DocBlocks, func(d *document) bool {
+		return d.get("network").String() == network && d.get("level").Int() == level
+	})
+	if !ok {
+		return block, recordNotFound("block in %s at level %d", network, level)
+	}
+	block.ParseElasticJSON(doc.hit())
+	return
+}
+
+// GetAllStates - return last block for all networks
+func (s *Storage) GetAllStates() ([]models.Block, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.find(elastic.DocBlocks, nil)
+	sortDocs(docs, "level", "desc")
+
+	blocks := make([]models.Block, 0)
+	networks := make(map[string]struct{})
+	for i := range docs {
+		network := docs[i].get("network").String()
+		if _, ok := networks[network]; ok {
+			continue
+		}
+		networks[network] = struct{}{}
+
+		var block models.Block
+		block.ParseElasticJSON(docs[i].hit())
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+func byNetwork(network string) func(d *document) bool {
+	return func(d *document) bool {
+		return d.get("network").String() == network
+	}
+}
@@ -0,0 +1,234 @@
+package memory
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/helpers"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/tidwall/gjson"
+)
+
+func parseContracts(docs []*document) []models.Contract {
+	contracts := make([]models.Contract, len(docs))
+	for i := range docs {
+		contracts[i].ParseElasticJSON(docs[i].hit())
+	}
+	return contracts
+}
+
+// GetContractByAddressAndNetwork -
+func (s *Storage) GetContractByAddressAndNetwork(network, address string) (models.Contract, error) {
+	return s.GetContract(map[string]interface{}{
+		"address": address,
+		"network": network,
+	})
+}
+
+// GetContract -
+func (s *Storage) GetContract(by map[string]interface{}) (c models.Contract, err error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	doc, ok := s.findOne(elastic.DocContracts, func(d *document) bool {
+		return matchAll(d, by)
+	})
+	if !ok {
+		return c, recordNotFound("%v", by)
+	}
+	c.ParseElasticJSON(doc.hit())
+	return
+}
+
+// GetContractsByIDsWithSort -
+func (s *Storage) GetContractsByIDsWithSort(ids []string, sortField, sortDirection string) ([]models.Contract, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.find(elastic.DocContracts, func(d *document) bool {
+		return helpers.StringInArray(d.id, ids)
+	})
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("Unknown contracts with IDs %s", ids)
+	}
+	sortDocs(docs, sortField, sortDirection)
+	return parseContracts(docs), nil
+}
+
+// GetContracts -
+func (s *Storage) GetContracts(by map[string]interface{}) ([]models.Contract, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return parseContracts(s.find(elastic.DocContracts, func(d *document) bool {
+		return matchAll(d, by)
+	})), nil
+}
+
+// GetRandomContract -
+func (s *Storage) GetRandomContract() (c models.Contract, err error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.find(elastic.DocContracts, func(d *document) bool {
+		return d.get("tx_count").Int() >= 2
+	})
+	if len(docs) == 0 {
+		return c, recordNotFound("random contract")
+	}
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	c.ParseElasticJSON(docs[r.Intn(len(docs))].hit())
+	return
+}
+
+// GetContractWithdrawn -
+func (s *Storage) GetContractWithdrawn(address, network string) (int64, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	var withdrawn int64
+	for _, doc := range s.find(elastic.DocOperations, func(d *document) bool {
+		return d.get("network").String() == network && d.get("source").String() == address
+	}) {
+		withdrawn += doc.get("amount").Int()
+	}
+	return withdrawn, nil
+}
+
+// GetContractID -
+func (s *Storage) GetContractID(by map[string]interface{}) (string, error) {
+	c, err := s.GetContract(by)
+	if err != nil {
+		return "", err
+	}
+	return c.ID, nil
+}
+
+// Recommendations -
+func (s *Storage) Recommendations(tags []string, language string, blackList []string, size int64) ([]models.Contract, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.find(elastic.DocContracts, func(d *document) bool {
+		if helpers.StringInArray(d.get("address").String(), blackList) {
+			return false
+		}
+		matches := 0
+		for i := range tags {
+			if hasTag(d, tags[i]) {
+				matches++
+			}
+		}
+		if d.get("language").String() == language {
+			matches++
+		}
+		return matches >= helpers.MinInt(2, len(tags)+2)
+	})
+	sortDocs(docs, "last_action", "desc")
+	return parseContracts(page(docs, size, 0)), nil
+}
+
+// IsFAContract -
+func (s *Storage) IsFAContract(network, address string) (bool, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	_, ok := s.findOne(elastic.DocContracts, func(d *document) bool {
+		return d.get("network").String() == network && d.get("address").String() == address && hasTag(d, "fa12", "fa1", "fa2")
+	})
+	return ok, nil
+}
+
+// UpdateContractMigrationsCount -
+func (s *Storage) UpdateContractMigrationsCount(address, network string) error {
+	contract, err := s.GetContractByAddressAndNetwork(network, address)
+	if err != nil {
+		return err
+	}
+	contract.MigrationsCount++
+
+	_, err = s.UpdateDoc(elastic.DocContracts, contract.ID, contract)
+	return err
+}
+
+// GetContractAddressesByNetworkAndLevel -
+func (s *Storage) GetContractAddressesByNetworkAndLevel(network string, maxLevel int64) (gjson.Result, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return hitsArray(s.find(elastic.DocContracts, func(d *document) bool {
+		return d.get("network").String() == network && d.get("level").Int() > maxLevel
+	})), nil
+}
+
+// NeedParseOperation -
+func (s *Storage) NeedParseOperation(network, source, destination string) (bool, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	_, ok := s.findOne(elastic.DocContracts, func(d *document) bool {
+		address := d.get("address").String()
+		return d.get("network").String() == network && (address == source || address == destination)
+	})
+	return ok, nil
+}
+
+// IsKnownContract -
+func (s *Storage) IsKnownContract(network, address string) (bool, error) {
+	return s.NeedParseOperation(network, address, address)
+}
+
+// GetContractsIDByAddress -
+func (s *Storage) GetContractsIDByAddress(addresses []string, network string) ([]string, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	ids := make([]string, 0)
+	for _, doc := range s.find(elastic.DocContracts, func(d *document) bool {
+		return d.get("network").String() == network && helpers.StringInArray(d.get("address").String(), addresses)
+	}) {
+		ids = append(ids, doc.id)
+	}
+	return ids, nil
+}
+
+// RecalcContractStats -
+func (s *Storage) RecalcContractStats(network, address string) (stats elastic.ContractStats, err error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	for _, doc := range s.find(elastic.DocOperations, func(d *document) bool {
+		return d.get("network").String() == network && (d.get("source").String() == address || d.get("destination").String() == address)
+	}) {
+		stats.TxCount++
+		if ts := doc.get("timestamp").Time().UTC(); ts.After(stats.LastAction) {
+			stats.LastAction = ts
+		}
+		if doc.get("status").String() != "applied" {
+			continue
+		}
+		amount := doc.get("amount").Int()
+		if doc.get("destination").String() == address {
+			stats.Balance += amount
+		} else {
+			stats.Balance -= amount
+		}
+		if doc.get("source").String() == address {
+			stats.TotalWithdrawn += amount
+		}
+	}
+	return
+}
+
+// GetContractMigrationStats -
+func (s *Storage) GetContractMigrationStats(network, address string) (stats elastic.ContractMigrationsStats, err error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	stats.MigrationsCount = int64(len(s.find(elastic.DocMigrations, func(d *document) bool {
+		return d.get("network").String() == network && d.get("address").String() == address
+	})))
+	return
+}
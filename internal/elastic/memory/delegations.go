@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+func bySource(network, address string, applied bool) func(d *document) bool {
+	return func(d *document) bool {
+		if applied && d.get("status").String() != consts.Applied {
+			return false
+		}
+		return d.get("network").String() == network && d.get("source").String() == address
+	}
+}
+
+// sortDelegations - the latest delegation goes first
+func sortDelegations(docs []*document) {
+	sortDocsBy(docs, "desc", "level", "counter", "internal", "internal_index")
+}
+
+// GetDelegations -
+func (s *Storage) GetDelegations(network, address string, size, offset int64) ([]models.Delegation, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.find(elastic.DocDelegations, bySource(network, address, false))
+	sortDelegations(docs)
+	docs = page(docs, size, offset)
+
+	delegations := make([]models.Delegation, len(docs))
+	for i := range docs {
+		delegations[i].ParseElasticJSON(docs[i].hit())
+	}
+	return delegations, nil
+}
+
+// GetLastDelegation -
+func (s *Storage) GetLastDelegation(network, address string) (d models.Delegation, err error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.find(elastic.DocDelegations, bySource(network, address, true))
+	if len(docs) == 0 {
+		return d, recordNotFound("delegation of %s in %s", address, network)
+	}
+	sortDelegations(docs)
+	d.ParseElasticJSON(docs[0].hit())
+	return
+}
+
+// GetReveal -
+func (s *Storage) GetReveal(network, address string) (r models.Reveal, err error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	doc, ok := s.findOne(elastic.DocReveals, bySource(network, address, true))
+	if !ok {
+		return r, recordNotFound("reveal of %s in %s", address, network)
+	}
+	r.ParseElasticJSON(doc.hit())
+	return
+}
@@ -0,0 +1,277 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/helpers"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// CreateIndexes -
+func (s *Storage) CreateIndexes() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for _, index := range []string{
+		elastic.DocContracts,
+		elastic.DocMetadata,
+		elastic.DocBigMapDiff,
+		elastic.DocOperations,
+		elastic.DocMigrations,
+		elastic.DocProtocol,
+		elastic.DocBlocks,
+		elastic.DocNotifications,
+		elastic.DocDelegations,
+		elastic.DocReveals,
+		elastic.DocBalanceChanges,
+		elastic.DocTokenBalanceChanges,
+		elastic.DocTransfers,
+		elastic.DocBigMaps,
+		elastic.DocInterfaces,
+	} {
+		s.index(index)
+	}
+	return nil
+}
+
+// DeleteByLevelAndNetwork -
+func (s *Storage) DeleteByLevelAndNetwork(indices []string, network string, maxLevel int64) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for _, index := range indices {
+		for id, doc := range s.indices[index] {
+			if doc.get("network").String() == network && doc.get("level").Int() > maxLevel {
+				delete(s.indices[index], id)
+			}
+		}
+	}
+	return nil
+}
+
+// GetByID -
+func (s *Storage) GetByID(ret elastic.Model) error {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	doc, ok := s.indices[ret.GetIndex()][ret.GetID()]
+	if !ok {
+		return recordNotFound("%s %s", ret.GetIndex(), ret.GetID())
+	}
+	ret.ParseElasticJSON(doc.hit())
+	return nil
+}
+
+// GetAll -
+func (s *Storage) GetAll(output interface{}) error {
+	return s.getByFilter(output, nil, "", "")
+}
+
+// GetByNetwork -
+func (s *Storage) GetByNetwork(network string, output interface{}) error {
+	return s.GetByNetworkWithSort(network, "level", "asc", output)
+}
+
+// GetByNetworkWithSort -
+func (s *Storage) GetByNetworkWithSort(network, sortField, sortOrder string, output interface{}) error {
+	return s.getByFilter(output, func(d *document) bool {
+		return d.get("network").String() == network
+	}, sortField, sortOrder)
+}
+
+// GetByIDs -
+func (s *Storage) GetByIDs(ids []string, output interface{}) error {
+	return s.getByFilter(output, func(d *document) bool {
+		return helpers.StringInArray(d.id, ids)
+	}, "", "")
+}
+
+func (s *Storage) getByFilter(output interface{}, where func(d *document) bool, sortField, sortOrder string) error {
+	typ, index, err := getElementType(output)
+	if err != nil {
+		return err
+	}
+
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.find(index, where)
+	if sortField != "" {
+		sortDocs(docs, sortField, sortOrder)
+	}
+
+	el := reflect.ValueOf(output).Elem()
+	for i := range docs {
+		n := reflect.New(typ)
+		n.Interface().(elastic.Model).ParseElasticJSON(docs[i].hit())
+		el.Set(reflect.Append(el, n.Elem()))
+	}
+	return nil
+}
+
+func getElementType(output interface{}) (reflect.Type, string, error) {
+	arr := reflect.TypeOf(output)
+	if arr.Kind() != reflect.Ptr || arr.Elem().Kind() != reflect.Slice {
+		return nil, "", fmt.Errorf("Invalid `output` type: %s", arr.Kind())
+	}
+	typ := arr.Elem().Elem()
+	model, ok := reflect.New(typ).Interface().(elastic.Model)
+	if !ok {
+		return nil, "", fmt.Errorf("Implements: 'output' is not implemented `Model` interface")
+	}
+	return typ, model.GetIndex(), nil
+}
+
+// AddDocumentWithID -
+func (s *Storage) AddDocumentWithID(v interface{}, index, docID string) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.put(index, docID, b)
+	return docID, nil
+}
+
+// CreateDocument - saves the document only if there is no document with the same ID
+func (s *Storage) CreateDocument(item elastic.Model) error {
+	b, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if _, ok := s.indices[item.GetIndex()][item.GetID()]; ok {
+		return fmt.Errorf("%s: %s %s", elastic.DocumentExists, item.GetIndex(), item.GetID())
+	}
+	s.put(item.GetIndex(), item.GetID(), b)
+	return nil
+}
+
+// UpdateDoc - updates document by ID
+func (s *Storage) UpdateDoc(index, id string, v interface{}) (gjson.Result, error) {
+	if _, err := s.AddDocumentWithID(v, index, id); err != nil {
+		return gjson.Result{}, err
+	}
+	return gjson.Parse(fmt.Sprintf(`{"_index":"%s","_id":"%s","result":"updated"}`, index, id)), nil
+}
+
+// UpdateFields -
+func (s *Storage) UpdateFields(index, id string, data interface{}, fields ...string) error {
+	val := reflect.ValueOf(data)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	t := val.Type()
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	doc, ok := s.indices[index][id]
+	if !ok {
+		return recordNotFound("%s %s", index, id)
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !helpers.StringInArray(field.Name, fields) {
+			continue
+		}
+		tagName := strings.Split(field.Tag.Get("json"), ",")[0]
+		source, err := sjson.SetBytes(doc.source, tagName, val.Field(i).Interface())
+		if err != nil {
+			return err
+		}
+		doc.source = source
+	}
+	return nil
+}
+
+// BulkInsert -
+func (s *Storage) BulkInsert(items []elastic.Model) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for i := range items {
+		data, err := json.Marshal(items[i])
+		if err != nil {
+			return err
+		}
+		s.put(items[i].GetIndex(), items[i].GetID(), data)
+	}
+	return nil
+}
+
+// BulkUpdate - merges updates into existing documents like the `doc` form of Elasticsearch update does
+func (s *Storage) BulkUpdate(updates []elastic.Model) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for i := range updates {
+		doc, ok := s.indices[updates[i].GetIndex()][updates[i].GetID()]
+		if !ok {
+			return recordNotFound("%s %s", updates[i].GetIndex(), updates[i].GetID())
+		}
+		data, err := json.Marshal(updates[i])
+		if err != nil {
+			return err
+		}
+		source := doc.source
+		for k, v := range gjson.ParseBytes(data).Map() {
+			if source, err = sjson.SetRawBytes(source, k, []byte(v.Raw)); err != nil {
+				return err
+			}
+		}
+		doc.source = source
+	}
+	return nil
+}
+
+// BulkDelete -
+func (s *Storage) BulkDelete(items []elastic.Model) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for i := range items {
+		delete(s.indices[items[i].GetIndex()], items[i].GetID())
+	}
+	return nil
+}
+
+var removeFieldScript = regexp.MustCompile(`^ctx\._source\.([\w\.]+)\.remove\('([^']+)'\)$`)
+
+// BulkRemoveField - supports only scripts like `ctx._source.<path>.remove('<key>')`
+func (s *Storage) BulkRemoveField(script string, where []elastic.Model) error {
+	matches := removeFieldScript.FindStringSubmatch(script)
+	if len(matches) != 3 {
+		return fmt.Errorf("Unsupported script: %s", script)
+	}
+	path := fmt.Sprintf("%s.%s", matches[1], matches[2])
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for i := range where {
+		doc, ok := s.indices[where[i].GetIndex()][where[i].GetID()]
+		if !ok {
+			continue
+		}
+		source, err := sjson.DeleteBytes(doc.source, path)
+		if err != nil {
+			return err
+		}
+		doc.source = source
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// Storage - in-memory implementation of `elastic.IElastic`. Documents are kept as JSON sources
+// and parsed by `ParseElasticJSON` exactly like search hits, so models behave the same way as with Elasticsearch.
+type Storage struct {
+	indices map[string]map[string]*document
+	counter int64
+
+	mux sync.RWMutex
+}
+
+type document struct {
+	id     string
+	index  string
+	source []byte
+	// insertion order is used as a stable sort tie-breaker
+	order int64
+}
+
+var _ elastic.IElastic = (*Storage)(nil)
+
+// New -
+func New() *Storage {
+	s := &Storage{
+		indices: make(map[string]map[string]*document),
+	}
+	_ = s.CreateIndexes()
+	return s
+}
+
+func (d *document) hit() gjson.Result {
+	return gjson.Parse(d.json())
+}
+
+func (d *document) json() string {
+	data, _ := sjson.SetRawBytes([]byte(`{}`), "_source", d.source)
+	data, _ = sjson.SetBytes(data, "_id", d.id)
+	data, _ = sjson.SetBytes(data, "_index", d.index)
+	return string(data)
+}
+
+func (d *document) get(path string) gjson.Result {
+	return gjson.GetBytes(d.source, path)
+}
+
+func (s *Storage) index(name string) map[string]*document {
+	idx, ok := s.indices[name]
+	if !ok {
+		idx = make(map[string]*document)
+		s.indices[name] = idx
+	}
+	return idx
+}
+
+func (s *Storage) put(index, id string, source []byte) {
+	idx := s.index(index)
+	if doc, ok := idx[id]; ok {
+		doc.source = source
+		return
+	}
+	s.counter++
+	idx[id] = &document{
+		id:     id,
+		index:  index,
+		source: source,
+		order:  s.counter,
+	}
+}
+
+func (s *Storage) find(index string, where func(d *document) bool) []*document {
+	result := make([]*document, 0)
+	for _, doc := range s.indices[index] {
+		if where == nil || where(doc) {
+			result = append(result, doc)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].order < result[j].order
+	})
+	return result
+}
+
+func (s *Storage) findOne(index string, where func(d *document) bool) (*document, bool) {
+	docs := s.find(index, where)
+	if len(docs) == 0 {
+		return nil, false
+	}
+	return docs[0], true
+}
+
+func recordNotFound(format string, args ...interface{}) error {
+	return fmt.Errorf("%s: %s", elastic.RecordNotFound, fmt.Sprintf(format, args...))
+}
+
+func sortDocs(docs []*document, field, order string) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		if order == "desc" {
+			return compare(docs[i].get(field), docs[j].get(field)) > 0
+		}
+		return compare(docs[i].get(field), docs[j].get(field)) < 0
+	})
+}
+
+// sortDocsBy - sorts documents by several fields in the same order
+func sortDocsBy(docs []*document, order string, fields ...string) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, field := range fields {
+			c := compare(docs[i].get(field), docs[j].get(field))
+			if c == 0 {
+				continue
+			}
+			if order == "desc" {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+func compare(a, b gjson.Result) int {
+	if a.Type == gjson.Number || b.Type == gjson.Number {
+		switch {
+		case a.Float() < b.Float():
+			return -1
+		case a.Float() > b.Float():
+			return 1
+		}
+		return 0
+	}
+	switch {
+	case a.String() < b.String():
+		return -1
+	case a.String() > b.String():
+		return 1
+	}
+	return 0
+}
+
+func page(docs []*document, size, offset int64) []*document {
+	if size == 0 {
+		size = elastic.DefaultSize
+	}
+	if offset >= int64(len(docs)) {
+		return []*document{}
+	}
+	end := offset + size
+	if end > int64(len(docs)) {
+		end = int64(len(docs))
+	}
+	return docs[offset:end]
+}
+
+func matchAll(d *document, by map[string]interface{}) bool {
+	for k, v := range by {
+		if d.get(k).String() != fmt.Sprintf("%v", v) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasTag(d *document, tags ...string) bool {
+	for _, tag := range d.get("tags").Array() {
+		for i := range tags {
+			if tag.String() == tags[i] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hitsArray(docs []*document) gjson.Result {
+	data := []byte(`[]`)
+	for i := range docs {
+		data, _ = sjson.SetRawBytes(data, "-1", []byte(docs[i].json()))
+	}
+	return gjson.ParseBytes(data)
+}
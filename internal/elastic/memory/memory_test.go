@@ -0,0 +1,532 @@
+package memory
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+func newTestStorage(t *testing.T) *Storage {
+	s := New()
+	items := []elastic.Model{
+		&models.Block{ID: "b1", Network: "mainnet", Level: 1, Hash: "BL1"},
+		&models.Block{ID: "b2", Network: "mainnet", Level: 2, Hash: "BL2", Predecessor: "BL1"},
+		&models.Block{ID: "b3", Network: "carthagenet", Level: 10, Hash: "BL10"},
+	}
+	if err := s.BulkInsert(items); err != nil {
+		t.Fatalf("BulkInsert error: %v", err)
+	}
+
+	contracts := []elastic.Model{
+		&models.Contract{ID: "c1", Network: "mainnet", Level: 1, Address: "KT1A", Tags: []string{"fa12"}, Timestamp: time.Unix(100, 0)},
+		&models.Contract{ID: "c2", Network: "mainnet", Level: 2, Address: "KT1B", Timestamp: time.Unix(200, 0)},
+	}
+	if err := s.BulkInsert(contracts); err != nil {
+		t.Fatalf("BulkInsert error: %v", err)
+	}
+
+	operations := []elastic.Model{
+		&models.Operation{ID: "o1", Network: "mainnet", Hash: "oo1", Level: 1, Counter: 1, Kind: "origination", Source: "tz1A", Destination: "KT1A", Status: "applied", IndexedTime: 1, DeffatedStorage: "s1"},
+		&models.Operation{ID: "o2", Network: "mainnet", Hash: "oo2", Level: 2, Counter: 2, Kind: "transaction", Source: "tz1A", Destination: "KT1A", Status: "applied", IndexedTime: 2, Amount: 10, DeffatedStorage: "s2"},
+		&models.Operation{ID: "o3", Network: "mainnet", Hash: "oo2", Level: 2, Counter: 2, Kind: "transaction", Source: "KT1A", Destination: "tz1B", Status: "applied", IndexedTime: 3, Amount: 4, Internal: true, InternalIndex: 1},
+	}
+	if err := s.BulkInsert(operations); err != nil {
+		t.Fatalf("BulkInsert error: %v", err)
+	}
+
+	bmd := []elastic.Model{
+		&models.BigMapDiff{ID: "d1", Network: "mainnet", Address: "KT1A", Ptr: 5, KeyHash: "k1", Value: "v1", Level: 1, IndexedTime: 1, OperationID: "o1"},
+		&models.BigMapDiff{ID: "d2", Network: "mainnet", Address: "KT1A", Ptr: 5, KeyHash: "k1", Value: "v2", Level: 2, IndexedTime: 2, OperationID: "o2"},
+		&models.BigMapDiff{ID: "d3", Network: "mainnet", Address: "KT1A", Ptr: 5, KeyHash: "k2", Value: "v3", Level: 2, IndexedTime: 3, OperationID: "o2"},
+	}
+	if err := s.BulkInsert(bmd); err != nil {
+		t.Fatalf("BulkInsert error: %v", err)
+	}
+	return s
+}
+
+func TestStorage_CurrentState(t *testing.T) {
+	s := newTestStorage(t)
+	tests := []struct {
+		network string
+		level   int64
+	}{
+		{"mainnet", 2},
+		{"carthagenet", 10},
+		{"zeronet", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.network, func(t *testing.T) {
+			block, err := s.CurrentState(tt.network)
+			if err != nil {
+				t.Errorf("CurrentState error: %v", err)
+				return
+			}
+			if block.Level != tt.level || block.Network != tt.network {
+				t.Errorf("CurrentState got %d (%s), want %d", block.Level, block.Network, tt.level)
+			}
+		})
+	}
+}
+
+func TestStorage_GetByID(t *testing.T) {
+	s := newTestStorage(t)
+
+	contract := models.Contract{ID: "c1"}
+	if err := s.GetByID(&contract); err != nil {
+		t.Errorf("GetByID error: %v", err)
+		return
+	}
+	if contract.Address != "KT1A" {
+		t.Errorf("GetByID address got %s", contract.Address)
+	}
+
+	unknown := models.Contract{ID: "unknown"}
+	if err := s.GetByID(&unknown); err == nil || !elastic.IsRecordNotFound(err) {
+		t.Errorf("GetByID must return record not found error, got %v", err)
+	}
+}
+
+func TestStorage_GetContractOperations(t *testing.T) {
+	s := newTestStorage(t)
+	tests := []struct {
+		name    string
+		filters map[string]interface{}
+		want    []string
+		lastID  string
+	}{
+		{
+			name:    "all",
+			filters: map[string]interface{}{},
+			want:    []string{"o2", "o3", "o1"},
+			lastID:  "1",
+		}, {
+			name:    "last_id",
+			filters: map[string]interface{}{"last_id": "2"},
+			want:    []string{"o1"},
+			lastID:  "1",
+		}, {
+			name:    "status",
+			filters: map[string]interface{}{"status": "'failed'"},
+			want:    []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			po, err := s.GetContractOperations("mainnet", "KT1A", 0, tt.filters)
+			if err != nil {
+				t.Errorf("GetContractOperations error: %v", err)
+				return
+			}
+			if len(po.Operations) != len(tt.want) {
+				t.Errorf("GetContractOperations length got %d want %d", len(po.Operations), len(tt.want))
+				return
+			}
+			for i := range tt.want {
+				if po.Operations[i].ID != tt.want[i] {
+					t.Errorf("GetContractOperations [%d] got %s want %s", i, po.Operations[i].ID, tt.want[i])
+				}
+			}
+			if po.LastID != tt.lastID {
+				t.Errorf("GetContractOperations last id got %s want %s", po.LastID, tt.lastID)
+			}
+		})
+	}
+}
+
+func TestStorage_GetBigMap(t *testing.T) {
+	s := newTestStorage(t)
+
+	bm, err := s.GetBigMap("KT1A", 5, "", 0, 0)
+	if err != nil {
+		t.Errorf("GetBigMap error: %v", err)
+		return
+	}
+	if len(bm) != 2 {
+		t.Errorf("GetBigMap length got %d", len(bm))
+		return
+	}
+	if bm[0].KeyHash != "k2" || bm[1].KeyHash != "k1" || bm[1].Value != "v2" || bm[1].Count != 2 {
+		t.Errorf("GetBigMap invalid result: %v", bm)
+	}
+}
+
+func TestStorage_StorageAtLevel(t *testing.T) {
+	s := newTestStorage(t)
+
+	tests := []struct {
+		name        string
+		level       int64
+		wantStorage string
+		wantValues  map[string]string
+	}{
+		{"head", 0, "s2", map[string]string{"k1": "v2", "k2": "v3"}},
+		{"level 1", 1, "s1", map[string]string{"k1": "v1"}},
+		{"level 2", 2, "s2", map[string]string{"k1": "v2", "k2": "v3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage, err := s.GetLastStorageAtLevel("mainnet", "KT1A", tt.level)
+			if err != nil {
+				t.Errorf("GetLastStorageAtLevel error: %v", err)
+				return
+			}
+			if got := storage.Get("_source.deffated_storage").String(); got != tt.wantStorage {
+				t.Errorf("GetLastStorageAtLevel got %s, want %s", got, tt.wantStorage)
+			}
+
+			bmd, err := s.GetBigMapDiffsForAddressAtLevel("mainnet", "KT1A", tt.level)
+			if err != nil {
+				t.Errorf("GetBigMapDiffsForAddressAtLevel error: %v", err)
+				return
+			}
+			values := make(map[string]string)
+			for i := range bmd {
+				values[bmd[i].KeyHash] = bmd[i].Value
+			}
+			if !reflect.DeepEqual(values, tt.wantValues) {
+				t.Errorf("GetBigMapDiffsForAddressAtLevel got %v, want %v", values, tt.wantValues)
+			}
+		})
+	}
+}
+
+func TestStorage_GetBigMapDiffsForAddress(t *testing.T) {
+	s := newTestStorage(t)
+	items := []elastic.Model{
+		&models.BigMapDiff{ID: "d4", Network: "mainnet", Address: "KT1A", Ptr: 6, KeyHash: "k1", Value: "v4", Level: 1, IndexedTime: 4, OperationID: "o1"},
+		&models.BigMapDiff{ID: "d5", Network: "carthagenet", Address: "KT1A", Ptr: 5, KeyHash: "k1", Value: "v5", Level: 3, IndexedTime: 5, OperationID: "o5"},
+	}
+	if err := s.BulkInsert(items); err != nil {
+		t.Fatalf("BulkInsert error: %v", err)
+	}
+
+	bmd, err := s.GetBigMapDiffsForAddress("mainnet", "KT1A")
+	if err != nil {
+		t.Errorf("GetBigMapDiffsForAddress error: %v", err)
+		return
+	}
+	got := make([]string, len(bmd))
+	for i := range bmd {
+		got[i] = bmd[i].ID
+	}
+	if want := []string{"d4", "d3", "d2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetBigMapDiffsForAddress got %v, want %v", got, want)
+	}
+}
+
+func TestStorage_FilterBigMapKeys(t *testing.T) {
+	s := newTestStorage(t)
+	match := func(key elastic.BigMapDiff) (bool, error) {
+		return key.Value != "v3", nil
+	}
+
+	tests := []struct {
+		name      string
+		offset    int64
+		wantTotal int64
+		wantKeys  []string
+	}{
+		{"first page", 0, 1, []string{"k1"}},
+		{"out of range", 1, 1, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, total, err := s.FilterBigMapKeys(elastic.GetBigMapKeysContext{Address: "KT1A", Ptr: 5, Offset: tt.offset}, match)
+			if err != nil {
+				t.Errorf("FilterBigMapKeys error: %v", err)
+				return
+			}
+			got := make([]string, len(keys))
+			for i := range keys {
+				got[i] = keys[i].KeyHash
+			}
+			if total != tt.wantTotal || !reflect.DeepEqual(got, tt.wantKeys) {
+				t.Errorf("FilterBigMapKeys got %v (%d), want %v (%d)", got, total, tt.wantKeys, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestStorage_GetFirstBigMapDiffs(t *testing.T) {
+	s := newTestStorage(t)
+	other := &models.BigMapDiff{ID: "d4", Network: "mainnet", Address: "KT1B", Ptr: 6, KeyHash: "k1", Value: "v4", Level: 3, IndexedTime: 4, OperationID: "o4"}
+	if err := s.BulkInsert([]elastic.Model{other}); err != nil {
+		t.Fatalf("BulkInsert error: %v", err)
+	}
+
+	bmd, err := s.GetFirstBigMapDiffs("mainnet")
+	if err != nil {
+		t.Errorf("GetFirstBigMapDiffs error: %v", err)
+		return
+	}
+	got := make([]string, len(bmd))
+	for i := range bmd {
+		got[i] = bmd[i].ID
+	}
+	if want := []string{"d1", "d4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetFirstBigMapDiffs got %v, want %v", got, want)
+	}
+}
+
+func TestStorage_GetBigMapKeys(t *testing.T) {
+	s := newTestStorage(t)
+	removed := &models.BigMapDiff{ID: "d4", Network: "mainnet", Address: "KT1A", Ptr: 5, KeyHash: "k2", Level: 3, IndexedTime: 4, OperationID: "o4"}
+	if err := s.BulkInsert([]elastic.Model{removed}); err != nil {
+		t.Fatalf("BulkInsert error: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		level     int64
+		wantCount int64
+		wantKeys  []string
+	}{
+		{"head", 0, 1, []string{"k1"}},
+		{"level 1", 1, 1, []string{"k1"}},
+		{"level 2", 2, 2, []string{"k2", "k1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := s.GetBigMapKeysCount("KT1A", 5, tt.level)
+			if err != nil {
+				t.Errorf("GetBigMapKeysCount error: %v", err)
+				return
+			}
+			if count != tt.wantCount {
+				t.Errorf("GetBigMapKeysCount got %d, want %d", count, tt.wantCount)
+			}
+
+			keys, err := s.GetBigMapKeys(elastic.GetBigMapKeysContext{Address: "KT1A", Ptr: 5, Level: tt.level})
+			if err != nil {
+				t.Errorf("GetBigMapKeys error: %v", err)
+				return
+			}
+			got := make([]string, len(keys))
+			for i := range keys {
+				got[i] = keys[i].KeyHash
+			}
+			if !reflect.DeepEqual(got, tt.wantKeys) {
+				t.Errorf("GetBigMapKeys got %v, want %v", got, tt.wantKeys)
+			}
+		})
+	}
+
+	history, err := s.GetBigMapKeyHistory("KT1A", 5, "k2")
+	if err != nil {
+		t.Errorf("GetBigMapKeyHistory error: %v", err)
+		return
+	}
+	got := make([]string, len(history))
+	for i := range history {
+		got[i] = history[i].OperationID
+	}
+	if want := []string{"o2", "o4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetBigMapKeyHistory got %v, want %v", got, want)
+	}
+}
+
+func TestStorage_DeleteByLevelAndNetwork(t *testing.T) {
+	s := newTestStorage(t)
+
+	if err := s.DeleteByLevelAndNetwork([]string{elastic.DocBlocks, elastic.DocOperations}, "mainnet", 1); err != nil {
+		t.Errorf("DeleteByLevelAndNetwork error: %v", err)
+		return
+	}
+	block, err := s.CurrentState("mainnet")
+	if err != nil {
+		t.Errorf("CurrentState error: %v", err)
+		return
+	}
+	if block.Level != 1 {
+		t.Errorf("CurrentState level got %d", block.Level)
+	}
+	levels, err := s.GetAllLevelsForNetwork("mainnet")
+	if err != nil {
+		t.Errorf("GetAllLevelsForNetwork error: %v", err)
+		return
+	}
+	if _, ok := levels[2]; ok || len(levels) != 1 {
+		t.Errorf("GetAllLevelsForNetwork got %v", levels)
+	}
+}
+
+func TestStorage_BulkRemoveField(t *testing.T) {
+	s := New()
+	m := &models.Metadata{
+		ID:        "KT1A",
+		Parameter: map[string]string{"babylon": "{}", "alpha": "{}"},
+		Storage:   map[string]string{"babylon": "{}"},
+	}
+	if err := s.BulkInsert([]elastic.Model{m}); err != nil {
+		t.Errorf("BulkInsert error: %v", err)
+		return
+	}
+	if err := s.BulkRemoveField("ctx._source.parameter.remove('babylon')", []elastic.Model{m}); err != nil {
+		t.Errorf("BulkRemoveField error: %v", err)
+		return
+	}
+	data, err := s.GetMetadata("KT1A")
+	if err != nil {
+		t.Errorf("GetMetadata error: %v", err)
+		return
+	}
+	if _, ok := data.Parameter["babylon"]; ok || len(data.Parameter) != 1 || len(data.Storage) != 1 {
+		t.Errorf("BulkRemoveField invalid result: %v", data)
+	}
+}
+
+func TestStorage_GetTokenHolders(t *testing.T) {
+	s := newTestStorage(t)
+	changes := []elastic.Model{
+		&models.TokenBalanceChange{ID: "t1", Network: "mainnet", Contract: "KT1A", Address: "tz1A", Level: 1, Change: "100"},
+		&models.TokenBalanceChange{ID: "t2", Network: "mainnet", Contract: "KT1A", Address: "tz1A", Level: 2, Change: "-30"},
+		&models.TokenBalanceChange{ID: "t3", Network: "mainnet", Contract: "KT1A", Address: "tz1B", Level: 2, Change: "30"},
+		&models.TokenBalanceChange{ID: "t4", Network: "mainnet", Contract: "KT1A", Address: "tz1C", Level: 3, Change: "5"},
+		&models.TokenBalanceChange{ID: "t5", Network: "mainnet", Contract: "KT1A", Address: "tz1C", Level: 4, Change: "-5"},
+		&models.TokenBalanceChange{ID: "t6", Network: "mainnet", Contract: "KT1A", Address: "tz1D", Level: 5, Change: "18446744073709551616"},
+		&models.TokenBalanceChange{ID: "t7", Network: "mainnet", Contract: "KT1A", Address: "tz1D", Level: 6, Change: "1"},
+	}
+	if err := s.BulkInsert(changes); err != nil {
+		t.Fatalf("BulkInsert error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		level int64
+		want  []models.TokenBalance
+	}{
+		{"head", 0, []models.TokenBalance{{Address: "tz1D", Balance: "18446744073709551617"}, {Address: "tz1A", Balance: "70"}, {Address: "tz1B", Balance: "30"}}},
+		{"level 1", 1, []models.TokenBalance{{Address: "tz1A", Balance: "100"}}},
+		{"level 3", 3, []models.TokenBalance{{Address: "tz1A", Balance: "70"}, {Address: "tz1B", Balance: "30"}, {Address: "tz1C", Balance: "5"}}},
+		{"level 5", 5, []models.TokenBalance{{Address: "tz1D", Balance: "18446744073709551616"}, {Address: "tz1A", Balance: "70"}, {Address: "tz1B", Balance: "30"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			holders, err := s.GetTokenHolders("mainnet", "KT1A", tt.level)
+			if err != nil {
+				t.Errorf("GetTokenHolders error: %v", err)
+				return
+			}
+			if len(holders) != len(tt.want) {
+				t.Errorf("GetTokenHolders got %v, want %v", holders, tt.want)
+				return
+			}
+			for i := range holders {
+				if holders[i] != tt.want[i] {
+					t.Errorf("GetTokenHolders got %v, want %v", holders, tt.want)
+					return
+				}
+			}
+		})
+	}
+
+	balance, err := s.GetTokenBalance("mainnet", "KT1A", "tz1A", 1)
+	if err != nil || balance != "100" {
+		t.Errorf("GetTokenBalance got %s %v", balance, err)
+	}
+}
+
+func TestStorage_GetTransfers(t *testing.T) {
+	s := newTestStorage(t)
+	// indexed time of transfers doesn't match their order in the chain
+	transfers := []elastic.Model{
+		&models.Transfer{ID: "t1", Network: "mainnet", Contract: "KT1A", From: "tz1A", To: "tz1B", Amount: "100", Level: 1, Counter: 1, IndexedTime: 1, Timestamp: time.Unix(100, 0)},
+		&models.Transfer{ID: "t2", Network: "mainnet", Contract: "KT1A", From: "tz1B", To: "tz1C", Amount: "5", Level: 2, Counter: 5, IndexedTime: 3, OperationID: "op2", Timestamp: time.Unix(200, 0)},
+		&models.Transfer{ID: "t3", Network: "mainnet", Contract: "KT1B", From: "tz1A", To: "tz1C", Amount: "50", Level: 2, Counter: 5, InternalIndex: 1, IndexedTime: 2, OperationID: "op3", Timestamp: time.Unix(200, 0)},
+		&models.Transfer{ID: "t4", Network: "mainnet", Contract: "KT1B", To: "tz1A", Amount: "10", Level: 3, Counter: 2, IndexedTime: 0, Timestamp: time.Unix(300, 0)},
+	}
+	if err := s.BulkInsert(transfers); err != nil {
+		t.Fatalf("BulkInsert error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		ctx        elastic.GetTransfersContext
+		want       []string
+		wantLastID string
+	}{
+		{"all", elastic.GetTransfersContext{Network: "mainnet"}, []string{"t4", "t3", "t2", "t1"}, "1_1_0_0"},
+		{"party", elastic.GetTransfersContext{Network: "mainnet", Address: "tz1A"}, []string{"t4", "t3", "t1"}, "1_1_0_0"},
+		{"sender", elastic.GetTransfersContext{Network: "mainnet", Sender: "tz1A", Contracts: []string{"KT1A"}}, []string{"t1"}, "1_1_0_0"},
+		{"min amount", elastic.GetTransfersContext{Network: "mainnet", MinAmount: "10"}, []string{"t4", "t3", "t1"}, "1_1_0_0"},
+		{"operations", elastic.GetTransfersContext{Network: "mainnet", Address: "tz1C", OperationIDs: []string{"op2", "op3"}}, []string{"t3", "t2"}, "2_5_0_0"},
+		{"time range", elastic.GetTransfersContext{Network: "mainnet", Start: 200000, End: 250000}, []string{"t3", "t2"}, "2_5_0_0"},
+		{"first page", elastic.GetTransfersContext{Network: "mainnet", Size: 2}, []string{"t4", "t3"}, "2_5_1_0"},
+		{"next page", elastic.GetTransfersContext{Network: "mainnet", Size: 2, LastID: "2_5_1_0"}, []string{"t2", "t1"}, "1_1_0_0"},
+		{"empty", elastic.GetTransfersContext{Network: "carthagenet"}, []string{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetTransfers(tt.ctx)
+			if err != nil {
+				t.Errorf("GetTransfers error: %v", err)
+				return
+			}
+			ids := make([]string, len(got.Transfers))
+			for i := range got.Transfers {
+				ids[i] = got.Transfers[i].ID
+			}
+			if !reflect.DeepEqual(ids, tt.want) || got.LastID != tt.wantLastID {
+				t.Errorf("GetTransfers got %v %s, want %v %s", ids, got.LastID, tt.want, tt.wantLastID)
+			}
+		})
+	}
+}
+
+func TestStorage_GetDelegations(t *testing.T) {
+	s := newTestStorage(t)
+	delegations := []elastic.Model{
+		&models.Delegation{ID: "d1", Network: "mainnet", Source: "KT1A", Delegate: "tz1A", Status: "applied", Level: 1, Counter: 1, IndexedTime: 3},
+		&models.Delegation{ID: "d2", Network: "mainnet", Source: "KT1A", Delegate: "tz1B", Status: "applied", Level: 2, Counter: 5, IndexedTime: 1},
+		&models.Delegation{ID: "d3", Network: "mainnet", Source: "KT1A", Delegate: "tz1C", Status: "applied", Level: 2, Counter: 5, Internal: true, InternalIndex: 1, IndexedTime: 2},
+		&models.Delegation{ID: "d4", Network: "mainnet", Source: "KT1A", Delegate: "tz1D", Status: "applied", Level: 2, Counter: 5, Internal: true, InternalIndex: 2, IndexedTime: 2},
+		&models.Delegation{ID: "d5", Network: "mainnet", Source: "KT1A", Delegate: "tz1E", Status: "applied", Level: 2, Counter: 4, IndexedTime: 4},
+	}
+	if err := s.BulkInsert(delegations); err != nil {
+		t.Fatalf("BulkInsert error: %v", err)
+	}
+
+	got, err := s.GetDelegations("mainnet", "KT1A", 10, 0)
+	if err != nil {
+		t.Fatalf("GetDelegations error: %v", err)
+	}
+	ids := make([]string, len(got))
+	for i := range got {
+		ids[i] = got[i].ID
+	}
+	if want := []string{"d4", "d3", "d2", "d5", "d1"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("GetDelegations got %v, want %v", ids, want)
+	}
+
+	last, err := s.GetLastDelegation("mainnet", "KT1A")
+	if err != nil {
+		t.Fatalf("GetLastDelegation error: %v", err)
+	}
+	if last.ID != "d4" {
+		t.Errorf("GetLastDelegation got %s, want d4", last.ID)
+	}
+}
+
+func TestStorage_CreateDocument(t *testing.T) {
+	s := New()
+	if err := s.CreateDocument(models.NewInterface("fa2", 1, "[]")); err != nil {
+		t.Fatalf("CreateDocument error: %v", err)
+	}
+
+	err := s.CreateDocument(models.NewInterface("fa2", 1, `[{"name":"transfer"}]`))
+	if err == nil || !elastic.IsDocumentExists(err) {
+		t.Errorf("CreateDocument of the same version error = %v, want document exists", err)
+	}
+	item := models.Interface{ID: models.NewInterface("fa2", 1, "").ID}
+	if err := s.GetByID(&item); err != nil || item.Entrypoints != "[]" {
+		t.Errorf("stored interface = %+v %v, want the first version", item, err)
+	}
+
+	if err := s.CreateDocument(models.NewInterface("fa2", 2, "[]")); err != nil {
+		t.Errorf("CreateDocument of the next version error: %v", err)
+	}
+}
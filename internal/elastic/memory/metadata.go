@@ -0,0 +1,12 @@
+package memory
+
+import (
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+// GetMetadata - returns metadata of contract with `address`
+func (s *Storage) GetMetadata(address string) (data models.Metadata, err error) {
+	data.ID = address
+	err = s.GetByID(&data)
+	return
+}
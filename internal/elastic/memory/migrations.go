@@ -0,0 +1,23 @@
+package memory
+
+import (
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+// GetMigrations -
+func (s *Storage) GetMigrations(network, address string) ([]models.Migration, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.find(elastic.DocMigrations, func(d *document) bool {
+		return d.get("network").String() == network && d.get("address").String() == address
+	})
+	sortDocs(docs, "level", "desc")
+
+	migrations := make([]models.Migration, len(docs))
+	for i := range docs {
+		migrations[i].ParseElasticJSON(docs[i].hit())
+	}
+	return migrations, nil
+}
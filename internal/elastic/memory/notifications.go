@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+func parseNotifications(docs []*document) []models.Notification {
+	notifications := make([]models.Notification, len(docs))
+	for i := range docs {
+		notifications[i].ParseElasticJSON(docs[i].hit())
+	}
+	return notifications
+}
+
+func byLevels(network string, fromLevel, toLevel int64) func(d *document) bool {
+	return func(d *document) bool {
+		level := d.get("level").Int()
+		return d.get("network").String() == network && level > fromLevel && level <= toLevel
+	}
+}
+
+// GetPendingNotifications -
+func (s *Storage) GetPendingNotifications(network string, now time.Time, size int64) ([]models.Notification, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.find(elastic.DocNotifications, func(d *document) bool {
+		return d.get("network").String() == network &&
+			d.get("status").String() == models.NotificationPending &&
+			!d.get("next_attempt").Time().After(now)
+	})
+	sortDocs(docs, "level", "asc")
+	return parseNotifications(page(docs, size, 0)), nil
+}
+
+// GetNotifications -
+func (s *Storage) GetNotifications(network string, fromLevel, toLevel int64) ([]models.Notification, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return parseNotifications(s.find(elastic.DocNotifications, byLevels(network, fromLevel, toLevel))), nil
+}
+
+// GetDocumentLevels -
+func (s *Storage) GetDocumentLevels(index, network string, fromLevel, toLevel int64) (map[string]int64, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	levels := make(map[string]int64)
+	for _, doc := range s.find(index, byLevels(network, fromLevel, toLevel)) {
+		levels[doc.id] = doc.get("level").Int()
+	}
+	return levels, nil
+}
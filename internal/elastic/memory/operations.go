@@ -0,0 +1,265 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/tidwall/gjson"
+)
+
+func parseOperations(docs []*document) []models.Operation {
+	operations := make([]models.Operation, len(docs))
+	for i := range docs {
+		operations[i].ParseElasticJSON(docs[i].hit())
+	}
+	return operations
+}
+
+// operationOrder - the same order as the painless script in `elastic.GetContractOperations`
+func operationOrder(d *document) int64 {
+	internal := int64(999)
+	if d.get("internal").Bool() {
+		internal = 999 - d.get("internal_index").Int()
+	}
+	return d.get("level").Int()*10000000000 + d.get("counter").Int()*1000 + internal
+}
+
+// GetOperationByHash -
+func (s *Storage) GetOperationByHash(hash string) ([]models.Operation, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.find(elastic.DocOperations, func(d *document) bool {
+		return d.get("hash").String() == hash
+	})
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("Unknown operation with hash %s", hash)
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		return operationOrder(docs[i]) > operationOrder(docs[j])
+	})
+	return parseOperations(docs), nil
+}
+
+type opgKey struct {
+	hash    string
+	counter int64
+}
+
+// GetContractOperations -
+func (s *Storage) GetContractOperations(network, address string, size uint64, filters map[string]interface{}) (po elastic.PageableOperations, err error) {
+	if size == 0 {
+		size = elastic.DefaultSize
+	}
+	where, err := prepareOperationFilters(filters)
+	if err != nil {
+		return
+	}
+
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.find(elastic.DocOperations, func(d *document) bool {
+		if d.get("network").String() != network {
+			return false
+		}
+		if d.get("source").String() != address && d.get("destination").String() != address {
+			return false
+		}
+		return where(d)
+	})
+	sortDocs(docs, "level", "desc")
+
+	opg := make(map[opgKey]struct{})
+	for i := range docs {
+		if uint64(len(opg)) == size {
+			break
+		}
+		opg[opgKey{docs[i].get("hash").String(), docs[i].get("counter").Int()}] = struct{}{}
+	}
+
+	result := s.find(elastic.DocOperations, func(d *document) bool {
+		_, ok := opg[opgKey{d.get("hash").String(), d.get("counter").Int()}]
+		return ok && d.get("network").String() == network
+	})
+	sort.SliceStable(result, func(i, j int) bool {
+		return operationOrder(result[i]) > operationOrder(result[j])
+	})
+
+	po.Operations = parseOperations(result)
+	for i := range result {
+		indexedTime := result[i].get("indexed_time").Int()
+		if lastID, _ := strconv.ParseInt(po.LastID, 10, 64); po.LastID == "" || indexedTime < lastID {
+			po.LastID = fmt.Sprintf("%d", indexedTime)
+		}
+	}
+	return
+}
+
+func prepareOperationFilters(filters map[string]interface{}) (func(d *document) bool, error) {
+	conditions := make([]func(d *document) bool, 0)
+	for k, v := range filters {
+		value := fmt.Sprintf("%v", v)
+		if value == "" {
+			continue
+		}
+		switch k {
+		case "from":
+			from, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, func(d *document) bool {
+				return d.get("timestamp").Time().UnixNano()/1000000 >= from
+			})
+		case "to":
+			to, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, func(d *document) bool {
+				return d.get("timestamp").Time().UnixNano()/1000000 <= to
+			})
+		case "entrypoints":
+			entrypoints := parseSQLList(value)
+			conditions = append(conditions, func(d *document) bool {
+				_, ok := entrypoints[d.get("entrypoint").String()]
+				return ok
+			})
+		case "last_id":
+			lastID, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, func(d *document) bool {
+				return d.get("indexed_time").Int() < lastID
+			})
+		case "status":
+			statuses := parseSQLList(value)
+			conditions = append(conditions, func(d *document) bool {
+				_, ok := statuses[d.get("status").String()]
+				return ok
+			})
+		default:
+			return nil, fmt.Errorf("Unknown operation filter: %s %v", k, v)
+		}
+	}
+	return func(d *document) bool {
+		for i := range conditions {
+			if !conditions[i](d) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// parseSQLList - parses lists like `'applied','failed'`
+func parseSQLList(value string) map[string]struct{} {
+	result := make(map[string]struct{})
+	for _, item := range strings.Split(value, ",") {
+		result[strings.Trim(strings.TrimSpace(item), "'")] = struct{}{}
+	}
+	return result
+}
+
+func (s *Storage) findStorageOperations(network, address string, where func(d *document) bool) []*document {
+	docs := s.find(elastic.DocOperations, func(d *document) bool {
+		return d.get("network").String() == network &&
+			d.get("destination").String() == address &&
+			d.get("status").String() == "applied" &&
+			d.get("deffated_storage").String() != "" &&
+			(where == nil || where(d))
+	})
+	sortDocs(docs, "indexed_time", "desc")
+	return docs
+}
+
+// GetLastStorage -
+func (s *Storage) GetLastStorage(network, address string) (gjson.Result, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.findStorageOperations(network, address, nil)
+	if len(docs) == 0 {
+		return gjson.Result{}, nil
+	}
+	return docs[0].hit(), nil
+}
+
+// GetLastStorageAtLevel -
+func (s *Storage) GetLastStorageAtLevel(network, address string, level int64) (gjson.Result, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.findStorageOperations(network, address, func(d *document) bool {
+		return level == 0 || d.get("level").Int() <= level
+	})
+	if len(docs) == 0 {
+		return gjson.Result{}, nil
+	}
+	return docs[0].hit(), nil
+}
+
+// GetPreviousOperation -
+func (s *Storage) GetPreviousOperation(address, network string, indexedTime int64) (op models.Operation, err error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.findStorageOperations(network, address, func(d *document) bool {
+		return d.get("indexed_time").Int() < indexedTime
+	})
+	if len(docs) == 0 {
+		return op, recordNotFound("%s in %s on %d", address, network, indexedTime)
+	}
+	op.ParseElasticJSON(docs[0].hit())
+	return
+}
+
+// GetAllLevelsForNetwork -
+func (s *Storage) GetAllLevelsForNetwork(network string) (map[int64]struct{}, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	levels := make(map[int64]struct{})
+	for _, doc := range s.find(elastic.DocOperations, byNetwork(network)) {
+		levels[doc.get("level").Int()] = struct{}{}
+	}
+	return levels, nil
+}
+
+// GetAffectedContracts -
+func (s *Storage) GetAffectedContracts(network string, fromLevel, toLevel int64) ([]string, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	addresses := make([]string, 0)
+	exists := make(map[string]struct{})
+	for _, index := range []string{elastic.DocOperations, elastic.DocDelegations} {
+		for _, doc := range s.find(index, byLevels(network, toLevel, fromLevel)) {
+			for _, field := range []string{"source", "destination"} {
+				address := doc.get(field).String()
+				if _, ok := exists[address]; ok || !strings.HasPrefix(address, "KT") {
+					continue
+				}
+				exists[address] = struct{}{}
+				addresses = append(addresses, address)
+			}
+		}
+	}
+	return addresses, nil
+}
+
+// GetAllOperationsByStatus -
+func (s *Storage) GetAllOperationsByStatus(network, status string) ([]models.Operation, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return parseOperations(s.find(elastic.DocOperations, func(d *document) bool {
+		return d.get("network").String() == network && d.get("status").String() == status
+	})), nil
+}
@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/tidwall/gjson"
+)
+
+type group struct {
+	key  string
+	docs []*document
+}
+
+// groupBy - groups documents by `field` keeping the order of the first appearance. Documents without the field are skipped.
+func groupBy(docs []*document, field string) []group {
+	groups := make([]group, 0)
+	positions := make(map[string]int)
+	for i := range docs {
+		value := docs[i].get(field)
+		if !value.Exists() || value.String() == "" {
+			continue
+		}
+		key := value.String()
+		pos, ok := positions[key]
+		if !ok {
+			pos = len(groups)
+			positions[key] = pos
+			groups = append(groups, group{key: key})
+		}
+		groups[pos].docs = append(groups[pos].docs, docs[i])
+	}
+	return groups
+}
+
+func top(docs []*document, field, order string) *document {
+	sorted := make([]*document, len(docs))
+	copy(sorted, docs)
+	sortDocs(sorted, field, order)
+	return sorted[0]
+}
+
+// GetLastProjectContracts -
+func (s *Storage) GetLastProjectContracts() ([]models.Contract, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	groups := groupBy(s.find(elastic.DocContracts, nil), "project_id")
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("Empty response: no projects")
+	}
+
+	contracts := make([]models.Contract, len(groups))
+	for i := range groups {
+		contracts[i].ParseElasticJSON(top(groups[i].docs, "timestamp", "desc").hit())
+	}
+	return contracts, nil
+}
+
+// GetSameContracts -
+func (s *Storage) GetSameContracts(c models.Contract, size, offset int64) (scp elastic.SameContractsResponse, err error) {
+	if c.Fingerprint == nil {
+		return scp, fmt.Errorf("Invalid contract data")
+	}
+
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.find(elastic.DocContracts, func(d *document) bool {
+		return d.get("hash").String() == c.Hash && d.get("address").String() != c.Address
+	})
+	sortDocs(docs, "last_action", "desc")
+
+	scp.Contracts = parseContracts(page(docs, size, offset))
+	scp.Count = uint64(len(docs))
+	return
+}
+
+// GetSimilarContracts -
+func (s *Storage) GetSimilarContracts(c models.Contract) ([]elastic.SimilarContract, error) {
+	if c.Fingerprint == nil {
+		return nil, nil
+	}
+
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.find(elastic.DocContracts, func(d *document) bool {
+		return d.get("project_id").String() == c.ProjectID && d.get("hash").String() != c.Hash
+	})
+
+	groups := groupBy(docs, "hash")
+	res := make([]elastic.SimilarContract, len(groups))
+	for i := range groups {
+		var buf models.Contract
+		buf.ParseElasticJSON(top(groups[i].docs, "last_action", "desc").hit())
+		res[i] = elastic.SimilarContract{
+			Contract: &buf,
+			Count:    int64(len(groups[i].docs)),
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].LastAction.After(res[j].LastAction.Time)
+	})
+	return res, nil
+}
+
+// GetProjectsStats - is not supported by in-memory storage
+func (s *Storage) GetProjectsStats() ([]elastic.ProjectStats, error) {
+	return nil, errNotSupported("GetProjectsStats")
+}
+
+// GetDiffTasks -
+func (s *Storage) GetDiffTasks(offset int64) ([]elastic.DiffTask, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	tasks := make([]elastic.DiffTask, 0)
+	for _, project := range groupBy(s.find(elastic.DocContracts, nil), "project_id") {
+		similar := groupBy(project.docs, "hash")
+		if len(similar) < 2 {
+			continue
+		}
+		last := make([]gjson.Result, len(similar))
+		for i := range similar {
+			last[i] = top(similar[i].docs, "last_action", "desc").hit()
+		}
+		for i := 0; i < len(last)-1; i++ {
+			for j := i + 1; j < len(last); j++ {
+				tasks = append(tasks, elastic.DiffTask{
+					Network1: last[i].Get("_source.network").String(),
+					Address1: last[i].Get("_source.address").String(),
+					Network2: last[j].Get("_source.network").String(),
+					Address2: last[j].Get("_source.address").String(),
+				})
+			}
+		}
+	}
+	return tasks, nil
+}
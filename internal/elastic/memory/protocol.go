@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+// GetProtocol - returns current protocol for `network` and `level` (`hash` is optional, leave empty string for default)
+func (s *Storage) GetProtocol(network, hash string, level int64) (p models.Protocol, err error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.find(elastic.DocProtocol, func(d *document) bool {
+		if d.get("network").String() != network {
+			return false
+		}
+		if level > -1 && d.get("start_level").Int() > level {
+			return false
+		}
+		return hash == "" || d.get("hash").String() == hash
+	})
+	if len(docs) == 0 {
+		err = fmt.Errorf("Couldn't find a protocol for %s (hash = %s) at level %d", network, hash, level)
+		return
+	}
+	sortDocs(docs, "start_level", "desc")
+	p.ParseElasticJSON(docs[0].hit())
+	return
+}
+
+// GetSymLinks - returns list of symlinks in `network` after `level`
+func (s *Storage) GetSymLinks(network string, level int64) (map[string]bool, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	symMap := make(map[string]bool)
+	for _, doc := range s.find(elastic.DocProtocol, byNetwork(network)) {
+		if doc.get("start_level").Int() > level {
+			symMap[doc.get("sym_link").String()] = true
+		}
+	}
+	return symMap, nil
+}
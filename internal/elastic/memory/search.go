@@ -0,0 +1,16 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+)
+
+func errNotSupported(method string) error {
+	return fmt.Errorf("%s is not supported by in-memory storage", method)
+}
+
+// SearchByText - full-text search is not supported by in-memory storage
+func (s *Storage) SearchByText(text string, offset int64, fields []string, filters map[string]interface{}, grouping bool) (elastic.SearchResult, error) {
+	return elastic.SearchResult{}, errNotSupported("SearchByText")
+}
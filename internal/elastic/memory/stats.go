@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/helpers"
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+// GetItemsCountForNetwork -
+func (s *Storage) GetItemsCountForNetwork(network string) (stats elastic.NetworkCountStats, err error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	stats.Contracts = int64(len(s.find(elastic.DocContracts, byNetwork(network))))
+	stats.Operations = int64(len(s.find(elastic.DocOperations, byNetwork(network))))
+	return
+}
+
+// GetDateHistogram -
+func (s *Storage) GetDateHistogram(network, index, period string) ([][]int64, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.find(index, byNetwork(network))
+	if len(docs) == 0 {
+		return [][]int64{}, nil
+	}
+
+	counts := make(map[int64]int64)
+	var start, end time.Time
+	for i := range docs {
+		key, err := truncate(docs[i].get("timestamp").Time().UTC(), period)
+		if err != nil {
+			return nil, err
+		}
+		counts[key.UnixNano()/1000000]++
+		if start.IsZero() || key.Before(start) {
+			start = key
+		}
+		if key.After(end) {
+			end = key
+		}
+	}
+
+	histogram := make([][]int64, 0)
+	for key := start; !key.After(end); key = next(key, period) {
+		ms := key.UnixNano() / 1000000
+		histogram = append(histogram, []int64{ms, counts[ms]})
+	}
+	return histogram, nil
+}
+
+func truncate(t time.Time, period string) (time.Time, error) {
+	switch period {
+	case "year":
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC), nil
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	case "week":
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7)), nil
+	case "day":
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+	case "hour":
+		return t.Truncate(time.Hour), nil
+	case "minute":
+		return t.Truncate(time.Minute), nil
+	default:
+		return t, fmt.Errorf("Unknown histogram period: %s", period)
+	}
+}
+
+func next(t time.Time, period string) time.Time {
+	switch period {
+	case "year":
+		return t.AddDate(1, 0, 0)
+	case "month":
+		return t.AddDate(0, 1, 0)
+	case "week":
+		return t.AddDate(0, 0, 7)
+	case "day":
+		return t.AddDate(0, 0, 1)
+	case "hour":
+		return t.Add(time.Hour)
+	default:
+		return t.Add(time.Minute)
+	}
+}
+
+// GetTimeline -
+func (s *Storage) GetTimeline(contracts []string, size, from int64) ([]elastic.TimelineItem, error) {
+	if len(contracts) == 0 {
+		return []elastic.TimelineItem{}, nil
+	}
+
+	var subscriptions []models.Contract
+	if err := s.GetByIDs(contracts, &subscriptions); err != nil {
+		return nil, err
+	}
+
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	isSubscribed := func(d *document) bool {
+		for i := range subscriptions {
+			if d.get("network").String() != subscriptions[i].Network {
+				continue
+			}
+			for _, field := range []string{"source", "destination", "address"} {
+				if d.get(field).String() == subscriptions[i].Address {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	docs := s.find(elastic.DocOperations, func(d *document) bool {
+		kind := d.get("kind").String()
+		return (kind == "origination" || kind == "genesis" || d.get("errors").Exists()) && isSubscribed(d)
+	})
+	docs = append(docs, s.find(elastic.DocMigrations, func(d *document) bool {
+		return d.get("kind").String() == "genesis" && isSubscribed(d)
+	})...)
+	sortDocs(docs, "timestamp", "desc")
+
+	timeline := make([]elastic.TimelineItem, 0)
+	for _, doc := range page(docs, helpers.MaxInt64(size, 0), from) {
+		var t elastic.TimelineItem
+		switch doc.index {
+		case elastic.DocOperations:
+			t.ParseJSONOperation(doc.hit())
+		case elastic.DocMigrations:
+			t.ParseJSONMigration(doc.hit())
+		}
+		timeline = append(timeline, t)
+	}
+	return timeline, nil
+}
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/tidwall/gjson"
+)
+
+// GetTokens -
+func (s *Storage) GetTokens(network string, size, offset int64) ([]models.Contract, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.find(elastic.DocContracts, func(d *document) bool {
+		return d.get("network").String() == network && hasTag(d, "fa12", "fa1", "fa2")
+	})
+	sortDocs(docs, "timestamp", "desc")
+	return parseContracts(page(docs, size, offset)), nil
+}
+
+// GetTokenTransferOperations -
+func (s *Storage) GetTokenTransferOperations(network, address, lastID string, size int64) (po elastic.PageableOperations, err error) {
+	var last int64
+	if lastID != "" {
+		if last, err = strconv.ParseInt(lastID, 10, 64); err != nil {
+			return
+		}
+	}
+
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.find(elastic.DocOperations, func(d *document) bool {
+		if d.get("network").String() != network {
+			return false
+		}
+		if entrypoint := d.get("entrypoint").String(); entrypoint != "mint" && entrypoint != "transfer" {
+			return false
+		}
+		if lastID != "" && d.get("indexed_time").Int() >= last {
+			return false
+		}
+		return hasString(d.get("parameter_strings"), address)
+	})
+	sortDocs(docs, "timestamp", "desc")
+	docs = page(docs, size, 0)
+
+	po.Operations = parseOperations(docs)
+	if len(docs) > 0 {
+		po.LastID = fmt.Sprintf("%d", docs[len(docs)-1].get("indexed_time").Int())
+	}
+	return
+}
+
+func hasString(arr gjson.Result, value string) bool {
+	for _, item := range arr.Array() {
+		if item.String() == value {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenBalances - sums changes of holder balances at `level` (0 is the head)
+func (s *Storage) tokenBalances(network, contract, address string, level int64) ([]models.TokenBalance, error) {
+	docs := s.find(elastic.DocTokenBalanceChanges, func(d *document) bool {
+		if d.get("network").String() != network || d.get("contract").String() != contract {
+			return false
+		}
+		if address != "" && d.get("address").String() != address {
+			return false
+		}
+		return level == 0 || d.get("level").Int() <= level
+	})
+	changes := make([]models.TokenBalanceChange, len(docs))
+	for i := range docs {
+		changes[i].ParseElasticJSON(docs[i].hit())
+	}
+	return models.SumTokenBalanceChanges(changes)
+}
+
+// GetTokenHolders -
+func (s *Storage) GetTokenHolders(network, contract string, level int64) ([]models.TokenBalance, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return s.tokenBalances(network, contract, "", level)
+}
+
+// GetTokenBalance -
+func (s *Storage) GetTokenBalance(network, contract, address string, level int64) (string, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	balances, err := s.tokenBalances(network, contract, address, level)
+	if err != nil || len(balances) == 0 {
+		return "0", err
+	}
+	return balances[0].Balance, nil
+}
@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/helpers"
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+// GetTransfers -
+func (s *Storage) GetTransfers(ctx elastic.GetTransfersContext) (response elastic.PageableTransfers, err error) {
+	var last elastic.TransferPosition
+	if ctx.LastID != "" {
+		if last, err = elastic.ParseTransferCursor(ctx.LastID); err != nil {
+			return
+		}
+	}
+
+	var minAmount *big.Int
+	if ctx.MinAmount != "" {
+		var ok bool
+		if minAmount, ok = new(big.Int).SetString(ctx.MinAmount, 10); !ok {
+			return response, fmt.Errorf("Invalid min amount: %s", ctx.MinAmount)
+		}
+	}
+
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	docs := s.find(elastic.DocTransfers, func(d *document) bool {
+		if d.get("network").String() != ctx.Network {
+			return false
+		}
+		if len(ctx.Contracts) > 0 && !helpers.StringInArray(d.get("contract").String(), ctx.Contracts) {
+			return false
+		}
+		if len(ctx.OperationIDs) > 0 && !helpers.StringInArray(d.get("operation_id").String(), ctx.OperationIDs) {
+			return false
+		}
+		from, to := d.get("from").String(), d.get("to").String()
+		if ctx.Address != "" && from != ctx.Address && to != ctx.Address {
+			return false
+		}
+		if (ctx.Sender != "" && from != ctx.Sender) || (ctx.Receiver != "" && to != ctx.Receiver) {
+			return false
+		}
+		timestamp := d.get("timestamp").Time().UnixNano() / 1000000
+		if (ctx.Start > 0 && timestamp < ctx.Start) || (ctx.End > 0 && timestamp > ctx.End) {
+			return false
+		}
+		if minAmount != nil {
+			amount, ok := new(big.Int).SetString(d.get("amount").String(), 10)
+			if !ok || amount.Cmp(minAmount) < 0 {
+				return false
+			}
+		}
+		if ctx.LastID != "" {
+			var t models.Transfer
+			t.ParseElasticJSON(d.hit())
+			return last.Follows(t)
+		}
+		return true
+	})
+	sortDocsBy(docs, "desc", "level", "counter", "internal_index", "nonce")
+	docs = page(docs, ctx.Size, 0)
+
+	response.Transfers = make([]models.Transfer, len(docs))
+	for i := range docs {
+		response.Transfers[i].ParseElasticJSON(docs[i].hit())
+	}
+	if len(docs) > 0 {
+		response.LastID = elastic.TransferCursor(response.Transfers[len(docs)-1])
+	}
+	return
+}
@@ -0,0 +1,12 @@
+package elastic
+
+import (
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+// GetMetadata - returns metadata of contract with `address`
+func (e *Elastic) GetMetadata(address string) (data models.Metadata, err error) {
+	data.ID = address
+	err = e.GetByID(&data)
+	return
+}
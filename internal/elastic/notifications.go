@@ -0,0 +1,82 @@
+package elastic
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+// GetPendingNotifications - returns notifications of `network` which have to be delivered not later than `now` in order of their levels
+func (e *Elastic) GetPendingNotifications(network string, now time.Time, size int64) ([]models.Notification, error) {
+	query := newQuery().Query(
+		boolQ(
+			filter(
+				matchPhrase("network", network),
+				matchPhrase("status", models.NotificationPending),
+				rangeQ("next_attempt", qItem{"lte": now.UTC().Format(time.RFC3339Nano)}),
+			),
+		),
+	).Size(size).Sort("level", "asc")
+
+	resp, err := e.query([]string{DocNotifications}, query)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := resp.Get("hits.hits").Array()
+	notifications := make([]models.Notification, len(hits))
+	for i := range hits {
+		notifications[i].ParseElasticJSON(hits[i])
+	}
+	return notifications, nil
+}
+
+// GetNotifications - returns all notifications of `network` with level in (`fromLevel`, `toLevel`]
+func (e *Elastic) GetNotifications(network string, fromLevel, toLevel int64) ([]models.Notification, error) {
+	notifications := make([]models.Notification, 0)
+	query := newQuery().Query(
+		boolQ(
+			filter(
+				matchPhrase("network", network),
+				rangeQ("level", qItem{"gt": fromLevel, "lte": toLevel}),
+			),
+		),
+	)
+	err := e.getByScroll(DocNotifications, query, reflect.TypeOf(models.Notification{}), &notifications)
+	return notifications, err
+}
+
+// GetDocumentLevels - returns levels of `index` documents of `network` with level in (`fromLevel`, `toLevel`] by their IDs
+func (e *Elastic) GetDocumentLevels(index, network string, fromLevel, toLevel int64) (map[string]int64, error) {
+	query := newQuery().Query(
+		boolQ(
+			filter(
+				matchPhrase("network", network),
+				rangeQ("level", qItem{"gt": fromLevel, "lte": toLevel}),
+			),
+		),
+	).Source(qItem{"includes": []string{"level"}})
+
+	result, err := e.createScroll(index, 1000, query)
+	if err != nil {
+		return nil, err
+	}
+
+	levels := make(map[string]int64)
+	for {
+		hits := result.Get("hits.hits")
+		if hits.Get("#").Int() < 1 {
+			break
+		}
+		for _, hit := range hits.Array() {
+			levels[hit.Get("_id").String()] = hit.Get("_source.level").Int()
+		}
+
+		result, err = e.queryScroll(result.Get("_scroll_id").String())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return levels, nil
+}
@@ -53,7 +53,7 @@ type opgForContract struct {
 
 func (e *Elastic) getContractOPG(address, network string, size uint64, filters map[string]interface{}) ([]opgForContract, error) {
 	if size == 0 {
-		size = defaultSize
+		size = DefaultSize
 	}
 
 	filtersString, err := prepareOperationFilters(filters)
@@ -167,14 +167,23 @@ func (e *Elastic) GetContractOperations(network, address string, size uint64, fi
 
 // GetLastStorage -
 func (e *Elastic) GetLastStorage(network, address string) (gjson.Result, error) {
+	return e.GetLastStorageAtLevel(network, address, 0)
+}
+
+// GetLastStorageAtLevel - returns the last operation with storage at or below `level`. Level 0 is the head.
+func (e *Elastic) GetLastStorageAtLevel(network, address string, level int64) (gjson.Result, error) {
+	mustItems := []qItem{
+		matchPhrase("network", network),
+		matchPhrase("destination", address),
+		term("status", "applied"),
+	}
+	if level > 0 {
+		mustItems = append(mustItems, rangeQ("level", qItem{"lte": level}))
+	}
 	query := newQuery().
 		Query(
 			boolQ(
-				must(
-					matchPhrase("network", network),
-					matchPhrase("destination", address),
-					term("status", "applied"),
-				),
+				must(mustItems...),
 				notMust(
 					term("deffated_storage", ""),
 				),
@@ -259,7 +268,7 @@ func (e *Elastic) GetAllLevelsForNetwork(network string) (map[int64]struct{}, er
 	return levels, nil
 }
 
-// GetAffectedContracts -
+// GetAffectedContracts - returns contracts which are source or destination of operations or source of delegations with level in (`toLevel`, `fromLevel`]
 func (e *Elastic) GetAffectedContracts(network string, fromLevel, toLevel int64) ([]string, error) {
 	query := newQuery().Query(
 		boolQ(
@@ -273,33 +282,35 @@ func (e *Elastic) GetAffectedContracts(network string, fromLevel, toLevel int64)
 		),
 	)
 
-	result, err := e.createScroll(DocOperations, 1000, query)
-	if err != nil {
-		return nil, err
-	}
-
 	addressesMap := make(map[string]struct{})
-	for {
-		scrollID := result.Get("_scroll_id").String()
-		hits := result.Get("hits.hits")
-		if hits.Get("#").Int() < 1 {
-			break
+	for _, index := range []string{DocOperations, DocDelegations} {
+		result, err := e.createScroll(index, 1000, query)
+		if err != nil {
+			return nil, err
 		}
 
-		for _, item := range hits.Array() {
-			source := item.Get("_source.source").String()
-			destination := item.Get("_source.destination").String()
-			if strings.HasPrefix(source, "KT") {
-				addressesMap[source] = struct{}{}
+		for {
+			scrollID := result.Get("_scroll_id").String()
+			hits := result.Get("hits.hits")
+			if hits.Get("#").Int() < 1 {
+				break
 			}
-			if strings.HasPrefix(destination, "KT") {
-				addressesMap[destination] = struct{}{}
+
+			for _, item := range hits.Array() {
+				source := item.Get("_source.source").String()
+				destination := item.Get("_source.destination").String()
+				if strings.HasPrefix(source, "KT") {
+					addressesMap[source] = struct{}{}
+				}
+				if strings.HasPrefix(destination, "KT") {
+					addressesMap[destination] = struct{}{}
+				}
 			}
-		}
 
-		result, err = e.queryScroll(scrollID)
-		if err != nil {
-			return nil, err
+			result, err = e.queryScroll(scrollID)
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -0,0 +1,28 @@
+package postgres
+
+import (
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+// GetBalanceChanges -
+func (s *Storage) GetBalanceChanges(network, address string) ([]models.BalanceChange, error) {
+	hits, err := s.query(elastic.DocBalanceChanges, `"network" = ? AND "address" = ?`, `ORDER BY "level"`, network, address)
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]models.BalanceChange, len(hits))
+	for i := range hits {
+		changes[i].ParseElasticJSON(hits[i])
+	}
+	return changes, nil
+}
+
+// GetBalanceAt -
+func (s *Storage) GetBalanceAt(network, address string, level int64) (balance int64, err error) {
+	err = s.db.Raw(
+		`SELECT COALESCE(SUM("change"), 0) FROM balance_changes WHERE "network" = ? AND "address" = ? AND "level" <= ?`,
+		network, address, level,
+	).Row().Scan(&balance)
+	return
+}
@@ -0,0 +1,252 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/tidwall/gjson"
+)
+
+// lastByKey - returns the latest diff of every big map key satisfying `where` ordered by its `indexed_time` desc
+// and the count of diffs of the key. Keys are identified by big map pointer and key hash. `tail` is appended to the outer query, e.g. `LIMIT 10`.
+func (s *Storage) lastByKey(where, tail string, args ...interface{}) ([]gjson.Result, []int64, error) {
+	sql := fmt.Sprintf(`SELECT id, data, cnt FROM (
+		SELECT DISTINCT ON ("ptr", "key_hash") id, data, "indexed_time", count(*) OVER (PARTITION BY "ptr", "key_hash") AS cnt
+		FROM big_map_diffs WHERE %s
+		ORDER BY "ptr", "key_hash", "indexed_time" DESC
+	) AS last ORDER BY "indexed_time" DESC %s`, where, tail)
+
+	rows, err := s.db.Raw(sql, args...).Rows()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	hits := make([]gjson.Result, 0)
+	counts := make([]int64, 0)
+	for rows.Next() {
+		var id, source string
+		var count int64
+		if err := rows.Scan(&id, &source, &count); err != nil {
+			return nil, nil, err
+		}
+		hits = append(hits, hit(elastic.DocBigMapDiff, id, source))
+		counts = append(counts, count)
+	}
+	return hits, counts, rows.Err()
+}
+
+func (s *Storage) lastBigMapDiffs(where string, args ...interface{}) ([]models.BigMapDiff, error) {
+	hits, _, err := s.lastByKey(where, "", args...)
+	if err != nil {
+		return nil, err
+	}
+	response := make([]models.BigMapDiff, len(hits))
+	for i := range hits {
+		response[i].ParseElasticJSON(hits[i])
+	}
+	return response, nil
+}
+
+func byPtr(ptr int64) (string, []interface{}) {
+	if ptr == 0 {
+		return `"ptr" IS NULL`, nil
+	}
+	return `"ptr" = ?`, []interface{}{ptr}
+}
+
+// GetUniqueBigMapDiffsByOperationID -
+func (s *Storage) GetUniqueBigMapDiffsByOperationID(operationID string) ([]models.BigMapDiff, error) {
+	return s.lastBigMapDiffs(`"operation_id" = ?`, operationID)
+}
+
+// GetPrevBigMapDiffs -
+func (s *Storage) GetPrevBigMapDiffs(filters []models.BigMapDiff, indexedTime int64, address string) ([]models.BigMapDiff, error) {
+	if len(filters) == 0 {
+		return []models.BigMapDiff{}, nil
+	}
+	keys := make([]string, len(filters))
+	args := []interface{}{address, indexedTime}
+	for i := range filters {
+		keys[i] = `("key_hash" = ? AND "bin_path" = ?)`
+		args = append(args, filters[i].KeyHash, filters[i].BinPath)
+	}
+	return s.lastBigMapDiffs(fmt.Sprintf(`"address" = ? AND "indexed_time" < ? AND (%s)`, strings.Join(keys, " OR ")), args...)
+}
+
+// GetBigMapDiffsForAddress -
+func (s *Storage) GetBigMapDiffsForAddress(network, address string) ([]models.BigMapDiff, error) {
+	return s.GetBigMapDiffsForAddressAtLevel(network, address, 0)
+}
+
+// GetBigMapDiffsForAddressAtLevel -
+func (s *Storage) GetBigMapDiffsForAddressAtLevel(network, address string, level int64) ([]models.BigMapDiff, error) {
+	return s.lastBigMapDiffs(`"network" = ? AND "address" = ? AND (? = 0 OR "level" <= ?)`, network, address, level, level)
+}
+
+// GetBigMap -
+func (s *Storage) GetBigMap(address string, ptr int64, searchText string, size, offset int64) ([]elastic.BigMapDiff, error) {
+	ptrWhere, ptrArgs := byPtr(ptr)
+	where := `"address" = ? AND ` + ptrWhere
+	args := append([]interface{}{address}, ptrArgs...)
+	if searchText != "" {
+		where += ` AND ("key_hash" ILIKE ? OR "key" ILIKE ? OR array_to_string("key_strings", ' ') ILIKE ?)`
+		pattern := fmt.Sprintf("%%%s%%", searchText)
+		args = append(args, pattern, pattern, pattern)
+	}
+	if size == 0 {
+		size = elastic.DefaultSize
+	}
+	args = append(args, size, offset)
+
+	hits, counts, err := s.lastByKey(where, "LIMIT ? OFFSET ?", args...)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]elastic.BigMapDiff, len(hits))
+	for i := range hits {
+		result[i].ParseElasticJSON(hits[i])
+		result[i].Count = counts[i]
+	}
+	return result, nil
+}
+
+// GetBigMapDiffByPtrAndKeyHash -
+func (s *Storage) GetBigMapDiffByPtrAndKeyHash(address string, ptr int64, keyHash string, size, offset int64) ([]elastic.BigMapDiff, int64, error) {
+	ptrWhere, ptrArgs := byPtr(ptr)
+	where := `"address" = ? AND "key_hash" = ? AND ` + ptrWhere
+	args := append([]interface{}{address, keyHash}, ptrArgs...)
+
+	var total int64
+	if err := s.db.Raw("SELECT count(*) FROM big_map_diffs WHERE "+where, args...).Row().Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	if size == 0 {
+		size = elastic.DefaultSize
+	}
+	hits, err := s.query(elastic.DocBigMapDiff, where, `ORDER BY "level" DESC LIMIT ? OFFSET ?`, append(args, size, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	result := make([]elastic.BigMapDiff, len(hits))
+	for i := range hits {
+		result[i].ParseElasticJSON(hits[i])
+	}
+	return result, total, nil
+}
+
+// GetBigMapDiffsJSONByOperationID -
+func (s *Storage) GetBigMapDiffsJSONByOperationID(operationID string) ([]gjson.Result, error) {
+	return s.query(elastic.DocBigMapDiff, `"operation_id" = ?`, "", operationID)
+}
+
+// GetAllBigMapDiffByPtr -
+func (s *Storage) GetAllBigMapDiffByPtr(address, network string, ptr int64) ([]models.BigMapDiff, error) {
+	return s.lastBigMapDiffs(`"network" = ? AND "address" = ? AND "ptr" = ?`, network, address, ptr)
+}
+
+// liveKeys - the last diffs of keys which are not removed. Format arguments are selected columns and the filter of diffs.
+const liveKeys = `SELECT %s FROM (
+	SELECT DISTINCT ON ("ptr", "key_hash") id, data, "indexed_time", "has_value", count(*) OVER (PARTITION BY "ptr", "key_hash") AS cnt
+	FROM big_map_diffs WHERE %s
+	ORDER BY "ptr", "key_hash", "indexed_time" DESC
+) AS last WHERE "has_value"`
+
+func liveKeysFilter(address string, ptr, level int64) (string, []interface{}) {
+	ptrWhere, ptrArgs := byPtr(ptr)
+	where := `"address" = ? AND (? = 0 OR "level" <= ?) AND ` + ptrWhere
+	return where, append([]interface{}{address, level, level}, ptrArgs...)
+}
+
+// GetBigMapKeys -
+func (s *Storage) GetBigMapKeys(ctx elastic.GetBigMapKeysContext) ([]elastic.BigMapDiff, error) {
+	if ctx.Size == 0 {
+		ctx.Size = elastic.DefaultSize
+	}
+	where, args := liveKeysFilter(ctx.Address, ctx.Ptr, ctx.Level)
+	sql := fmt.Sprintf(liveKeys, "id, data, cnt", where) + ` ORDER BY "indexed_time" DESC LIMIT ? OFFSET ?`
+
+	rows, err := s.db.Raw(sql, append(args, ctx.Size, ctx.Offset)...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]elastic.BigMapDiff, 0)
+	for rows.Next() {
+		var id, source string
+		var b elastic.BigMapDiff
+		if err := rows.Scan(&id, &source, &b.Count); err != nil {
+			return nil, err
+		}
+		b.ParseElasticJSON(hit(elastic.DocBigMapDiff, id, source))
+		result = append(result, b)
+	}
+	return result, rows.Err()
+}
+
+// FilterBigMapKeys -
+func (s *Storage) FilterBigMapKeys(ctx elastic.GetBigMapKeysContext, match func(elastic.BigMapDiff) (bool, error)) ([]elastic.BigMapDiff, int64, error) {
+	if ctx.Size == 0 {
+		ctx.Size = elastic.DefaultSize
+	}
+	where, args := liveKeysFilter(ctx.Address, ctx.Ptr, ctx.Level)
+	rows, err := s.db.Raw(fmt.Sprintf(liveKeys, "id, data, cnt", where)+` ORDER BY "indexed_time" DESC`, args...).Rows()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var total int64
+	result := make([]elastic.BigMapDiff, 0)
+	for rows.Next() {
+		var id, source string
+		var b elastic.BigMapDiff
+		if err := rows.Scan(&id, &source, &b.Count); err != nil {
+			return nil, 0, err
+		}
+		b.ParseElasticJSON(hit(elastic.DocBigMapDiff, id, source))
+
+		ok, err := match(b)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !ok {
+			continue
+		}
+		if total >= ctx.Offset && int64(len(result)) < ctx.Size {
+			result = append(result, b)
+		}
+		total++
+	}
+	return result, total, rows.Err()
+}
+
+// GetBigMapKeysCount -
+func (s *Storage) GetBigMapKeysCount(address string, ptr, level int64) (count int64, err error) {
+	where, args := liveKeysFilter(address, ptr, level)
+	err = s.db.Raw(fmt.Sprintf(liveKeys, "count(*)", where), args...).Row().Scan(&count)
+	return
+}
+
+// GetBigMapKeyHistory -
+func (s *Storage) GetBigMapKeyHistory(address string, ptr int64, keyHash string) ([]elastic.BigMapDiff, error) {
+	ptrWhere, ptrArgs := byPtr(ptr)
+	hits, err := s.query(
+		elastic.DocBigMapDiff,
+		`"address" = ? AND "key_hash" = ? AND `+ptrWhere,
+		`ORDER BY "indexed_time"`,
+		append([]interface{}{address, keyHash}, ptrArgs...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]elastic.BigMapDiff, len(hits))
+	for i := range hits {
+		result[i].ParseElasticJSON(hits[i])
+	}
+	return result, nil
+}
@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+// GetRemovedBigMaps -
+func (s *Storage) GetRemovedBigMaps(network string, level int64) ([]models.BigMap, error) {
+	hits, err := s.query(elastic.DocBigMaps, `"network" = ? AND "removed_level" > ?`, "", network, level)
+	if err != nil {
+		return nil, err
+	}
+	bigMaps := make([]models.BigMap, len(hits))
+	for i := range hits {
+		bigMaps[i].ParseElasticJSON(hits[i])
+	}
+	return bigMaps, nil
+}
+
+// GetFirstBigMapDiffs -
+func (s *Storage) GetFirstBigMapDiffs(network string) ([]models.BigMapDiff, error) {
+	rows, err := s.db.Raw(`SELECT DISTINCT ON ("ptr") id, data FROM big_map_diffs
+		WHERE "network" = ? AND "ptr" IS NOT NULL
+		ORDER BY "ptr", "indexed_time"`, network).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	response := make([]models.BigMapDiff, 0)
+	for rows.Next() {
+		var id, source string
+		if err := rows.Scan(&id, &source); err != nil {
+			return nil, err
+		}
+		var b models.BigMapDiff
+		b.ParseElasticJSON(hit(elastic.DocBigMapDiff, id, source))
+		response = append(response, b)
+	}
+	return response, rows.Err()
+}
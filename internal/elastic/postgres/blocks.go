@@ -0,0 +1,44 @@
+package postgres
+
+import (
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+// CurrentState - returns current indexer state for network
+func (s *Storage) CurrentState(network string) (block models.Block, err error) {
+	block.Network = network
+	hits, err := s.query(elastic.DocBlocks, `"network" = ?`, `ORDER BY "level" DESC LIMIT 1`, network)
+	if err != nil || len(hits) == 0 {
+		return
+	}
+	block.ParseElasticJSON(hits[0])
+	return
+}
+
+// GetBlock -
+func (s *Storage) GetBlock(network string, level int64) (block models.Block, err error) {
+	block.Network = network
+	hits, err := s.query(elastic.DocBlocks, `"network" = ? AND "level" = ?`, "LIMIT 1", network, level)
+	if err != nil {
+		return
+	}
+	if len(hits) == 0 {
+		return block, recordNotFound("block in %s at level %d", network, level)
+	}
+	block.ParseElasticJSON(hits[0])
+	return
+}
+
+// GetAllStates - return last block for all networks
+func (s *Storage) GetAllStates() ([]models.Block, error) {
+	hits, err := s.scanHits(s.db, elastic.DocBlocks, `SELECT DISTINCT ON ("network") id, data FROM blocks ORDER BY "network", "level" DESC`)
+	if err != nil {
+		return nil, err
+	}
+	blocks := make([]models.Block, len(hits))
+	for i := range hits {
+		blocks[i].ParseElasticJSON(hits[i])
+	}
+	return blocks, nil
+}
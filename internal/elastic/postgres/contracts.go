@@ -0,0 +1,197 @@
+package postgres
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/tidwall/gjson"
+)
+
+func parseContracts(hits []gjson.Result) []models.Contract {
+	contracts := make([]models.Contract, len(hits))
+	for i := range hits {
+		contracts[i].ParseElasticJSON(hits[i])
+	}
+	return contracts
+}
+
+// conditions - builds `WHERE` clause by exact match of columns
+func conditions(t table, by map[string]interface{}) (string, []interface{}, error) {
+	keys := make([]string, 0, len(by))
+	for k := range by {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	where := make([]string, len(keys))
+	args := make([]interface{}, len(keys))
+	for i, k := range keys {
+		if !t.hasColumn(k) {
+			return "", nil, fmt.Errorf("Unknown filter field for %s: %s", t.name, k)
+		}
+		where[i] = fmt.Sprintf("%s = ?", quote(k))
+		args[i] = by[k]
+	}
+	return strings.Join(where, " AND "), args, nil
+}
+
+// GetContractByAddressAndNetwork -
+func (s *Storage) GetContractByAddressAndNetwork(network, address string) (models.Contract, error) {
+	return s.GetContract(map[string]interface{}{
+		"address": address,
+		"network": network,
+	})
+}
+
+// GetContract -
+func (s *Storage) GetContract(by map[string]interface{}) (c models.Contract, err error) {
+	where, args, err := conditions(tables[elastic.DocContracts], by)
+	if err != nil {
+		return
+	}
+	hits, err := s.query(elastic.DocContracts, where, "LIMIT 1", args...)
+	if err != nil {
+		return
+	}
+	if len(hits) == 0 {
+		return c, recordNotFound("%v", by)
+	}
+	c.ParseElasticJSON(hits[0])
+	return
+}
+
+// GetContractsByIDsWithSort -
+func (s *Storage) GetContractsByIDsWithSort(ids []string, sortField, sortDirection string) ([]models.Contract, error) {
+	order, err := orderBy(tables[elastic.DocContracts], sortField, sortDirection)
+	if err != nil {
+		return s.IElastic.GetContractsByIDsWithSort(ids, sortField, sortDirection)
+	}
+	hits, err := s.query(elastic.DocContracts, "id IN (?)", order, ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(hits) == 0 {
+		return nil, fmt.Errorf("Unknown contracts with IDs %s", ids)
+	}
+	return parseContracts(hits), nil
+}
+
+// GetContracts -
+func (s *Storage) GetContracts(by map[string]interface{}) ([]models.Contract, error) {
+	where, args, err := conditions(tables[elastic.DocContracts], by)
+	if err != nil {
+		return nil, err
+	}
+	hits, err := s.query(elastic.DocContracts, where, "", args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseContracts(hits), nil
+}
+
+// GetRandomContract -
+func (s *Storage) GetRandomContract() (c models.Contract, err error) {
+	hits, err := s.query(elastic.DocContracts, `"tx_count" >= 2`, "ORDER BY random() LIMIT 1")
+	if err != nil {
+		return
+	}
+	if len(hits) == 0 {
+		return c, recordNotFound("random contract")
+	}
+	c.ParseElasticJSON(hits[0])
+	return
+}
+
+// GetContractWithdrawn -
+func (s *Storage) GetContractWithdrawn(address, network string) (withdrawn int64, err error) {
+	err = s.db.Raw(`SELECT COALESCE(SUM("amount"), 0) FROM operations WHERE "network" = ? AND "source" = ?`, network, address).Row().Scan(&withdrawn)
+	return
+}
+
+// GetContractID -
+func (s *Storage) GetContractID(by map[string]interface{}) (string, error) {
+	c, err := s.GetContract(by)
+	if err != nil {
+		return "", err
+	}
+	return c.ID, nil
+}
+
+// IsFAContract -
+func (s *Storage) IsFAContract(network, address string) (bool, error) {
+	hits, err := s.query(
+		elastic.DocContracts,
+		`"network" = ? AND "address" = ? AND "tags" && ?::text[]`,
+		"LIMIT 1",
+		network, address, textArray([]string{"fa12", "fa1", "fa2"}),
+	)
+	if err != nil {
+		return false, err
+	}
+	return len(hits) > 0, nil
+}
+
+// UpdateContractMigrationsCount -
+func (s *Storage) UpdateContractMigrationsCount(address, network string) error {
+	contract, err := s.GetContractByAddressAndNetwork(network, address)
+	if err != nil {
+		return err
+	}
+	contract.MigrationsCount++
+
+	_, err = s.UpdateDoc(elastic.DocContracts, contract.ID, contract)
+	return err
+}
+
+// GetContractAddressesByNetworkAndLevel -
+func (s *Storage) GetContractAddressesByNetworkAndLevel(network string, maxLevel int64) (gjson.Result, error) {
+	hits, err := s.query(elastic.DocContracts, `"network" = ? AND "level" > ?`, "", network, maxLevel)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	return hitsArray(hits), nil
+}
+
+// NeedParseOperation -
+func (s *Storage) NeedParseOperation(network, source, destination string) (bool, error) {
+	var count int64
+	if err := s.db.Raw(`SELECT count(*) FROM contracts WHERE "network" = ? AND "address" IN (?, ?)`, network, source, destination).Row().Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// IsKnownContract -
+func (s *Storage) IsKnownContract(network, address string) (bool, error) {
+	return s.NeedParseOperation(network, address, address)
+}
+
+// GetContractsIDByAddress -
+func (s *Storage) GetContractsIDByAddress(addresses []string, network string) ([]string, error) {
+	hits, err := s.query(elastic.DocContracts, `"network" = ? AND "address" IN (?)`, "", network, addresses)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(hits))
+	for i := range hits {
+		ids[i] = hits[i].Get("_id").String()
+	}
+	return ids, nil
+}
+
+// RecalcContractStats -
+func (s *Storage) RecalcContractStats(network, address string) (stats elastic.ContractStats, err error) {
+	err = s.db.Raw(`SELECT
+		count(*),
+		COALESCE(max("timestamp"), to_timestamp(0)),
+		COALESCE(SUM(CASE WHEN "status" <> 'applied' THEN 0 WHEN "destination" = ? THEN "amount" ELSE -"amount" END), 0),
+		COALESCE(SUM(CASE WHEN "status" = 'applied' AND "source" = ? THEN "amount" ELSE 0 END), 0)
+	FROM operations WHERE "network" = ? AND ("source" = ? OR "destination" = ?)`,
+		address, address, network, address, address,
+	).Row().Scan(&stats.TxCount, &stats.LastAction, &stats.Balance, &stats.TotalWithdrawn)
+	stats.LastAction = stats.LastAction.UTC()
+	return
+}
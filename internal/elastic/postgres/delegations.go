@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+// delegationsOrder - the latest delegation goes first. Internal operations are applied after the external one in their index order.
+const delegationsOrder = `ORDER BY "level" DESC, "counter" DESC, "internal" DESC, "internal_index" DESC`
+
+// GetDelegations -
+func (s *Storage) GetDelegations(network, address string, size, offset int64) ([]models.Delegation, error) {
+	if size == 0 {
+		size = elastic.DefaultSize
+	}
+	hits, err := s.query(
+		elastic.DocDelegations,
+		`"network" = ? AND "source" = ?`,
+		delegationsOrder+` LIMIT ? OFFSET ?`,
+		network, address, size, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	delegations := make([]models.Delegation, len(hits))
+	for i := range hits {
+		delegations[i].ParseElasticJSON(hits[i])
+	}
+	return delegations, nil
+}
+
+// GetLastDelegation -
+func (s *Storage) GetLastDelegation(network, address string) (d models.Delegation, err error) {
+	hits, err := s.query(
+		elastic.DocDelegations,
+		`"network" = ? AND "source" = ? AND "status" = ?`,
+		delegationsOrder+` LIMIT 1`,
+		network, address, consts.Applied,
+	)
+	if err != nil {
+		return
+	}
+	if len(hits) == 0 {
+		return d, recordNotFound("delegation of %s in %s", address, network)
+	}
+	d.ParseElasticJSON(hits[0])
+	return
+}
+
+// GetReveal -
+func (s *Storage) GetReveal(network, address string) (r models.Reveal, err error) {
+	hits, err := s.query(
+		elastic.DocReveals,
+		`"network" = ? AND "source" = ? AND "status" = ?`,
+		`LIMIT 1`,
+		network, address, consts.Applied,
+	)
+	if err != nil {
+		return
+	}
+	if len(hits) == 0 {
+		return r, recordNotFound("reveal of %s in %s", address, network)
+	}
+	r.ParseElasticJSON(hits[0])
+	return
+}
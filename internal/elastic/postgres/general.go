@@ -0,0 +1,383 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/helpers"
+	"github.com/jinzhu/gorm"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+func (s *Storage) transaction(fn func(tx *gorm.DB) error) error {
+	tx := s.db.Begin()
+	if err := tx.Error; err != nil {
+		return err
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit().Error
+}
+
+func upsert(tx *gorm.DB, t table, id string, source []byte) error {
+	sql, args := t.upsertSQL(id, source)
+	return tx.Exec(sql, args...).Error
+}
+
+// source - returns stored document. The row is locked until the end of transaction.
+func source(tx *gorm.DB, t table, id string) ([]byte, error) {
+	rows, err := tx.Raw(fmt.Sprintf("SELECT data FROM %s WHERE id = ? FOR UPDATE", t.name), id).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	var data string
+	if err := rows.Scan(&data); err != nil {
+		return nil, err
+	}
+	return []byte(data), nil
+}
+
+// CreateIndexes -
+func (s *Storage) CreateIndexes() error {
+	if err := s.migrate(); err != nil {
+		return err
+	}
+	return s.IElastic.CreateIndexes()
+}
+
+// DeleteByLevelAndNetwork -
+func (s *Storage) DeleteByLevelAndNetwork(indices []string, network string, maxLevel int64) error {
+	return s.write(func(tx *gorm.DB, batch string) error {
+		for _, index := range indices {
+			t, ok := tables[index]
+			if !ok {
+				continue
+			}
+			if err := tx.Exec(
+				fmt.Sprintf(`INSERT INTO es_sync (batch, "index", doc_id) SELECT ?, ?, id FROM %s WHERE "network" = ? AND "level" > ?`, t.name),
+				batch, index, network, maxLevel,
+			).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE "network" = ? AND "level" > ?`, t.name), network, maxLevel).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func() error {
+		return s.IElastic.DeleteByLevelAndNetwork(indices, network, maxLevel)
+	})
+}
+
+// GetByID -
+func (s *Storage) GetByID(ret elastic.Model) error {
+	if _, ok := tables[ret.GetIndex()]; !ok {
+		return s.IElastic.GetByID(ret)
+	}
+	hits, err := s.query(ret.GetIndex(), "id = ?", "", ret.GetID())
+	if err != nil {
+		return err
+	}
+	if len(hits) == 0 {
+		return recordNotFound("%s %s", ret.GetIndex(), ret.GetID())
+	}
+	ret.ParseElasticJSON(hits[0])
+	return nil
+}
+
+// GetAll -
+func (s *Storage) GetAll(output interface{}) error {
+	typ, index, err := getElementType(output)
+	if err != nil {
+		return err
+	}
+	if _, ok := tables[index]; !ok {
+		return s.IElastic.GetAll(output)
+	}
+	return s.getByFilter(output, typ, index, "", "")
+}
+
+// GetByNetwork -
+func (s *Storage) GetByNetwork(network string, output interface{}) error {
+	return s.GetByNetworkWithSort(network, "level", "asc", output)
+}
+
+// GetByNetworkWithSort -
+func (s *Storage) GetByNetworkWithSort(network, sortField, sortOrder string, output interface{}) error {
+	typ, index, err := getElementType(output)
+	if err != nil {
+		return err
+	}
+	t, ok := tables[index]
+	if !ok {
+		return s.IElastic.GetByNetworkWithSort(network, sortField, sortOrder, output)
+	}
+	order, err := orderBy(t, sortField, sortOrder)
+	if err != nil {
+		return err
+	}
+	return s.getByFilter(output, typ, index, `"network" = ?`, order, network)
+}
+
+// GetByIDs -
+func (s *Storage) GetByIDs(ids []string, output interface{}) error {
+	typ, index, err := getElementType(output)
+	if err != nil {
+		return err
+	}
+	if _, ok := tables[index]; !ok {
+		return s.IElastic.GetByIDs(ids, output)
+	}
+	return s.getByFilter(output, typ, index, "id IN (?)", "", ids)
+}
+
+func (s *Storage) getByFilter(output interface{}, typ reflect.Type, index, where, tail string, args ...interface{}) error {
+	hits, err := s.query(index, where, tail, args...)
+	if err != nil {
+		return err
+	}
+
+	el := reflect.ValueOf(output).Elem()
+	for i := range hits {
+		n := reflect.New(typ)
+		n.Interface().(elastic.Model).ParseElasticJSON(hits[i])
+		el.Set(reflect.Append(el, n.Elem()))
+	}
+	return nil
+}
+
+func getElementType(output interface{}) (reflect.Type, string, error) {
+	arr := reflect.TypeOf(output)
+	if arr.Kind() != reflect.Ptr || arr.Elem().Kind() != reflect.Slice {
+		return nil, "", fmt.Errorf("Invalid `output` type: %s", arr.Kind())
+	}
+	typ := arr.Elem().Elem()
+	model, ok := reflect.New(typ).Interface().(elastic.Model)
+	if !ok {
+		return nil, "", fmt.Errorf("Implements: 'output' is not implemented `Model` interface")
+	}
+	return typ, model.GetIndex(), nil
+}
+
+// orderBy - builds `ORDER BY` clause. Only indexed columns are allowed to be used for sorting.
+func orderBy(t table, field, order string) (string, error) {
+	if field == "" {
+		return "", nil
+	}
+	if !t.hasColumn(field) {
+		return "", fmt.Errorf("Unknown sort field for %s: %s", t.name, field)
+	}
+	switch strings.ToLower(order) {
+	case "asc", "":
+		order = "ASC"
+	case "desc":
+		order = "DESC"
+	default:
+		return "", fmt.Errorf("Unknown sort order: %s", order)
+	}
+	return fmt.Sprintf("ORDER BY %s %s", quote(field), order), nil
+}
+
+// AddDocumentWithID -
+func (s *Storage) AddDocumentWithID(v interface{}, index, docID string) (id string, err error) {
+	t, ok := tables[index]
+	if !ok {
+		return s.IElastic.AddDocumentWithID(v, index, docID)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	err = s.write(func(tx *gorm.DB, batch string) error {
+		if err := upsert(tx, t, docID, data); err != nil {
+			return err
+		}
+		return mark(tx, batch, index, docID)
+	}, func() (err error) {
+		id, err = s.IElastic.AddDocumentWithID(v, index, docID)
+		return
+	})
+	return
+}
+
+// CreateDocument - saves the document only if there is no document with the same ID
+func (s *Storage) CreateDocument(item elastic.Model) error {
+	t, ok := tables[item.GetIndex()]
+	if !ok {
+		return s.IElastic.CreateDocument(item)
+	}
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	return s.write(func(tx *gorm.DB, batch string) error {
+		sql, args := t.createSQL(item.GetID(), data)
+		res := tx.Exec(sql, args...)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("%s: %s %s", elastic.DocumentExists, item.GetIndex(), item.GetID())
+		}
+		return mark(tx, batch, item.GetIndex(), item.GetID())
+	}, func() error {
+		return s.IElastic.CreateDocument(item)
+	})
+}
+
+// UpdateDoc - updates document by ID
+func (s *Storage) UpdateDoc(index, id string, v interface{}) (res gjson.Result, err error) {
+	t, ok := tables[index]
+	if !ok {
+		return s.IElastic.UpdateDoc(index, id, v)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	err = s.write(func(tx *gorm.DB, batch string) error {
+		if err := upsert(tx, t, id, data); err != nil {
+			return err
+		}
+		return mark(tx, batch, index, id)
+	}, func() (err error) {
+		res, err = s.IElastic.UpdateDoc(index, id, v)
+		return
+	})
+	return
+}
+
+// UpdateFields -
+func (s *Storage) UpdateFields(index, id string, data interface{}, fields ...string) error {
+	t, ok := tables[index]
+	if !ok {
+		return s.IElastic.UpdateFields(index, id, data, fields...)
+	}
+
+	val := reflect.ValueOf(data)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	typ := val.Type()
+
+	return s.write(func(tx *gorm.DB, batch string) error {
+		doc, err := source(tx, t, id)
+		if err != nil {
+			return err
+		}
+		if doc == nil {
+			return recordNotFound("%s %s", index, id)
+		}
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if !helpers.StringInArray(field.Name, fields) {
+				continue
+			}
+			tagName := strings.Split(field.Tag.Get("json"), ",")[0]
+			if doc, err = sjson.SetBytes(doc, tagName, val.Field(i).Interface()); err != nil {
+				return err
+			}
+		}
+		if err := upsert(tx, t, id, doc); err != nil {
+			return err
+		}
+		return mark(tx, batch, index, id)
+	}, func() error {
+		return s.IElastic.UpdateFields(index, id, data, fields...)
+	})
+}
+
+// BulkInsert -
+func (s *Storage) BulkInsert(items []elastic.Model) error {
+	return s.write(func(tx *gorm.DB, batch string) error {
+		for i := range items {
+			t, ok := tables[items[i].GetIndex()]
+			if !ok {
+				continue
+			}
+			data, err := json.Marshal(items[i])
+			if err != nil {
+				return err
+			}
+			if err := upsert(tx, t, items[i].GetID(), data); err != nil {
+				return err
+			}
+			if err := mark(tx, batch, items[i].GetIndex(), items[i].GetID()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func() error {
+		return s.IElastic.BulkInsert(items)
+	})
+}
+
+// BulkUpdate - merges updates into existing documents like the `doc` form of Elasticsearch update does. Unknown documents are skipped.
+func (s *Storage) BulkUpdate(updates []elastic.Model) error {
+	return s.write(func(tx *gorm.DB, batch string) error {
+		for i := range updates {
+			t, ok := tables[updates[i].GetIndex()]
+			if !ok {
+				continue
+			}
+			doc, err := source(tx, t, updates[i].GetID())
+			if err != nil {
+				return err
+			}
+			if doc == nil {
+				continue
+			}
+			data, err := json.Marshal(updates[i])
+			if err != nil {
+				return err
+			}
+			for k, v := range gjson.ParseBytes(data).Map() {
+				if doc, err = sjson.SetRawBytes(doc, k, []byte(v.Raw)); err != nil {
+					return err
+				}
+			}
+			if err := upsert(tx, t, updates[i].GetID(), doc); err != nil {
+				return err
+			}
+			if err := mark(tx, batch, updates[i].GetIndex(), updates[i].GetID()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func() error {
+		return s.IElastic.BulkUpdate(updates)
+	})
+}
+
+// BulkDelete -
+func (s *Storage) BulkDelete(items []elastic.Model) error {
+	return s.write(func(tx *gorm.DB, batch string) error {
+		for i := range items {
+			t, ok := tables[items[i].GetIndex()]
+			if !ok {
+				continue
+			}
+			if err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE id = ?", t.name), items[i].GetID()).Error; err != nil {
+				return err
+			}
+			if err := mark(tx, batch, items[i].GetIndex(), items[i].GetID()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func() error {
+		return s.IElastic.BulkDelete(items)
+	})
+}
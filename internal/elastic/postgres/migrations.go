@@ -0,0 +1,25 @@
+package postgres
+
+import (
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+// GetMigrations -
+func (s *Storage) GetMigrations(network, address string) ([]models.Migration, error) {
+	hits, err := s.query(elastic.DocMigrations, `"network" = ? AND "address" = ?`, `ORDER BY "level" DESC`, network, address)
+	if err != nil {
+		return nil, err
+	}
+	migrations := make([]models.Migration, len(hits))
+	for i := range hits {
+		migrations[i].ParseElasticJSON(hits[i])
+	}
+	return migrations, nil
+}
+
+// GetContractMigrationStats -
+func (s *Storage) GetContractMigrationStats(network, address string) (stats elastic.ContractMigrationsStats, err error) {
+	err = s.db.Raw(`SELECT count(*) FROM migrations WHERE "network" = ? AND "address" = ?`, network, address).Row().Scan(&stats.MigrationsCount)
+	return
+}
@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/tidwall/gjson"
+)
+
+func parseNotifications(hits []gjson.Result) []models.Notification {
+	notifications := make([]models.Notification, len(hits))
+	for i := range hits {
+		notifications[i].ParseElasticJSON(hits[i])
+	}
+	return notifications
+}
+
+// GetPendingNotifications -
+func (s *Storage) GetPendingNotifications(network string, now time.Time, size int64) ([]models.Notification, error) {
+	hits, err := s.query(
+		elastic.DocNotifications,
+		`"network" = ? AND "status" = ? AND "next_attempt" <= ?`,
+		`ORDER BY "level" LIMIT ?`,
+		network, models.NotificationPending, now.UTC(), size,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return parseNotifications(hits), nil
+}
+
+// GetNotifications -
+func (s *Storage) GetNotifications(network string, fromLevel, toLevel int64) ([]models.Notification, error) {
+	hits, err := s.query(elastic.DocNotifications, `"network" = ? AND "level" > ? AND "level" <= ?`, "", network, fromLevel, toLevel)
+	if err != nil {
+		return nil, err
+	}
+	return parseNotifications(hits), nil
+}
+
+// GetDocumentLevels -
+func (s *Storage) GetDocumentLevels(index, network string, fromLevel, toLevel int64) (map[string]int64, error) {
+	t, ok := tables[index]
+	if !ok {
+		return s.IElastic.GetDocumentLevels(index, network, fromLevel, toLevel)
+	}
+	rows, err := s.db.Raw(`SELECT id, "level" FROM `+t.name+` WHERE "network" = ? AND "level" > ? AND "level" <= ?`, network, fromLevel, toLevel).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	levels := make(map[string]int64)
+	for rows.Next() {
+		var id string
+		var level int64
+		if err := rows.Scan(&id, &level); err != nil {
+			return nil, err
+		}
+		levels[id] = level
+	}
+	return levels, rows.Err()
+}
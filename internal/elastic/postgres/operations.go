@@ -0,0 +1,210 @@
+package postgres
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/tidwall/gjson"
+)
+
+// operationOrder - the same order as the painless script in `elastic.GetContractOperations`
+const operationOrder = `"level" DESC, "counter" DESC, (CASE WHEN "internal" THEN 999 - "internal_index" ELSE 999 END) DESC`
+
+func parseOperations(hits []gjson.Result) []models.Operation {
+	operations := make([]models.Operation, len(hits))
+	for i := range hits {
+		operations[i].ParseElasticJSON(hits[i])
+	}
+	return operations
+}
+
+// GetOperationByHash -
+func (s *Storage) GetOperationByHash(hash string) ([]models.Operation, error) {
+	hits, err := s.query(elastic.DocOperations, `"hash" = ?`, "ORDER BY "+operationOrder, hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(hits) == 0 {
+		return nil, fmt.Errorf("Unknown operation with hash %s", hash)
+	}
+	return parseOperations(hits), nil
+}
+
+// GetContractOperations -
+func (s *Storage) GetContractOperations(network, address string, size uint64, filters map[string]interface{}) (po elastic.PageableOperations, err error) {
+	if size == 0 {
+		size = elastic.DefaultSize
+	}
+	where, args, err := prepareOperationFilters(filters)
+	if err != nil {
+		return
+	}
+
+	sql := fmt.Sprintf(`WITH opg AS (
+		SELECT "hash", "counter", max("level") AS "level" FROM operations
+		WHERE "network" = ? AND ("source" = ? OR "destination" = ?)%s
+		GROUP BY "hash", "counter" ORDER BY "level" DESC LIMIT ?
+	)
+	SELECT o.id, o.data FROM operations AS o
+	JOIN opg ON o."hash" = opg."hash" AND o."counter" = opg."counter"
+	WHERE o."network" = ?
+	ORDER BY o."level" DESC, o."counter" DESC, (CASE WHEN o."internal" THEN 999 - o."internal_index" ELSE 999 END) DESC`, where)
+
+	queryArgs := append([]interface{}{network, address, address}, args...)
+	queryArgs = append(queryArgs, size, network)
+
+	hits, err := s.scanHits(s.db, elastic.DocOperations, sql, queryArgs...)
+	if err != nil {
+		return
+	}
+
+	po.Operations = parseOperations(hits)
+	for i := range hits {
+		indexedTime := hits[i].Get("_source.indexed_time").Int()
+		if lastID, _ := strconv.ParseInt(po.LastID, 10, 64); po.LastID == "" || indexedTime < lastID {
+			po.LastID = fmt.Sprintf("%d", indexedTime)
+		}
+	}
+	return
+}
+
+func prepareOperationFilters(filters map[string]interface{}) (string, []interface{}, error) {
+	var sb strings.Builder
+	args := make([]interface{}, 0)
+	for k, v := range filters {
+		value := fmt.Sprintf("%v", v)
+		if value == "" {
+			continue
+		}
+		switch k {
+		case "from":
+			from, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return "", nil, err
+			}
+			sb.WriteString(` AND "timestamp" >= to_timestamp(?::double precision / 1000)`)
+			args = append(args, from)
+		case "to":
+			to, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return "", nil, err
+			}
+			sb.WriteString(` AND "timestamp" <= to_timestamp(?::double precision / 1000)`)
+			args = append(args, to)
+		case "entrypoints":
+			sb.WriteString(` AND "entrypoint" IN (?)`)
+			args = append(args, parseSQLList(value))
+		case "last_id":
+			lastID, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return "", nil, err
+			}
+			sb.WriteString(` AND "indexed_time" < ?`)
+			args = append(args, lastID)
+		case "status":
+			sb.WriteString(` AND "status" IN (?)`)
+			args = append(args, parseSQLList(value))
+		default:
+			return "", nil, fmt.Errorf("Unknown operation filter: %s %v", k, v)
+		}
+	}
+	return sb.String(), args, nil
+}
+
+// parseSQLList - parses lists like `'applied','failed'`
+func parseSQLList(value string) []string {
+	items := strings.Split(value, ",")
+	for i := range items {
+		items[i] = strings.Trim(strings.TrimSpace(items[i]), "'")
+	}
+	return items
+}
+
+const storageOperations = `"network" = ? AND "destination" = ? AND "status" = 'applied' AND "has_deffated_storage"`
+
+// GetLastStorage -
+func (s *Storage) GetLastStorage(network, address string) (gjson.Result, error) {
+	hits, err := s.query(elastic.DocOperations, storageOperations, `ORDER BY "indexed_time" DESC LIMIT 1`, network, address)
+	if err != nil || len(hits) == 0 {
+		return gjson.Result{}, err
+	}
+	return hits[0], nil
+}
+
+// GetLastStorageAtLevel -
+func (s *Storage) GetLastStorageAtLevel(network, address string, level int64) (gjson.Result, error) {
+	hits, err := s.query(elastic.DocOperations, storageOperations+` AND (? = 0 OR "level" <= ?)`, `ORDER BY "indexed_time" DESC LIMIT 1`, network, address, level, level)
+	if err != nil || len(hits) == 0 {
+		return gjson.Result{}, err
+	}
+	return hits[0], nil
+}
+
+// GetPreviousOperation -
+func (s *Storage) GetPreviousOperation(address, network string, indexedTime int64) (op models.Operation, err error) {
+	hits, err := s.query(elastic.DocOperations, storageOperations+` AND "indexed_time" < ?`, `ORDER BY "indexed_time" DESC LIMIT 1`, network, address, indexedTime)
+	if err != nil {
+		return
+	}
+	if len(hits) == 0 {
+		return op, recordNotFound("%s in %s on %d", address, network, indexedTime)
+	}
+	op.ParseElasticJSON(hits[0])
+	return
+}
+
+// GetAllLevelsForNetwork -
+func (s *Storage) GetAllLevelsForNetwork(network string) (map[int64]struct{}, error) {
+	rows, err := s.db.Raw(`SELECT DISTINCT "level" FROM operations WHERE "network" = ?`, network).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	levels := make(map[int64]struct{})
+	for rows.Next() {
+		var level int64
+		if err := rows.Scan(&level); err != nil {
+			return nil, err
+		}
+		levels[level] = struct{}{}
+	}
+	return levels, rows.Err()
+}
+
+// GetAffectedContracts -
+func (s *Storage) GetAffectedContracts(network string, fromLevel, toLevel int64) ([]string, error) {
+	rows, err := s.db.Raw(`SELECT address FROM (
+		SELECT "source" AS address FROM operations WHERE "network" = ? AND "level" <= ? AND "level" > ?
+		UNION
+		SELECT "destination" AS address FROM operations WHERE "network" = ? AND "level" <= ? AND "level" > ?
+		UNION
+		SELECT "source" AS address FROM delegations WHERE "network" = ? AND "level" <= ? AND "level" > ?
+	) AS affected WHERE address LIKE 'KT%'`, network, fromLevel, toLevel, network, fromLevel, toLevel, network, fromLevel, toLevel).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addresses := make([]string, 0)
+	for rows.Next() {
+		var address string
+		if err := rows.Scan(&address); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, rows.Err()
+}
+
+// GetAllOperationsByStatus -
+func (s *Storage) GetAllOperationsByStatus(network, status string) ([]models.Operation, error) {
+	hits, err := s.query(elastic.DocOperations, `"network" = ? AND "status" = ?`, "", network, status)
+	if err != nil {
+		return nil, err
+	}
+	return parseOperations(hits), nil
+}
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/jinzhu/gorm"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	// postgres driver
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+)
+
+// Storage - keeps chain data (blocks, protocols, contracts, operations, big map diffs, migrations, delegations, reveals, balance changes, token balance changes, token transfers and big map registry) in PostgreSQL.
+// PostgreSQL is the source of truth for these documents. Every write is duplicated to Elasticsearch which is still used
+// for full-text search, aggregations and the rest of indices. Documents of writes failed in Elasticsearch are re-sent in background.
+type Storage struct {
+	elastic.IElastic
+
+	db   *gorm.DB
+	stop chan struct{}
+}
+
+var _ elastic.IElastic = (*Storage)(nil)
+
+// New - connects to PostgreSQL. `es` is used for everything PostgreSQL doesn't store.
+func New(connString string, es elastic.IElastic) (*Storage, error) {
+	db, err := gorm.Open("postgres", connString)
+	if err != nil {
+		return nil, err
+	}
+	db.LogMode(false)
+
+	s := &Storage{
+		IElastic: es,
+		db:       db,
+		stop:     make(chan struct{}),
+	}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	go s.runRepair(repairPeriod, s.stop)
+	return s, nil
+}
+
+// WaitNew - waits until PostgreSQL is available
+func WaitNew(connString string, es elastic.IElastic) *Storage {
+	var s *Storage
+	var err error
+	for s == nil {
+		s, err = New(connString, es)
+		if err != nil {
+			log.Printf("Wait PostgreSQL up 30 seconds: %s", err)
+			time.Sleep(time.Second * 30)
+		}
+	}
+	return s
+}
+
+// Close -
+func (s *Storage) Close() error {
+	close(s.stop)
+	return s.db.Close()
+}
+
+// hit - builds document in the form of Elasticsearch hit, so it can be parsed by `ParseElasticJSON`
+func hit(index, id, source string) gjson.Result {
+	data, _ := sjson.SetRaw(`{}`, "_source", source)
+	data, _ = sjson.Set(data, "_id", id)
+	data, _ = sjson.Set(data, "_index", index)
+	return gjson.Parse(data)
+}
+
+func recordNotFound(format string, args ...interface{}) error {
+	return fmt.Errorf("%s: %s", elastic.RecordNotFound, fmt.Sprintf(format, args...))
+}
+
+// query - selects documents of `index` satisfying `where` (may be empty). `tail` is appended as is, e.g. `ORDER BY level DESC LIMIT 1`.
+func (s *Storage) query(index, where, tail string, args ...interface{}) ([]gjson.Result, error) {
+	t, ok := tables[index]
+	if !ok {
+		return nil, fmt.Errorf("Unknown table for index: %s", index)
+	}
+	sql := fmt.Sprintf("SELECT id, data FROM %s", t.name)
+	if where != "" {
+		sql = fmt.Sprintf("%s WHERE %s", sql, where)
+	}
+	if tail != "" {
+		sql = fmt.Sprintf("%s %s", sql, tail)
+	}
+	return s.scanHits(s.db, index, sql, args...)
+}
+
+func (s *Storage) scanHits(db *gorm.DB, index, sql string, args ...interface{}) ([]gjson.Result, error) {
+	rows, err := db.Raw(sql, args...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := make([]gjson.Result, 0)
+	for rows.Next() {
+		var id, source string
+		if err := rows.Scan(&id, &source); err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit(index, id, source))
+	}
+	return hits, rows.Err()
+}
+
+// hitsArray - joins hits to the JSON array like `hits.hits` of Elasticsearch response
+func hitsArray(hits []gjson.Result) gjson.Result {
+	data := "[]"
+	for i := range hits {
+		data, _ = sjson.SetRaw(data, "-1", hits[i].Raw)
+	}
+	return gjson.Parse(data)
+}
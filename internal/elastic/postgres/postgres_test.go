@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/tidwall/gjson"
+)
+
+func TestColumnValue(t *testing.T) {
+	source := gjson.Parse(`{"level": 100, "internal": true, "network": "mainnet", "timestamp": "2020-04-01T10:00:00Z", "tags": ["fa12", "say \"hi\""], "key": {"int": "1"}, "value": ""}`)
+	tests := []struct {
+		name   string
+		column column
+		want   interface{}
+	}{
+		{"text", column{"network", columnText}, "mainnet"},
+		{"int", column{"level", columnInt}, int64(100)},
+		{"missing int", column{"counter", columnInt}, int64(0)},
+		{"null int", column{"ptr", columnNullInt}, nil},
+		{"bool", column{"internal", columnBool}, true},
+		{"time", column{"timestamp", columnTime}, time.Date(2020, 4, 1, 10, 0, 0, 0, time.UTC)},
+		{"missing time", column{"last_action", columnTime}, nil},
+		{"text array", column{"tags", columnTextArray}, `{"fa12","say \"hi\""}`},
+		{"missing text array", column{"key_strings", columnTextArray}, `{}`},
+		{"raw", column{"key", columnRaw}, `{"int": "1"}`},
+		{"exists", column{"has_network", columnExists}, true},
+		{"empty exists", column{"has_value", columnExists}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.column.value(source); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("value() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpsertSQL(t *testing.T) {
+	sql, args := tables[elastic.DocMigrations].upsertSQL("id1", []byte(`{"network":"mainnet","address":"KT1","level":5,"kind":"update"}`))
+
+	wantSQL := `INSERT INTO migrations (id, "network", "address", "level", "kind", data) VALUES (?, ?, ?, ?, ?, ?::jsonb) ` +
+		`ON CONFLICT (id) DO UPDATE SET "network" = EXCLUDED."network", "address" = EXCLUDED."address", "level" = EXCLUDED."level", "kind" = EXCLUDED."kind", data = EXCLUDED.data`
+	if sql != wantSQL {
+		t.Errorf("upsertSQL() sql = %s, want %s", sql, wantSQL)
+	}
+	wantArgs := []interface{}{"id1", "mainnet", "KT1", int64(5), "update", `{"network":"mainnet","address":"KT1","level":5,"kind":"update"}`}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("upsertSQL() args = %v, want %v", args, wantArgs)
+	}
+}
+
+func TestConditions(t *testing.T) {
+	tests := []struct {
+		name      string
+		by        map[string]interface{}
+		wantWhere string
+		wantArgs  []interface{}
+		wantErr   bool
+	}{
+		{
+			name:      "columns",
+			by:        map[string]interface{}{"network": "mainnet", "address": "KT1", "language": "smartpy"},
+			wantWhere: `"address" = ? AND "language" = ? AND "network" = ?`,
+			wantArgs:  []interface{}{"KT1", "smartpy", "mainnet"},
+		}, {
+			name:    "unknown field",
+			by:      map[string]interface{}{"fingerprint.parameter": "abc", "network": "mainnet"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args, err := conditions(tables[elastic.DocContracts], tt.by)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("conditions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if where != tt.wantWhere {
+				t.Errorf("conditions() where = %s, want %s", where, tt.wantWhere)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("conditions() args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestPrepareOperationFilters(t *testing.T) {
+	where, args, err := prepareOperationFilters(map[string]interface{}{
+		"status": "'applied','failed'",
+	})
+	if err != nil {
+		t.Errorf("prepareOperationFilters() error = %v", err)
+		return
+	}
+	if where != ` AND "status" IN (?)` {
+		t.Errorf("prepareOperationFilters() where = %s", where)
+	}
+	if !reflect.DeepEqual(args, []interface{}{[]string{"applied", "failed"}}) {
+		t.Errorf("prepareOperationFilters() args = %v", args)
+	}
+
+	if _, _, err := prepareOperationFilters(map[string]interface{}{"unknown": 1}); err == nil {
+		t.Errorf("prepareOperationFilters() expected error for unknown filter")
+	}
+}
+
+func TestRawDocument(t *testing.T) {
+	doc := new(rawDocument)
+	doc.ParseElasticJSON(hit(elastic.DocMigrations, "id1", `{"network":"mainnet","level":5}`))
+	if doc.GetID() != "id1" || doc.GetIndex() != elastic.DocMigrations {
+		t.Errorf("rawDocument = %s %s", doc.GetIndex(), doc.GetID())
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"network":"mainnet","level":5}` {
+		t.Errorf("json.Marshal() = %s", data)
+	}
+}
@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+// GetProtocol - returns current protocol for `network` and `level` (`hash` is optional, leave empty string for default)
+func (s *Storage) GetProtocol(network, hash string, level int64) (p models.Protocol, err error) {
+	where := `"network" = ?`
+	args := []interface{}{network}
+	if level > -1 {
+		where += ` AND "start_level" <= ?`
+		args = append(args, level)
+	}
+	if hash != "" {
+		where += ` AND "hash" = ?`
+		args = append(args, hash)
+	}
+
+	hits, err := s.query(elastic.DocProtocol, where, `ORDER BY "start_level" DESC LIMIT 1`, args...)
+	if err != nil {
+		return
+	}
+	if len(hits) == 0 {
+		err = fmt.Errorf("Couldn't find a protocol for %s (hash = %s) at level %d", network, hash, level)
+		return
+	}
+	p.ParseElasticJSON(hits[0])
+	return
+}
+
+// GetSymLinks - returns list of symlinks in `network` after `level`
+func (s *Storage) GetSymLinks(network string, level int64) (map[string]bool, error) {
+	hits, err := s.query(elastic.DocProtocol, `"network" = ? AND "start_level" > ?`, "", network, level)
+	if err != nil {
+		return nil, err
+	}
+	symMap := make(map[string]bool)
+	for i := range hits {
+		symMap[hits[i].Get("_source.sym_link").String()] = true
+	}
+	return symMap, nil
+}
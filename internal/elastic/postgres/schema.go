@@ -0,0 +1,582 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/tidwall/gjson"
+)
+
+type columnKind int
+
+const (
+	columnText columnKind = iota
+	columnInt
+	columnNullInt
+	columnBool
+	columnTime
+	columnNumeric
+	columnTextArray
+	columnRaw
+	columnExists
+)
+
+// column - indexed column which value is extracted from the document by the same JSON path as its name.
+// `columnExists` columns are named `has_<field>` and keep whether the document field is not empty.
+type column struct {
+	name string
+	kind columnKind
+}
+
+func (c column) value(source gjson.Result) interface{} {
+	value := source.Get(c.name)
+	switch c.kind {
+	case columnInt:
+		return value.Int()
+	case columnNullInt:
+		if !value.Exists() {
+			return nil
+		}
+		return value.Int()
+	case columnBool:
+		return value.Bool()
+	case columnTime:
+		if !value.Exists() || value.String() == "" {
+			return nil
+		}
+		return value.Time().UTC()
+	case columnNumeric:
+		if value.String() == "" {
+			return "0"
+		}
+		return value.String()
+	case columnTextArray:
+		items := make([]string, 0)
+		for _, item := range value.Array() {
+			items = append(items, item.String())
+		}
+		return textArray(items)
+	case columnRaw:
+		return value.Raw
+	case columnExists:
+		return source.Get(strings.TrimPrefix(c.name, "has_")).String() != ""
+	default:
+		return value.String()
+	}
+}
+
+func (c column) placeholder() string {
+	if c.kind == columnTextArray {
+		return "?::text[]"
+	}
+	return "?"
+}
+
+// textArray - returns literal of PostgreSQL text array
+func textArray(items []string) string {
+	quoted := make([]string, len(items))
+	for i := range items {
+		item := strings.ReplaceAll(items[i], `\`, `\\`)
+		quoted[i] = `"` + strings.ReplaceAll(item, `"`, `\"`) + `"`
+	}
+	return "{" + strings.Join(quoted, ",") + "}"
+}
+
+// table - every document is stored in the `data` column as is. Columns are typed copies of the document fields;
+// queries filter and sort only by columns and read `data` to build documents.
+type table struct {
+	name    string
+	columns []column
+}
+
+func (t table) hasColumn(name string) bool {
+	for i := range t.columns {
+		if t.columns[i].name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// upsertSQL - returns statement inserting or replacing the document and its arguments
+func (t table) upsertSQL(id string, source []byte) (string, []interface{}) {
+	return t.insertSQL(id, source, true)
+}
+
+// createSQL - returns statement inserting the document if there is no document with the same ID
+func (t table) createSQL(id string, source []byte) (string, []interface{}) {
+	return t.insertSQL(id, source, false)
+}
+
+func (t table) insertSQL(id string, source []byte, replace bool) (string, []interface{}) {
+	parsed := gjson.ParseBytes(source)
+
+	names := []string{"id"}
+	placeholders := []string{"?"}
+	updates := make([]string, 0, len(t.columns)+1)
+	args := []interface{}{id}
+	for _, c := range t.columns {
+		names = append(names, quote(c.name))
+		placeholders = append(placeholders, c.placeholder())
+		updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", quote(c.name), quote(c.name)))
+		args = append(args, c.value(parsed))
+	}
+	names = append(names, "data")
+	placeholders = append(placeholders, "?::jsonb")
+	updates = append(updates, "data = EXCLUDED.data")
+	args = append(args, string(source))
+
+	conflict := "DO NOTHING"
+	if replace {
+		conflict = "DO UPDATE SET " + strings.Join(updates, ", ")
+	}
+	return fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (id) %s",
+		t.name,
+		strings.Join(names, ", "),
+		strings.Join(placeholders, ", "),
+		conflict,
+	), args
+}
+
+func quote(name string) string {
+	return fmt.Sprintf(`"%s"`, name)
+}
+
+var tables = map[string]table{
+	elastic.DocBlocks: {
+		name: "blocks",
+		columns: []column{
+			{"network", columnText},
+			{"level", columnInt},
+			{"hash", columnText},
+			{"timestamp", columnTime},
+		},
+	},
+	elastic.DocProtocol: {
+		name: "protocols",
+		columns: []column{
+			{"network", columnText},
+			{"hash", columnText},
+			{"start_level", columnInt},
+			{"end_level", columnInt},
+			{"sym_link", columnText},
+		},
+	},
+	elastic.DocContracts: {
+		name: "contracts",
+		columns: []column{
+			{"network", columnText},
+			{"address", columnText},
+			{"level", columnInt},
+			{"timestamp", columnTime},
+			{"hash", columnText},
+			{"project_id", columnText},
+			{"last_action", columnTime},
+			{"tx_count", columnInt},
+			{"language", columnText},
+			{"tags", columnTextArray},
+		},
+	},
+	elastic.DocOperations: {
+		name: "operations",
+		columns: []column{
+			{"network", columnText},
+			{"hash", columnText},
+			{"counter", columnInt},
+			{"internal", columnBool},
+			{"internal_index", columnInt},
+			{"level", columnInt},
+			{"timestamp", columnTime},
+			{"indexed_time", columnInt},
+			{"kind", columnText},
+			{"status", columnText},
+			{"source", columnText},
+			{"destination", columnText},
+			{"entrypoint", columnText},
+			{"amount", columnInt},
+			{"has_deffated_storage", columnExists},
+		},
+	},
+	elastic.DocBigMapDiff: {
+		name: "big_map_diffs",
+		columns: []column{
+			{"network", columnText},
+			{"address", columnText},
+			{"ptr", columnNullInt},
+			{"key_hash", columnText},
+			{"bin_path", columnText},
+			{"operation_id", columnText},
+			{"level", columnInt},
+			{"indexed_time", columnInt},
+			{"key", columnRaw},
+			{"key_strings", columnTextArray},
+			{"has_value", columnExists},
+		},
+	},
+	elastic.DocMigrations: {
+		name: "migrations",
+		columns: []column{
+			{"network", columnText},
+			{"address", columnText},
+			{"level", columnInt},
+			{"kind", columnText},
+		},
+	},
+	elastic.DocNotifications: {
+		name: "notifications",
+		columns: []column{
+			{"network", columnText},
+			{"level", columnInt},
+			{"status", columnText},
+			{"next_attempt", columnTime},
+		},
+	},
+	elastic.DocDelegations: {
+		name: "delegations",
+		columns: []column{
+			{"network", columnText},
+			{"level", columnInt},
+			{"counter", columnInt},
+			{"internal", columnBool},
+			{"internal_index", columnInt},
+			{"indexed_time", columnInt},
+			{"status", columnText},
+			{"source", columnText},
+			{"delegate", columnText},
+		},
+	},
+	elastic.DocReveals: {
+		name: "reveals",
+		columns: []column{
+			{"network", columnText},
+			{"level", columnInt},
+			{"status", columnText},
+			{"source", columnText},
+		},
+	},
+	elastic.DocBalanceChanges: {
+		name: "balance_changes",
+		columns: []column{
+			{"network", columnText},
+			{"address", columnText},
+			{"level", columnInt},
+			{"change", columnInt},
+		},
+	},
+	elastic.DocTokenBalanceChanges: {
+		name: "token_balance_changes",
+		columns: []column{
+			{"network", columnText},
+			{"contract", columnText},
+			{"address", columnText},
+			{"level", columnInt},
+			{"change", columnNumeric},
+		},
+	},
+	elastic.DocTransfers: {
+		name: "transfers",
+		columns: []column{
+			{"network", columnText},
+			{"contract", columnText},
+			{"from", columnText},
+			{"to", columnText},
+			{"amount", columnNumeric},
+			{"level", columnInt},
+			{"counter", columnInt},
+			{"internal_index", columnInt},
+			{"timestamp", columnTime},
+			{"indexed_time", columnInt},
+			{"nonce", columnInt},
+			{"operation_id", columnText},
+		},
+	},
+	elastic.DocBigMaps: {
+		name: "big_maps",
+		columns: []column{
+			{"network", columnText},
+			{"ptr", columnInt},
+			{"address", columnText},
+			{"level", columnInt},
+			{"removed_level", columnInt},
+		},
+	},
+}
+
+// migrations - schema changes applied in order. Never edit an applied migration, append a new one instead.
+var migrations = []string{
+	`CREATE TABLE blocks (
+		id text PRIMARY KEY,
+		"network" text NOT NULL,
+		"level" bigint NOT NULL,
+		"hash" text NOT NULL,
+		"timestamp" timestamptz,
+		data jsonb NOT NULL
+	);
+	CREATE INDEX blocks_network_level_idx ON blocks ("network", "level");
+
+	CREATE TABLE protocols (
+		id text PRIMARY KEY,
+		"network" text NOT NULL,
+		"hash" text NOT NULL,
+		"start_level" bigint NOT NULL,
+		"end_level" bigint NOT NULL,
+		"sym_link" text NOT NULL,
+		data jsonb NOT NULL
+	);
+	CREATE INDEX protocols_network_start_level_idx ON protocols ("network", "start_level");
+
+	CREATE TABLE contracts (
+		id text PRIMARY KEY,
+		"network" text NOT NULL,
+		"address" text NOT NULL,
+		"level" bigint NOT NULL,
+		"timestamp" timestamptz,
+		"hash" text NOT NULL,
+		"project_id" text NOT NULL,
+		"last_action" timestamptz,
+		"tx_count" bigint NOT NULL,
+		data jsonb NOT NULL
+	);
+	CREATE INDEX contracts_network_address_idx ON contracts ("network", "address");
+	CREATE INDEX contracts_network_level_idx ON contracts ("network", "level");
+	CREATE INDEX contracts_project_id_idx ON contracts ("project_id");
+
+	CREATE TABLE operations (
+		id text PRIMARY KEY,
+		"network" text NOT NULL,
+		"hash" text NOT NULL,
+		"counter" bigint NOT NULL,
+		"internal" boolean NOT NULL,
+		"internal_index" bigint NOT NULL,
+		"level" bigint NOT NULL,
+		"timestamp" timestamptz,
+		"indexed_time" bigint NOT NULL,
+		"kind" text NOT NULL,
+		"status" text NOT NULL,
+		"source" text NOT NULL,
+		"destination" text NOT NULL,
+		"entrypoint" text NOT NULL,
+		"amount" bigint NOT NULL,
+		data jsonb NOT NULL
+	);
+	CREATE INDEX operations_hash_idx ON operations ("hash");
+	CREATE INDEX operations_network_level_idx ON operations ("network", "level");
+	CREATE INDEX operations_network_source_idx ON operations ("network", "source");
+	CREATE INDEX operations_network_destination_idx ON operations ("network", "destination", "indexed_time");
+
+	CREATE TABLE big_map_diffs (
+		id text PRIMARY KEY,
+		"network" text NOT NULL,
+		"address" text NOT NULL,
+		"ptr" bigint,
+		"key_hash" text NOT NULL,
+		"bin_path" text NOT NULL,
+		"operation_id" text NOT NULL,
+		"level" bigint NOT NULL,
+		"indexed_time" bigint NOT NULL,
+		data jsonb NOT NULL
+	);
+	CREATE INDEX big_map_diffs_address_ptr_idx ON big_map_diffs ("address", "ptr", "key_hash");
+	CREATE INDEX big_map_diffs_operation_id_idx ON big_map_diffs ("operation_id");
+	CREATE INDEX big_map_diffs_network_level_idx ON big_map_diffs ("network", "level");
+
+	CREATE TABLE migrations (
+		id text PRIMARY KEY,
+		"network" text NOT NULL,
+		"address" text NOT NULL,
+		"level" bigint NOT NULL,
+		"kind" text NOT NULL,
+		data jsonb NOT NULL
+	);
+	CREATE INDEX migrations_network_address_idx ON migrations ("network", "address");`,
+
+	`CREATE TABLE notifications (
+		id text PRIMARY KEY,
+		"network" text NOT NULL,
+		"level" bigint NOT NULL,
+		"status" text NOT NULL,
+		"next_attempt" timestamptz,
+		data jsonb NOT NULL
+	);
+	CREATE INDEX notifications_pending_idx ON notifications ("network", "next_attempt") WHERE "status" = 'pending';
+	CREATE INDEX notifications_network_level_idx ON notifications ("network", "level");`,
+
+	`CREATE TABLE delegations (
+		id text PRIMARY KEY,
+		"network" text NOT NULL,
+		"level" bigint NOT NULL,
+		"indexed_time" bigint NOT NULL,
+		"status" text NOT NULL,
+		"source" text NOT NULL,
+		"delegate" text NOT NULL,
+		data jsonb NOT NULL
+	);
+	CREATE INDEX delegations_network_source_idx ON delegations ("network", "source", "indexed_time");
+	CREATE INDEX delegations_network_level_idx ON delegations ("network", "level");
+
+	CREATE TABLE reveals (
+		id text PRIMARY KEY,
+		"network" text NOT NULL,
+		"level" bigint NOT NULL,
+		"status" text NOT NULL,
+		"source" text NOT NULL,
+		data jsonb NOT NULL
+	);
+	CREATE INDEX reveals_network_source_idx ON reveals ("network", "source");
+	CREATE INDEX reveals_network_level_idx ON reveals ("network", "level");`,
+
+	`CREATE TABLE balance_changes (
+		id text PRIMARY KEY,
+		"network" text NOT NULL,
+		"address" text NOT NULL,
+		"level" bigint NOT NULL,
+		"change" bigint NOT NULL,
+		data jsonb NOT NULL
+	);
+	CREATE INDEX balance_changes_network_address_idx ON balance_changes ("network", "address", "level");
+	CREATE INDEX balance_changes_network_level_idx ON balance_changes ("network", "level");`,
+
+	`CREATE TABLE token_balance_changes (
+		id text PRIMARY KEY,
+		"network" text NOT NULL,
+		"contract" text NOT NULL,
+		"address" text NOT NULL,
+		"level" bigint NOT NULL,
+		"change" bigint NOT NULL,
+		data jsonb NOT NULL
+	);
+	CREATE INDEX token_balance_changes_network_contract_idx ON token_balance_changes ("network", "contract", "address", "level");
+	CREATE INDEX token_balance_changes_network_level_idx ON token_balance_changes ("network", "level");`,
+
+	`CREATE TABLE transfers (
+		id text PRIMARY KEY,
+		"network" text NOT NULL,
+		"contract" text NOT NULL,
+		"from" text NOT NULL,
+		"to" text NOT NULL,
+		"amount" bigint NOT NULL,
+		"level" bigint NOT NULL,
+		"timestamp" timestamptz,
+		"indexed_time" bigint NOT NULL,
+		"nonce" bigint NOT NULL,
+		data jsonb NOT NULL
+	);
+	CREATE INDEX transfers_network_from_idx ON transfers ("network", "from", "indexed_time" DESC, "nonce" DESC);
+	CREATE INDEX transfers_network_to_idx ON transfers ("network", "to", "indexed_time" DESC, "nonce" DESC);
+	CREATE INDEX transfers_network_contract_idx ON transfers ("network", "contract", "indexed_time" DESC, "nonce" DESC);
+	CREATE INDEX transfers_network_level_idx ON transfers ("network", "level");`,
+
+	`CREATE TABLE big_maps (
+		id text PRIMARY KEY,
+		"network" text NOT NULL,
+		"ptr" bigint NOT NULL,
+		"address" text NOT NULL,
+		"level" bigint NOT NULL,
+		"removed_level" bigint NOT NULL,
+		data jsonb NOT NULL
+	);
+	CREATE INDEX big_maps_network_address_idx ON big_maps ("network", "address");
+	CREATE INDEX big_maps_network_removed_level_idx ON big_maps ("network", "removed_level");
+	CREATE INDEX big_maps_network_level_idx ON big_maps ("network", "level");`,
+
+	`ALTER TABLE delegations
+		ADD COLUMN "counter" bigint NOT NULL DEFAULT 0,
+		ADD COLUMN "internal" boolean NOT NULL DEFAULT false,
+		ADD COLUMN "internal_index" bigint NOT NULL DEFAULT 0;
+	UPDATE delegations SET
+		"counter" = COALESCE((data->>'counter')::bigint, 0),
+		"internal" = COALESCE((data->>'internal')::boolean, false),
+		"internal_index" = COALESCE((data->>'internal_index')::bigint, 0);
+	DROP INDEX delegations_network_source_idx;
+	CREATE INDEX delegations_network_source_idx ON delegations ("network", "source", "level" DESC, "counter" DESC, "internal" DESC, "internal_index" DESC);`,
+
+	`ALTER TABLE token_balance_changes ALTER COLUMN "change" TYPE numeric USING ("change"::numeric);
+	ALTER TABLE transfers ALTER COLUMN "amount" TYPE numeric USING ("amount"::numeric);`,
+
+	`ALTER TABLE transfers
+		ADD COLUMN "counter" bigint NOT NULL DEFAULT 0,
+		ADD COLUMN "internal_index" bigint NOT NULL DEFAULT 0;
+	UPDATE transfers SET
+		"counter" = COALESCE((data->>'counter')::bigint, 0),
+		"internal_index" = COALESCE((data->>'internal_index')::bigint, 0);
+	DROP INDEX transfers_network_from_idx;
+	DROP INDEX transfers_network_to_idx;
+	DROP INDEX transfers_network_contract_idx;
+	CREATE INDEX transfers_network_from_idx ON transfers ("network", "from", "level" DESC, "counter" DESC, "internal_index" DESC, "nonce" DESC);
+	CREATE INDEX transfers_network_to_idx ON transfers ("network", "to", "level" DESC, "counter" DESC, "internal_index" DESC, "nonce" DESC);
+	CREATE INDEX transfers_network_contract_idx ON transfers ("network", "contract", "level" DESC, "counter" DESC, "internal_index" DESC, "nonce" DESC);`,
+
+	`ALTER TABLE contracts
+		ADD COLUMN "language" text NOT NULL DEFAULT '',
+		ADD COLUMN "tags" text[] NOT NULL DEFAULT '{}';
+	UPDATE contracts SET
+		"language" = COALESCE(data->>'language', ''),
+		"tags" = CASE WHEN jsonb_typeof(data->'tags') = 'array' THEN ARRAY(SELECT jsonb_array_elements_text(data->'tags')) ELSE '{}' END;
+	CREATE INDEX contracts_tags_idx ON contracts USING gin ("tags");
+
+	ALTER TABLE operations ADD COLUMN "has_deffated_storage" boolean NOT NULL DEFAULT false;
+	UPDATE operations SET "has_deffated_storage" = COALESCE(data->>'deffated_storage', '') <> '';
+
+	ALTER TABLE big_map_diffs
+		ADD COLUMN "key" text NOT NULL DEFAULT '',
+		ADD COLUMN "key_strings" text[] NOT NULL DEFAULT '{}',
+		ADD COLUMN "has_value" boolean NOT NULL DEFAULT false;
+	UPDATE big_map_diffs SET
+		"key" = COALESCE(data->>'key', ''),
+		"key_strings" = CASE WHEN jsonb_typeof(data->'key_strings') = 'array' THEN ARRAY(SELECT jsonb_array_elements_text(data->'key_strings')) ELSE '{}' END,
+		"has_value" = COALESCE(data->>'value', '') <> '';`,
+
+	`CREATE TABLE es_sync (
+		id bigserial PRIMARY KEY,
+		batch text NOT NULL,
+		"index" text NOT NULL,
+		doc_id text NOT NULL,
+		created_at timestamptz NOT NULL DEFAULT now()
+	);
+	CREATE INDEX es_sync_batch_idx ON es_sync (batch);
+	CREATE INDEX es_sync_doc_idx ON es_sync ("index", doc_id);`,
+
+	`ALTER TABLE transfers ADD COLUMN "operation_id" text NOT NULL DEFAULT '';
+	UPDATE transfers SET "operation_id" = COALESCE(data->>'operation_id', '');
+	CREATE INDEX transfers_operation_id_idx ON transfers ("operation_id");`,
+}
+
+// migrationsLock - key of the advisory lock preventing concurrent migrations by several services
+const migrationsLock = 7135417
+
+// migrate - applies schema migrations which are not applied yet. Applied versions are kept in `schema_migrations`.
+func (s *Storage) migrate() error {
+	tx := s.db.Begin()
+	if err := tx.Error; err != nil {
+		return err
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationsLock).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint PRIMARY KEY,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`).Error; err != nil {
+		return err
+	}
+
+	var current int64
+	if err := tx.Raw("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Row().Scan(&current); err != nil {
+		return err
+	}
+
+	for i := current; i < int64(len(migrations)); i++ {
+		version := i + 1
+		if err := tx.Exec(migrations[i]).Error; err != nil {
+			return fmt.Errorf("Schema migration %d: %s", version, err)
+		}
+		if err := tx.Exec("INSERT INTO schema_migrations (version) VALUES (?)", version).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Commit().Error
+}
@@ -0,0 +1,189 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/helpers"
+	"github.com/baking-bad/bcdhub/internal/logger"
+	"github.com/jinzhu/gorm"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// repairPeriod - how often documents which are not synced to Elasticsearch are re-sent
+	repairPeriod = time.Minute
+	// repairGrace - marks younger than it may belong to writes which are sending to Elasticsearch right now
+	repairGrace     = time.Minute
+	repairBatchSize = 1000
+)
+
+// rawDocument - document stored in PostgreSQL which is re-sent to Elasticsearch as is
+type rawDocument struct {
+	index  string
+	id     string
+	source json.RawMessage
+}
+
+// GetID -
+func (d *rawDocument) GetID() string {
+	return d.id
+}
+
+// GetIndex -
+func (d *rawDocument) GetIndex() string {
+	return d.index
+}
+
+// ParseElasticJSON -
+func (d *rawDocument) ParseElasticJSON(hit gjson.Result) {
+	d.id = hit.Get("_id").String()
+	d.index = hit.Get("_index").String()
+	d.source = json.RawMessage(hit.Get("_source").Raw)
+}
+
+// MarshalJSON -
+func (d *rawDocument) MarshalJSON() ([]byte, error) {
+	return d.source, nil
+}
+
+// write - commits `fn` to PostgreSQL and then duplicates the write to Elasticsearch by `sync`. Documents changed by `fn`
+// are marked as not synced by the same transaction and the marks are removed when Elasticsearch accepts the write,
+// so documents of failed Elasticsearch writes are re-sent by `Repair`.
+func (s *Storage) write(fn func(tx *gorm.DB, batch string) error, sync func() error) error {
+	batch := helpers.GenerateID()
+	if err := s.transaction(func(tx *gorm.DB) error {
+		return fn(tx, batch)
+	}); err != nil {
+		return err
+	}
+	if err := sync(); err != nil {
+		return err
+	}
+	return s.db.Exec("DELETE FROM es_sync WHERE batch = ?", batch).Error
+}
+
+// mark - marks the document as not synced to Elasticsearch
+func mark(tx *gorm.DB, batch, index, id string) error {
+	return tx.Exec(`INSERT INTO es_sync (batch, "index", doc_id) VALUES (?, ?, ?)`, batch, index, id).Error
+}
+
+type syncMark struct {
+	index string
+	docID string
+	last  int64
+}
+
+// Repair - re-sends documents which were committed to PostgreSQL, but failed in Elasticsearch. Current documents are indexed again,
+// documents deleted from PostgreSQL are deleted from Elasticsearch. Returns count of repaired documents.
+func (s *Storage) Repair() (int, error) {
+	var repaired int
+	for {
+		marks, err := s.unsynced(time.Now().UTC().Add(-repairGrace))
+		if err != nil {
+			return repaired, err
+		}
+		if len(marks) == 0 {
+			return repaired, nil
+		}
+		if err := s.resend(marks); err != nil {
+			return repaired, err
+		}
+		if err := s.transaction(func(tx *gorm.DB) error {
+			for i := range marks {
+				if err := tx.Exec(`DELETE FROM es_sync WHERE "index" = ? AND doc_id = ? AND id <= ?`, marks[i].index, marks[i].docID, marks[i].last).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return repaired, err
+		}
+		repaired += len(marks)
+	}
+}
+
+func (s *Storage) unsynced(before time.Time) ([]syncMark, error) {
+	rows, err := s.db.Raw(
+		`SELECT "index", doc_id, MAX(id) FROM es_sync WHERE created_at < ? GROUP BY "index", doc_id ORDER BY MAX(id) LIMIT ?`,
+		before, repairBatchSize,
+	).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	marks := make([]syncMark, 0)
+	for rows.Next() {
+		var m syncMark
+		if err := rows.Scan(&m.index, &m.docID, &m.last); err != nil {
+			return nil, err
+		}
+		marks = append(marks, m)
+	}
+	return marks, rows.Err()
+}
+
+// resend - Elasticsearch deletes documents of one index by a bulk request, so documents are grouped by index
+func (s *Storage) resend(marks []syncMark) error {
+	ids := make(map[string][]string)
+	for i := range marks {
+		ids[marks[i].index] = append(ids[marks[i].index], marks[i].docID)
+	}
+
+	for index, docIDs := range ids {
+		t, ok := tables[index]
+		if !ok {
+			return fmt.Errorf("Unknown table for index: %s", index)
+		}
+		hits, err := s.scanHits(s.db, index, fmt.Sprintf("SELECT id, data FROM %s WHERE id IN (?)", t.name), docIDs)
+		if err != nil {
+			return err
+		}
+		found := make(map[string]struct{}, len(hits))
+		updates := make([]elastic.Model, len(hits))
+		for i := range hits {
+			doc := new(rawDocument)
+			doc.ParseElasticJSON(hits[i])
+			found[doc.id] = struct{}{}
+			updates[i] = doc
+		}
+		deletes := make([]elastic.Model, 0)
+		for _, id := range docIDs {
+			if _, ok := found[id]; !ok {
+				deletes = append(deletes, &rawDocument{index: index, id: id})
+			}
+		}
+
+		if err := s.IElastic.BulkInsert(updates); err != nil {
+			return err
+		}
+		if err := s.IElastic.BulkDelete(deletes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runRepair - repairs Elasticsearch every `period` until `stop` is closed
+func (s *Storage) runRepair(period time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			count, err := s.Repair()
+			if err != nil {
+				logger.Errorf("[postgres] Elasticsearch repair: %s", err)
+			}
+			if count > 0 {
+				logger.Warning("[postgres] %d documents are re-sent to Elasticsearch", count)
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+// GetTokenHolders - `change` is numeric, so balances are summed without loss of precision
+func (s *Storage) GetTokenHolders(network, contract string, level int64) ([]models.TokenBalance, error) {
+	rows, err := s.db.Raw(
+		`SELECT "address", SUM("change")::text AS balance FROM token_balance_changes
+		WHERE "network" = ? AND "contract" = ? AND (? = 0 OR "level" <= ?)
+		GROUP BY "address" HAVING SUM("change") <> 0
+		ORDER BY SUM("change") DESC, "address"`,
+		network, contract, level, level,
+	).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holders := make([]models.TokenBalance, 0)
+	for rows.Next() {
+		var holder models.TokenBalance
+		if err := rows.Scan(&holder.Address, &holder.Balance); err != nil {
+			return nil, err
+		}
+		holders = append(holders, holder)
+	}
+	return holders, rows.Err()
+}
+
+// GetTokenBalance -
+func (s *Storage) GetTokenBalance(network, contract, address string, level int64) (balance string, err error) {
+	err = s.db.Raw(
+		`SELECT COALESCE(SUM("change"), 0)::text FROM token_balance_changes WHERE "network" = ? AND "contract" = ? AND "address" = ? AND (? = 0 OR "level" <= ?)`,
+		network, contract, address, level, level,
+	).Row().Scan(&balance)
+	return
+}
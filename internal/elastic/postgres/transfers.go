@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"strings"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+// GetTransfers -
+func (s *Storage) GetTransfers(ctx elastic.GetTransfersContext) (response elastic.PageableTransfers, err error) {
+	if ctx.Size == 0 {
+		ctx.Size = elastic.DefaultSize
+	}
+
+	conditions := []string{`"network" = ?`}
+	args := []interface{}{ctx.Network}
+	if len(ctx.Contracts) > 0 {
+		conditions = append(conditions, `"contract" IN (?)`)
+		args = append(args, ctx.Contracts)
+	}
+	if ctx.Address != "" {
+		conditions = append(conditions, `("from" = ? OR "to" = ?)`)
+		args = append(args, ctx.Address, ctx.Address)
+	}
+	if len(ctx.OperationIDs) > 0 {
+		conditions = append(conditions, `"operation_id" IN (?)`)
+		args = append(args, ctx.OperationIDs)
+	}
+	if ctx.Sender != "" {
+		conditions = append(conditions, `"from" = ?`)
+		args = append(args, ctx.Sender)
+	}
+	if ctx.Receiver != "" {
+		conditions = append(conditions, `"to" = ?`)
+		args = append(args, ctx.Receiver)
+	}
+	if ctx.Start > 0 {
+		conditions = append(conditions, `"timestamp" >= to_timestamp(?::double precision / 1000)`)
+		args = append(args, ctx.Start)
+	}
+	if ctx.End > 0 {
+		conditions = append(conditions, `"timestamp" <= to_timestamp(?::double precision / 1000)`)
+		args = append(args, ctx.End)
+	}
+	if ctx.MinAmount != "" {
+		conditions = append(conditions, `"amount" >= ?::numeric`)
+		args = append(args, ctx.MinAmount)
+	}
+	if ctx.LastID != "" {
+		position, err := elastic.ParseTransferCursor(ctx.LastID)
+		if err != nil {
+			return response, err
+		}
+		conditions = append(conditions, `("level", "counter", "internal_index", "nonce") < (?, ?, ?, ?)`)
+		args = append(args, position.Level, position.Counter, position.InternalIndex, position.Nonce)
+	}
+	args = append(args, ctx.Size)
+
+	hits, err := s.query(
+		elastic.DocTransfers,
+		strings.Join(conditions, " AND "),
+		`ORDER BY "level" DESC, "counter" DESC, "internal_index" DESC, "nonce" DESC LIMIT ?`,
+		args...,
+	)
+	if err != nil {
+		return
+	}
+
+	response.Transfers = make([]models.Transfer, len(hits))
+	for i := range hits {
+		response.Transfers[i].ParseElasticJSON(hits[i])
+	}
+	if len(hits) > 0 {
+		response.LastID = elastic.TransferCursor(response.Transfers[len(hits)-1])
+	}
+	return
+}
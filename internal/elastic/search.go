@@ -9,9 +9,8 @@ import (
 	"github.com/tidwall/gjson"
 )
 
-const (
-	defaultSize = 10
-)
+// DefaultSize - page size used by storages when the request doesn't set it
+const DefaultSize = 10
 
 func getMapFields(allFields []string) map[string]string {
 	res := make(map[string]string)
@@ -161,7 +160,7 @@ func (e *Elastic) searchWithoutGroup(text string, offset int64, fields []string,
 		b.Get("bool").Extend(must(mustItems...))
 	}
 
-	query.Query(b).From(offset).Size(defaultSize)
+	query.Query(b).From(offset).Size(DefaultSize)
 
 	resp, err := e.query(indices, query)
 	if err != nil {
@@ -226,7 +225,7 @@ func (e *Elastic) searchWithGroup(text string, offset int64, fields []string, fi
 							} else if (doc.containsKey('hash')) {
 								return doc['hash.keyword'].value
 							} else return doc['key_hash.keyword'].value`,
-					"size": defaultSize + offset,
+					"size": DefaultSize + offset,
 					"order": qList{
 						qItem{"bucket_score": "desc"},
 						qItem{"bucket_time": "desc"},
@@ -254,7 +253,7 @@ func (e *Elastic) searchWithGroup(text string, offset int64, fields []string, fi
 		return SearchResult{}, err
 	}
 	return SearchResult{
-		Items: parseSearchGroupingResponse(resp, defaultSize, offset),
+		Items: parseSearchGroupingResponse(resp, DefaultSize, offset),
 		Time:  resp.Get("took").Int(),
 		Count: resp.Get("hits.total.value").Int(),
 	}, nil
@@ -1,6 +1,8 @@
 package elastic
 
 import (
+	"reflect"
+
 	"github.com/baking-bad/bcdhub/internal/models"
 )
 
@@ -10,7 +12,7 @@ func (e *Elastic) GetTokens(network string, size, offset int64) ([]models.Contra
 		boolQ(
 			filter(
 				matchQ("network", network),
-				in("tags", []string{"fa12", "fa1"}),
+				in("tags", []string{"fa12", "fa1", "fa2"}),
 			),
 		),
 	).Sort("timestamp", "desc").Size(size).From(offset)
@@ -29,10 +31,10 @@ func (e *Elastic) GetTokens(network string, size, offset int64) ([]models.Contra
 	return contracts, nil
 }
 
-// GetTokenTransferOperations -
+// GetTokenTransferOperations - returns page of `mint` and `transfer` calls with the address in parameters. `lastID` is `indexed_time` of the last operation of the previous page.
 func (e *Elastic) GetTokenTransferOperations(network, address, lastID string, size int64) (PageableOperations, error) {
 	if size == 0 {
-		size = defaultSize
+		size = DefaultSize
 	}
 	filterItems := []qItem{
 		in("entrypoint", []string{"mint", "transfer"}),
@@ -66,3 +68,51 @@ func (e *Elastic) GetTokenTransferOperations(network, address, lastID string, si
 	po.LastID = result.Get("hits").Get("hits|@reverse|0").Get("_source.indexed_time").String()
 	return po, nil
 }
+
+func tokenBalanceFilters(network, contract string, level int64) []qItem {
+	filters := []qItem{
+		matchPhrase("network", network),
+		matchPhrase("contract", contract),
+	}
+	if level > 0 {
+		filters = append(filters, rangeQ("level", qItem{"lte": level}))
+	}
+	return filters
+}
+
+func (e *Elastic) getTokenBalanceChanges(filters []qItem) ([]models.TokenBalanceChange, error) {
+	changes := make([]models.TokenBalanceChange, 0)
+	query := newQuery().Query(
+		boolQ(
+			filter(filters...),
+		),
+	)
+	if err := e.getByScroll(DocTokenBalanceChanges, query, reflect.TypeOf(models.TokenBalanceChange{}), &changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// GetTokenHolders - returns holders of the token with non-zero balance at `level` (0 is the head) sorted by balance.
+// Changes are summed here because `sum` aggregation loses precision of amounts above 2^53.
+func (e *Elastic) GetTokenHolders(network, contract string, level int64) ([]models.TokenBalance, error) {
+	changes, err := e.getTokenBalanceChanges(tokenBalanceFilters(network, contract, level))
+	if err != nil {
+		return nil, err
+	}
+	return models.SumTokenBalanceChanges(changes)
+}
+
+// GetTokenBalance - returns balance of the holder at `level` (0 is the head)
+func (e *Elastic) GetTokenBalance(network, contract, address string, level int64) (string, error) {
+	filters := append(tokenBalanceFilters(network, contract, level), matchPhrase("address", address))
+	changes, err := e.getTokenBalanceChanges(filters)
+	if err != nil {
+		return "", err
+	}
+	balances, err := models.SumTokenBalanceChanges(changes)
+	if err != nil || len(balances) == 0 {
+		return "0", err
+	}
+	return balances[0].Balance, nil
+}
@@ -0,0 +1,165 @@
+package elastic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/baking-bad/bcdhub/internal/models"
+)
+
+// TransferPosition - order of transfers in the chain. Transfers are ordered by level, operation counter,
+// index of the internal operation and index of the transfer in the operation.
+type TransferPosition struct {
+	Level         int64
+	Counter       int64
+	InternalIndex int64
+	Nonce         int64
+}
+
+// TransferCursor - cursor pointing at the transfer
+func TransferCursor(t models.Transfer) string {
+	return fmt.Sprintf("%d_%d_%d_%d", t.Level, t.Counter, t.InternalIndex, t.Nonce)
+}
+
+// ParseTransferCursor - returns position of the transfer from the cursor
+func ParseTransferCursor(cursor string) (position TransferPosition, err error) {
+	parts := strings.Split(cursor, "_")
+	if len(parts) != 4 {
+		return position, fmt.Errorf("Invalid transfers cursor: %s", cursor)
+	}
+	values := []*int64{&position.Level, &position.Counter, &position.InternalIndex, &position.Nonce}
+	for i := range parts {
+		if *values[i], err = strconv.ParseInt(parts[i], 10, 64); err != nil {
+			return position, fmt.Errorf("Invalid transfers cursor: %s", cursor)
+		}
+	}
+	return
+}
+
+// Follows - returns true if transfer `t` is after the position in the descending order of transfers
+func (p TransferPosition) Follows(t models.Transfer) bool {
+	for _, pair := range [][2]int64{
+		{t.Level, p.Level},
+		{t.Counter, p.Counter},
+		{t.InternalIndex, p.InternalIndex},
+		{t.Nonce, p.Nonce},
+	} {
+		if pair[0] != pair[1] {
+			return pair[0] < pair[1]
+		}
+	}
+	return false
+}
+
+// transfersAfter - transfers which are less than the position by (level, counter, internal_index, nonce)
+func transfersAfter(p TransferPosition) qItem {
+	fields := []string{"level", "counter", "internal_index", "nonce"}
+	values := []int64{p.Level, p.Counter, p.InternalIndex, p.Nonce}
+
+	conditions := make([]qItem, len(fields))
+	for i := range fields {
+		filters := make([]qItem, 0, i+1)
+		for j := 0; j < i; j++ {
+			filters = append(filters, term(fields[j], values[j]))
+		}
+		filters = append(filters, rangeQ(fields[i], qItem{"lt": values[i]}))
+		conditions[i] = boolQ(filter(filters...))
+	}
+	return boolQ(
+		should(conditions...),
+		minimumShouldMatch(1),
+	)
+}
+
+// GetTransfers - returns page of transfers sorted from the newest to the oldest
+func (e *Elastic) GetTransfers(ctx GetTransfersContext) (PageableTransfers, error) {
+	if ctx.Size == 0 {
+		ctx.Size = DefaultSize
+	}
+
+	filters := []qItem{
+		matchPhrase("network", ctx.Network),
+	}
+	if len(ctx.Contracts) > 0 {
+		contracts := make([]qItem, len(ctx.Contracts))
+		for i := range ctx.Contracts {
+			contracts[i] = matchPhrase("contract", ctx.Contracts[i])
+		}
+		filters = append(filters, boolQ(should(contracts...), minimumShouldMatch(1)))
+	}
+	if ctx.Address != "" {
+		filters = append(filters, boolQ(
+			should(
+				matchPhrase("from", ctx.Address),
+				matchPhrase("to", ctx.Address),
+			),
+			minimumShouldMatch(1),
+		))
+	}
+	if len(ctx.OperationIDs) > 0 {
+		filters = append(filters, in("operation_id.keyword", ctx.OperationIDs))
+	}
+	if ctx.Sender != "" {
+		filters = append(filters, matchPhrase("from", ctx.Sender))
+	}
+	if ctx.Receiver != "" {
+		filters = append(filters, matchPhrase("to", ctx.Receiver))
+	}
+	if ctx.Start > 0 {
+		filters = append(filters, rangeQ("timestamp", qItem{"gte": ctx.Start}))
+	}
+	if ctx.End > 0 {
+		filters = append(filters, rangeQ("timestamp", qItem{"lte": ctx.End}))
+	}
+	if ctx.MinAmount != "" {
+		// amounts are stored as strings to keep precision, so they are compared as big integers
+		filters = append(filters, qItem{
+			"script": qItem{
+				"script": qItem{
+					"source": "new BigInteger(doc['amount'].value).compareTo(new BigInteger(params.min)) >= 0",
+					"params": qItem{
+						"min": ctx.MinAmount,
+					},
+				},
+			},
+		})
+	}
+	if ctx.LastID != "" {
+		position, err := ParseTransferCursor(ctx.LastID)
+		if err != nil {
+			return PageableTransfers{}, err
+		}
+		filters = append(filters, transfersAfter(position))
+	}
+
+	query := newQuery().Query(
+		boolQ(
+			filter(filters...),
+		),
+	).Add(qItem{
+		"sort": qList{
+			sort("level", "desc"),
+			sort("counter", "desc"),
+			sort("internal_index", "desc"),
+			sort("nonce", "desc"),
+		},
+	}).Size(ctx.Size)
+
+	result, err := e.query([]string{DocTransfers}, query)
+	if err != nil {
+		return PageableTransfers{}, err
+	}
+
+	hits := result.Get("hits.hits").Array()
+	response := PageableTransfers{
+		Transfers: make([]models.Transfer, len(hits)),
+	}
+	for i := range hits {
+		response.Transfers[i].ParseElasticJSON(hits[i])
+	}
+	if len(hits) > 0 {
+		response.LastID = TransferCursor(response.Transfers[len(hits)-1])
+	}
+	return response, nil
+}
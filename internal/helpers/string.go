@@ -24,6 +24,18 @@ func GenerateID() string {
 	return strings.ReplaceAll(uuid.New().String(), "-", "")
 }
 
+// GenerateIDFrom - returns the same ID for the same `parts`. It's used for documents with natural keys, so re-indexing overwrites them instead of creating duplicates.
+func GenerateIDFrom(parts ...interface{}) string {
+	var sb strings.Builder
+	for i := range parts {
+		if i > 0 {
+			sb.WriteByte('|')
+		}
+		fmt.Fprintf(&sb, "%v", parts[i])
+	}
+	return strings.ReplaceAll(uuid.NewSHA1(uuid.NameSpaceOID, []byte(sb.String())).String(), "-", "")
+}
+
 // URLJoin -
 func URLJoin(baseURL, queryPath string) string {
 	u, err := url.Parse(baseURL)
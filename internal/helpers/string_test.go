@@ -0,0 +1,41 @@
+package helpers
+
+import "testing"
+
+func TestGenerateIDFrom(t *testing.T) {
+	tests := []struct {
+		name  string
+		a     []interface{}
+		b     []interface{}
+		equal bool
+	}{
+		{
+			name:  "same parts",
+			a:     []interface{}{"mainnet", "oo5XsmdPjxvBAbCyL9kh3x5irUmkWNwUFfi2rfiKqJGKA6Sxjzf", int64(1), int64(0)},
+			b:     []interface{}{"mainnet", "oo5XsmdPjxvBAbCyL9kh3x5irUmkWNwUFfi2rfiKqJGKA6Sxjzf", int64(1), int64(0)},
+			equal: true,
+		}, {
+			name:  "different internal index",
+			a:     []interface{}{"mainnet", "oo5XsmdPjxvBAbCyL9kh3x5irUmkWNwUFfi2rfiKqJGKA6Sxjzf", int64(1), int64(0)},
+			b:     []interface{}{"mainnet", "oo5XsmdPjxvBAbCyL9kh3x5irUmkWNwUFfi2rfiKqJGKA6Sxjzf", int64(1), int64(1)},
+			equal: false,
+		}, {
+			name:  "parts are separated",
+			a:     []interface{}{"ab", "c"},
+			b:     []interface{}{"a", "bc"},
+			equal: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := GenerateIDFrom(tt.a...)
+			b := GenerateIDFrom(tt.b...)
+			if (a == b) != tt.equal {
+				t.Errorf("GenerateIDFrom() %s == %s is %v, want %v", a, b, a == b, tt.equal)
+			}
+			if len(a) != 32 {
+				t.Errorf("GenerateIDFrom() invalid length %d", len(a))
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/elastic"
 	"github.com/baking-bad/bcdhub/internal/helpers"
 
 	"github.com/baking-bad/bcdhub/internal/classification/functions"
@@ -60,6 +61,15 @@ func (h *Handler) UpdateContractStats(c *models.Contract) error {
 	c.TotalWithdrawn = stats.TotalWithdrawn
 	c.MigrationsCount = migrationsStats.MigrationsCount
 
+	// contracts indexed before delegations have no delegation history, so their delegates are kept
+	delegation, err := h.ES.GetLastDelegation(c.Network, c.Address)
+	switch {
+	case err == nil:
+		c.Delegate = delegation.Delegate
+	case !elastic.IsRecordNotFound(err):
+		return err
+	}
+
 	return nil
 }
 
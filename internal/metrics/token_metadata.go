@@ -0,0 +1,385 @@
+package metrics
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/contractparser/meta"
+	"github.com/baking-bad/bcdhub/internal/contractparser/newmiguel"
+	"github.com/baking-bad/bcdhub/internal/contractparser/unpack"
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/helpers"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/tidwall/gjson"
+)
+
+// Names of big maps and storage fields containing token metadata
+const (
+	tokenMetadataBigMap    = "token_metadata"
+	contractMetadataBigMap = "metadata"
+
+	fieldName     = "name"
+	fieldSymbol   = "symbol"
+	fieldDecimals = "decimals"
+
+	tezosStoragePrefix = "tezos-storage:"
+)
+
+// SetTokenMetadata - refreshes metadata of the token originated by the operation or the token which metadata big maps are changed by the operation
+func (h *Handler) SetTokenMetadata(op models.Operation) error {
+	if op.Status != consts.Applied || op.DeffatedStorage == "" || !strings.HasPrefix(op.Destination, "KT") {
+		return nil
+	}
+	if op.Kind != consts.Origination && op.Kind != consts.Transaction {
+		return nil
+	}
+
+	contract, err := h.ES.GetContract(map[string]interface{}{
+		"network": op.Network,
+		"address": op.Destination,
+	})
+	if err != nil {
+		if elastic.IsRecordNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if !isTokenContract(contract) {
+		return nil
+	}
+
+	storageMetadata, err := meta.GetMetadata(h.ES, op.Destination, consts.STORAGE, op.Protocol)
+	if err != nil {
+		return err
+	}
+	sources := findTokenMetadataSources(storageMetadata)
+	if sources.isEmpty() {
+		return nil
+	}
+
+	if op.Kind == consts.Transaction && len(sources.fields) == 0 {
+		changed, err := h.isTokenMetadataChanged(op.ID, sources)
+		if err != nil || !changed {
+			return err
+		}
+	}
+
+	_, err = h.UpdateTokenMetadata(&contract, storageMetadata, op.DeffatedStorage, op.Level)
+	return err
+}
+
+// UpdateTokenMetadata - extracts token metadata from the contract `storage` and its big maps and saves it if it's changed
+func (h *Handler) UpdateTokenMetadata(contract *models.Contract, storageMetadata meta.Metadata, storage string, level int64) (bool, error) {
+	sources := findTokenMetadataSources(storageMetadata)
+	data := gjson.Parse(storage)
+
+	var tzip7, tzip16 models.TokenMetadata
+	if sources.tokenMetadata != "" {
+		diffs, err := h.getBigMapState(*contract, data, sources.tokenMetadata)
+		if err != nil {
+			return false, err
+		}
+		tzip7 = parseTokenMetadataBigMap(diffs, sources.tokenMetadata, storageMetadata)
+	}
+	if sources.contractMetadata != "" {
+		diffs, err := h.getBigMapState(*contract, data, sources.contractMetadata)
+		if err != nil {
+			return false, err
+		}
+		tzip16 = parseContractMetadataBigMap(diffs)
+	}
+	fields := parseTokenMetadataFields(data, sources.fields)
+
+	tm := mergeTokenMetadata(tzip7, tzip16, fields)
+	switch {
+	case tm.IsEmpty() && contract.TokenMetadata == nil:
+		return false, nil
+	case tm.IsEmpty():
+		contract.TokenMetadata = nil
+	case contract.TokenMetadata != nil && contract.TokenMetadata.Equal(tm):
+		return false, nil
+	default:
+		tm.Level = level
+		contract.TokenMetadata = &tm
+	}
+	return true, h.ES.UpdateFields(elastic.DocContracts, contract.ID, *contract, "TokenMetadata")
+}
+
+func isTokenContract(contract models.Contract) bool {
+	for _, tag := range []string{consts.FA1Tag, consts.FA12Tag, consts.FA2Tag} {
+		if helpers.StringInArray(tag, contract.Tags) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) isTokenMetadataChanged(operationID string, sources tokenMetadataSources) (bool, error) {
+	diffs, err := h.ES.GetUniqueBigMapDiffsByOperationID(operationID)
+	if err != nil {
+		return false, err
+	}
+	for i := range diffs {
+		if diffs[i].BinPath == sources.tokenMetadata || diffs[i].BinPath == sources.contractMetadata {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// getBigMapState - returns current values of the big map which pointer is kept in the storage by `binPath`
+func (h *Handler) getBigMapState(contract models.Contract, storage gjson.Result, binPath string) ([]models.BigMapDiff, error) {
+	ptr := storageValue(storage, binPath).Get("int")
+	if !ptr.Exists() {
+		return nil, nil
+	}
+	return h.ES.GetAllBigMapDiffByPtr(contract.Address, contract.Network, ptr.Int())
+}
+
+// tokenMetadataSources - bin paths of metadata big maps and paths of storage fields by their names
+type tokenMetadataSources struct {
+	tokenMetadata    string
+	contractMetadata string
+	fields           map[string]string
+}
+
+func (s tokenMetadataSources) isEmpty() bool {
+	return s.tokenMetadata == "" && s.contractMetadata == "" && len(s.fields) == 0
+}
+
+func findTokenMetadataSources(metadata meta.Metadata) tokenMetadataSources {
+	sources := tokenMetadataSources{
+		fields: make(map[string]string),
+	}
+	for path, node := range metadata {
+		name := strings.ToLower(nodeName(node))
+		switch {
+		case node.Prim == consts.BIGMAP && name == tokenMetadataBigMap:
+			sources.tokenMetadata = path
+		case node.Prim == consts.BIGMAP && name == contractMetadataBigMap:
+			sources.contractMetadata = path
+		case isMetadataField(name, node.Prim) && isPairField(metadata, "0", path):
+			sources.fields[name] = path
+		}
+	}
+	return sources
+}
+
+func nodeName(node *meta.NodeMetadata) string {
+	if node.FieldName != "" {
+		return node.FieldName
+	}
+	return node.Name
+}
+
+func isMetadataField(name, prim string) bool {
+	switch name {
+	case fieldName, fieldSymbol:
+		return prim == consts.STRING || prim == consts.BYTES
+	case fieldDecimals:
+		return prim == consts.NAT || prim == consts.INT
+	}
+	return false
+}
+
+// isPairField - returns true if `path` is reachable from `root` through pairs only
+func isPairField(metadata meta.Metadata, root, path string) bool {
+	if !strings.HasPrefix(path, root+"/") {
+		return false
+	}
+	parent := root
+	for _, part := range strings.Split(strings.TrimPrefix(path, root+"/"), "/") {
+		node, ok := metadata[parent]
+		if !ok || node.Prim != consts.PAIR || (part != "0" && part != "1") {
+			return false
+		}
+		parent += "/" + part
+	}
+	return true
+}
+
+// storageValue - returns storage node by its metadata path
+func storageValue(storage gjson.Result, path string) gjson.Result {
+	if path == "0" {
+		return storage
+	}
+	return storage.Get(newmiguel.GetGJSONPath(strings.TrimPrefix(path, "0/")))
+}
+
+func parseTokenMetadataFields(storage gjson.Result, fields map[string]string) models.TokenMetadata {
+	values := make(map[string]gjson.Result)
+	for name, path := range fields {
+		values[name] = storageValue(storage, path)
+	}
+	return tokenMetadataFromValues(values)
+}
+
+// parseTokenMetadataBigMap - TZIP-7 `token_metadata` is `nat -> (pair token_id (pair symbol (pair name (pair decimals extras))))`.
+// Fields are found by annotations or by their positions. Metadata of token with the lowest ID is returned.
+func parseTokenMetadataBigMap(diffs []models.BigMapDiff, binPath string, metadata meta.Metadata) models.TokenMetadata {
+	valuePath := binPath + "/v"
+	fields := make(map[string]string)
+	leaves := make([]string, 0)
+	for path, node := range metadata {
+		if !isPairField(metadata, valuePath, path) || metadata[path].Prim == consts.PAIR {
+			continue
+		}
+		leaves = append(leaves, path)
+		if name := strings.ToLower(nodeName(node)); isMetadataField(name, node.Prim) {
+			fields[name] = path
+		}
+	}
+	if len(fields) == 0 {
+		sort.Strings(leaves)
+		if len(leaves) >= 4 && metadata[leaves[1]].Prim == consts.STRING && metadata[leaves[2]].Prim == consts.STRING && metadata[leaves[3]].Prim == consts.NAT {
+			fields[fieldSymbol] = leaves[1]
+			fields[fieldName] = leaves[2]
+			fields[fieldDecimals] = leaves[3]
+		}
+	}
+	if len(fields) == 0 {
+		return models.TokenMetadata{}
+	}
+
+	var value string
+	var tokenID int64 = -1
+	for i := range diffs {
+		if diffs[i].Value == "" {
+			continue
+		}
+		id := bigMapKey(diffs[i].Key).Get("int").Int()
+		if tokenID == -1 || id < tokenID {
+			tokenID = id
+			value = diffs[i].Value
+		}
+	}
+	if value == "" {
+		return models.TokenMetadata{}
+	}
+
+	data := gjson.Parse(value)
+	values := make(map[string]gjson.Result)
+	for name, path := range fields {
+		values[name] = data.Get(newmiguel.GetGJSONPath(strings.TrimPrefix(path, valuePath+"/")))
+	}
+	return tokenMetadataFromValues(values)
+}
+
+// parseContractMetadataBigMap - TZIP-16 `metadata` is `string -> bytes`. Empty key keeps URI of metadata JSON,
+// only `tezos-storage:` URIs pointing to the same big map are resolved.
+func parseContractMetadataBigMap(diffs []models.BigMapDiff) models.TokenMetadata {
+	values := make(map[string]string)
+	for i := range diffs {
+		if diffs[i].Value == "" {
+			continue
+		}
+		key := bigMapKey(diffs[i].Key).Get("string").String()
+		values[key] = decodeBytes(gjson.Parse(diffs[i].Value).Get("bytes").String())
+	}
+
+	uri, ok := values[""]
+	if !ok || !strings.HasPrefix(uri, tezosStoragePrefix) {
+		return models.TokenMetadata{}
+	}
+	location := strings.TrimPrefix(uri, tezosStoragePrefix)
+	if strings.HasPrefix(location, "//") {
+		idx := strings.Index(location[2:], "/")
+		if idx == -1 {
+			return models.TokenMetadata{}
+		}
+		location = location[idx+3:]
+	}
+	location, err := url.PathUnescape(location)
+	if err != nil {
+		return models.TokenMetadata{}
+	}
+
+	content := gjson.Parse(values[location])
+	if !content.IsObject() {
+		return models.TokenMetadata{}
+	}
+	tm := models.TokenMetadata{
+		Name:   content.Get(fieldName).String(),
+		Symbol: content.Get(fieldSymbol).String(),
+	}
+	if decimals := content.Get(fieldDecimals); decimals.Exists() {
+		value := decimals.Int()
+		tm.Decimals = &value
+	}
+	return tm
+}
+
+func tokenMetadataFromValues(values map[string]gjson.Result) models.TokenMetadata {
+	var tm models.TokenMetadata
+	for name, value := range values {
+		if !value.Exists() {
+			continue
+		}
+		switch name {
+		case fieldName:
+			tm.Name = michelineString(value)
+		case fieldSymbol:
+			tm.Symbol = michelineString(value)
+		case fieldDecimals:
+			decimals := value.Get("int").Int()
+			tm.Decimals = &decimals
+		}
+	}
+	return tm
+}
+
+func michelineString(value gjson.Result) string {
+	if s := value.Get("string"); s.Exists() {
+		return s.String()
+	}
+	return decodeBytes(value.Get("bytes").String())
+}
+
+// decodeBytes - decodes packed Michelson string or UTF-8 text
+func decodeBytes(input string) string {
+	if len(input) < 2 {
+		return input
+	}
+	decoded := unpack.Bytes(input)
+	if decoded != input {
+		if s, err := strconv.Unquote(decoded); err == nil {
+			return s
+		}
+		return decoded
+	}
+	if data, err := hex.DecodeString(input); err == nil && utf8.Valid(data) {
+		return string(data)
+	}
+	return input
+}
+
+func bigMapKey(key interface{}) gjson.Result {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return gjson.Result{}
+	}
+	return gjson.ParseBytes(data)
+}
+
+// mergeTokenMetadata - every field is taken from the first source where it's set
+func mergeTokenMetadata(sources ...models.TokenMetadata) models.TokenMetadata {
+	var tm models.TokenMetadata
+	for _, source := range sources {
+		if tm.Name == "" {
+			tm.Name = source.Name
+		}
+		if tm.Symbol == "" {
+			tm.Symbol = source.Symbol
+		}
+		if tm.Decimals == nil {
+			tm.Decimals = source.Decimals
+		}
+	}
+	return tm
+}
@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser/meta"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/tidwall/gjson"
+)
+
+func int64Ptr(value int64) *int64 {
+	return &value
+}
+
+func Test_findTokenMetadataSources(t *testing.T) {
+	tests := []struct {
+		name    string
+		storage string
+		want    tokenMetadataSources
+	}{
+		{
+			name:    "fields",
+			storage: `{"prim":"pair","args":[{"prim":"big_map","args":[{"prim":"address"},{"prim":"nat"}],"annots":["%ledger"]},{"prim":"pair","args":[{"prim":"string","annots":["%name"]},{"prim":"pair","args":[{"prim":"string","annots":["%symbol"]},{"prim":"nat","annots":["%decimals"]}]}]}]}`,
+			want: tokenMetadataSources{
+				fields: map[string]string{"name": "0/1/0", "symbol": "0/1/1/0", "decimals": "0/1/1/1"},
+			},
+		}, {
+			name:    "big maps",
+			storage: `{"prim":"pair","args":[{"prim":"big_map","args":[{"prim":"nat"},{"prim":"pair","args":[{"prim":"nat"},{"prim":"pair","args":[{"prim":"string"},{"prim":"pair","args":[{"prim":"string"},{"prim":"pair","args":[{"prim":"nat"},{"prim":"map","args":[{"prim":"string"},{"prim":"string"}]}]}]}]}]}],"annots":["%token_metadata"]},{"prim":"big_map","args":[{"prim":"string"},{"prim":"bytes"}],"annots":["%metadata"]}]}`,
+			want: tokenMetadataSources{
+				tokenMetadata:    "0/0",
+				contractMetadata: "0/1",
+				fields:           map[string]string{},
+			},
+		}, {
+			name:    "name in option",
+			storage: `{"prim":"pair","args":[{"prim":"option","args":[{"prim":"string","annots":["%name"]}]},{"prim":"nat","annots":["%total"]}]}`,
+			want: tokenMetadataSources{
+				fields: map[string]string{},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, err := meta.ParseMetadata(gjson.Parse(tt.storage))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := findTokenMetadataSources(metadata); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findTokenMetadataSources() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseTokenMetadataFields(t *testing.T) {
+	storage := gjson.Parse(`{"prim":"Pair","args":[{"int":"12"},{"prim":"Pair","args":[{"bytes":"d096d0b5d182d0bed0bd"},{"prim":"Pair","args":[{"string":"TT"},{"int":"8"}]}]}]}`)
+	got := parseTokenMetadataFields(storage, map[string]string{"name": "0/1/0", "symbol": "0/1/1/0", "decimals": "0/1/1/1"})
+	want := models.TokenMetadata{Name: "Жетон", Symbol: "TT", Decimals: int64Ptr(8)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTokenMetadataFields() = %+v, want %+v", got, want)
+	}
+}
+
+func Test_parseTokenMetadataBigMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		storage string
+		diffs   []models.BigMapDiff
+		want    models.TokenMetadata
+	}{
+		{
+			name:    "positional",
+			storage: `{"prim":"big_map","args":[{"prim":"nat"},{"prim":"pair","args":[{"prim":"nat"},{"prim":"pair","args":[{"prim":"string"},{"prim":"pair","args":[{"prim":"string"},{"prim":"pair","args":[{"prim":"nat"},{"prim":"map","args":[{"prim":"string"},{"prim":"string"}]}]}]}]}]}],"annots":["%token_metadata"]}`,
+			diffs: []models.BigMapDiff{
+				{Key: map[string]interface{}{"int": "1"}, Value: `{"prim":"Pair","args":[{"int":"1"},{"prim":"Pair","args":[{"string":"SEC"},{"prim":"Pair","args":[{"string":"Second"},{"prim":"Pair","args":[{"int":"0"},[]]}]}]}]}`},
+				{Key: map[string]interface{}{"int": "0"}, Value: `{"prim":"Pair","args":[{"int":"0"},{"prim":"Pair","args":[{"string":"TT"},{"prim":"Pair","args":[{"string":"Tezos Token"},{"prim":"Pair","args":[{"int":"6"},[]]}]}]}]}`},
+			},
+			want: models.TokenMetadata{Name: "Tezos Token", Symbol: "TT", Decimals: int64Ptr(6)},
+		}, {
+			name:    "annotated",
+			storage: `{"prim":"big_map","args":[{"prim":"nat"},{"prim":"pair","args":[{"prim":"nat","annots":["%decimals"]},{"prim":"string","annots":["%symbol"]}]}],"annots":["%token_metadata"]}`,
+			diffs: []models.BigMapDiff{
+				{Key: map[string]interface{}{"int": "0"}, Value: `{"prim":"Pair","args":[{"int":"2"},{"string":"TT"}]}`},
+			},
+			want: models.TokenMetadata{Symbol: "TT", Decimals: int64Ptr(2)},
+		}, {
+			name:    "removed",
+			storage: `{"prim":"big_map","args":[{"prim":"nat"},{"prim":"pair","args":[{"prim":"nat","annots":["%decimals"]},{"prim":"string","annots":["%symbol"]}]}],"annots":["%token_metadata"]}`,
+			diffs: []models.BigMapDiff{
+				{Key: map[string]interface{}{"int": "0"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, err := meta.ParseMetadata(gjson.Parse(tt.storage))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := parseTokenMetadataBigMap(tt.diffs, "0", metadata); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTokenMetadataBigMap() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseContractMetadataBigMap(t *testing.T) {
+	content := models.BigMapDiff{
+		Key:   map[string]interface{}{"string": "content"},
+		Value: `{"bytes":"7b226e616d65223a2254657a6f7320546f6b656e222c2273796d626f6c223a225454222c22646563696d616c73223a2236227d"}`,
+	}
+	tests := []struct {
+		name  string
+		diffs []models.BigMapDiff
+		want  models.TokenMetadata
+	}{
+		{
+			name: "tezos-storage",
+			diffs: []models.BigMapDiff{
+				{Key: map[string]interface{}{"string": ""}, Value: `{"bytes":"74657a6f732d73746f726167653a636f6e74656e74"}`},
+				content,
+			},
+			want: models.TokenMetadata{Name: "Tezos Token", Symbol: "TT", Decimals: int64Ptr(6)},
+		}, {
+			name: "tezos-storage with address",
+			diffs: []models.BigMapDiff{
+				{Key: map[string]interface{}{"string": ""}, Value: `{"bytes":"74657a6f732d73746f726167653a2f2f4b54315144464575384a696a5962734a717a6f5871376d4b7666615151616d4844316b582f2536336f6e74656e74"}`},
+				content,
+			},
+			want: models.TokenMetadata{Name: "Tezos Token", Symbol: "TT", Decimals: int64Ptr(6)},
+		}, {
+			name: "https",
+			diffs: []models.BigMapDiff{
+				{Key: map[string]interface{}{"string": ""}, Value: `{"bytes":"68747470733a2f2f6578616d706c652e636f6d"}`},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseContractMetadataBigMap(tt.diffs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseContractMetadataBigMap() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_decodeBytes(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"text", "54657a6f7320546f6b656e", "Tezos Token"},
+		{"utf-8", "d096d0b5d182d0bed0bd", "Жетон"},
+		{"packed string", "050100000003544f4b", "TOK"},
+		{"binary", "00ff", "00ff"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeBytes(tt.input); got != tt.want {
+				t.Errorf("decodeBytes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/contractparser/meta"
+	"github.com/baking-bad/bcdhub/internal/contractparser/newmiguel"
+	"github.com/baking-bad/bcdhub/internal/contractparser/unpack"
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/helpers"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/baking-bad/bcdhub/internal/tokens"
+	"github.com/tidwall/gjson"
+)
+
+// SetTokenBalances - saves changes of holder balances made by the applied operation with the FA1.2 token.
+// Balances are taken from the ledger big map if the contract has one, otherwise from `transfer`, `mint` and `burn` parameters.
+func (h *Handler) SetTokenBalances(op models.Operation) error {
+	if op.Status != consts.Applied || op.Kind != consts.Transaction || !strings.HasPrefix(op.Destination, "KT") {
+		return nil
+	}
+
+	contract, err := h.ES.GetContract(map[string]interface{}{
+		"network": op.Network,
+		"address": op.Destination,
+	})
+	if err != nil {
+		if elastic.IsRecordNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if !helpers.StringInArray(consts.FA12Tag, contract.Tags) {
+		return nil
+	}
+
+	storageMetadata, err := meta.GetMetadata(h.ES, op.Destination, consts.STORAGE, op.Protocol)
+	if err != nil {
+		return err
+	}
+
+	var changes tokenChanges
+	if ledger, ok := findLedger(storageMetadata); ok {
+		changes, err = h.getLedgerChanges(op, ledger)
+	} else {
+		changes, err = h.getTransferChanges(op)
+	}
+	if err != nil {
+		return err
+	}
+
+	items := make([]elastic.Model, 0)
+	for address, change := range changes {
+		if change.Sign() == 0 {
+			continue
+		}
+		items = append(items, &models.TokenBalanceChange{
+			ID:          helpers.GenerateIDFrom(op.ID, address),
+			Network:     op.Network,
+			Contract:    op.Destination,
+			Address:     address,
+			Level:       op.Level,
+			Timestamp:   op.Timestamp,
+			OperationID: op.ID,
+			Change:      change.String(),
+		})
+	}
+	return h.ES.BulkInsert(items)
+}
+
+// tokenLedger - big map `address -> balance` of the token. `balancePath` is gjson path of the balance in the big map value.
+type tokenLedger struct {
+	binPath     string
+	balancePath string
+}
+
+// findLedger - returns big map with address keys and nat balance in value. Big map named `ledger` or `balances` is preferred.
+func findLedger(metadata meta.Metadata) (ledger tokenLedger, ok bool) {
+	paths := make([]string, 0)
+	for path := range metadata {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		node := metadata[path]
+		if node.Prim != consts.BIGMAP {
+			continue
+		}
+		key, hasKey := metadata[path+"/k"]
+		if !hasKey || key.Prim != consts.ADDRESS {
+			continue
+		}
+		balancePath, found := findBalancePath(metadata, path+"/v")
+		if !found {
+			continue
+		}
+		candidate := tokenLedger{
+			binPath:     path,
+			balancePath: balancePath,
+		}
+		if isBalanceName(node.FieldName) || isBalanceName(node.Name) {
+			return candidate, true
+		}
+		if !ok {
+			ledger, ok = candidate, true
+		}
+	}
+	return
+}
+
+func findBalancePath(metadata meta.Metadata, valuePath string) (string, bool) {
+	value, ok := metadata[valuePath]
+	if !ok {
+		return "", false
+	}
+	if value.Prim == consts.NAT {
+		return "int", true
+	}
+	if value.Prim != consts.PAIR {
+		return "", false
+	}
+
+	var balancePath string
+	for _, argPath := range value.Args {
+		arg := metadata[argPath]
+		if arg == nil || arg.Prim != consts.NAT {
+			continue
+		}
+		path := newmiguel.GetGJSONPath(strings.TrimPrefix(argPath, valuePath+"/")) + ".int"
+		if isBalanceName(arg.FieldName) || isBalanceName(arg.Name) {
+			return path, true
+		}
+		if balancePath == "" {
+			balancePath = path
+		}
+	}
+	return balancePath, balancePath != ""
+}
+
+func isBalanceName(name string) bool {
+	name = strings.ToLower(name)
+	return strings.Contains(name, "ledger") || strings.Contains(name, "balance")
+}
+
+func (l tokenLedger) balance(bmd models.BigMapDiff) (*big.Int, error) {
+	balance := new(big.Int)
+	if bmd.Value == "" {
+		return balance, nil
+	}
+	value := gjson.Parse(bmd.Value).Get(l.balancePath).String()
+	if _, ok := balance.SetString(value, 10); !ok {
+		return nil, fmt.Errorf("[tokenLedger.balance] Invalid balance of %s: %s", bmd.KeyHash, value)
+	}
+	return balance, nil
+}
+
+// getLedgerChanges - differences between ledger values after the operation and before it
+func (h *Handler) getLedgerChanges(op models.Operation, ledger tokenLedger) (tokenChanges, error) {
+	diffs, err := h.ES.GetUniqueBigMapDiffsByOperationID(op.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make([]models.BigMapDiff, 0)
+	var indexedTime int64
+	for i := range diffs {
+		if diffs[i].BinPath != ledger.binPath {
+			continue
+		}
+		if indexedTime == 0 || diffs[i].IndexedTime < indexedTime {
+			indexedTime = diffs[i].IndexedTime
+		}
+		updates = append(updates, diffs[i])
+	}
+	if len(updates) == 0 {
+		return nil, nil
+	}
+
+	prev, err := h.ES.GetPrevBigMapDiffs(updates, indexedTime, op.Destination)
+	if err != nil {
+		return nil, err
+	}
+	prevBalances := make(map[string]*big.Int)
+	for i := range prev {
+		if prevBalances[prev[i].KeyHash], err = ledger.balance(prev[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	changes := make(tokenChanges)
+	for i := range updates {
+		holder, err := getLedgerHolder(updates[i].Key)
+		if err != nil {
+			return nil, err
+		}
+		balance, err := ledger.balance(updates[i])
+		if err != nil {
+			return nil, err
+		}
+		if prevBalance, ok := prevBalances[updates[i].KeyHash]; ok {
+			balance.Sub(balance, prevBalance)
+		}
+		changes.add(holder, balance)
+	}
+	return changes, nil
+}
+
+func getLedgerHolder(key interface{}) (string, error) {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+	value := gjson.ParseBytes(data)
+	if address := value.Get("string"); address.Exists() {
+		return address.String(), nil
+	}
+	if address := value.Get("bytes"); address.Exists() {
+		return unpack.Address(address.String())
+	}
+	return "", fmt.Errorf("[getLedgerHolder] Unknown ledger key: %s", value.Raw)
+}
+
+// getTransferChanges - changes of balances by `transfer(from, to, amount)`, `mint(to, amount)` and `burn(from, amount)` calls
+func (h *Handler) getTransferChanges(op models.Operation) (tokenChanges, error) {
+	switch op.Entrypoint {
+	case "transfer", "mint", "burn":
+	default:
+		return nil, nil
+	}
+
+	metadata, err := meta.GetMetadata(h.ES, op.Destination, consts.PARAMETER, op.Protocol)
+	if err != nil {
+		return nil, err
+	}
+	parameters, err := newmiguel.ParameterToMiguel(gjson.Parse(op.Parameters), metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	transfers, err := tokens.ParseFA12Transfer(op.Entrypoint, parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(tokenChanges)
+	for _, t := range transfers {
+		amount, ok := new(big.Int).SetString(t.Amount, 10)
+		if !ok {
+			return nil, fmt.Errorf("[getTransferChanges] Invalid amount: %s", t.Amount)
+		}
+		if t.From != "" {
+			changes.add(t.From, new(big.Int).Neg(amount))
+		}
+		if t.To != "" {
+			changes.add(t.To, amount)
+		}
+	}
+	return changes, nil
+}
+
+// tokenChanges - changes of holder balances. Amounts of tokens may exceed int64, so they are summed as big integers.
+type tokenChanges map[string]*big.Int
+
+func (c tokenChanges) add(address string, change *big.Int) {
+	if sum, ok := c[address]; ok {
+		sum.Add(sum, change)
+	} else {
+		c[address] = new(big.Int).Set(change)
+	}
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser/meta"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/tidwall/gjson"
+)
+
+func Test_findLedger(t *testing.T) {
+	tests := []struct {
+		name    string
+		storage string
+		want    tokenLedger
+		wantOk  bool
+	}{
+		{
+			name:    "balance in pair",
+			storage: `{"prim":"pair","args":[{"prim":"big_map","args":[{"prim":"address"},{"prim":"pair","args":[{"prim":"map","args":[{"prim":"address"},{"prim":"nat"}]},{"prim":"nat","annots":[":balance"]}]}],"annots":["%ledger"]},{"prim":"nat","annots":["%totalSupply"]}]}`,
+			want:    tokenLedger{binPath: "0/0", balancePath: "args.1.int"},
+			wantOk:  true,
+		}, {
+			name:    "nat value",
+			storage: `{"prim":"pair","args":[{"prim":"address","annots":["%admin"]},{"prim":"big_map","args":[{"prim":"address"},{"prim":"nat"}],"annots":["%balances"]}]}`,
+			want:    tokenLedger{binPath: "0/1", balancePath: "int"},
+			wantOk:  true,
+		}, {
+			name:    "allowances only",
+			storage: `{"prim":"big_map","args":[{"prim":"pair","args":[{"prim":"address"},{"prim":"address"}]},{"prim":"nat"}]}`,
+			wantOk:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, err := meta.ParseMetadata(gjson.Parse(tt.storage))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := findLedger(metadata)
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("findLedger() = %+v %v, want %+v %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_tokenLedger_balance(t *testing.T) {
+	tests := []struct {
+		name   string
+		ledger tokenLedger
+		value  string
+		want   string
+	}{
+		{"nat", tokenLedger{balancePath: "int"}, `{"int":"100"}`, "100"},
+		{"pair", tokenLedger{balancePath: "args.1.int"}, `{"prim":"Pair","args":[[],{"int":"42"}]}`, "42"},
+		{"above int64", tokenLedger{balancePath: "int"}, `{"int":"100000000000000000000000"}`, "100000000000000000000000"},
+		{"removed", tokenLedger{balancePath: "int"}, ``, "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.ledger.balance(models.BigMapDiff{Value: tt.value})
+			if err != nil {
+				t.Errorf("balance() error = %v", err)
+				return
+			}
+			if got.String() != tt.want {
+				t.Errorf("balance() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getLedgerHolder(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     interface{}
+		want    string
+		wantErr bool
+	}{
+		{"string", map[string]interface{}{"string": "tz1a5fMLLY5WCarCzH7RKTJHX9mJFN8eaaWG"}, "tz1a5fMLLY5WCarCzH7RKTJHX9mJFN8eaaWG", false},
+		{"bytes", map[string]interface{}{"bytes": "00009e6ac2e529a49aedbcdd0ac9542d5c0f4ce76f77"}, "tz1a5fMLLY5WCarCzH7RKTJHX9mJFN8eaaWG", false},
+		{"int", map[string]interface{}{"int": "1"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getLedgerHolder(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getLedgerHolder() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("getLedgerHolder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
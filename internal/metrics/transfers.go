@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"strings"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser/cerrors"
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/contractparser/meta"
+	"github.com/baking-bad/bcdhub/internal/contractparser/newmiguel"
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/helpers"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/baking-bad/bcdhub/internal/tokens"
+	"github.com/tidwall/gjson"
+)
+
+// SetTokenTransfers - saves transfers of FA1.2 and FA2 tokens decoded from the operation parameters.
+// Transfers of failed and backtracked operations are saved too, they differ by status.
+func (h *Handler) SetTokenTransfers(op models.Operation) error {
+	if op.Kind != consts.Transaction || !strings.HasPrefix(op.Destination, "KT") || op.Parameters == "" {
+		return nil
+	}
+	switch op.Entrypoint {
+	case "transfer", "mint", "burn":
+	default:
+		return nil
+	}
+	if cerrors.HasParametersError(op.Errors) || cerrors.HasGasExhaustedError(op.Errors) {
+		return nil
+	}
+
+	contract, err := h.ES.GetContract(map[string]interface{}{
+		"network": op.Network,
+		"address": op.Destination,
+	})
+	if err != nil {
+		if elastic.IsRecordNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	isFA12 := helpers.StringInArray(consts.FA12Tag, contract.Tags)
+	isFA2 := helpers.StringInArray(consts.FA2Tag, contract.Tags) && op.Entrypoint == "transfer"
+	if !isFA12 && !isFA2 {
+		return nil
+	}
+
+	metadata, err := meta.GetMetadata(h.ES, op.Destination, consts.PARAMETER, op.Protocol)
+	if err != nil {
+		return err
+	}
+	parameters, err := newmiguel.ParameterToMiguel(gjson.Parse(op.Parameters), metadata)
+	if err != nil {
+		return err
+	}
+
+	var transfers []tokens.Transfer
+	if isFA12 {
+		transfers, err = tokens.ParseFA12Transfer(op.Entrypoint, parameters)
+	} else {
+		transfers, err = tokens.ParseFA2Transfer(parameters)
+	}
+	if err != nil {
+		return err
+	}
+
+	items := make([]elastic.Model, len(transfers))
+	for i, t := range transfers {
+		items[i] = newTransfer(op, t, int64(i))
+	}
+	return h.ES.BulkInsert(items)
+}
+
+func newTransfer(op models.Operation, t tokens.Transfer, nonce int64) *models.Transfer {
+	return &models.Transfer{
+		ID:            helpers.GenerateIDFrom(op.ID, nonce),
+		Network:       op.Network,
+		Contract:      op.Destination,
+		Protocol:      op.Protocol,
+		Hash:          op.Hash,
+		Counter:       op.Counter,
+		InternalIndex: op.InternalIndex,
+		Status:        op.Status,
+		Timestamp:     op.Timestamp,
+		Level:         op.Level,
+		IndexedTime:   op.IndexedTime,
+		Nonce:         nonce,
+		OperationID:   op.ID,
+		Entrypoint:    op.Entrypoint,
+		Source:        op.Source,
+		From:          t.From,
+		To:            t.To,
+		TokenID:       t.TokenID,
+		Amount:        t.Amount,
+	}
+}
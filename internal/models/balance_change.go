@@ -0,0 +1,78 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/helpers"
+	"github.com/tidwall/gjson"
+)
+
+// BalanceChange - change of the contract balance. `Kind` is the kind of operation which changed the balance
+// or `migration` if the balance is changed by protocol migration.
+type BalanceChange struct {
+	ID string `json:"-"`
+
+	Network     string    `json:"network"`
+	Address     string    `json:"address"`
+	Level       int64     `json:"level"`
+	Timestamp   time.Time `json:"timestamp"`
+	Kind        string    `json:"kind"`
+	OperationID string    `json:"operation_id,omitempty"`
+	Hash        string    `json:"hash,omitempty"`
+	Change      int64     `json:"change"`
+}
+
+// NewBalanceChanges - returns changes of contract balances made by the applied operation
+func NewBalanceChanges(op Operation) []*BalanceChange {
+	changes := make([]*BalanceChange, 0)
+	if op.Status != consts.Applied {
+		return changes
+	}
+	for i, update := range op.BalanceUpdates {
+		if update.Change == 0 || !strings.HasPrefix(update.Contract, "KT") {
+			continue
+		}
+		changes = append(changes, &BalanceChange{
+			ID:          helpers.GenerateIDFrom(op.ID, update.Contract, i),
+			Network:     op.Network,
+			Address:     update.Contract,
+			Level:       op.Level,
+			Timestamp:   op.Timestamp,
+			Kind:        op.Kind,
+			OperationID: op.ID,
+			Hash:        op.Hash,
+			Change:      update.Change,
+		})
+	}
+	return changes
+}
+
+// BalanceChangeID - returns ID of balance change of `address` which is not made by an operation, e.g. by protocol migration
+func BalanceChangeID(network, address string, level int64, kind string) string {
+	return helpers.GenerateIDFrom(network, address, level, kind)
+}
+
+// GetID -
+func (b *BalanceChange) GetID() string {
+	return b.ID
+}
+
+// GetIndex -
+func (b *BalanceChange) GetIndex() string {
+	return "balance_change"
+}
+
+// ParseElasticJSON -
+func (b *BalanceChange) ParseElasticJSON(hit gjson.Result) {
+	b.ID = hit.Get("_id").String()
+	b.Network = hit.Get("_source.network").String()
+	b.Address = hit.Get("_source.address").String()
+	b.Level = hit.Get("_source.level").Int()
+	b.Timestamp = hit.Get("_source.timestamp").Time().UTC()
+	b.Kind = hit.Get("_source.kind").String()
+	b.OperationID = hit.Get("_source.operation_id").String()
+	b.Hash = hit.Get("_source.hash").String()
+	b.Change = hit.Get("_source.change").Int()
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/helpers"
+	"github.com/tidwall/gjson"
+)
+
+// BigMap - big map pointer registry entry. Pointer is unique in the network, so the entry is identified by network and pointer.
+// `SourcePtr` is set if the big map was created by copying another one. `Temporary` is the negative pointer of the temporary big map
+// which was copied into this one. Entries of big maps created before the registry are backfilled from their first diffs without types and lineage.
+type BigMap struct {
+	ID string `json:"-"`
+
+	Network     string    `json:"network"`
+	Ptr         int64     `json:"ptr"`
+	Address     string    `json:"address"`
+	BinPath     string    `json:"bin_path"`
+	KeyType     string    `json:"key_type"`
+	ValueType   string    `json:"value_type"`
+	OperationID string    `json:"operation_id"`
+	Level       int64     `json:"level"`
+	Timestamp   time.Time `json:"timestamp"`
+	Protocol    string    `json:"protocol"`
+	SourcePtr   *int64    `json:"source_ptr,omitempty"`
+	Temporary   int64     `json:"temporary,omitempty"`
+
+	RemovedOperationID string `json:"removed_operation_id,omitempty"`
+	RemovedLevel       int64  `json:"removed_level,omitempty"`
+}
+
+// BigMapID - returns ID of the registry entry of big map `ptr`
+func BigMapID(network string, ptr int64) string {
+	return helpers.GenerateIDFrom(network, ptr)
+}
+
+// GetID -
+func (b *BigMap) GetID() string {
+	return b.ID
+}
+
+// GetIndex -
+func (b *BigMap) GetIndex() string {
+	return "bigmap"
+}
+
+// ParseElasticJSON -
+func (b *BigMap) ParseElasticJSON(hit gjson.Result) {
+	b.ID = hit.Get("_id").String()
+	b.Network = hit.Get("_source.network").String()
+	b.Ptr = hit.Get("_source.ptr").Int()
+	b.Address = hit.Get("_source.address").String()
+	b.BinPath = hit.Get("_source.bin_path").String()
+	b.KeyType = hit.Get("_source.key_type").String()
+	b.ValueType = hit.Get("_source.value_type").String()
+	b.OperationID = hit.Get("_source.operation_id").String()
+	b.Level = hit.Get("_source.level").Int()
+	b.Timestamp = hit.Get("_source.timestamp").Time().UTC()
+	b.Protocol = hit.Get("_source.protocol").String()
+	b.Temporary = hit.Get("_source.temporary").Int()
+	b.RemovedOperationID = hit.Get("_source.removed_operation_id").String()
+	b.RemovedLevel = hit.Get("_source.removed_level").Int()
+
+	if sourcePtr := hit.Get("_source.source_ptr"); sourcePtr.Exists() {
+		ptr := sourcePtr.Int()
+		b.SourcePtr = &ptr
+	}
+}
+
+// IsRemoved -
+func (b *BigMap) IsRemoved() bool {
+	return b.RemovedLevel > 0
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"sort"
 	"time"
 
 	"github.com/baking-bad/bcdhub/internal/helpers"
@@ -19,6 +20,7 @@ type Contract struct {
 	Hash        string       `json:"hash"`
 	Fingerprint *Fingerprint `json:"fingerprint,omitempty"`
 	Tags        []string     `json:"tags,omitempty"`
+	TagVersions []TagVersion `json:"tag_versions,omitempty"`
 	Hardcoded   []string     `json:"hardcoded,omitempty"`
 	FailStrings []string     `json:"fail_strings,omitempty"`
 	Annotations []string     `json:"annotations,omitempty"`
@@ -36,6 +38,29 @@ type Contract struct {
 	TotalWithdrawn  int64   `json:"total_withdrawn,omitempty"`
 	Alias           string  `json:"alias,omitempty"`
 	DelegateAlias   string  `json:"delegate_alias,omitempty"`
+
+	TokenMetadata *TokenMetadata `json:"token_metadata,omitempty"`
+}
+
+// TagVersion - version of the interface which the contract matched when it was tagged
+type TagVersion struct {
+	Tag     string `json:"tag"`
+	Version int64  `json:"version"`
+}
+
+// NewTagVersions - converts interface versions by tag to the list sorted by tag
+func NewTagVersions(versions map[string]int64) []TagVersion {
+	res := make([]TagVersion, 0, len(versions))
+	for tag, version := range versions {
+		res = append(res, TagVersion{
+			Tag:     tag,
+			Version: version,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Tag < res[j].Tag
+	})
+	return res
 }
 
 // GetID -
@@ -58,6 +83,12 @@ func (c *Contract) ParseElasticJSON(hit gjson.Result) {
 	c.Language = hit.Get("_source.language").String()
 
 	c.Tags = parseStringArray(hit, "_source.tags")
+	for _, item := range hit.Get("_source.tag_versions").Array() {
+		c.TagVersions = append(c.TagVersions, TagVersion{
+			Tag:     item.Get("tag").String(),
+			Version: item.Get("version").Int(),
+		})
+	}
 	c.Hardcoded = parseStringArray(hit, "_source.hardcoded")
 	c.Annotations = parseStringArray(hit, "_source.annotations")
 	c.FailStrings = parseStringArray(hit, "_source.fail_strings")
@@ -85,6 +116,11 @@ func (c *Contract) ParseElasticJSON(hit gjson.Result) {
 	c.TotalWithdrawn = hit.Get("_source.total_withdrawn").Int()
 	c.Alias = hit.Get("_source.alias").String()
 
+	if tm := hit.Get("_source.token_metadata"); tm.Exists() {
+		c.TokenMetadata = &TokenMetadata{}
+		c.TokenMetadata.ParseElasticJSON(tm)
+	}
+
 	c.FoundBy = c.FoundByName(hit)
 }
 
@@ -94,6 +130,8 @@ func (c *Contract) GetScores(search string) []string {
 		return []string{
 			"address^10",
 			"alias^9",
+			"token_metadata.name^9",
+			"token_metadata.symbol^9",
 			"tags^9",
 			"entrypoints^8",
 			"fail_strings^6",
@@ -106,6 +144,8 @@ func (c *Contract) GetScores(search string) []string {
 	}
 	return []string{
 		"alias^10",
+		"token_metadata.name^9",
+		"token_metadata.symbol^9",
 		"tags^9",
 		"entrypoints^8",
 		"fail_strings^6",
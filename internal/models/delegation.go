@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Delegation - change of the delegate of `Source`. Empty `Delegate` means the delegation is withdrawn.
+// `Kind` is `origination` if the delegate is set by the origination of the contract.
+type Delegation struct {
+	ID          string `json:"-"`
+	IndexedTime int64  `json:"indexed_time"`
+
+	Network       string    `json:"network"`
+	Protocol      string    `json:"protocol"`
+	Hash          string    `json:"hash"`
+	Counter       int64     `json:"counter"`
+	Internal      bool      `json:"internal"`
+	InternalIndex int64     `json:"internal_index,omitempty"`
+	Level         int64     `json:"level"`
+	Timestamp     time.Time `json:"timestamp"`
+	Kind          string    `json:"kind"`
+	Status        string    `json:"status"`
+	Source        string    `json:"source"`
+	Delegate      string    `json:"delegate,omitempty"`
+	Fee           int64     `json:"fee,omitempty"`
+}
+
+// GetID -
+func (d *Delegation) GetID() string {
+	return d.ID
+}
+
+// GetIndex -
+func (d *Delegation) GetIndex() string {
+	return "delegation"
+}
+
+// ParseElasticJSON -
+func (d *Delegation) ParseElasticJSON(hit gjson.Result) {
+	d.ID = hit.Get("_id").String()
+	d.IndexedTime = hit.Get("_source.indexed_time").Int()
+
+	d.Network = hit.Get("_source.network").String()
+	d.Protocol = hit.Get("_source.protocol").String()
+	d.Hash = hit.Get("_source.hash").String()
+	d.Counter = hit.Get("_source.counter").Int()
+	d.Internal = hit.Get("_source.internal").Bool()
+	d.InternalIndex = hit.Get("_source.internal_index").Int()
+	d.Level = hit.Get("_source.level").Int()
+	d.Timestamp = hit.Get("_source.timestamp").Time().UTC()
+	d.Kind = hit.Get("_source.kind").String()
+	d.Status = hit.Get("_source.status").String()
+	d.Source = hit.Get("_source.source").String()
+	d.Delegate = hit.Get("_source.delegate").String()
+	d.Fee = hit.Get("_source.fee").Int()
+}
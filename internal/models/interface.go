@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/helpers"
+	"github.com/tidwall/gjson"
+)
+
+// Interface - user-defined interface used for contract tagging. `Entrypoints` is JSON array of `{"name": ..., "type": <Micheline type>}`.
+// `Retagged` contains networks where existing contracts were already re-tagged by this version.
+type Interface struct {
+	ID          string    `json:"-"`
+	Name        string    `json:"name"`
+	Version     int64     `json:"version"`
+	Entrypoints string    `json:"entrypoints"`
+	Retagged    []string  `json:"retagged,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// GetID -
+func (i *Interface) GetID() string {
+	return i.ID
+}
+
+// GetIndex -
+func (i *Interface) GetIndex() string {
+	return "interface"
+}
+
+// ParseElasticJSON -
+func (i *Interface) ParseElasticJSON(hit gjson.Result) {
+	i.ID = hit.Get("_id").String()
+	i.Name = hit.Get("_source.name").String()
+	i.Version = hit.Get("_source.version").Int()
+	i.Entrypoints = hit.Get("_source.entrypoints").String()
+	i.Retagged = parseStringArray(hit, "_source.retagged")
+	i.CreatedAt = hit.Get("_source.created_at").Time().UTC()
+}
+
+// NewInterface - creates version of user-defined interface
+func NewInterface(name string, version int64, entrypoints string) *Interface {
+	return &Interface{
+		ID:          helpers.GenerateIDFrom(name, version),
+		Name:        name,
+		Version:     version,
+		Entrypoints: entrypoints,
+		CreatedAt:   time.Now().UTC(),
+	}
+}
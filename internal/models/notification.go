@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/helpers"
+	"github.com/tidwall/gjson"
+)
+
+// Notification statuses
+const (
+	NotificationPending   = "pending"
+	NotificationSent      = "sent"
+	NotificationProcessed = "processed"
+)
+
+// Notification - message for the `metrics` service about a new document. It's saved together with the block data
+// and delivered to RabbitMQ by the outbox relay, so a message can't be lost if RabbitMQ is unavailable.
+type Notification struct {
+	ID string `json:"-"`
+
+	Network     string    `json:"network"`
+	Level       int64     `json:"level"`
+	Queue       string    `json:"queue"`
+	EntityID    string    `json:"entity_id"`
+	Status      string    `json:"status"`
+	Attempts    int64     `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt"`
+	Error       string    `json:"error,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// NewNotification -
+func NewNotification(network string, level int64, queue, entityID string) *Notification {
+	now := time.Now().UTC()
+	return &Notification{
+		ID:          NotificationID(queue, entityID),
+		Network:     network,
+		Level:       level,
+		Queue:       queue,
+		EntityID:    entityID,
+		Status:      NotificationPending,
+		NextAttempt: now,
+		Timestamp:   now,
+	}
+}
+
+// NotificationID - returns ID of notification about `entityID` sent to `queue`
+func NotificationID(queue, entityID string) string {
+	return helpers.GenerateIDFrom(queue, entityID)
+}
+
+// GetID -
+func (n *Notification) GetID() string {
+	return n.ID
+}
+
+// GetIndex -
+func (n *Notification) GetIndex() string {
+	return "notification"
+}
+
+// ParseElasticJSON -
+func (n *Notification) ParseElasticJSON(hit gjson.Result) {
+	n.ID = hit.Get("_id").String()
+	n.Network = hit.Get("_source.network").String()
+	n.Level = hit.Get("_source.level").Int()
+	n.Queue = hit.Get("_source.queue").String()
+	n.EntityID = hit.Get("_source.entity_id").String()
+	n.Status = hit.Get("_source.status").String()
+	n.Attempts = hit.Get("_source.attempts").Int()
+	n.NextAttempt = hit.Get("_source.next_attempt").Time()
+	n.Error = hit.Get("_source.error").String()
+	n.Timestamp = hit.Get("_source.timestamp").Time()
+}
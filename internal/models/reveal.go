@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Reveal - publication of the public key of the implicit account
+type Reveal struct {
+	ID          string `json:"-"`
+	IndexedTime int64  `json:"indexed_time"`
+
+	Network   string    `json:"network"`
+	Protocol  string    `json:"protocol"`
+	Hash      string    `json:"hash"`
+	Counter   int64     `json:"counter"`
+	Level     int64     `json:"level"`
+	Timestamp time.Time `json:"timestamp"`
+	Status    string    `json:"status"`
+	Source    string    `json:"source"`
+	PublicKey string    `json:"public_key"`
+	Fee       int64     `json:"fee,omitempty"`
+}
+
+// GetID -
+func (r *Reveal) GetID() string {
+	return r.ID
+}
+
+// GetIndex -
+func (r *Reveal) GetIndex() string {
+	return "reveal"
+}
+
+// ParseElasticJSON -
+func (r *Reveal) ParseElasticJSON(hit gjson.Result) {
+	r.ID = hit.Get("_id").String()
+	r.IndexedTime = hit.Get("_source.indexed_time").Int()
+
+	r.Network = hit.Get("_source.network").String()
+	r.Protocol = hit.Get("_source.protocol").String()
+	r.Hash = hit.Get("_source.hash").String()
+	r.Counter = hit.Get("_source.counter").Int()
+	r.Level = hit.Get("_source.level").Int()
+	r.Timestamp = hit.Get("_source.timestamp").Time().UTC()
+	r.Status = hit.Get("_source.status").String()
+	r.Source = hit.Get("_source.source").String()
+	r.PublicKey = hit.Get("_source.public_key").String()
+	r.Fee = hit.Get("_source.fee").Int()
+}
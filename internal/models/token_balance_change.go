@@ -0,0 +1,90 @@
+package models
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// TokenBalanceChange - change of the holder balance in the FA1.2 token contract made by the operation.
+// `Change` is a signed decimal string because token amounts may exceed int64.
+type TokenBalanceChange struct {
+	ID string `json:"-"`
+
+	Network     string    `json:"network"`
+	Contract    string    `json:"contract"`
+	Address     string    `json:"address"`
+	Level       int64     `json:"level"`
+	Timestamp   time.Time `json:"timestamp"`
+	OperationID string    `json:"operation_id"`
+	Change      string    `json:"change"`
+}
+
+// GetID -
+func (t *TokenBalanceChange) GetID() string {
+	return t.ID
+}
+
+// GetIndex -
+func (t *TokenBalanceChange) GetIndex() string {
+	return "token_balance_change"
+}
+
+// ParseElasticJSON -
+func (t *TokenBalanceChange) ParseElasticJSON(hit gjson.Result) {
+	t.ID = hit.Get("_id").String()
+	t.Network = hit.Get("_source.network").String()
+	t.Contract = hit.Get("_source.contract").String()
+	t.Address = hit.Get("_source.address").String()
+	t.Level = hit.Get("_source.level").Int()
+	t.Timestamp = hit.Get("_source.timestamp").Time().UTC()
+	t.OperationID = hit.Get("_source.operation_id").String()
+	t.Change = hit.Get("_source.change").String()
+}
+
+// TokenBalance - balance of the token holder. `Balance` is a decimal string because token amounts may exceed int64.
+type TokenBalance struct {
+	Address string `json:"address"`
+	Balance string `json:"balance"`
+}
+
+// SumTokenBalances - sums changes of holder balances. Holders with zero balance are skipped, others are sorted by balance descending.
+func SumTokenBalanceChanges(changes []TokenBalanceChange) ([]TokenBalance, error) {
+	sums := make(map[string]*big.Int)
+	for i := range changes {
+		change, ok := new(big.Int).SetString(changes[i].Change, 10)
+		if !ok {
+			return nil, fmt.Errorf("Invalid token balance change %s: %s", changes[i].ID, changes[i].Change)
+		}
+		if sum, ok := sums[changes[i].Address]; ok {
+			sum.Add(sum, change)
+		} else {
+			sums[changes[i].Address] = change
+		}
+	}
+
+	addresses := make([]string, 0, len(sums))
+	for address, sum := range sums {
+		if sum.Sign() != 0 {
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		if c := sums[addresses[i]].Cmp(sums[addresses[j]]); c != 0 {
+			return c > 0
+		}
+		return addresses[i] < addresses[j]
+	})
+
+	balances := make([]TokenBalance, len(addresses))
+	for i, address := range addresses {
+		balances[i] = TokenBalance{
+			Address: address,
+			Balance: sums[address].String(),
+		}
+	}
+	return balances, nil
+}
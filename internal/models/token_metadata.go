@@ -0,0 +1,39 @@
+package models
+
+import "github.com/tidwall/gjson"
+
+// TokenMetadata - name, symbol and decimals of the token. They are taken from TZIP-7 `token_metadata` big map,
+// TZIP-16 `metadata` big map or storage fields with the same names.
+type TokenMetadata struct {
+	Name     string `json:"name,omitempty"`
+	Symbol   string `json:"symbol,omitempty"`
+	Decimals *int64 `json:"decimals,omitempty"`
+	Level    int64  `json:"level"`
+}
+
+// ParseElasticJSON -
+func (t *TokenMetadata) ParseElasticJSON(hit gjson.Result) {
+	t.Name = hit.Get("name").String()
+	t.Symbol = hit.Get("symbol").String()
+	if decimals := hit.Get("decimals"); decimals.Exists() {
+		value := decimals.Int()
+		t.Decimals = &value
+	}
+	t.Level = hit.Get("level").Int()
+}
+
+// IsEmpty - returns true if nothing is known about the token
+func (t TokenMetadata) IsEmpty() bool {
+	return t.Name == "" && t.Symbol == "" && t.Decimals == nil
+}
+
+// Equal - compares metadata fields without level
+func (t TokenMetadata) Equal(other TokenMetadata) bool {
+	if t.Name != other.Name || t.Symbol != other.Symbol {
+		return false
+	}
+	if t.Decimals == nil || other.Decimals == nil {
+		return t.Decimals == other.Decimals
+	}
+	return *t.Decimals == *other.Decimals
+}
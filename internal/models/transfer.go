@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Transfer - token transfer decoded from the operation parameters. `InternalIndex` is the index of the internal operation
+// and `Nonce` is the index of the transfer in the operation.
+// Mints have no sender and burns have no receiver. `TokenID` and `Amount` are decimal strings because they may exceed int64.
+type Transfer struct {
+	ID string `json:"-"`
+
+	Network       string    `json:"network"`
+	Contract      string    `json:"contract"`
+	Protocol      string    `json:"protocol"`
+	Hash          string    `json:"hash"`
+	Counter       int64     `json:"counter"`
+	InternalIndex int64     `json:"internal_index"`
+	Status        string    `json:"status"`
+	Timestamp     time.Time `json:"timestamp"`
+	Level         int64     `json:"level"`
+	IndexedTime   int64     `json:"indexed_time"`
+	Nonce         int64     `json:"nonce"`
+	OperationID   string    `json:"operation_id"`
+	Entrypoint    string    `json:"entrypoint"`
+	Source        string    `json:"source"`
+	From          string    `json:"from"`
+	To            string    `json:"to"`
+	TokenID       string    `json:"token_id"`
+	Amount        string    `json:"amount"`
+}
+
+// GetID -
+func (t *Transfer) GetID() string {
+	return t.ID
+}
+
+// GetIndex -
+func (t *Transfer) GetIndex() string {
+	return "transfer"
+}
+
+// ParseElasticJSON -
+func (t *Transfer) ParseElasticJSON(hit gjson.Result) {
+	t.ID = hit.Get("_id").String()
+	t.Network = hit.Get("_source.network").String()
+	t.Contract = hit.Get("_source.contract").String()
+	t.Protocol = hit.Get("_source.protocol").String()
+	t.Hash = hit.Get("_source.hash").String()
+	t.Counter = hit.Get("_source.counter").Int()
+	t.InternalIndex = hit.Get("_source.internal_index").Int()
+	t.Status = hit.Get("_source.status").String()
+	t.Timestamp = hit.Get("_source.timestamp").Time().UTC()
+	t.Level = hit.Get("_source.level").Int()
+	t.IndexedTime = hit.Get("_source.indexed_time").Int()
+	t.Nonce = hit.Get("_source.nonce").Int()
+	t.OperationID = hit.Get("_source.operation_id").String()
+	t.Entrypoint = hit.Get("_source.entrypoint").String()
+	t.Source = hit.Get("_source.source").String()
+	t.From = hit.Get("_source.from").String()
+	t.To = hit.Get("_source.to").String()
+	t.TokenID = hit.Get("_source.token_id").String()
+	t.Amount = hit.Get("_source.amount").String()
+}
@@ -7,6 +7,12 @@ import (
 	"github.com/streadway/amqp"
 )
 
+// Publisher - sends messages to queues
+type Publisher interface {
+	Send(channel, queue string, v interface{}) error
+	Close()
+}
+
 // MQ -
 type MQ struct {
 	Conn    *amqp.Connection
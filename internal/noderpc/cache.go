@@ -0,0 +1,203 @@
+package noderpc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultFinalityDepth - count of blocks after which the level is considered as finalized
+const DefaultFinalityDepth = 60
+
+// Cache - disk-backed storage of node responses for finalized levels. Responses are keyed by request URI,
+// file name is SHA-256 of the URI. When total size exceeds the limit, least recently used responses are evicted.
+type Cache struct {
+	dir     string
+	maxSize int64
+	depth   int64
+	head    int64
+
+	mux     sync.Mutex
+	entries map[string]*cacheEntry
+	size    int64
+}
+
+type cacheEntry struct {
+	key        string
+	size       int64
+	accessTime time.Time
+}
+
+// CacheOption -
+type CacheOption func(*Cache)
+
+// WithFinalityDepth - responses for levels deeper than `depth` blocks from the head are cached
+func WithFinalityDepth(depth int64) CacheOption {
+	return func(c *Cache) {
+		if depth >= 0 {
+			c.depth = depth
+		}
+	}
+}
+
+// NewCache - creates cache in `dir` which is not bigger than `maxSize` bytes. Zero `maxSize` means unlimited size.
+func NewCache(dir string, maxSize int64, opts ...CacheOption) (*Cache, error) {
+	c := &Cache{
+		dir:     dir,
+		maxSize: maxSize,
+		depth:   DefaultFinalityDepth,
+		entries: make(map[string]*cacheEntry),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Cache) load() error {
+	return filepath.Walk(c.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		key := filepath.Base(path)
+		if filepath.Ext(key) == ".tmp" {
+			return os.Remove(path)
+		}
+		c.entries[key] = &cacheEntry{
+			key:        key,
+			size:       info.Size(),
+			accessTime: info.ModTime(),
+		}
+		c.size += info.Size()
+		return nil
+	})
+}
+
+func cacheKey(uri string) string {
+	hash := sha256.Sum256([]byte(uri))
+	return hex.EncodeToString(hash[:])
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+// SetHead - updates known head level of the network. Responses are not saved until the head is known.
+func (c *Cache) SetHead(level int64) {
+	for {
+		head := atomic.LoadInt64(&c.head)
+		if level <= head || atomic.CompareAndSwapInt64(&c.head, head, level) {
+			return
+		}
+	}
+}
+
+// IsFinalized -
+func (c *Cache) IsFinalized(level int64) bool {
+	head := atomic.LoadInt64(&c.head)
+	return level > 0 && head > 0 && level <= head-c.depth
+}
+
+// Get - returns cached response for `uri`
+func (c *Cache) Get(uri string) ([]byte, bool) {
+	key := cacheKey(uri)
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	data, err := ioutil.ReadFile(c.path(key))
+	if err != nil {
+		c.remove(entry)
+		return nil, false
+	}
+	entry.accessTime = time.Now()
+	_ = os.Chtimes(c.path(key), entry.accessTime, entry.accessTime)
+	return data, true
+}
+
+// Set - saves response for `uri` if `level` is finalized
+func (c *Cache) Set(uri string, level int64, data []byte) error {
+	if !c.IsFinalized(level) {
+		return nil
+	}
+	if c.maxSize > 0 && int64(len(data)) > c.maxSize {
+		return nil
+	}
+	key := cacheKey(uri)
+	path := c.path(key)
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if _, ok := c.entries[key]; ok {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	// write to temporary file and rename, so readers never see partially written response
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	c.entries[key] = &cacheEntry{
+		key:        key,
+		size:       int64(len(data)),
+		accessTime: time.Now(),
+	}
+	c.size += int64(len(data))
+	c.evict()
+	return nil
+}
+
+// Size - total size of cached responses in bytes
+func (c *Cache) Size() int64 {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.size
+}
+
+func (c *Cache) evict() {
+	if c.maxSize == 0 || c.size <= c.maxSize {
+		return
+	}
+	entries := make([]*cacheEntry, 0, len(c.entries))
+	for _, entry := range c.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].accessTime.Before(entries[j].accessTime)
+	})
+	for i := 0; i < len(entries) && c.size > c.maxSize; i++ {
+		c.remove(entries[i])
+	}
+}
+
+func (c *Cache) remove(entry *cacheEntry) {
+	_ = os.Remove(c.path(entry.key))
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+}
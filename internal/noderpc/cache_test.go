@@ -0,0 +1,117 @@
+package noderpc
+
+import (
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestCache(t *testing.T, maxSize int64) (*Cache, func()) {
+	dir, err := ioutil.TempDir("", "rpc_cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := NewCache(dir, maxSize, WithFinalityDepth(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cache, func() { os.RemoveAll(dir) }
+}
+
+func TestCache_finalized(t *testing.T) {
+	cache, cleanup := newTestCache(t, 0)
+	defer cleanup()
+
+	if err := cache.Set("a", 10, []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.Get("a"); ok {
+		t.Errorf("response is cached while head is unknown")
+	}
+
+	cache.SetHead(100)
+	cache.SetHead(50)
+	tests := []struct {
+		level int64
+		want  bool
+	}{
+		{90, true},
+		{91, false},
+		{0, false},
+	}
+	for _, tt := range tests {
+		if got := cache.IsFinalized(tt.level); got != tt.want {
+			t.Errorf("IsFinalized(%d) = %v, want %v", tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestCache_eviction(t *testing.T) {
+	cache, cleanup := newTestCache(t, 10)
+	defer cleanup()
+	cache.SetHead(100)
+
+	for _, uri := range []string{"a", "b"} {
+		if err := cache.Set(uri, 1, []byte("12345")); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// `a` becomes the most recently used
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatalf("a is not cached")
+	}
+	if err := cache.Set("c", 1, []byte("12345")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := cache.Get("b"); ok {
+		t.Errorf("least recently used response is not evicted")
+	}
+	for _, uri := range []string{"a", "c"} {
+		if _, ok := cache.Get(uri); !ok {
+			t.Errorf("%s is evicted", uri)
+		}
+	}
+	if size := cache.Size(); size != 10 {
+		t.Errorf("Size() = %d, want 10", size)
+	}
+
+	reopened, err := NewCache(cache.dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reopened.Get("c"); !ok || reopened.Size() != 10 {
+		t.Errorf("cache is not restored from disk")
+	}
+}
+
+func TestNodeRPC_cache(t *testing.T) {
+	node := newTestNode(100)
+	cache, cleanup := newTestCache(t, 0)
+	defer cleanup()
+
+	pool := NewPool([]string{node.URL}, time.Second, WithCache(cache))
+	if _, err := pool.GetLevel(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := pool.GetScriptJSON("KT1", 50); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pool.GetScriptJSON("KT1", 95); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls := atomic.LoadInt64(&node.calls); calls != 5 {
+		t.Errorf("node was called %d times, want 5", calls)
+	}
+
+	// finalized responses are available without the node
+	node.Close()
+	if _, err := pool.GetScriptJSON("KT1", 50); err != nil {
+		t.Errorf("cached response: %s", err)
+	}
+}
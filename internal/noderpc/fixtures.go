@@ -0,0 +1,119 @@
+package noderpc
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// fixturesURL - base URL of the pool which serves recorded responses
+const fixturesURL = "http://fixtures"
+
+// fixturePath - response for `chains/main/blocks/1/header` is stored in `dir/chains/main/blocks/1/header.json`
+func fixturePath(dir, uri string) string {
+	uri = strings.Trim(uri, "/")
+	return filepath.Join(dir, filepath.FromSlash(uri)+".json")
+}
+
+// FixtureTransport - serves GET requests from the directory of recorded responses
+type FixtureTransport struct {
+	dir string
+}
+
+// NewFixtureTransport -
+func NewFixtureTransport(dir string) *FixtureTransport {
+	return &FixtureTransport{dir}
+}
+
+// RoundTrip -
+func (t *FixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return nil, fmt.Errorf("Fixtures support only GET requests: %s %s", req.Method, req.URL.Path)
+	}
+	data, err := ioutil.ReadFile(fixturePath(t.dir, req.URL.Path))
+	if err != nil {
+		return nil, fmt.Errorf("Fixture is not found: %s", req.URL.Path)
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}, nil
+}
+
+// Recorder - saves successful responses of GET requests to the directory in format of `FixtureTransport`
+type Recorder struct {
+	dir    string
+	prefix string
+	next   http.RoundTripper
+}
+
+// NewRecorder - records responses of the node with `baseURL` received by `next`. If `next` is nil, `http.DefaultTransport` is used.
+func NewRecorder(dir, baseURL string, next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	var prefix string
+	if u, err := url.Parse(baseURL); err == nil {
+		prefix = strings.TrimSuffix(u.Path, "/")
+	}
+	return &Recorder{dir, prefix, next}
+}
+
+// RoundTrip -
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil || req.Method != http.MethodGet || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	path := fixturePath(r.dir, strings.TrimPrefix(req.URL.Path, r.prefix))
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// WithRecorder - nodes of the pool save all responses to `dir`, so they can be replayed by `NewFixturePool`
+func WithRecorder(dir string) PoolOption {
+	return func(p Pool) {
+		for i := range p {
+			p[i].node.SetTransport(NewRecorder(dir, p[i].url, p[i].node.transport))
+		}
+	}
+}
+
+// NewFixturePool - creates pool which serves responses recorded in `dir` without access to the network
+func NewFixturePool(dir string, opts ...PoolOption) Pool {
+	pool := NewPool([]string{fixturesURL}, time.Second)
+	for i := range pool {
+		pool[i].node.SetTransport(NewFixtureTransport(dir))
+		pool[i].node.retryCount = 1
+	}
+	for _, opt := range opts {
+		opt(pool)
+	}
+	return pool
+}
@@ -0,0 +1,39 @@
+package noderpc
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	node := newTestNode(100)
+	pool := NewPool([]string{node.URL + "/mainnet"}, time.Second, WithRecorder(dir))
+	if _, err := pool.GetHeader(100); err != nil {
+		t.Fatal(err)
+	}
+	node.Close()
+
+	if _, err := os.Stat(fixturePath(dir, "chains/main/blocks/100/header")); err != nil {
+		t.Fatalf("response is not recorded: %s", err)
+	}
+
+	replay := NewFixturePool(dir)
+	header, err := replay.GetHeader(100)
+	if err != nil {
+		t.Fatalf("GetHeader() error = %v", err)
+	}
+	if header.Level != 100 {
+		t.Errorf("GetHeader() level = %d, want 100", header.Level)
+	}
+	if _, err := replay.GetHeader(101); err == nil {
+		t.Errorf("GetHeader() expected error for missing fixture")
+	}
+}
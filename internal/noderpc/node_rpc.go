@@ -20,6 +20,8 @@ type NodeRPC struct {
 
 	timeout    time.Duration
 	retryCount int
+	cache      *Cache
+	transport  http.RoundTripper
 }
 
 // NewNodeRPC -
@@ -31,19 +33,59 @@ func NewNodeRPC(baseURL string) *NodeRPC {
 	}
 }
 
+// SetCache - responses for finalized levels will be saved to `cache`
+func (rpc *NodeRPC) SetCache(cache *Cache) {
+	rpc.cache = cache
+}
+
+// SetTransport - replaces HTTP transport, e.g. by recorded fixtures. Default is `http.DefaultTransport`.
+func (rpc *NodeRPC) SetTransport(transport http.RoundTripper) {
+	rpc.transport = transport
+}
+
 // SetTimeout - default is 10 sec
 func (rpc *NodeRPC) SetTimeout(timeout time.Duration) {
 	rpc.timeout = timeout
 }
 
 func (rpc *NodeRPC) get(uri string) (res gjson.Result, err error) {
+	b, _, err := rpc.getRaw(uri)
+	if err != nil {
+		return
+	}
+	return gjson.ParseBytes(b), nil
+}
+
+// getAt - requests data of the `level`. Responses for finalized levels are served from cache if it's set.
+func (rpc *NodeRPC) getAt(uri string, level int64) (res gjson.Result, err error) {
+	if rpc.cache == nil || level <= 0 {
+		return rpc.get(uri)
+	}
+	if data, ok := rpc.cache.Get(uri); ok {
+		return gjson.ParseBytes(data), nil
+	}
+
+	b, status, err := rpc.getRaw(uri)
+	if err != nil {
+		return
+	}
+	if status == http.StatusOK {
+		if err := rpc.cache.Set(uri, level, b); err != nil {
+			log.Printf("Cache %s: %s", uri, err.Error())
+		}
+	}
+	return gjson.ParseBytes(b), nil
+}
+
+func (rpc *NodeRPC) getRaw(uri string) ([]byte, int, error) {
 	url := helpers.URLJoin(rpc.baseURL, uri)
 	client := http.Client{
-		Timeout: rpc.timeout,
+		Timeout:   rpc.timeout,
+		Transport: rpc.transport,
 	}
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return res, fmt.Errorf("get.NewRequest: %v", err)
+		return nil, 0, fmt.Errorf("get.NewRequest: %v", err)
 	}
 
 	var resp *http.Response
@@ -57,24 +99,28 @@ func (rpc *NodeRPC) get(uri string) (res gjson.Result, err error) {
 	}
 
 	if count == rpc.retryCount {
-		return res, errors.New("Max HTTP request retry exceeded")
+		return nil, 0, fmt.Errorf("Max HTTP request retry exceeded: %v", err)
 	}
+	defer resp.Body.Close()
 
 	b, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return res, fmt.Errorf("get.ReadAll: %v", err)
+		return nil, 0, fmt.Errorf("get.ReadAll: %v", err)
 	}
-
-	res = gjson.ParseBytes(b)
-
-	resp.Body.Close()
-	return
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, resp.StatusCode, newInvalidResponseError(resp.StatusCode, b)
+	}
+	if resp.StatusCode < http.StatusMultipleChoices && !gjson.ValidBytes(b) {
+		return nil, resp.StatusCode, newInvalidResponseError(resp.StatusCode, b)
+	}
+	return b, resp.StatusCode, nil
 }
 
 func (rpc *NodeRPC) post(uri string, data map[string]interface{}) (res gjson.Result, err error) {
 	url := helpers.URLJoin(rpc.baseURL, uri)
 	client := http.Client{
-		Timeout: rpc.timeout,
+		Timeout:   rpc.timeout,
+		Transport: rpc.transport,
 	}
 
 	bData, err := json.Marshal(data)
@@ -101,17 +147,46 @@ func (rpc *NodeRPC) post(uri string, data map[string]interface{}) (res gjson.Res
 		return res, errors.New("Max HTTP request retry exceeded")
 	}
 
+	defer resp.Body.Close()
+
 	b, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return res, fmt.Errorf("post.ReadAll: %v", err)
 	}
 
-	res = gjson.ParseBytes(b)
+	// the node returns errors of scripts as JSON with 500 status
+	if resp.StatusCode > http.StatusInternalServerError || !gjson.ValidBytes(b) {
+		return res, newInvalidResponseError(resp.StatusCode, b)
+	}
 
-	resp.Body.Close()
+	res = gjson.ParseBytes(b)
 	return
 }
 
+// InvalidResponseError - response of the proxy or the failing node instead of the node answer
+type InvalidResponseError struct {
+	Status int
+	Body   string
+}
+
+// maxErrorBodySize - length of the response body kept in the error
+const maxErrorBodySize = 128
+
+func newInvalidResponseError(status int, body []byte) *InvalidResponseError {
+	if len(body) > maxErrorBodySize {
+		body = body[:maxErrorBodySize]
+	}
+	return &InvalidResponseError{
+		Status: status,
+		Body:   string(body),
+	}
+}
+
+// Error -
+func (e *InvalidResponseError) Error() string {
+	return fmt.Sprintf("Invalid node response: %d %s", e.Status, e.Body)
+}
+
 // GetHead - get head
 func (rpc *NodeRPC) GetHead() (header Header, err error) {
 	data, err := rpc.get("chains/main/blocks/head/header")
@@ -119,6 +194,7 @@ func (rpc *NodeRPC) GetHead() (header Header, err error) {
 		return
 	}
 	header.parseGJSON(data)
+	rpc.setHead(header.Level)
 	return
 }
 
@@ -128,7 +204,15 @@ func (rpc *NodeRPC) GetLevel() (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	return head.Get("level").Int(), nil
+	level := head.Get("level").Int()
+	rpc.setHead(level)
+	return level, nil
+}
+
+func (rpc *NodeRPC) setHead(level int64) {
+	if rpc.cache != nil {
+		rpc.cache.SetHead(level)
+	}
 }
 
 // GetHeader - get head
@@ -137,7 +221,7 @@ func (rpc *NodeRPC) GetHeader(level int64) (header Header, err error) {
 	if level > 0 {
 		block = fmt.Sprintf("%d", level)
 	}
-	data, err := rpc.get(fmt.Sprintf("chains/main/blocks/%s/header", block))
+	data, err := rpc.getAt(fmt.Sprintf("chains/main/blocks/%s/header", block), level)
 	if err != nil {
 		return
 	}
@@ -151,7 +235,7 @@ func (rpc *NodeRPC) GetLevelTime(level int) (time.Time, error) {
 	if level > 0 {
 		block = fmt.Sprintf("%d", level)
 	}
-	head, err := rpc.get(fmt.Sprintf("chains/main/blocks/%s/header", block))
+	head, err := rpc.getAt(fmt.Sprintf("chains/main/blocks/%s/header", block), int64(level))
 	if err != nil {
 		return time.Now(), err
 	}
@@ -165,7 +249,7 @@ func (rpc *NodeRPC) GetScriptJSON(address string, level int64) (gjson.Result, er
 		block = fmt.Sprintf("%d", level)
 	}
 
-	contract, err := rpc.get(fmt.Sprintf("chains/main/blocks/%s/context/contracts/%s", block, address))
+	contract, err := rpc.getAt(fmt.Sprintf("chains/main/blocks/%s/context/contracts/%s", block, address), level)
 	if err != nil {
 		return gjson.Result{}, err
 	}
@@ -180,7 +264,7 @@ func (rpc *NodeRPC) GetScriptStorageJSON(address string, level int64) (gjson.Res
 		block = fmt.Sprintf("%d", level)
 	}
 
-	return rpc.get(fmt.Sprintf("chains/main/blocks/%s/context/contracts/%s/storage", block, address))
+	return rpc.getAt(fmt.Sprintf("chains/main/blocks/%s/context/contracts/%s/storage", block, address), level)
 }
 
 // GetContractBalance -
@@ -189,7 +273,7 @@ func (rpc *NodeRPC) GetContractBalance(address string, level int64) (int64, erro
 	if level > 0 {
 		block = fmt.Sprintf("%d", level)
 	}
-	contract, err := rpc.get(fmt.Sprintf("chains/main/blocks/%s/context/contracts/%s", block, address))
+	contract, err := rpc.getAt(fmt.Sprintf("chains/main/blocks/%s/context/contracts/%s", block, address), level)
 	if err != nil {
 		return 0, err
 	}
@@ -204,12 +288,17 @@ func (rpc *NodeRPC) GetContractJSON(address string, level int64) (gjson.Result,
 		block = fmt.Sprintf("%d", level)
 	}
 
-	return rpc.get(fmt.Sprintf("chains/main/blocks/%s/context/contracts/%s", block, address))
+	return rpc.getAt(fmt.Sprintf("chains/main/blocks/%s/context/contracts/%s", block, address), level)
 }
 
 // GetOperations -
 func (rpc *NodeRPC) GetOperations(block int64) (res gjson.Result, err error) {
-	return rpc.get(fmt.Sprintf("chains/main/blocks/%d/operations/3", block))
+	return rpc.getAt(fmt.Sprintf("chains/main/blocks/%d/operations/3", block), block)
+}
+
+// GetBlockMetadata -
+func (rpc *NodeRPC) GetBlockMetadata(level int64) (res gjson.Result, err error) {
+	return rpc.getAt(fmt.Sprintf("chains/main/blocks/%d/metadata", level), level)
 }
 
 // GetContractsByBlock -
@@ -217,7 +306,7 @@ func (rpc *NodeRPC) GetContractsByBlock(block int64) ([]string, error) {
 	if block != 1 {
 		return nil, fmt.Errorf("For less loading node RPC `block` value is only 1")
 	}
-	data, err := rpc.get(fmt.Sprintf("chains/main/blocks/%d/context/contracts", block))
+	data, err := rpc.getAt(fmt.Sprintf("chains/main/blocks/%d/context/contracts", block), block)
 	if err != nil {
 		return nil, err
 	}
@@ -235,6 +324,36 @@ func (rpc *NodeRPC) GetNetworkConstants() (res gjson.Result, err error) {
 
 // RunCode -
 func (rpc *NodeRPC) RunCode(script, storage, input gjson.Result, chainID, source, payer, entrypoint string, amount, gas int64) (res gjson.Result, err error) {
+	data := runCodeData(script, storage, input, chainID, source, payer, entrypoint, amount, gas)
+	return rpc.post("chains/main/blocks/head/helpers/scripts/run_code", data)
+}
+
+// TraceCode - same as `RunCode` but the response contains the execution trace with remaining gas after every instruction
+func (rpc *NodeRPC) TraceCode(script, storage, input gjson.Result, chainID, source, payer, entrypoint string, amount, gas int64) (res gjson.Result, err error) {
+	data := runCodeData(script, storage, input, chainID, source, payer, entrypoint, amount, gas)
+	return rpc.post("chains/main/blocks/head/helpers/scripts/trace_code", data)
+}
+
+// TypeCheckCode - type checks the script code. The response contains the type map and the remaining gas
+func (rpc *NodeRPC) TypeCheckCode(code gjson.Result, gas int64) (res gjson.Result, err error) {
+	data := map[string]interface{}{
+		"program": code.Value(),
+		"gas":     fmt.Sprintf("%d", gas),
+	}
+	return rpc.post("chains/main/blocks/head/helpers/scripts/typecheck_code", data)
+}
+
+// TypeCheckData - type checks the data against the type. The response contains the remaining gas
+func (rpc *NodeRPC) TypeCheckData(data, typ gjson.Result, gas int64) (res gjson.Result, err error) {
+	body := map[string]interface{}{
+		"data": data.Value(),
+		"type": typ.Value(),
+		"gas":  fmt.Sprintf("%d", gas),
+	}
+	return rpc.post("chains/main/blocks/head/helpers/scripts/typecheck_data", body)
+}
+
+func runCodeData(script, storage, input gjson.Result, chainID, source, payer, entrypoint string, amount, gas int64) map[string]interface{} {
 	data := map[string]interface{}{
 		"script":   script.Value(),
 		"storage":  storage.Value(),
@@ -255,6 +374,5 @@ func (rpc *NodeRPC) RunCode(script, storage, input gjson.Result, chainID, source
 	if entrypoint != "" {
 		data["entrypoint"] = entrypoint
 	}
-
-	return rpc.post("chains/main/blocks/head/helpers/scripts/run_code", data)
+	return data
 }
@@ -1,212 +1,490 @@
 package noderpc
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
-	"reflect"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/baking-bad/bcdhub/internal/logger"
 	"github.com/tidwall/gjson"
 )
 
-// Pool - node pool
-type Pool []poolItem
+const (
+	// maxLag - node is excluded if its head is behind the highest head in the pool by more than `maxLag` levels
+	maxLag = 2
+	// headTTL - how long the known head level of the node is valid
+	headTTL = 30 * time.Second
+	// minWeight - chance of the worst node to be picked, so it can prove that it's recovered
+	minWeight = 0.01
+	// ewmaAlpha - smoothing factor of latency and error rate
+	ewmaAlpha = 0.2
+)
+
+var blockDuration = time.Minute
+
+func init() {
+	rand.Seed(time.Now().UnixNano())
+}
+
+// Pool - node pool. Nodes are picked randomly with weights based on their latency and error rate.
+// Nodes which failed recently or whose head lags behind the other nodes are not used while there are better ones.
+type Pool []*poolItem
 
 type poolItem struct {
-	node      *NodeRPC
-	blockTime time.Time
+	url  string
+	node *NodeRPC
+
+	mux          sync.RWMutex
+	latency      time.Duration
+	errorRate    float64
+	failures     int
+	blockTime    time.Time
+	level        int64
+	levelTime    time.Time
+	levelPending bool
 }
 
-var blockDuration = time.Minute
+// NodeHealth - state of the node in the pool
+type NodeHealth struct {
+	URL       string  `json:"url"`
+	Level     int64   `json:"level"`
+	Lag       int64   `json:"lag"`
+	Latency   int64   `json:"latency_ms"`
+	ErrorRate float64 `json:"error_rate"`
+	Available bool    `json:"available"`
+}
+
+func newPoolItem(url string, timeout time.Duration) *poolItem {
+	item := &poolItem{
+		url:  url,
+		node: NewNodeRPC(url),
+	}
+	item.node.SetTimeout(timeout)
+	return item
+}
+
+// PoolOption -
+type PoolOption func(Pool)
+
+// WithCache - nodes of the pool share disk cache of responses for finalized levels
+func WithCache(cache *Cache) PoolOption {
+	return func(p Pool) {
+		for i := range p {
+			p[i].node.SetCache(cache)
+		}
+	}
+}
 
 // NewPool - creates `Pool` struct by `urls`
-func NewPool(urls []string, timeout time.Duration) Pool {
+func NewPool(urls []string, timeout time.Duration, opts ...PoolOption) Pool {
 	data := make(Pool, len(urls))
 	for i := range urls {
-		data[i] = poolItem{
-			node:      NewNodeRPC(urls[i]),
-			blockTime: time.Now(),
-		}
-		data[i].node.SetTimeout(timeout)
+		data[i] = newPoolItem(urls[i], timeout)
+	}
+	for _, opt := range opts {
+		opt(data)
 	}
 	return data
 }
 
-// NewWaitNode -
-func NewWaitNode(url string, timeout time.Duration) Pool {
-	item := poolItem{node: NewNodeRPC(url)}
-
-	item.node.SetTimeout(timeout)
-
+// NewWaitPool - creates `Pool` struct by `urls` and waits until any of nodes is up
+func NewWaitPool(urls []string, timeout time.Duration, opts ...PoolOption) Pool {
+	pool := NewPool(urls, timeout, opts...)
 	for {
-		if _, err := item.node.GetLevel(); err == nil {
-			break
+		if _, err := pool.GetLevel(); err == nil {
+			return pool
 		}
 
 		logger.Warning("Waiting node up 30 second...")
 		time.Sleep(time.Second * 30)
 	}
+}
 
-	item.blockTime = time.Now()
+func (item *poolItem) success(latency time.Duration) {
+	item.mux.Lock()
+	defer item.mux.Unlock()
 
-	return Pool{item}
+	if item.latency == 0 {
+		item.latency = latency
+	} else {
+		item.latency = time.Duration(ewmaAlpha*float64(latency) + (1-ewmaAlpha)*float64(item.latency))
+	}
+	item.errorRate *= 1 - ewmaAlpha
+	item.failures = 0
 }
 
-func (p Pool) getNode() (poolItem, error) {
-	rand.Seed(time.Now().UnixNano())
-	nodes := make([]poolItem, 0)
-	for i := range p {
-		if time.Now().After(p[i].blockTime) {
-			nodes = append(nodes, p[i])
+// fail - node is blocked for 1s, 2s, 4s... after consecutive failures but not longer than `blockDuration`
+func (item *poolItem) fail() {
+	item.mux.Lock()
+	defer item.mux.Unlock()
+
+	item.errorRate = ewmaAlpha + (1-ewmaAlpha)*item.errorRate
+	item.failures++
+
+	delay := time.Second
+	for i := 1; i < item.failures && delay < blockDuration; i++ {
+		delay *= 2
+	}
+	if delay > blockDuration {
+		delay = blockDuration
+	}
+	item.blockTime = time.Now().Add(delay)
+}
+
+func (item *poolItem) setLevel(level int64) {
+	item.mux.Lock()
+	defer item.mux.Unlock()
+
+	item.level = level
+	item.levelTime = time.Now()
+	item.levelPending = false
+}
+
+// refreshLevel - asynchronously updates head level of the node if it's outdated
+func (item *poolItem) refreshLevel() {
+	item.mux.Lock()
+	if item.levelPending || time.Since(item.levelTime) < headTTL {
+		item.mux.Unlock()
+		return
+	}
+	item.levelPending = true
+	item.mux.Unlock()
+
+	go func() {
+		start := time.Now()
+		level, err := item.node.GetLevel()
+		if err != nil {
+			item.fail()
+			item.mux.Lock()
+			item.levelPending = false
+			item.mux.Unlock()
+			return
 		}
+		item.success(time.Since(start))
+		item.setLevel(level)
+	}()
+}
+
+func (item *poolItem) weight() float64 {
+	item.mux.RLock()
+	defer item.mux.RUnlock()
+
+	latency := item.latency.Seconds()
+	if latency < 0.001 {
+		latency = 0.001
+	}
+	weight := (1 - item.errorRate) * (1 - item.errorRate) / latency
+	if weight < minWeight {
+		weight = minWeight
 	}
+	return weight
+}
 
-	if len(nodes) == 0 {
-		return poolItem{}, fmt.Errorf("No availiable nodes")
+func (item *poolItem) health(maxLevel int64, now time.Time) NodeHealth {
+	item.mux.RLock()
+	defer item.mux.RUnlock()
+
+	lag := maxLevel - item.level
+	return NodeHealth{
+		URL:       item.url,
+		Level:     item.level,
+		Lag:       lag,
+		Latency:   item.latency.Milliseconds(),
+		ErrorRate: item.errorRate,
+		Available: now.After(item.blockTime) && (item.level == 0 || lag <= maxLag),
+	}
+}
+
+func (p Pool) maxLevel() int64 {
+	var level int64
+	for i := range p {
+		p[i].mux.RLock()
+		if p[i].level > level {
+			level = p[i].level
+		}
+		p[i].mux.RUnlock()
 	}
+	return level
+}
 
-	return nodes[rand.Intn(len(nodes))], nil
+// Health - returns state of all nodes in the pool
+func (p Pool) Health() []NodeHealth {
+	maxLevel := p.maxLevel()
+	now := time.Now()
+	result := make([]NodeHealth, len(p))
+	for i := range p {
+		result[i] = p[i].health(maxLevel, now)
+	}
+	return result
 }
 
-func (p Pool) call(method string, args ...interface{}) (reflect.Value, error) {
-	node, err := p.getNode()
-	if err != nil {
-		return reflect.Value{}, err
+// getNode - picks one of available nodes which are not in `exclude`. If all nodes are unavailable, the node which is unblocked first is returned.
+func (p Pool) getNode(exclude map[*poolItem]struct{}) (*poolItem, error) {
+	if len(p) > 1 {
+		for i := range p {
+			p[i].refreshLevel()
+		}
 	}
-	nodeVal := reflect.ValueOf(&node.node)
-	if nodeVal.Kind() == reflect.Ptr {
-		nodeVal = nodeVal.Elem()
+
+	health := p.Health()
+	nodes := make([]*poolItem, 0, len(p))
+	weights := make([]float64, 0, len(p))
+	var total float64
+	for i := range p {
+		if _, ok := exclude[p[i]]; ok || !health[i].Available {
+			continue
+		}
+		weight := p[i].weight()
+		nodes = append(nodes, p[i])
+		weights = append(weights, weight)
+		total += weight
+	}
+
+	if len(nodes) == 0 {
+		return p.fallbackNode(exclude)
 	}
 
-	mthd := nodeVal.MethodByName(method)
-	numIn := mthd.Type().NumIn()
-	if numIn != len(args) {
-		return reflect.Value{}, fmt.Errorf("Invalid args count: wait %d got %d", numIn, len(args))
+	r := rand.Float64() * total
+	for i := range nodes {
+		if r < weights[i] {
+			return nodes[i], nil
+		}
+		r -= weights[i]
 	}
+	return nodes[len(nodes)-1], nil
+}
 
-	in := make([]reflect.Value, numIn)
-	for i := range args {
-		in[i] = reflect.ValueOf(args[i])
+func (p Pool) fallbackNode(exclude map[*poolItem]struct{}) (*poolItem, error) {
+	nodes := make([]*poolItem, 0, len(p))
+	for i := range p {
+		if _, ok := exclude[p[i]]; !ok {
+			nodes = append(nodes, p[i])
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("No availiable nodes")
 	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		nodes[i].mux.RLock()
+		defer nodes[i].mux.RUnlock()
+		nodes[j].mux.RLock()
+		defer nodes[j].mux.RUnlock()
+		return nodes[i].blockTime.Before(nodes[j].blockTime)
+	})
+	return nodes[0], nil
+}
 
-	response := mthd.Call(in)
-	if len(response) != 2 {
-		node.blockTime = time.Now().Add(blockDuration)
-		return reflect.Value{}, fmt.Errorf("Invalid response length: %d", len(response))
+// call - executes `fn` on the picked node. If the call is `idempotent`, errors including invalid responses (5xx, not JSON)
+// are failures of the node and the call is retried on other nodes of the pool. Otherwise the request may be the cause of the error,
+// so only transport and gateway errors are failures of the node.
+func (p Pool) call(idempotent bool, fn func(node *NodeRPC) error) error {
+	tried := make(map[*poolItem]struct{})
+	errs := make([]string, 0)
+	for {
+		item, err := p.getNode(tried)
+		if err != nil {
+			if len(errs) > 0 {
+				return fmt.Errorf("%s", strings.Join(errs, "; "))
+			}
+			return err
+		}
+		tried[item] = struct{}{}
+
+		start := time.Now()
+		if err := fn(item.node); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", item.url, err))
+			if idempotent {
+				item.fail()
+				continue
+			}
+			if isNodeFailure(err) {
+				item.fail()
+			} else {
+				item.success(time.Since(start))
+			}
+			return fmt.Errorf("%s", strings.Join(errs, "; "))
+		}
+		item.success(time.Since(start))
+		return nil
 	}
+}
 
-	if !response[1].IsNil() {
-		node.blockTime = time.Now().Add(blockDuration)
-		return p.call(method, args...)
+// isNodeFailure - any response except gateway errors of the proxy in front of the node is the answer of the node to the request
+func isNodeFailure(err error) bool {
+	var invalid *InvalidResponseError
+	if !errors.As(err, &invalid) {
+		return true
+	}
+	switch invalid.Status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
 	}
-	return response[0], nil
 }
 
 // GetHead -
-func (p Pool) GetHead() (Header, error) {
-	data, err := p.call("GetHead")
-	if err != nil {
-		return Header{}, err
+func (p Pool) GetHead() (header Header, err error) {
+	err = p.call(true, func(node *NodeRPC) (err error) {
+		if header, err = node.GetHead(); err == nil {
+			p.setLevel(node, header.Level)
+		}
+		return
+	})
+	return
+}
+
+// setLevel - remembers head level received from the `node`
+func (p Pool) setLevel(node *NodeRPC, level int64) {
+	for i := range p {
+		if p[i].node == node {
+			p[i].setLevel(level)
+			return
+		}
 	}
-	return data.Interface().(Header), nil
 }
 
 // GetHeader -
-func (p Pool) GetHeader(block int64) (Header, error) {
-	data, err := p.call("GetHeader", block)
-	if err != nil {
-		return Header{}, err
-	}
-	return data.Interface().(Header), nil
+func (p Pool) GetHeader(block int64) (header Header, err error) {
+	err = p.call(true, func(node *NodeRPC) (err error) {
+		header, err = node.GetHeader(block)
+		return
+	})
+	return
 }
 
 // GetLevel -
-func (p Pool) GetLevel() (int64, error) {
-	data, err := p.call("GetLevel")
-	if err != nil {
-		return 0, err
-	}
-	return data.Int(), nil
+func (p Pool) GetLevel() (level int64, err error) {
+	err = p.call(true, func(node *NodeRPC) (err error) {
+		if level, err = node.GetLevel(); err == nil {
+			p.setLevel(node, level)
+		}
+		return
+	})
+	return
 }
 
 // GetLevelTime - get level time
-func (p Pool) GetLevelTime(level int) (time.Time, error) {
-	data, err := p.call("GetLevelTime", level)
+func (p Pool) GetLevelTime(level int) (ts time.Time, err error) {
+	err = p.call(true, func(node *NodeRPC) (err error) {
+		ts, err = node.GetLevelTime(level)
+		return
+	})
 	if err != nil {
 		return time.Now(), err
 	}
-	return data.Interface().(time.Time), nil
+	return
 }
 
 // GetScriptJSON -
-func (p Pool) GetScriptJSON(address string, level int64) (gjson.Result, error) {
-	data, err := p.call("GetScriptJSON", address, level)
-	if err != nil {
-		return gjson.Result{}, err
-	}
-	return data.Interface().(gjson.Result), nil
+func (p Pool) GetScriptJSON(address string, level int64) (res gjson.Result, err error) {
+	err = p.call(true, func(node *NodeRPC) (err error) {
+		res, err = node.GetScriptJSON(address, level)
+		return
+	})
+	return
 }
 
 // GetScriptStorageJSON -
-func (p Pool) GetScriptStorageJSON(address string, level int64) (gjson.Result, error) {
-	data, err := p.call("GetScriptStorageJSON", address, level)
-	if err != nil {
-		return gjson.Result{}, err
-	}
-	return data.Interface().(gjson.Result), nil
+func (p Pool) GetScriptStorageJSON(address string, level int64) (res gjson.Result, err error) {
+	err = p.call(true, func(node *NodeRPC) (err error) {
+		res, err = node.GetScriptStorageJSON(address, level)
+		return
+	})
+	return
 }
 
 // GetContractBalance -
-func (p Pool) GetContractBalance(address string, level int64) (int64, error) {
-	data, err := p.call("GetContractBalance", address, level)
-	if err != nil {
-		return 0, err
-	}
-	return data.Int(), nil
+func (p Pool) GetContractBalance(address string, level int64) (balance int64, err error) {
+	err = p.call(true, func(node *NodeRPC) (err error) {
+		balance, err = node.GetContractBalance(address, level)
+		return
+	})
+	return
 }
 
 // GetContractJSON -
-func (p Pool) GetContractJSON(address string, level int64) (gjson.Result, error) {
-	data, err := p.call("GetContractJSON", address, level)
-	if err != nil {
-		return gjson.Result{}, err
-	}
-	return data.Interface().(gjson.Result), nil
+func (p Pool) GetContractJSON(address string, level int64) (res gjson.Result, err error) {
+	err = p.call(true, func(node *NodeRPC) (err error) {
+		res, err = node.GetContractJSON(address, level)
+		return
+	})
+	return
 }
 
 // GetOperations -
 func (p Pool) GetOperations(block int64) (res gjson.Result, err error) {
-	data, err := p.call("GetOperations", block)
-	if err != nil {
+	err = p.call(true, func(node *NodeRPC) (err error) {
+		res, err = node.GetOperations(block)
 		return
-	}
-	return data.Interface().(gjson.Result), nil
+	})
+	return
+}
+
+// GetBlockMetadata -
+func (p Pool) GetBlockMetadata(level int64) (res gjson.Result, err error) {
+	err = p.call(true, func(node *NodeRPC) (err error) {
+		res, err = node.GetBlockMetadata(level)
+		return
+	})
+	return
 }
 
 // GetContractsByBlock -
-func (p Pool) GetContractsByBlock(block int64) ([]string, error) {
-	data, err := p.call("GetContractsByBlock", block)
-	if err != nil {
-		return nil, err
-	}
-	return data.Interface().([]string), nil
+func (p Pool) GetContractsByBlock(block int64) (addresses []string, err error) {
+	err = p.call(true, func(node *NodeRPC) (err error) {
+		addresses, err = node.GetContractsByBlock(block)
+		return
+	})
+	return
 }
 
 // GetNetworkConstants -
 func (p Pool) GetNetworkConstants() (res gjson.Result, err error) {
-	data, err := p.call("GetNetworkConstants")
-	if err != nil {
+	err = p.call(true, func(node *NodeRPC) (err error) {
+		res, err = node.GetNetworkConstants()
 		return
-	}
-	return data.Interface().(gjson.Result), nil
+	})
+	return
 }
 
-// RunCode -
+// RunCode - POST requests are not retried on another node
 func (p Pool) RunCode(script, storage, input gjson.Result, chainID, source, payer, entrypoint string, amount, gas int64) (res gjson.Result, err error) {
-	data, err := p.call("RunCode", script, storage, input, chainID, source, payer, entrypoint, amount, gas)
-	if err != nil {
+	err = p.call(false, func(node *NodeRPC) (err error) {
+		res, err = node.RunCode(script, storage, input, chainID, source, payer, entrypoint, amount, gas)
 		return
-	}
-	return data.Interface().(gjson.Result), nil
+	})
+	return
+}
+
+// TraceCode - POST requests are not retried on another node
+func (p Pool) TraceCode(script, storage, input gjson.Result, chainID, source, payer, entrypoint string, amount, gas int64) (res gjson.Result, err error) {
+	err = p.call(false, func(node *NodeRPC) (err error) {
+		res, err = node.TraceCode(script, storage, input, chainID, source, payer, entrypoint, amount, gas)
+		return
+	})
+	return
+}
+
+// TypeCheckCode - POST requests are not retried on another node
+func (p Pool) TypeCheckCode(code gjson.Result, gas int64) (res gjson.Result, err error) {
+	err = p.call(false, func(node *NodeRPC) (err error) {
+		res, err = node.TypeCheckCode(code, gas)
+		return
+	})
+	return
+}
+
+// TypeCheckData - POST requests are not retried on another node
+func (p Pool) TypeCheckData(data, typ gjson.Result, gas int64) (res gjson.Result, err error) {
+	err = p.call(false, func(node *NodeRPC) (err error) {
+		res, err = node.TypeCheckData(data, typ, gas)
+		return
+	})
+	return
 }
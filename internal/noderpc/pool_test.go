@@ -0,0 +1,190 @@
+package noderpc
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+type testNode struct {
+	*httptest.Server
+	level int64
+	calls int64
+}
+
+func newTestNode(level int64) *testNode {
+	node := &testNode{level: level}
+	node.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&node.calls, 1)
+		fmt.Fprintf(w, `{"level": %d}`, node.level)
+	}))
+	return node
+}
+
+func newDeadNode() *testNode {
+	node := newTestNode(0)
+	node.Close()
+	return node
+}
+
+func TestPool_retry(t *testing.T) {
+	alive := newTestNode(100)
+	defer alive.Close()
+	dead := newDeadNode()
+
+	pool := NewPool([]string{dead.URL, alive.URL}, time.Second)
+	for i := 0; i < 10; i++ {
+		header, err := pool.GetHeader(100)
+		if err != nil {
+			t.Fatalf("GetHeader() error = %v", err)
+		}
+		if header.Level != 100 {
+			t.Errorf("GetHeader() level = %d, want 100", header.Level)
+		}
+	}
+
+	health := pool.Health()
+	if health[0].Available || health[0].ErrorRate == 0 {
+		t.Errorf("dead node health = %+v", health[0])
+	}
+	if !health[1].Available || health[1].ErrorRate != 0 {
+		t.Errorf("alive node health = %+v", health[1])
+	}
+}
+
+func TestPool_invalidResponse(t *testing.T) {
+	alive := newTestNode(100)
+	defer alive.Close()
+
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"bad gateway", http.StatusBadGateway, `<html>502 Bad Gateway</html>`},
+		{"not JSON", http.StatusOK, `<html>maintenance</html>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer proxy.Close()
+
+			pool := NewPool([]string{proxy.URL, alive.URL}, time.Second)
+			// the alive node is blocked, so the proxy is picked first
+			pool[1].blockTime = time.Now().Add(time.Minute)
+			pool[1].levelTime = time.Now()
+
+			header, err := pool.GetHeader(100)
+			if err != nil {
+				t.Fatalf("GetHeader() error = %v", err)
+			}
+			if header.Level != 100 {
+				t.Errorf("GetHeader() level = %d, want 100", header.Level)
+			}
+			if health := pool.Health(); health[0].ErrorRate == 0 {
+				t.Errorf("invalid response is not counted as failure: %+v", health[0])
+			}
+		})
+	}
+}
+
+func TestPool_scriptError(t *testing.T) {
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, `[{"kind":"temporary","id":"proto.006-PsCARTHA.michelson_v1.script_rejected"}]`)
+	}))
+	defer node.Close()
+
+	pool := NewPool([]string{node.URL}, time.Second)
+	res, err := pool.RunCode(gjson.Result{}, gjson.Result{}, gjson.Result{}, "", "", "", "", 0, 0)
+	if err != nil {
+		t.Fatalf("RunCode() error = %v", err)
+	}
+	if !res.IsArray() {
+		t.Errorf("RunCode() = %s, want errors of the script", res.Raw)
+	}
+	if health := pool.Health(); health[0].ErrorRate != 0 {
+		t.Errorf("script error is counted as node failure: %+v", health[0])
+	}
+}
+
+func TestPool_requestError(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		failure bool
+	}{
+		{"bad request", http.StatusBadRequest, `Failed to parse the request body`, false},
+		{"internal error", http.StatusInternalServerError, `Internal error`, false},
+		{"bad gateway", http.StatusBadGateway, `<html>502 Bad Gateway</html>`, true},
+		{"unavailable", http.StatusServiceUnavailable, `<html>503 Service Unavailable</html>`, true},
+		{"gateway timeout", http.StatusGatewayTimeout, `<html>504 Gateway Time-out</html>`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer node.Close()
+
+			pool := NewPool([]string{node.URL}, time.Second)
+			if _, err := pool.RunCode(gjson.Result{}, gjson.Result{}, gjson.Result{}, "", "", "", "", 0, 0); err == nil {
+				t.Fatalf("RunCode() expected error")
+			}
+			if health := pool.Health(); (health[0].ErrorRate != 0) != tt.failure {
+				t.Errorf("node health = %+v, want failure %v", health[0], tt.failure)
+			}
+		})
+	}
+}
+
+func TestPool_notIdempotent(t *testing.T) {
+	dead := newDeadNode()
+	alive := newTestNode(100)
+	defer alive.Close()
+
+	pool := NewPool([]string{dead.URL, alive.URL}, time.Second)
+	// the alive node is blocked, so the dead one is picked first
+	pool[1].blockTime = time.Now().Add(time.Minute)
+	pool[1].levelTime = time.Now()
+
+	if _, err := pool.RunCode(gjson.Result{}, gjson.Result{}, gjson.Result{}, "", "", "", "", 0, 0); err == nil {
+		t.Errorf("RunCode() expected error from the dead node")
+	}
+	if calls := atomic.LoadInt64(&alive.calls); calls != 0 {
+		t.Errorf("RunCode() was retried on another node: %d calls", calls)
+	}
+}
+
+func TestPool_lag(t *testing.T) {
+	synced := newTestNode(100)
+	defer synced.Close()
+	lagging := newTestNode(90)
+	defer lagging.Close()
+
+	pool := NewPool([]string{synced.URL, lagging.URL}, time.Second)
+	pool[0].setLevel(100)
+	pool[1].setLevel(90)
+
+	for i := 0; i < 20; i++ {
+		if _, err := pool.GetScriptJSON("KT1", 0); err != nil {
+			t.Fatalf("GetScriptJSON() error = %v", err)
+		}
+	}
+	if calls := atomic.LoadInt64(&lagging.calls); calls != 0 {
+		t.Errorf("lagging node was called %d times", calls)
+	}
+	if health := pool.Health(); health[1].Available || health[1].Lag != 10 {
+		t.Errorf("lagging node health = %+v", health[1])
+	}
+}
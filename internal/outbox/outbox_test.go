@@ -0,0 +1,132 @@
+package outbox
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/elastic/memory"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/baking-bad/bcdhub/internal/mq"
+)
+
+func TestRelay_backoff(t *testing.T) {
+	r := NewRelay("mainnet", nil, nil, WithMaxBackoff(5*time.Second))
+	tests := []struct {
+		attempts int64
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second},
+		{100, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := r.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// consumerPublisher - confirms notifications as the `metrics` consumer does right after they are sent
+type consumerPublisher struct {
+	es   elastic.IElastic
+	fail map[string]bool
+}
+
+func (p *consumerPublisher) Send(channel, queue string, v interface{}) error {
+	entityID := v.(string)
+	if p.fail[entityID] {
+		return fmt.Errorf("connection refused")
+	}
+	notification := models.Notification{Status: models.NotificationProcessed}
+	return p.es.UpdateFields(elastic.DocNotifications, models.NotificationID(queue, entityID), notification, "Status")
+}
+
+func (p *consumerPublisher) Close() {}
+
+func TestRelay_Deliver(t *testing.T) {
+	es := memory.New()
+	items := []elastic.Model{
+		models.NewNotification("mainnet", 10, mq.QueueOperations, "op1"),
+		models.NewNotification("mainnet", 10, mq.QueueOperations, "op2"),
+	}
+	if err := es.BulkInsert(items); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRelay("mainnet", es, &consumerPublisher{es: es, fail: map[string]bool{"op2": true}})
+	delivered, err := r.Deliver()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivered != 1 {
+		t.Errorf("Deliver() = %d, want 1", delivered)
+	}
+
+	notifications, err := es.GetNotifications("mainnet", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]models.Notification)
+	for i := range notifications {
+		got[notifications[i].EntityID] = notifications[i]
+	}
+	if n := got["op1"]; n.Status != models.NotificationProcessed {
+		t.Errorf("op1 status = %s, want %s", n.Status, models.NotificationProcessed)
+	}
+	if n := got["op2"]; n.Status != models.NotificationPending || n.Attempts != 1 || n.Error == "" || !n.NextAttempt.After(n.Timestamp) {
+		t.Errorf("op2 = %+v, want pending with backoff", n)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	es := memory.New()
+	old := time.Now().UTC().Add(-time.Hour)
+
+	processed := models.NewNotification("mainnet", 10, mq.QueueOperations, "op1")
+	processed.Status = models.NotificationProcessed
+	processed.Timestamp = old
+
+	sent := models.NewNotification("mainnet", 10, mq.QueueOperations, "op2")
+	sent.Status = models.NotificationSent
+	sent.Timestamp = old
+
+	fresh := models.NewNotification("mainnet", 11, mq.QueueOperations, "op3")
+	fresh.Status = models.NotificationSent
+
+	items := []elastic.Model{
+		&models.Operation{ID: "op1", Network: "mainnet", Level: 10},
+		&models.Operation{ID: "op2", Network: "mainnet", Level: 10},
+		&models.Operation{ID: "op3", Network: "mainnet", Level: 11},
+		&models.Operation{ID: "op4", Network: "mainnet", Level: 11},
+		&models.Operation{ID: "op5", Network: "mainnet", Level: 20},
+		&models.Operation{ID: "op6", Network: "babylonnet", Level: 11},
+		processed, sent, fresh,
+	}
+	if err := es.BulkInsert(items); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Reconcile(es, "mainnet", 0, 15, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Missing != 1 || report.Unprocessed != 1 {
+		t.Errorf("Reconcile() = %+v, want 1 missing and 1 unprocessed", report)
+	}
+
+	pending, err := es.GetPendingNotifications("mainnet", time.Now().UTC(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]bool)
+	for i := range pending {
+		got[pending[i].EntityID] = true
+	}
+	if len(got) != 2 || !got["op2"] || !got["op4"] {
+		t.Errorf("pending notifications = %v, want op2 and op4", got)
+	}
+}
@@ -0,0 +1,96 @@
+package outbox
+
+import (
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/baking-bad/bcdhub/internal/mq"
+)
+
+// queues - documents which have to be processed by the `metrics` service
+var queues = map[string]string{
+	elastic.DocContracts:  mq.QueueContracts,
+	elastic.DocOperations: mq.QueueOperations,
+	elastic.DocMigrations: mq.QueueMigrations,
+}
+
+// Report - result of reconciliation
+type Report struct {
+	// Missing - documents without notification, e.g. indexed before the outbox was introduced
+	Missing int
+	// Unprocessed - notifications which are not confirmed by the `metrics` service
+	Unprocessed int
+}
+
+// Reconcile - finds documents of `network` with level in (`fromLevel`, `toLevel`] which never reached the `metrics` consumer
+// and makes their notifications pending again, so the relay delivers them once more.
+// Notifications younger than `grace` are skipped because the consumer may be processing them right now.
+func Reconcile(es elastic.IElastic, network string, fromLevel, toLevel int64, grace time.Duration) (report Report, err error) {
+	notifications, err := es.GetNotifications(network, fromLevel, toLevel)
+	if err != nil {
+		return
+	}
+	known := make(map[string]struct{}, len(notifications))
+	for i := range notifications {
+		known[notifications[i].ID] = struct{}{}
+	}
+
+	now := time.Now().UTC()
+	updates := make([]elastic.Model, 0)
+	for i := range notifications {
+		n := &notifications[i]
+		if n.Status == models.NotificationProcessed || now.Sub(n.Timestamp) < grace {
+			continue
+		}
+		n.Status = models.NotificationPending
+		n.Attempts = 0
+		n.NextAttempt = now
+		updates = append(updates, n)
+	}
+	report.Unprocessed = len(updates)
+
+	inserts := make([]elastic.Model, 0)
+	for index, queue := range queues {
+		levels, err := es.GetDocumentLevels(index, network, fromLevel, toLevel)
+		if err != nil {
+			return report, err
+		}
+		for id, level := range levels {
+			if _, ok := known[models.NotificationID(queue, id)]; ok {
+				continue
+			}
+			inserts = append(inserts, models.NewNotification(network, level, queue, id))
+		}
+	}
+
+	// notification may have another level than its document, e.g. migrations found on protocol change
+	if len(inserts) > 0 {
+		ids := make([]string, len(inserts))
+		for i := range inserts {
+			ids[i] = inserts[i].GetID()
+		}
+		var existing []models.Notification
+		if err = es.GetByIDs(ids, &existing); err != nil {
+			return
+		}
+		exists := make(map[string]struct{}, len(existing))
+		for i := range existing {
+			exists[existing[i].ID] = struct{}{}
+		}
+		missing := make([]elastic.Model, 0, len(inserts))
+		for i := range inserts {
+			if _, ok := exists[inserts[i].GetID()]; !ok {
+				missing = append(missing, inserts[i])
+			}
+		}
+		inserts = missing
+	}
+	report.Missing = len(inserts)
+
+	if err = es.BulkUpdate(updates); err != nil {
+		return
+	}
+	err = es.BulkInsert(inserts)
+	return
+}
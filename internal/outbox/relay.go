@@ -0,0 +1,141 @@
+package outbox
+
+import (
+	"sync"
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/logger"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/baking-bad/bcdhub/internal/mq"
+)
+
+const (
+	defaultBatchSize  = 1000
+	defaultMaxBackoff = 10 * time.Minute
+)
+
+// Relay - delivers pending notifications of the network to RabbitMQ. Failed deliveries are retried with exponential backoff.
+type Relay struct {
+	network    string
+	es         elastic.IElastic
+	mq         mq.Publisher
+	batchSize  int64
+	maxBackoff time.Duration
+
+	mux sync.Mutex
+}
+
+// RelayOption -
+type RelayOption func(*Relay)
+
+// WithBatchSize - count of notifications delivered by one `Deliver` call
+func WithBatchSize(size int64) RelayOption {
+	return func(r *Relay) {
+		if size > 0 {
+			r.batchSize = size
+		}
+	}
+}
+
+// WithMaxBackoff - maximum delay between delivery attempts
+func WithMaxBackoff(backoff time.Duration) RelayOption {
+	return func(r *Relay) {
+		if backoff > 0 {
+			r.maxBackoff = backoff
+		}
+	}
+}
+
+// NewRelay -
+func NewRelay(network string, es elastic.IElastic, messageQueue mq.Publisher, opts ...RelayOption) *Relay {
+	r := &Relay{
+		network:    network,
+		es:         es,
+		mq:         messageQueue,
+		batchSize:  defaultBatchSize,
+		maxBackoff: defaultMaxBackoff,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Deliver - sends pending notifications which are due. Returns count of delivered notifications.
+// Notifications are marked as sent before sending, so a stale update can't overwrite the `processed` status
+// set by the consumer. Notifications lost by a crash after the mark are sent again by reconciliation.
+func (r *Relay) Deliver() (int, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	now := time.Now().UTC()
+	notifications, err := r.es.GetPendingNotifications(r.network, now, r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+	if len(notifications) == 0 {
+		return 0, nil
+	}
+
+	updates := make([]elastic.Model, len(notifications))
+	for i := range notifications {
+		n := &notifications[i]
+		n.Attempts++
+		n.Status = models.NotificationSent
+		n.NextAttempt = now.Add(r.backoff(n.Attempts))
+		n.Error = ""
+		updates[i] = n
+	}
+	if err := r.es.BulkUpdate(updates); err != nil {
+		return 0, err
+	}
+
+	failed := make([]elastic.Model, 0)
+	for i := range notifications {
+		n := &notifications[i]
+		if err := r.mq.Send(mq.ChannelNew, n.Queue, n.EntityID); err != nil {
+			n.Status = models.NotificationPending
+			n.Error = err.Error()
+			failed = append(failed, n)
+		}
+	}
+
+	delivered := len(notifications) - len(failed)
+	if err := r.es.BulkUpdate(failed); err != nil {
+		return delivered, err
+	}
+	if len(failed) > 0 {
+		logger.Warning("[%s] Outbox: %d of %d notifications are not delivered, will retry", r.network, len(failed), len(notifications))
+	}
+	return delivered, nil
+}
+
+// Run - delivers notifications every `period` until `stop` is closed
+func (r *Relay) Run(period time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if _, err := r.Deliver(); err != nil {
+				logger.Errorf("[%s] Outbox: %s", r.network, err)
+			}
+		}
+	}
+}
+
+// backoff - 1s, 2s, 4s ... but not more than `maxBackoff`
+func (r *Relay) backoff(attempts int64) time.Duration {
+	delay := time.Second
+	for i := int64(1); i < attempts && delay < r.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.maxBackoff {
+		delay = r.maxBackoff
+	}
+	return delay
+}
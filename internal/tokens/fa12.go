@@ -0,0 +1,51 @@
+package tokens
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser/newmiguel"
+)
+
+// ParseFA12Transfer - returns transfer made by `transfer(from, to, amount)`, `mint(to, amount)` or `burn(from, amount)` call of the FA1.2 token.
+// Calls of other entrypoints return nil.
+func ParseFA12Transfer(entrypoint string, parameters *newmiguel.Node) ([]Transfer, error) {
+	if parameters == nil {
+		return nil, fmt.Errorf("[ParseFA12Transfer] Empty parameters")
+	}
+
+	args := make([]string, len(parameters.Children))
+	for i, child := range parameters.Children {
+		args[i] = fmt.Sprintf("%v", child.Value)
+	}
+
+	// FA1.2 contract has the only token
+	transfer := Transfer{TokenID: "0"}
+	switch {
+	case entrypoint == "transfer" && len(args) == 3:
+		transfer.From = args[0]
+		transfer.To = args[1]
+	case entrypoint == "mint" && len(args) == 2:
+		transfer.To = args[0]
+	case entrypoint == "burn" && len(args) == 2:
+		transfer.From = args[0]
+	default:
+		return nil, nil
+	}
+
+	amount, err := parseNat(args[len(args)-1])
+	if err != nil {
+		return nil, err
+	}
+	transfer.Amount = amount
+	return []Transfer{transfer}, nil
+}
+
+// parseNat - returns normalized decimal string of the Michelson `nat`. Token amounts may exceed int64, so they are kept as strings.
+func parseNat(value string) (string, error) {
+	n, ok := new(big.Int).SetString(value, 10)
+	if !ok || n.Sign() < 0 {
+		return "", fmt.Errorf("Invalid nat value: %s", value)
+	}
+	return n.String(), nil
+}
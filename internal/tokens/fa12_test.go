@@ -0,0 +1,67 @@
+package tokens
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser/meta"
+	"github.com/baking-bad/bcdhub/internal/contractparser/newmiguel"
+	"github.com/tidwall/gjson"
+)
+
+func TestParseFA12Transfer(t *testing.T) {
+	parameter := `{"prim":"or","args":[{"prim":"or","args":[{"prim":"pair","args":[{"prim":"address","annots":[":from"]},{"prim":"pair","args":[{"prim":"address","annots":[":to"]},{"prim":"nat","annots":[":value"]}]}],"annots":["%transfer"]},{"prim":"pair","args":[{"prim":"address","annots":[":to"]},{"prim":"nat","annots":[":value"]}],"annots":["%mint"]}]},{"prim":"or","args":[{"prim":"pair","args":[{"prim":"address","annots":[":from"]},{"prim":"nat","annots":[":value"]}],"annots":["%burn"]},{"prim":"pair","args":[{"prim":"address","annots":[":spender"]},{"prim":"nat","annots":[":value"]}],"annots":["%approve"]}]}]}`
+	metadata, err := meta.ParseMetadata(gjson.Parse(parameter))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		entrypoint string
+		parameters string
+		want       []Transfer
+	}{
+		{
+			name:       "transfer",
+			entrypoint: "transfer",
+			parameters: `{"entrypoint":"transfer","value":{"prim":"Pair","args":[{"string":"tz1a5fMLLY5WCarCzH7RKTJHX9mJFN8eaaWG"},{"prim":"Pair","args":[{"string":"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx"},{"int":"10"}]}]}}`,
+			want:       []Transfer{{TokenID: "0", From: "tz1a5fMLLY5WCarCzH7RKTJHX9mJFN8eaaWG", To: "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx", Amount: "10"}},
+		}, {
+			name:       "mint",
+			entrypoint: "mint",
+			parameters: `{"entrypoint":"mint","value":{"prim":"Pair","args":[{"string":"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx"},{"int":"7"}]}}`,
+			want:       []Transfer{{TokenID: "0", To: "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx", Amount: "7"}},
+		}, {
+			name:       "burn",
+			entrypoint: "burn",
+			parameters: `{"entrypoint":"burn","value":{"prim":"Pair","args":[{"string":"tz1a5fMLLY5WCarCzH7RKTJHX9mJFN8eaaWG"},{"int":"3"}]}}`,
+			want:       []Transfer{{TokenID: "0", From: "tz1a5fMLLY5WCarCzH7RKTJHX9mJFN8eaaWG", Amount: "3"}},
+		}, {
+			name:       "amount above int64",
+			entrypoint: "mint",
+			parameters: `{"entrypoint":"mint","value":{"prim":"Pair","args":[{"string":"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx"},{"int":"1000000000000000000000000"}]}}`,
+			want:       []Transfer{{TokenID: "0", To: "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx", Amount: "1000000000000000000000000"}},
+		}, {
+			name:       "approve",
+			entrypoint: "approve",
+			parameters: `{"entrypoint":"approve","value":{"prim":"Pair","args":[{"string":"tz1a5fMLLY5WCarCzH7RKTJHX9mJFN8eaaWG"},{"int":"3"}]}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parameters, err := newmiguel.ParameterToMiguel(gjson.Parse(tt.parameters), metadata)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ParseFA12Transfer(tt.entrypoint, parameters)
+			if err != nil {
+				t.Errorf("ParseFA12Transfer() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFA12Transfer() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
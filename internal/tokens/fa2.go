@@ -0,0 +1,53 @@
+package tokens
+
+import (
+	"fmt"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/contractparser/newmiguel"
+)
+
+// Transfer - transfer of `Amount` of the token `TokenID` from `From` to `To`. `TokenID` and `Amount` are decimal strings
+// because Michelson `nat` is unbounded.
+type Transfer struct {
+	From    string
+	To      string
+	TokenID string
+	Amount  string
+}
+
+// ParseFA2Transfer - returns transfers of the FA2 `transfer` call. Parameter is a list of batches, every batch has
+// the sender `from_` and the list `txs` of (`to_`, `token_id`, `amount`).
+func ParseFA2Transfer(parameters *newmiguel.Node) ([]Transfer, error) {
+	if parameters == nil || parameters.Prim != consts.LIST {
+		return nil, fmt.Errorf("[ParseFA2Transfer] Invalid parameter type")
+	}
+
+	transfers := make([]Transfer, 0)
+	for _, batch := range parameters.Children {
+		if len(batch.Children) != 2 {
+			return nil, fmt.Errorf("[ParseFA2Transfer] Invalid batch: %d args", len(batch.Children))
+		}
+		from := fmt.Sprintf("%v", batch.Children[0].Value)
+		for _, tx := range batch.Children[1].Children {
+			if len(tx.Children) != 3 {
+				return nil, fmt.Errorf("[ParseFA2Transfer] Invalid transaction: %d args", len(tx.Children))
+			}
+			tokenID, err := parseNat(fmt.Sprintf("%v", tx.Children[1].Value))
+			if err != nil {
+				return nil, err
+			}
+			amount, err := parseNat(fmt.Sprintf("%v", tx.Children[2].Value))
+			if err != nil {
+				return nil, err
+			}
+			transfers = append(transfers, Transfer{
+				From:    from,
+				To:      fmt.Sprintf("%v", tx.Children[0].Value),
+				TokenID: tokenID,
+				Amount:  amount,
+			})
+		}
+	}
+	return transfers, nil
+}
@@ -0,0 +1,64 @@
+package tokens
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/baking-bad/bcdhub/internal/contractparser/meta"
+	"github.com/baking-bad/bcdhub/internal/contractparser/newmiguel"
+	"github.com/tidwall/gjson"
+)
+
+func TestParseFA2Transfer(t *testing.T) {
+	file, err := ioutil.ReadFile("../contractparser/testdata/tags/fa2-tzip12.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata, err := meta.ParseMetadata(gjson.ParseBytes(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		parameters string
+		want       []Transfer
+		wantErr    bool
+	}{
+		{
+			name:       "two batches",
+			parameters: `{"entrypoint":"transfer","value":[{"prim":"Pair","args":[{"string":"tz1a5fMLLY5WCarCzH7RKTJHX9mJFN8eaaWG"},[{"prim":"Pair","args":[{"string":"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx"},{"prim":"Pair","args":[{"int":"1"},{"int":"10"}]}]},{"prim":"Pair","args":[{"string":"tz1VQnqCCqX4K5sP3FNkVSNKTdCAMJDd3E1n"},{"prim":"Pair","args":[{"int":"0"},{"int":"5"}]}]}]]},{"prim":"Pair","args":[{"bytes":"00009e6ac2e529a49aedbcdd0ac9542d5c0f4ce76f77"},[]]}]}`,
+			want: []Transfer{
+				{From: "tz1a5fMLLY5WCarCzH7RKTJHX9mJFN8eaaWG", To: "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx", TokenID: "1", Amount: "10"},
+				{From: "tz1a5fMLLY5WCarCzH7RKTJHX9mJFN8eaaWG", To: "tz1VQnqCCqX4K5sP3FNkVSNKTdCAMJDd3E1n", TokenID: "0", Amount: "5"},
+			},
+		}, {
+			name:       "token id above int64",
+			parameters: `{"entrypoint":"transfer","value":[{"prim":"Pair","args":[{"string":"tz1a5fMLLY5WCarCzH7RKTJHX9mJFN8eaaWG"},[{"prim":"Pair","args":[{"string":"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx"},{"prim":"Pair","args":[{"int":"18446744073709551616"},{"int":"100000000000000000000"}]}]}]]}]}`,
+			want: []Transfer{
+				{From: "tz1a5fMLLY5WCarCzH7RKTJHX9mJFN8eaaWG", To: "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx", TokenID: "18446744073709551616", Amount: "100000000000000000000"},
+			},
+		}, {
+			name:       "operators are not transfers",
+			parameters: `{"entrypoint":"update_operators","value":[{"prim":"Left","args":[{"prim":"Pair","args":[{"string":"tz1a5fMLLY5WCarCzH7RKTJHX9mJFN8eaaWG"},{"prim":"Pair","args":[{"string":"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx"},{"int":"1"}]}]}]}]}`,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parameters, err := newmiguel.ParameterToMiguel(gjson.Parse(tt.parameters), metadata)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ParseFA2Transfer(parameters)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseFA2Transfer() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFA2Transfer() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ import (
 
 var mappingNames = []string{
 	elastic.DocBigMapDiff, elastic.DocBlocks, elastic.DocContracts, elastic.DocMetadata, elastic.DocMigrations, elastic.DocOperations, elastic.DocProtocol,
+	elastic.DocDelegations, elastic.DocReveals, elastic.DocBalanceChanges, elastic.DocTokenBalanceChanges, elastic.DocTransfers, elastic.DocBigMaps, elastic.DocInterfaces,
 }
 
 func createRepository(es *elastic.Elastic, creds awsData) error {
@@ -35,6 +35,11 @@ func main() {
 		"set_operation_errors":      &migrations.SetOperationErrors{},
 		"set_contract_hash":         &migrations.SetContractHash{},
 		"recalc_contract_metrics":   &migrations.RecalcContractMetrics{},
+		"balance_changes":           &migrations.SetBalanceChanges{},
+		"token_balances":            &migrations.SetTokenBalances{},
+		"token_transfers":           &migrations.SetTokenTransfers{},
+		"token_metadata":            &migrations.SetTokenMetadata{},
+		"big_maps":                  &migrations.SetBigMaps{},
 	}
 
 	cfg, err := config.LoadDefaultConfig()
@@ -60,6 +65,7 @@ func main() {
 
 	ctx := config.NewContext(
 		config.WithElasticSearch(cfg.Elastic),
+		config.WithStorage(cfg.Storage),
 		config.WithDatabase(cfg.DB),
 		config.WithRPC(cfg.RPC),
 		config.WithConfigCopy(cfg),
@@ -0,0 +1,61 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/config"
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/logger"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/schollz/progressbar/v3"
+)
+
+// SetBalanceChanges - migration that builds balance changes of contracts from balance updates of indexed operations.
+// Corrections made by protocol migrations are saved by the indexer only.
+type SetBalanceChanges struct{}
+
+// Description -
+func (m *SetBalanceChanges) Description() string {
+	return "build balance changes of contracts from operations"
+}
+
+// Do - migrate function
+func (m *SetBalanceChanges) Do(ctx *config.Context) error {
+	logger.Info("Start SetBalanceChanges migration...")
+	start := time.Now()
+
+	if err := ctx.ES.CreateIndexes(); err != nil {
+		return err
+	}
+
+	for _, network := range ctx.Config.Migrations.Networks {
+		operations, err := ctx.ES.GetAllOperationsByStatus(network, consts.Applied)
+		if err != nil {
+			return err
+		}
+
+		logger.Info("Found %d applied operations in %s", len(operations), network)
+
+		bar := progressbar.NewOptions(len(operations), progressbar.OptionSetPredictTime(false), progressbar.OptionClearOnFinish(), progressbar.OptionShowCount())
+
+		changes := make([]elastic.Model, 0)
+		for i := range operations {
+			bar.Add(1)
+
+			for _, change := range models.NewBalanceChanges(operations[i]) {
+				changes = append(changes, change)
+			}
+
+			if len(changes) >= 1000 || i == len(operations)-1 {
+				if err := ctx.ES.BulkInsert(changes); err != nil {
+					return err
+				}
+				changes = make([]elastic.Model, 0)
+			}
+		}
+	}
+
+	logger.Info("Time spent: %v", time.Since(start))
+	return nil
+}
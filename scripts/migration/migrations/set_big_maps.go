@@ -0,0 +1,67 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/config"
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/logger"
+	"github.com/baking-bad/bcdhub/internal/models"
+	"github.com/schollz/progressbar/v3"
+)
+
+// SetBigMaps - migration that fills the big map registry by big maps which were created before the registry.
+// Every big map is taken from its first diff, so big maps which never had keys are not found. Types and lineage of the found big maps stay empty.
+type SetBigMaps struct{}
+
+// Description -
+func (m *SetBigMaps) Description() string {
+	return "fill the big map registry by indexed big map diffs"
+}
+
+// Do - migrate function
+func (m *SetBigMaps) Do(ctx *config.Context) error {
+	logger.Info("Start SetBigMaps migration...")
+	start := time.Now()
+
+	if err := ctx.ES.CreateIndexes(); err != nil {
+		return err
+	}
+
+	for _, network := range ctx.Config.Migrations.Networks {
+		diffs, err := ctx.ES.GetFirstBigMapDiffs(network)
+		if err != nil {
+			return err
+		}
+		logger.Info("Found %d big maps in %s", len(diffs), network)
+
+		var created int
+		bar := progressbar.NewOptions(len(diffs), progressbar.OptionSetPredictTime(false), progressbar.OptionClearOnFinish(), progressbar.OptionShowCount())
+		for i := range diffs {
+			bar.Add(1)
+
+			bigMap := models.BigMap{
+				ID:          models.BigMapID(network, diffs[i].Ptr),
+				Network:     network,
+				Ptr:         diffs[i].Ptr,
+				Address:     diffs[i].Address,
+				BinPath:     diffs[i].BinPath,
+				OperationID: diffs[i].OperationID,
+				Level:       diffs[i].Level,
+				Timestamp:   diffs[i].Timestamp,
+				Protocol:    diffs[i].Protocol,
+			}
+			if err := ctx.ES.CreateDocument(&bigMap); err != nil {
+				if elastic.IsDocumentExists(err) {
+					continue
+				}
+				return err
+			}
+			created++
+		}
+		logger.Info("Registered %d big maps in %s", created, network)
+	}
+
+	logger.Info("Time spent: %v", time.Since(start))
+	return nil
+}
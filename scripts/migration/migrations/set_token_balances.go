@@ -0,0 +1,74 @@
+package migrations
+
+import (
+	"strings"
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/config"
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/helpers"
+	"github.com/baking-bad/bcdhub/internal/logger"
+	"github.com/baking-bad/bcdhub/internal/metrics"
+	"github.com/schollz/progressbar/v3"
+)
+
+// SetTokenBalances - migration that builds balances of FA1.2 token holders from indexed operations
+type SetTokenBalances struct{}
+
+// Description -
+func (m *SetTokenBalances) Description() string {
+	return "build balances of FA1.2 token holders"
+}
+
+// Do - migrate function
+func (m *SetTokenBalances) Do(ctx *config.Context) error {
+	logger.Info("Start SetTokenBalances migration...")
+	start := time.Now()
+	h := metrics.New(ctx.ES, ctx.DB)
+
+	if err := ctx.ES.CreateIndexes(); err != nil {
+		return err
+	}
+
+	for _, network := range ctx.Config.Migrations.Networks {
+		contracts, err := ctx.ES.GetContracts(map[string]interface{}{
+			"network": network,
+		})
+		if err != nil {
+			return err
+		}
+		tokens := make(map[string]struct{})
+		for i := range contracts {
+			if helpers.StringInArray(consts.FA12Tag, contracts[i].Tags) {
+				tokens[contracts[i].Address] = struct{}{}
+			}
+		}
+		logger.Info("Found %d FA1.2 tokens in %s", len(tokens), network)
+		if len(tokens) == 0 {
+			continue
+		}
+
+		operations, err := ctx.ES.GetAllOperationsByStatus(network, consts.Applied)
+		if err != nil {
+			return err
+		}
+
+		bar := progressbar.NewOptions(len(operations), progressbar.OptionSetPredictTime(false), progressbar.OptionClearOnFinish(), progressbar.OptionShowCount())
+		for i := range operations {
+			bar.Add(1)
+
+			if !strings.HasPrefix(operations[i].Destination, "KT") {
+				continue
+			}
+			if _, ok := tokens[operations[i].Destination]; !ok {
+				continue
+			}
+			if err := h.SetTokenBalances(operations[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	logger.Info("Time spent: %v", time.Since(start))
+	return nil
+}
@@ -0,0 +1,72 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/config"
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/contractparser/meta"
+	"github.com/baking-bad/bcdhub/internal/helpers"
+	"github.com/baking-bad/bcdhub/internal/logger"
+	"github.com/baking-bad/bcdhub/internal/metrics"
+	"github.com/schollz/progressbar/v3"
+)
+
+// SetTokenMetadata - migration that extracts name, symbol and decimals of tokens from their last storage
+type SetTokenMetadata struct{}
+
+// Description -
+func (m *SetTokenMetadata) Description() string {
+	return "extract metadata of tokens"
+}
+
+// Do - migrate function
+func (m *SetTokenMetadata) Do(ctx *config.Context) error {
+	logger.Info("Start SetTokenMetadata migration...")
+	start := time.Now()
+	h := metrics.New(ctx.ES, ctx.DB)
+
+	for _, network := range ctx.Config.Migrations.Networks {
+		contracts, err := ctx.ES.GetContracts(map[string]interface{}{
+			"network": network,
+		})
+		if err != nil {
+			return err
+		}
+
+		var updated int
+		bar := progressbar.NewOptions(len(contracts), progressbar.OptionSetPredictTime(false), progressbar.OptionClearOnFinish(), progressbar.OptionShowCount())
+		for i := range contracts {
+			bar.Add(1)
+
+			if !helpers.StringInArray(consts.FA12Tag, contracts[i].Tags) && !helpers.StringInArray(consts.FA2Tag, contracts[i].Tags) && !helpers.StringInArray(consts.FA1Tag, contracts[i].Tags) {
+				continue
+			}
+
+			storage, err := ctx.ES.GetLastStorage(network, contracts[i].Address)
+			if err != nil {
+				return err
+			}
+			protocol := storage.Get("_source.protocol").String()
+			if protocol == "" {
+				continue
+			}
+			storageMetadata, err := meta.GetMetadata(ctx.ES, contracts[i].Address, consts.STORAGE, protocol)
+			if err != nil {
+				return err
+			}
+
+			ok, err := h.UpdateTokenMetadata(&contracts[i], storageMetadata, storage.Get("_source.deffated_storage").String(), storage.Get("_source.level").Int())
+			if err != nil {
+				return err
+			}
+			if ok {
+				updated++
+			}
+		}
+		logger.Info("Metadata of %d tokens is updated in %s", updated, network)
+	}
+
+	logger.Info("Time spent: %v", time.Since(start))
+	return nil
+}
@@ -0,0 +1,76 @@
+package migrations
+
+import (
+	"strings"
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/config"
+	"github.com/baking-bad/bcdhub/internal/contractparser/consts"
+	"github.com/baking-bad/bcdhub/internal/helpers"
+	"github.com/baking-bad/bcdhub/internal/logger"
+	"github.com/baking-bad/bcdhub/internal/metrics"
+	"github.com/schollz/progressbar/v3"
+)
+
+// SetTokenTransfers - migration that decodes transfers of FA1.2 and FA2 tokens from indexed operations
+type SetTokenTransfers struct{}
+
+// Description -
+func (m *SetTokenTransfers) Description() string {
+	return "decode transfers of FA1.2 and FA2 tokens"
+}
+
+// Do - migrate function
+func (m *SetTokenTransfers) Do(ctx *config.Context) error {
+	logger.Info("Start SetTokenTransfers migration...")
+	start := time.Now()
+	h := metrics.New(ctx.ES, ctx.DB)
+
+	if err := ctx.ES.CreateIndexes(); err != nil {
+		return err
+	}
+
+	for _, network := range ctx.Config.Migrations.Networks {
+		contracts, err := ctx.ES.GetContracts(map[string]interface{}{
+			"network": network,
+		})
+		if err != nil {
+			return err
+		}
+		tokens := make(map[string]struct{})
+		for i := range contracts {
+			if helpers.StringInArray(consts.FA12Tag, contracts[i].Tags) || helpers.StringInArray(consts.FA2Tag, contracts[i].Tags) {
+				tokens[contracts[i].Address] = struct{}{}
+			}
+		}
+		logger.Info("Found %d tokens in %s", len(tokens), network)
+		if len(tokens) == 0 {
+			continue
+		}
+
+		for _, status := range []string{consts.Applied, consts.Failed, consts.Backtracked} {
+			operations, err := ctx.ES.GetAllOperationsByStatus(network, status)
+			if err != nil {
+				return err
+			}
+
+			bar := progressbar.NewOptions(len(operations), progressbar.OptionSetPredictTime(false), progressbar.OptionClearOnFinish(), progressbar.OptionShowCount())
+			for i := range operations {
+				bar.Add(1)
+
+				if !strings.HasPrefix(operations[i].Destination, "KT") {
+					continue
+				}
+				if _, ok := tokens[operations[i].Destination]; !ok {
+					continue
+				}
+				if err := h.SetTokenTransfers(operations[i]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	logger.Info("Time spent: %v", time.Since(start))
+	return nil
+}
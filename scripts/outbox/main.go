@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/baking-bad/bcdhub/internal/config"
+	"github.com/baking-bad/bcdhub/internal/elastic"
+	"github.com/baking-bad/bcdhub/internal/logger"
+	"github.com/baking-bad/bcdhub/internal/mq"
+	"github.com/baking-bad/bcdhub/internal/outbox"
+)
+
+// defaultGrace - notifications younger than this are considered in progress
+const defaultGrace = 10 * time.Minute
+
+func main() {
+	cfg, err := config.LoadDefaultConfig()
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	network := os.Getenv("NETWORK")
+	if network == "" {
+		fmt.Println("Please, set NETWORK env variable")
+		return
+	}
+	if _, ok := cfg.RPC[network]; !ok {
+		logger.Fatal(fmt.Errorf("Unknown network %s", network))
+	}
+
+	es := config.NewStorage(cfg.Storage, elastic.WaitNew([]string{cfg.Elastic.URI}))
+	state, err := es.CurrentState(network)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	fromLevel, err := getLevel("FROM", 0)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	toLevel, err := getLevel("TO", state.Level)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	grace := defaultGrace
+	if value := os.Getenv("GRACE"); value != "" {
+		if grace, err = time.ParseDuration(value); err != nil {
+			logger.Fatal(fmt.Errorf("Invalid GRACE: %s", err))
+		}
+	}
+
+	logger.Info("Reconciling '%s' notifications in levels (%d, %d]...", network, fromLevel, toLevel)
+	report, err := outbox.Reconcile(es, network, fromLevel, toLevel, grace)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	logger.Info("Missing notifications: %d. Unprocessed notifications: %d", report.Missing, report.Unprocessed)
+
+	if report.Missing+report.Unprocessed == 0 {
+		logger.Success("Nothing to deliver")
+		return
+	}
+
+	messageQueue, err := mq.New(cfg.RabbitMQ.URI, cfg.RabbitMQ.Queues)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	defer messageQueue.Close()
+
+	relay := outbox.NewRelay(network, es, messageQueue)
+	for {
+		delivered, err := relay.Deliver()
+		if err != nil {
+			logger.Fatal(err)
+		}
+		if delivered == 0 {
+			break
+		}
+		logger.Info("Delivered %d notifications", delivered)
+	}
+	logger.Success("Done")
+}
+
+func getLevel(env string, defaultValue int64) (int64, error) {
+	value := os.Getenv(env)
+	if value == "" {
+		return defaultValue, nil
+	}
+	level, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s has to be in range 0..HEAD, not %s", env, value)
+	}
+	return level, nil
+}
@@ -45,7 +45,7 @@ func main() {
 		panic(err)
 	}
 
-	es := elastic.WaitNew([]string{cfg.Elastic.URI})
+	es := config.NewStorage(cfg.Storage, elastic.WaitNew([]string{cfg.Elastic.URI}))
 	state, err := es.CurrentState(network)
 	if err != nil {
 		panic(err)
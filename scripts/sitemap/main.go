@@ -63,6 +63,7 @@ func main() {
 
 	ctx := config.NewContext(
 		config.WithElasticSearch(cfg.Elastic),
+		config.WithStorage(cfg.Storage),
 		config.WithDatabase(cfg.DB),
 	)
 	defer ctx.Close()